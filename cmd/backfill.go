@@ -0,0 +1,101 @@
+package main
+
+import (
+	"tezos-delegation/internal/db"
+	"tezos-delegation/internal/services"
+
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// runBackfill implements the backfill subcommand, re-fetching the tracked operations in a time window
+// from Tzkt and storing any that are missing:
+//
+//	main backfill --from 2022-01-01 --to 2022-02-01 [--best-effort]
+//
+// It uses the same configuration as the service and exits once the window is done.
+func runBackfill(args []string) {
+	cfg := mustLoadConfig(setupLogger("info", "json"))
+	logger := setupLogger(cfg.LogLevel, cfg.LogFormat)
+
+	opts, err := parseBackfillArgs(args)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Invalid backfill arguments")
+	}
+
+	dbConn, err := initDB(cfg, logger, db.NewDBConnectionFromDSN)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Database connection error")
+	}
+	defer dbConn.Close()
+	if cfg.DBAutoMigrate {
+		mustMigrate(dbConn, logger)
+	}
+
+	pollerOpts := pollerOptions(cfg)
+	pollerOpts.BackfillBestEffort = opts.bestEffort
+	pollerService := services.NewPoller(db.NewDelegationRepository(dbConn, repositoryOptions(cfg, logger)), logger, pollerOpts)
+
+	// Stop between pages on SIGINT/SIGTERM; rows stored so far are kept
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	logger.Info().Time("from", opts.from).Time("to", opts.to).Bool("best_effort", opts.bestEffort).Msg("Starting backfill")
+	inserted, err := pollerService.BackfillRange(ctx, opts.from, opts.to)
+	if err != nil {
+		logger.Fatal().Err(err).Int64("inserted", inserted).Msg("Backfill error")
+	}
+	logger.Info().Int64("inserted", inserted).Msg("Backfill finished")
+}
+
+// backfillArgs holds the parsed flags of the backfill subcommand
+type backfillArgs struct {
+	from, to   time.Time
+	bestEffort bool
+}
+
+// parseBackfillArgs parses the flags of the backfill subcommand. --from and --to are required
+// and accept a date (2006-01-02, UTC) or an RFC 3339 timestamp; --best-effort is optional.
+func parseBackfillArgs(args []string) (opts backfillArgs, err error) {
+	fs := flag.NewFlagSet("backfill", flag.ContinueOnError)
+	fromStr := fs.String("from", "", "start of the window, inclusive (2006-01-02 or RFC 3339)")
+	toStr := fs.String("to", "", "end of the window, exclusive (2006-01-02 or RFC 3339)")
+	fs.BoolVar(&opts.bestEffort, "best-effort", false, "skip and log rows that fail to insert instead of stopping")
+	if err = fs.Parse(args); err != nil {
+		return opts, err
+	}
+	if fs.NArg() > 0 {
+		return opts, fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+	if *fromStr == "" || *toStr == "" {
+		return opts, errors.New("--from and --to are required")
+	}
+
+	if opts.from, err = parseBackfillTime(*fromStr); err != nil {
+		return opts, fmt.Errorf("invalid --from: %w", err)
+	}
+	if opts.to, err = parseBackfillTime(*toStr); err != nil {
+		return opts, fmt.Errorf("invalid --to: %w", err)
+	}
+	if !opts.from.Before(opts.to) {
+		return opts, fmt.Errorf("--to (%s) must be after --from (%s)", *toStr, *fromStr)
+	}
+	return opts, nil
+}
+
+// parseBackfillTime parses a date or an RFC 3339 timestamp
+func parseBackfillTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("must be a date (2006-01-02) or RFC 3339 timestamp, got %q", value)
+	}
+	return t, nil
+}
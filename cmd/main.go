@@ -2,13 +2,18 @@ package main
 
 import (
 	"tezos-delegation/internal/api"
+	"tezos-delegation/internal/apperrors"
 	"tezos-delegation/internal/config"
 	"tezos-delegation/internal/db"
 	"tezos-delegation/internal/services"
 
 	"context"
+	"errors"
+	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -20,120 +25,403 @@ import (
 
 // main is the entry point for the Tezos Delegation service.
 // It sets up configuration, database, services, HTTP server, poller, and graceful shutdown.
+// "main backfill --from --to" runs a one-off backfill instead (see runBackfill).
 func main() {
-	// --- Logger Setup ---
-	logger := setupLogger()
+	if len(os.Args) > 1 && os.Args[1] == "backfill" {
+		runBackfill(os.Args[2:])
+		return
+	}
 
 	// --- Config Load ---
-	cfg := mustLoadConfig(logger)
+	// Config errors are reported with the default logger, since the log settings come from config
+	cfg := mustLoadConfig(setupLogger("info", "json"))
+
+	// --- Logger Setup ---
+	logger := setupLogger(cfg.LogLevel, cfg.LogFormat)
+
+	logger.Info().Fields(cfg.LogFields()).Msg("Loaded configuration")
+	if cfg.ImportEnabled && cfg.Env == "production" {
+		logger.Warn().Msg("IMPORT_ENABLED is set in production: any client can write delegations through POST /xtz/delegations/import")
+	}
+
+	// Request-path logs can flood log storage under load, so they may be sampled; the poller and
+	// startup keep the unsampled logger, their logs are low-volume but important
+	requestLogger := logger
+	if cfg.LogSampling {
+		requestLogger = sampleLogger(logger, cfg.LogSampleRate)
+	}
 
 	// --- Database Init ---
-	dbConn := mustInitDB(cfg, logger)
+	dbConn, err := initDB(cfg, logger, db.NewDBConnectionFromDSN)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Database connection error")
+	}
 	defer dbConn.Close()
 
+	// The HTTP server starts before the migrations so probes are answered while they run; /ready and the
+	// delegation routes answer 503 until started is set
+	var started atomic.Bool
+
 	// --- Service and Handler Wiring ---
-	delegationRepo := db.NewDelegationRepository(dbConn)
-	pollerService := services.NewPoller(delegationRepo, logger)
-	delegationService := services.NewDelegationService(delegationRepo, logger)
-	delegationHandler := api.NewDelegationHandler(delegationService, logger)
+	delegationRepo := db.NewDelegationRepository(dbConn, repositoryOptions(cfg, logger))
+	// Read-only replicas leave pollerService nil and only serve queries
+	var pollerService *services.PollerService
+	if cfg.PollerEnabled {
+		pollerService = services.NewPoller(delegationRepo, logger, pollerOptions(cfg))
+	}
+	delegationServiceOpts := services.DelegationServiceOptions{MaxOffset: cfg.MaxOffset, MaxYear: cfg.MaxYear}
+	if pollerService != nil {
+		delegationServiceOpts.TzktLatest = pollerService.LatestTzktDelegation
+	}
+	delegationService := services.NewDelegationService(delegationRepo, requestLogger, delegationServiceOpts)
+	delegationHandler := api.NewDelegationHandler(delegationService, requestLogger, api.HandlerOptions{
+		CacheSize: cfg.ResponseCacheSize,
+		CacheTTL:  cfg.ResponseCacheTTL,
+		MaxYear:   cfg.MaxYear,
+	})
+	// Set on SIGTERM; /ready answers 503 while the delegation routes keep serving until shutdown
+	var draining atomic.Bool
+	healthOpts := services.HealthOptions{SkipSyncCheck: !cfg.PollerEnabled, Started: started.Load, Draining: draining.Load}
+	if pollerService != nil {
+		healthOpts.CircuitState = pollerService.CircuitState
+		healthOpts.SyncComplete = pollerService.HistoricalSyncComplete
+	}
+	healthService := services.NewHealthService(delegationRepo, requestLogger, healthOpts)
+	healthHandler := api.NewHealthHandler(healthService, requestLogger)
 
 	// --- HTTP Server Setup ---
-	app := setupHTTPServer(delegationHandler)
+	app := setupHTTPServer(delegationHandler, healthHandler, requestLogger, api.RouterOptions{
+		AccessLogSkipPaths:     cfg.AccessLogSkipPaths,
+		RelaxedSecurityHeaders: !cfg.SecurityHeadersStrict,
+		MaxURLLength:           cfg.MaxURLLength,
+		MaxHeaderBytes:         cfg.MaxHeaderBytes,
+		RequestTimeout:         cfg.RequestTimeout,
+		MaxConcurrentRequests:  cfg.MaxConcurrentRequests,
+		RateLimit:              cfg.RateLimit,
+		GzipLevel:              cfg.GzipLevel,
+		GzipContentTypes:       cfg.GzipContentTypes,
+		ImportEnabled:          cfg.ImportEnabled,
+		AdminToken:             cfg.AdminToken,
+		Ready:                  started.Load,
+	})
 
 	// --- Signal Handling ---
 	quit := setupSignalHandler()
+	stopReload := setupReloadHandler(cfg.ReloadLogLevel, logger)
+	defer stopReload()
+
+	// --- HTTP Server Start ---
+	go startHTTPServer(app, cfg.ServerPort, logger)
+
+	// --- Migrations ---
+	if cfg.DBAutoMigrate {
+		mustMigrate(dbConn, logger)
+	}
+	if err := dbConn.PingContext(context.Background()); err != nil {
+		logger.Fatal().Err(err).Msg("Database health check failed")
+	}
+	started.Store(true)
+	logger.Info().Msg("Startup complete, serving queries")
 
 	// --- Poller Start ---
 	pollerCtx, cancelPoller := context.WithCancel(context.Background())
 	defer cancelPoller()
-	pollerService.Start(pollerCtx)
-
-	// --- HTTP Server Start ---
-	go startHTTPServer(app, cfg.ServerPort, logger)
+	if pollerService != nil {
+		pollerService.Start(pollerCtx)
+	} else {
+		logger.Info().Msg("Poller disabled, running in read-only mode")
+	}
 
 	// --- Graceful Shutdown ---
-	waitForShutdown(quit, app, pollerService, cancelPoller, logger)
+	waitForShutdown(quit, app, pollerService, cancelPoller, shutdownOptions{
+		Draining:      &draining,
+		DrainDelay:    cfg.ShutdownDrainDelay,
+		PollerTimeout: cfg.ShutdownPollerTimeout,
+		HTTPTimeout:   cfg.ShutdownHTTPTimeout,
+	}, logger)
 }
 
-func setupLogger() zerolog.Logger {
+// setupLogger creates the root logger writing to stdout.
+// format is "json" or "console" (human-readable, colored); level is a zerolog level name.
+// Invalid values are reported as a warning and fall back to json output at info level.
+func setupLogger(level, format string) zerolog.Logger {
 	zerolog.TimeFieldFormat = time.RFC3339
-	return zerolog.New(os.Stdout).With().Timestamp().Logger()
+
+	var logger zerolog.Logger
+	invalidFormat := false
+	switch format {
+	case "json":
+		logger = zerolog.New(os.Stdout).With().Timestamp().Logger()
+	case "console":
+		logger = zerolog.New(zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: time.RFC3339}).With().Timestamp().Logger()
+	default:
+		invalidFormat = true
+		logger = zerolog.New(os.Stdout).With().Timestamp().Logger()
+	}
+
+	lvl, err := parseLogLevel(level)
+	if err != nil {
+		lvl = zerolog.InfoLevel
+	}
+	zerolog.SetGlobalLevel(lvl)
+
+	if invalidFormat {
+		logger.Warn().Str("LOG_FORMAT", format).Msg("Invalid log format, falling back to json")
+	}
+	if err != nil {
+		logger.Warn().Err(err).Str("LOG_LEVEL", level).Msg("Invalid log level, falling back to info")
+	}
+	return logger
+}
+
+// sampleLogger returns logger keeping only one in every n trace, debug and info events.
+// Warnings and errors are never sampled.
+func sampleLogger(logger zerolog.Logger, n int) zerolog.Logger {
+	sampler := &zerolog.BasicSampler{N: uint32(n)}
+	return logger.Sample(zerolog.LevelSampler{
+		TraceSampler: sampler,
+		DebugSampler: sampler,
+		InfoSampler:  sampler,
+	})
+}
+
+// parseLogLevel parses one of the supported LOG_LEVEL values
+func parseLogLevel(level string) (zerolog.Level, error) {
+	switch level {
+	case "trace":
+		return zerolog.TraceLevel, nil
+	case "debug":
+		return zerolog.DebugLevel, nil
+	case "info":
+		return zerolog.InfoLevel, nil
+	case "warn":
+		return zerolog.WarnLevel, nil
+	case "error":
+		return zerolog.ErrorLevel, nil
+	default:
+		return zerolog.NoLevel, fmt.Errorf("must be one of trace, debug, info, warn, error, got %q", level)
+	}
 }
 
 func mustLoadConfig(logger zerolog.Logger) *config.Config {
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		logger.Fatal().Err(err).Msg("Load config error")
+		event := logger.Fatal().Err(err)
+		var configErr *apperrors.ConfigurationError
+		if errors.As(err, &configErr) && configErr.Key != "" {
+			event = event.Str("key", configErr.Key)
+		}
+		event.Msg("Invalid configuration, check the environment variables")
 	}
 	return cfg
 }
 
-func mustInitDB(cfg *config.Config, logger zerolog.Logger) *sql.DB {
-	const maxRetries = 10
-	const retryDelay = 1 * time.Second
+// repositoryOptions maps the repository settings from cfg, logging slow queries to logger
+func repositoryOptions(cfg *config.Config, logger zerolog.Logger) db.DelegationRepositoryOptions {
+	return db.DelegationRepositoryOptions{
+		UpsertMode:         cfg.PollerUpsertMode,
+		InsertChunkSize:    cfg.DBInsertChunkSize,
+		ReadRetries:        cfg.DBReadRetries,
+		Location:           cfg.Timezone,
+		SlowQueryThreshold: cfg.SlowQueryThreshold,
+		Logger:             logger.With().Str("component", "DelegationRepository").Logger(),
+	}
+}
+
+// pollerOptions maps the poller settings from cfg
+func pollerOptions(cfg *config.Config) services.PollerOptions {
+	return services.PollerOptions{
+		VerifyInserts:     cfg.PollerVerifyInserts,
+		HistoricalWorkers: cfg.PollerHistoricalWorkers,
+		RateLimit:         cfg.TzktRateLimit,
+		TrackOriginations: cfg.PollerTrackOriginations,
+		AnalyzeAfterSync:  cfg.PollerAnalyzeAfterSync,
+		APIKey:            cfg.TzktAPIKey,
+		BaseURL:           cfg.TzktBaseURL,
+		PageSize:          cfg.TzktPageSize,
+		InsertBatchSize:   cfg.PollerInsertBatchSize,
+		LockRetryInterval: cfg.PollerLockRetryInterval,
+		Retry: services.RetryPolicy{
+			MaxRetries:     cfg.PollerMaxRetries,
+			InitialBackoff: cfg.PollerInitialBackoff,
+			MaxTotalWait:   cfg.PollerMaxTotalWait,
+		},
+		Breaker: services.BreakerPolicy{
+			Threshold: cfg.PollerBreakerThreshold,
+			Cooldown:  cfg.PollerBreakerCooldown,
+		},
+		Transport: services.TransportPolicy{
+			MaxIdleConns:        cfg.TzktTransport.MaxIdleConns,
+			MaxIdleConnsPerHost: cfg.TzktTransport.MaxIdleConnsPerHost,
+			IdleConnTimeout:     cfg.TzktTransport.IdleConnTimeout,
+			TLSHandshakeTimeout: cfg.TzktTransport.TLSHandshakeTimeout,
+			RequestTimeout:      cfg.TzktTransport.RequestTimeout,
+		},
+		KeepEmptyDelegators: !cfg.PollerSkipEmptyDelegator,
+	}
+}
+
+// dbConnectAttempts is how many times startup tries to connect before giving up, since Postgres may still be starting
+const dbConnectAttempts = 10
+
+// dbConnectRetryDelay is the pause between startup connection attempts; tests shorten it
+var dbConnectRetryDelay = 1 * time.Second
+
+// dbConnector opens a database connection from a DSN and checks that it is usable
+type dbConnector func(dsn string) (*sql.DB, error)
+
+// initDB connects to the database with connect, or db.NewDBConnectionFromDSN if nil, making up to
+// dbConnectAttempts attempts dbConnectRetryDelay apart. Returns the last connection error if every attempt fails.
+func initDB(cfg *config.Config, logger zerolog.Logger, connect dbConnector) (*sql.DB, error) {
+	if connect == nil {
+		connect = db.NewDBConnectionFromDSN
+	}
 
 	var lastErr error
-	attempt := 0
-	for {
-		dbConn, err := db.NewDBConnectionFromDSN(cfg.DBUrl)
+	for attempt := 1; attempt <= dbConnectAttempts; attempt++ {
+		dbConn, err := connect(cfg.DBUrl)
 		if err == nil {
 			logger.Info().Int("attempt", attempt).Str("ssl_mode", cfg.SSLMode).Msg("Database connection established successfully")
-			return dbConn
+			return dbConn, nil
 		}
+		lastErr = err
 
-		logger.Warn().Err(err).Int("attempt", attempt).Int("maxRetries", maxRetries).Str("dsn", cfg.GetMaskedDBUrl()).Msg("Database connection attempt failed")
-
-		if attempt <= maxRetries {
-			logger.Info().Int("attempt", attempt).Dur("delay", retryDelay).Msg("Retrying database connection")
-			time.Sleep(retryDelay)
-			attempt++
-			continue
+		logger.Warn().Err(err).Int("attempt", attempt).Int("max_attempts", dbConnectAttempts).Str("dsn", cfg.GetMaskedDBUrl()).Msg("Database connection attempt failed")
+		if attempt < dbConnectAttempts {
+			logger.Info().Int("attempt", attempt).Dur("delay", dbConnectRetryDelay).Msg("Retrying database connection")
+			time.Sleep(dbConnectRetryDelay)
 		}
-		logger.Fatal().Err(lastErr).Msg("Database connection error after all retries")
-
 	}
+	return nil, fmt.Errorf("database connection failed after %d attempts: %w", dbConnectAttempts, lastErr)
+}
 
-	// This should never be reached
+// mustMigrate applies pending schema migrations, exiting if any of them fails
+func mustMigrate(dbConn *sql.DB, logger zerolog.Logger) {
+	applied, err := db.Migrate(context.Background(), dbConn)
+	if err != nil {
+		logger.Fatal().Err(err).Int("applied", applied).Msg("Database migration error")
+	}
+	logger.Info().Int("applied", applied).Msg("Database migrations complete")
 }
 
-func setupHTTPServer(delegationHandler *api.DelegationHandler) *iris.Application {
+func setupHTTPServer(delegationHandler *api.DelegationHandler, healthHandler *api.HealthHandler, logger zerolog.Logger, opts api.RouterOptions) *iris.Application {
 	app := iris.New()
-	api.RegisterRoutes(app, delegationHandler)
+	api.RegisterRoutes(app, delegationHandler, healthHandler, logger, opts)
 	return app
 }
 
+// shutdownSignals start a graceful shutdown; reloadSignal re-reads LOG_LEVEL instead of killing the process
+var (
+	shutdownSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM}
+	reloadSignal    = syscall.SIGHUP
+)
+
 func setupSignalHandler() chan os.Signal {
 	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(quit, shutdownSignals...)
 	return quit
 }
 
+// setupReloadHandler applies the log level returned by readLevel, e.g. Config.ReloadLogLevel, each time
+// reloadSignal is received, until the returned function is called
+func setupReloadHandler(readLevel func() string, logger zerolog.Logger) func() {
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, reloadSignal)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range reload {
+			reloadLogLevel(readLevel(), logger)
+		}
+	}()
+	return func() {
+		signal.Stop(reload)
+		close(reload)
+		<-done
+	}
+}
+
+// reloadLogLevel sets the global log level to level. An invalid level is reported and the current one
+// kept, as the service is already running.
+func reloadLogLevel(level string, logger zerolog.Logger) {
+	lvl, err := parseLogLevel(level)
+	if err != nil {
+		logger.Warn().Err(err).Str("LOG_LEVEL", level).Msg("Invalid log level on reload, keeping the current level")
+		return
+	}
+	previous := zerolog.GlobalLevel()
+	zerolog.SetGlobalLevel(lvl)
+	logger.WithLevel(zerolog.NoLevel).Str("previous", previous.String()).Str("level", lvl.String()).Msg("Reloaded log level")
+}
+
+// startHTTPServer serves app on port until it is shut down. Failing to listen, e.g. because another
+// process holds the port, is fatal.
 func startHTTPServer(app *iris.Application, port string, logger zerolog.Logger) {
-	if err := app.Listen(":"+port, iris.WithoutInterruptHandler); err != nil {
-		logger.Fatal().Err(err).Msg("HTTP server error")
+	if err := listenHTTP(app, ":"+port); err != nil {
+		logger.Fatal().Err(err).Str("port", port).Msg("HTTP server error")
+	}
+}
+
+// listenHTTP serves app on addr until it is shut down. A graceful shutdown returns nil; a port that is
+// already taken is reported as such rather than as a bare bind error.
+func listenHTTP(app *iris.Application, addr string) error {
+	err := app.Listen(addr, iris.WithoutInterruptHandler)
+	switch {
+	case err == nil, errors.Is(err, http.ErrServerClosed):
+		return nil
+	case errors.Is(err, syscall.EADDRINUSE):
+		return fmt.Errorf("address %s already in use, is another instance running or SERVER_PORT taken? %w", addr, err)
+	default:
+		return err
 	}
 }
 
-func waitForShutdown(quit <-chan os.Signal, app *iris.Application, pollerService *services.PollerService, cancelPoller context.CancelFunc, logger zerolog.Logger) {
+// shutdownOptions holds the graceful shutdown settings loaded from configuration.
+type shutdownOptions struct {
+	Draining      *atomic.Bool  // Set when shutdown begins, failing readiness checks; may be nil
+	DrainDelay    time.Duration // How long to keep serving with readiness failing before stopping anything
+	PollerTimeout time.Duration // How long to wait for the poller to stop
+	HTTPTimeout   time.Duration // How long to wait for in-flight HTTP requests to finish
+}
+
+// drain marks the service as draining, so /ready answers 503 and load balancers stop routing new
+// requests to it, then waits delay for them to notice while requests are still served.
+func drain(draining *atomic.Bool, delay time.Duration, logger zerolog.Logger) {
+	if draining != nil {
+		draining.Store(true)
+	}
+	if delay > 0 {
+		logger.Info().Dur("delay", delay).Msg("Draining: reporting not ready before shutting down")
+		time.Sleep(delay)
+	}
+}
+
+// waitForShutdown blocks until a shutdown signal, drains traffic for opts.DrainDelay, then stops the poller
+// and the HTTP server, giving each up to its timeout to finish in-flight work. pollerService is nil when the
+// poller is disabled.
+func waitForShutdown(quit <-chan os.Signal, app *iris.Application, pollerService *services.PollerService, cancelPoller context.CancelFunc, opts shutdownOptions, logger zerolog.Logger) {
 	<-quit
+	drain(opts.Draining, opts.DrainDelay, logger)
 	app.Logger().Info("Shutting down server...")
 
 	// Stop poller and wait for completion
-	logger.Info().Msg("Shutting down poller")
 	cancelPoller()
-	done := make(chan struct{})
-	go func() {
-		pollerService.Wait()
-		close(done)
-	}()
-	select {
-	case <-done:
-		logger.Info().Msg("Poller shut down cleanly")
-	case <-time.After(5 * time.Second):
-		logger.Warn().Msg("WARNING: Poller did not shut down within 5 seconds, forcing exit")
+	if pollerService != nil {
+		logger.Info().Msg("Shutting down poller")
+		done := make(chan struct{})
+		go func() {
+			pollerService.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+			logger.Info().Msg("Poller shut down cleanly")
+		case <-time.After(opts.PollerTimeout):
+			logger.Warn().Dur("timeout", opts.PollerTimeout).Msg("WARNING: Poller did not shut down within the timeout, forcing exit")
+		}
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), opts.HTTPTimeout)
 	defer cancel()
 	if err := app.Shutdown(ctx); err != nil {
 		logger.Fatal().Err(err).Msg("HTTP Server forced to shutdown")
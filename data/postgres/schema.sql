@@ -7,7 +7,8 @@ CREATE TABLE IF NOT EXISTS delegations (
     timestamp TIMESTAMP NOT NULL,       -- UTC timestamp of the delegation operation
     amount BIGINT NOT NULL,             -- Amount delegated (in mutez, 1 tez = 1,000,000 mutez)
     delegator TEXT NOT NULL,            -- Sender's (delegator's) address
-    level BIGINT NOT NULL               -- Block height of the delegation
+    level BIGINT NOT NULL,              -- Block height of the delegation
+    type TEXT NOT NULL DEFAULT 'delegation' -- Operation type: 'delegation' or 'origination'
 );
 
 
@@ -18,5 +19,15 @@ ALTER TABLE delegations ADD CONSTRAINT chk_level_non_negative CHECK (level >= 0)
 
 -- Create indexes for performance
 CREATE INDEX IF NOT EXISTS idx_timestamp_tzkt_id_desc ON delegations (timestamp DESC, tzkt_id DESC);
-CREATE INDEX IF NOT EXISTS idx_year_timestamp_tzkt_id_desc ON delegations (EXTRACT(YEAR FROM timestamp), timestamp DESC, tzkt_id DESC);
+CREATE INDEX IF NOT EXISTS idx_type_timestamp_tzkt_id_desc ON delegations (type, timestamp DESC, tzkt_id DESC);
+CREATE INDEX IF NOT EXISTS idx_delegator_timestamp ON delegations (delegator, timestamp);
 
+
+
+-- Poller sync state (single row) so restarts can resume and report progress
+CREATE TABLE IF NOT EXISTS sync_state (
+    id SMALLINT PRIMARY KEY DEFAULT 1 CHECK (id = 1),   -- Enforces a single row
+    last_tzkt_id BIGINT NOT NULL DEFAULT 0,             -- Highest Tzkt ID processed by the poller
+    last_poll_at TIMESTAMP,                             -- UTC time of the last successful batch
+    historical_complete BOOLEAN NOT NULL DEFAULT FALSE  -- Whether the initial historical sync has finished
+);
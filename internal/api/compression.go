@@ -0,0 +1,114 @@
+package api
+
+import (
+	"compress/gzip"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// gzipRouterWrapper compresses responses whose media type is in contentTypes with gzip at level, for
+// clients that accept it. It wraps the whole router, so every route and error response is covered.
+// The decision is made when the response header is written, as only then is the content type known;
+// bodiless responses and those already carrying a Content-Encoding are passed through.
+func gzipRouterWrapper(level int, contentTypes []string) func(http.ResponseWriter, *http.Request, http.HandlerFunc) {
+	types := make(map[string]bool, len(contentTypes))
+	for _, contentType := range contentTypes {
+		types[strings.ToLower(contentType)] = true
+	}
+
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		gw := &gzipResponseWriter{ResponseWriter: w, level: level, types: types, accepted: acceptsGzip(r.Header.Get("Accept-Encoding"))}
+		defer gw.close()
+		next(gw, r)
+	}
+}
+
+// acceptsGzip reports whether an Accept-Encoding header value allows a gzip response
+func acceptsGzip(acceptEncoding string) bool {
+	for _, coding := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(coding, ";")
+		if name = strings.TrimSpace(name); name != "gzip" && name != "*" {
+			continue
+		}
+		q, ok := strings.CutPrefix(strings.TrimSpace(params), "q=")
+		if !ok {
+			return true
+		}
+		if weight, err := strconv.ParseFloat(q, 64); err == nil && weight > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// gzipResponseWriter compresses the response body once WriteHeader has found its media type in types
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	level       int
+	types       map[string]bool
+	accepted    bool // Whether the client accepts gzip
+	wroteHeader bool
+	gz          *gzip.Writer // nil unless the response is compressed
+}
+
+// compressible reports whether a response with status and the headers set so far gets compressed
+func (w *gzipResponseWriter) compressible(status int) bool {
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified {
+		return false
+	}
+	header := w.Header()
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil || !w.types[mediaType] {
+		return false
+	}
+	// Caches must tell clients that do and don't accept gzip apart, whichever this one does
+	header.Add("Vary", "Accept-Encoding")
+	return w.accepted
+}
+
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if w.compressible(status) {
+		header := w.Header()
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		// The level is validated when the configuration is loaded, so this can't fail
+		w.gz, _ = gzip.NewWriterLevel(w.ResponseWriter, w.level)
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.gz != nil {
+		return w.gz.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush sends the data compressed so far to the client, so streamed exports still arrive incrementally
+func (w *gzipResponseWriter) Flush() {
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// close writes the gzip footer once the response is complete
+func (w *gzipResponseWriter) close() {
+	if w.gz != nil {
+		w.gz.Close()
+	}
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"tezos-delegation/internal/mocks"
+	"tezos-delegation/internal/model"
+
+	"github.com/golang/mock/gomock"
+	"github.com/kataras/iris/v12"
+	"github.com/kataras/iris/v12/httptest"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGzipRouterWrapper(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockDelegationServicePort(ctrl)
+	handler := NewDelegationHandler(service, zerolog.Nop(), HandlerOptions{})
+
+	app := iris.New()
+	app.WrapRouter(gzipRouterWrapper(gzip.BestSpeed, []string{"application/json", "text/csv"}))
+	app.Get("/xtz/delegations.csv", handler.ExportDelegationsCSV)
+	app.Get("/binary", func(ctx iris.Context) {
+		ctx.ContentType("application/octet-stream")
+		ctx.Write([]byte{0x00, 0x01, 0x02})
+	})
+	app.Get("/not-modified", func(ctx iris.Context) {
+		ctx.ContentType(contentTypeJSON)
+		ctx.StatusCode(http.StatusNotModified)
+	})
+	test := httptest.New(t, app)
+
+	const csvBody = "timestamp,amount,delegator,level,tzkt_id\n2022-05-05T06:29:14Z,100,tz1,7,1\n"
+	streamOne := func(_ context.Context, _ model.DelegationFilter, fn func(model.Delegation) error) error {
+		return fn(model.Delegation{TzktID: 1, Delegator: "tz1", Amount: 100, Level: 7, Timestamp: fixedTime()})
+	}
+
+	t.Run("csv compressed", func(t *testing.T) {
+		service.EXPECT().StreamDelegations(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(streamOne)
+
+		resp := test.GET("/xtz/delegations.csv").WithHeader("Accept-Encoding", "gzip, deflate").Expect().Status(http.StatusOK)
+		resp.Header("Content-Encoding").IsEqual("gzip")
+		resp.Header("Vary").IsEqual("Accept-Encoding")
+		resp.Header("Content-Type").HasPrefix("text/csv")
+
+		reader, err := gzip.NewReader(bytes.NewReader([]byte(resp.Body().Raw())))
+		if assert.NoError(t, err) {
+			body, err := io.ReadAll(reader)
+			assert.NoError(t, err)
+			assert.Equal(t, csvBody, string(body))
+		}
+	})
+
+	t.Run("client without gzip", func(t *testing.T) {
+		service.EXPECT().StreamDelegations(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(streamOne)
+
+		resp := test.GET("/xtz/delegations.csv").Expect().Status(http.StatusOK)
+		resp.Header("Content-Encoding").IsEmpty()
+		resp.Header("Vary").IsEqual("Accept-Encoding")
+		resp.Body().IsEqual(csvBody)
+	})
+
+	t.Run("unlisted content type passed through", func(t *testing.T) {
+		resp := test.GET("/binary").WithHeader("Accept-Encoding", "gzip").Expect().Status(http.StatusOK)
+		resp.Header("Content-Encoding").IsEmpty()
+		resp.Header("Vary").IsEmpty()
+		assert.Equal(t, []byte{0x00, 0x01, 0x02}, []byte(resp.Body().Raw()))
+	})
+
+	t.Run("bodiless response passed through", func(t *testing.T) {
+		resp := test.GET("/not-modified").WithHeader("Accept-Encoding", "gzip").Expect().Status(http.StatusNotModified)
+		resp.Header("Content-Encoding").IsEmpty()
+	})
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"", false},
+		{"gzip", true},
+		{"deflate, gzip;q=0.5", true},
+		{"gzip;q=0", false},
+		{"br, *", true},
+		{"identity", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			assert.Equal(t, tt.want, acceptsGzip(tt.header))
+		})
+	}
+}
@@ -1,12 +1,249 @@
 package api
 
+import (
+	"encoding/xml"
+	"time"
+)
+
+// Machine-readable error codes returned in ErrorResponse.Code. Codes are stable; messages may change.
+const (
+	CodeInvalidPage          = "INVALID_PAGE"
+	CodeInvalidPageSize      = "INVALID_PAGE_SIZE"
+	CodeInvalidYear          = "INVALID_YEAR"
+	CodeInvalidSinceTzktID   = "INVALID_SINCE_TZKT_ID"
+	CodeInvalidMaxID         = "INVALID_MAX_ID"
+	CodeInvalidType          = "INVALID_TYPE"
+	CodeInvalidMinLevel      = "INVALID_MIN_LEVEL"
+	CodeInvalidAccountType   = "INVALID_ACCOUNT_TYPE"
+	CodeInvalidSnapshot      = "INVALID_SNAPSHOT"
+	CodeInvalidLinks         = "INVALID_LINKS"
+	CodeInvalidNumeric       = "INVALID_NUMERIC"
+	CodeInvalidTimeFormat    = "INVALID_TIME_FORMAT"
+	CodeInvalidCountOnly     = "INVALID_COUNT_ONLY"
+	CodeInvalidCount         = "INVALID_COUNT"
+	CodeInvalidLimit         = "INVALID_LIMIT"
+	CodeInvalidTzktID        = "INVALID_TZKT_ID"
+	CodeInvalidFields        = "INVALID_FIELDS"
+	CodeInvalidAddress       = "INVALID_ADDRESS"
+	CodeInvalidBody          = "INVALID_BODY"
+	CodeOffsetTooLarge       = "OFFSET_TOO_LARGE"
+	CodeInvalidRequest       = "INVALID_REQUEST" // Validation failed in the service layer
+	CodeUnauthorized         = "UNAUTHORIZED"
+	CodeNotFound             = "NOT_FOUND"
+	CodeMethodNotAllowed     = "METHOD_NOT_ALLOWED"
+	CodeNotAcceptable        = "NOT_ACCEPTABLE"
+	CodeBodyTooLarge         = "BODY_TOO_LARGE"
+	CodeUnsupportedMediaType = "UNSUPPORTED_MEDIA_TYPE"
+	CodeURITooLong           = "URI_TOO_LONG"
+	CodeRateLimited          = "RATE_LIMITED"
+	CodeHeadersTooLarge      = "HEADERS_TOO_LARGE"
+	CodeDatabaseError        = "DATABASE_ERROR"
+	CodeDBUnavailable        = "DATABASE_UNAVAILABLE"
+	CodeNotReady             = "NOT_READY"
+	CodeRequestTimeout       = "REQUEST_TIMEOUT"
+	CodeTzktUnavailable      = "TZKT_UNAVAILABLE"
+	CodeOverloaded           = "OVERLOADED"
+	CodeInternalError        = "INTERNAL_ERROR"
+)
+
+// ErrorResponse is the body of every error response
+type ErrorResponse struct {
+	Error   string        `json:"error"`             // Human-readable message
+	Code    string        `json:"code"`              // One of the Code* constants
+	Details []ErrorDetail `json:"details,omitempty"` // Per-field reasons, when the service rejected a parameter
+}
+
+// ErrorDetail names a rejected request parameter and why it was rejected, for clients that show errors per field
+type ErrorDetail struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
 type DelegationDto struct {
-	Timestamp string `json:"timestamp"`
-	Amount    string `json:"amount"`
-	Delegator string `json:"delegator"`
-	Level     string `json:"level"`
+	Timestamp string `json:"timestamp" xml:"timestamp"`
+	Amount    string `json:"amount" xml:"amount"`
+	Delegator string `json:"delegator" xml:"delegator"`
+	Level     string `json:"level" xml:"level"`
+}
+
+// NumericDelegationDto is DelegationDto with amount and level as JSON integers, returned with numeric=true.
+// Both fit in an int64, which encoding/json writes out exactly.
+type NumericDelegationDto struct {
+	Timestamp string `json:"timestamp" xml:"timestamp"`
+	Amount    int64  `json:"amount" xml:"amount"`
+	Delegator string `json:"delegator" xml:"delegator"`
+	Level     int64  `json:"level" xml:"level"`
+}
+
+// delegationFields lists the DelegationDto fields that can be selected with the fields parameter, in output order
+var delegationFields = []string{"timestamp", "amount", "delegator", "level"}
+
+// sparseDelegationDto holds the selected subset of a DelegationDto's fields, keyed by field name
+type sparseDelegationDto map[string]any
+
+// MarshalXML encodes the selected fields as child elements in delegationFields order, since maps have no XML encoding
+func (d sparseDelegationDto) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, name := range delegationFields {
+		if value, ok := d[name]; ok {
+			if err := e.EncodeElement(value, xml.StartElement{Name: xml.Name{Local: name}}); err != nil {
+				return err
+			}
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// PageMeta describes the position of a page without counting the full result set
+type PageMeta struct {
+	Page     int  `json:"page" xml:"page"`
+	PageSize int  `json:"page_size" xml:"page_size"`
+	HasNext  bool `json:"has_next" xml:"has_next"`
+	HasPrev  bool `json:"has_prev" xml:"has_prev"`
 }
 
+// PageLinks holds the URLs of the current, next and previous pages, with the request's filters preserved.
+// Next is empty on the last page and Prev on the first.
+type PageLinks struct {
+	Self string `json:"self" xml:"self"`
+	Next string `json:"next,omitempty" xml:"next,omitempty"`
+	Prev string `json:"prev,omitempty" xml:"prev,omitempty"`
+}
+
+// GetDelegationsResponse is served as JSON by default, or as XML with a <delegations> root element
 type GetDelegationsResponse struct {
-	Data []DelegationDto `json:"data"`
+	XMLName       xml.Name        `json:"-" xml:"delegations"`
+	Data          []DelegationDto `json:"data" xml:"data>delegation"`
+	Meta          PageMeta        `json:"meta" xml:"meta"`
+	SnapshotMaxID *int64          `json:"snapshot_max_id,omitempty" xml:"snapshot_max_id,omitempty"`
+	Links         *PageLinks      `json:"_links,omitempty" xml:"links,omitempty"` // Only with links=true
+}
+
+// getSparseDelegationsResponse is GetDelegationsResponse with a field selection applied to each delegation
+type getSparseDelegationsResponse struct {
+	XMLName       xml.Name              `json:"-" xml:"delegations"`
+	Data          []sparseDelegationDto `json:"data" xml:"data>delegation"`
+	Meta          PageMeta              `json:"meta" xml:"meta"`
+	SnapshotMaxID *int64                `json:"snapshot_max_id,omitempty" xml:"snapshot_max_id,omitempty"`
+	Links         *PageLinks            `json:"_links,omitempty" xml:"links,omitempty"`
+}
+
+// getNumericDelegationsResponse is GetDelegationsResponse with numeric amounts and levels
+type getNumericDelegationsResponse struct {
+	XMLName       xml.Name               `json:"-" xml:"delegations"`
+	Data          []NumericDelegationDto `json:"data" xml:"data>delegation"`
+	Meta          PageMeta               `json:"meta" xml:"meta"`
+	SnapshotMaxID *int64                 `json:"snapshot_max_id,omitempty" xml:"snapshot_max_id,omitempty"`
+	Links         *PageLinks             `json:"_links,omitempty" xml:"links,omitempty"`
+}
+
+type GetDelegationResponse struct {
+	Data DelegationDto `json:"data"`
+}
+
+// getNumericDelegationResponse is GetDelegationResponse with a numeric amount and level
+type getNumericDelegationResponse struct {
+	Data NumericDelegationDto `json:"data"`
+}
+
+type YearStatsDto struct {
+	Year           int    `json:"year"`
+	Count          int64  `json:"count"`
+	TotalAmount    string `json:"total_amount"`     // mutez
+	TotalAmountTez string `json:"total_amount_tez"` // tez, with 6 decimal places
+}
+
+type GetStatsByYearResponse struct {
+	Data []YearStatsDto `json:"data"`
+}
+
+type MonthStatsDto struct {
+	Month          int    `json:"month"` // 1 (January) to 12 (December)
+	Count          int64  `json:"count"`
+	TotalAmount    string `json:"total_amount"`     // mutez
+	TotalAmountTez string `json:"total_amount_tez"` // tez, with 6 decimal places
+}
+
+type GetMonthlyStatsResponse struct {
+	Year int             `json:"year"`
+	Data []MonthStatsDto `json:"data"`
+}
+
+type DelegatorStatsDto struct {
+	Delegator      string `json:"delegator"`
+	Count          int64  `json:"count"`
+	TotalAmount    string `json:"total_amount"`     // mutez
+	TotalAmountTez string `json:"total_amount_tez"` // tez, with 6 decimal places
+}
+
+type GetTopDelegatorsResponse struct {
+	Data []DelegatorStatsDto `json:"data"`
+}
+
+type GetDistinctDelegatorsResponse struct {
+	DistinctDelegators int64 `json:"distinct_delegators"`
+}
+
+type DelegatorSummaryDto struct {
+	Delegator      string `json:"delegator"`
+	FirstSeen      string `json:"first_seen"` // RFC3339, UTC
+	LastSeen       string `json:"last_seen"`  // RFC3339, UTC
+	Count          int64  `json:"count"`
+	TotalAmount    string `json:"total_amount"`     // mutez
+	TotalAmountTez string `json:"total_amount_tez"` // tez, with 6 decimal places
+}
+
+type GetDelegatorSummaryResponse struct {
+	Data DelegatorSummaryDto `json:"data"`
+}
+
+// SyncPointDto identifies a delegation the sync status compares
+type SyncPointDto struct {
+	TzktID    int64  `json:"tzkt_id"`
+	Timestamp string `json:"timestamp"` // RFC3339, UTC
+}
+
+// SyncStatusDto reports how far the stored delegations are behind Tzkt. The lag is null unless both
+// sides are known; lag_tzkt_ids is a gap in the Tzkt ID space all operation types share, not a count.
+type SyncStatusDto struct {
+	Latest     *SyncPointDto `json:"latest"`      // Newest stored delegation; null if none is stored
+	TzktLatest *SyncPointDto `json:"tzkt_latest"` // Newest delegation on Tzkt; null if this instance doesn't poll Tzkt
+	LagTzktIDs *int64        `json:"lag_tzkt_ids"`
+	LagSeconds *int64        `json:"lag_seconds"`
+}
+
+type GetSyncStatusResponse struct {
+	Data SyncStatusDto `json:"data"`
+}
+
+// ImportDelegationDto is one delegation of an import request. Unlike DelegationDto, amounts and levels
+// are plain JSON numbers and the Tzkt ID is included, since it is the key delegations are stored under.
+type ImportDelegationDto struct {
+	TzktID    int64     `json:"tzkt_id"`
+	Timestamp time.Time `json:"timestamp"` // RFC3339
+	Amount    int64     `json:"amount"`    // mutez
+	Delegator string    `json:"delegator"`
+	Level     int64     `json:"level"`
+	Type      string    `json:"type,omitempty"` // "delegation" (default) or "origination"
+}
+
+// ImportErrorDto reports a delegation rejected by an import
+type ImportErrorDto struct {
+	Index  int    `json:"index"` // Position in the request array
+	TzktID int64  `json:"tzkt_id"`
+	Error  string `json:"error"`
+}
+
+type ImportDelegationsResponse struct {
+	Inserted int64            `json:"inserted"`
+	Skipped  int64            `json:"skipped"` // Already stored
+	Errors   []ImportErrorDto `json:"errors"`
+}
+
+type HealthResponse struct {
+	Status      string `json:"status"`
+	Reason      string `json:"reason,omitempty"`
+	TzktCircuit string `json:"tzkt_circuit,omitempty"` // Poller circuit breaker state, omitted when the poller is disabled
 }
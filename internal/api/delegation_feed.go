@@ -0,0 +1,104 @@
+package api
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"tezos-delegation/internal/model"
+	"time"
+
+	"github.com/kataras/iris/v12"
+)
+
+const (
+	feedSize        = 50 // Most recent delegations included in the Atom feed
+	feedPath        = "/xtz/delegations/feed.atom"
+	feedID          = "urn:tezos-delegation:delegations"
+	contentTypeAtom = "application/atom+xml; charset=utf-8"
+)
+
+// atomFeed is an Atom (RFC 4287) feed document
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Link    atomLink    `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+}
+
+// atomEntry is a single delegation. Atom requires an alternate link on entries without content,
+// which points at the delegation's JSON representation.
+type atomEntry struct {
+	ID      string   `xml:"id"`
+	Title   string   `xml:"title"`
+	Updated string   `xml:"updated"`
+	Link    atomLink `xml:"link"`
+	Summary string   `xml:"summary"`
+}
+
+// buildAtomFeed builds the Atom feed of delegations, most recent first. The feed's updated time is that
+// of the newest delegation, or now when there are none, since Atom requires one.
+func buildAtomFeed(delegations []model.Delegation, now time.Time) atomFeed {
+	updated := now
+	if len(delegations) > 0 {
+		updated = delegations[0].Timestamp
+	}
+
+	feed := atomFeed{
+		ID:      feedID,
+		Title:   "Tezos delegations",
+		Updated: updated.UTC().Format(time.RFC3339),
+		Author:  atomAuthor{Name: "tezos-delegation"},
+		Link:    atomLink{Rel: "self", Href: feedPath},
+		Entries: make([]atomEntry, len(delegations)),
+	}
+	for i, d := range delegations {
+		timestamp := d.Timestamp.UTC().Format(time.RFC3339)
+		feed.Entries[i] = atomEntry{
+			ID:      feedID + ":" + strconv.FormatInt(d.TzktID, 10),
+			Title:   fmt.Sprintf("%s delegated %s tez", d.Delegator, formatTez(d.Amount)),
+			Updated: timestamp,
+			Link:    atomLink{Rel: "alternate", Href: "/xtz/delegations/" + strconv.FormatInt(d.TzktID, 10)},
+			Summary: fmt.Sprintf("%s delegated %s tez (%d mutez) at level %d on %s", d.Delegator, formatTez(d.Amount), d.Amount, d.Level, timestamp),
+		}
+	}
+	return feed
+}
+
+// GetDelegationsFeed handles GET /xtz/delegations/feed.atom
+// @Summary Atom feed of recent delegations
+// @Description Returns the 50 most recent delegations as an Atom feed, for following new delegations in a feed reader
+// @Tags delegations
+// @Produce application/atom+xml
+// @Success 200 {string} string "Atom feed"
+// @Success 304 "Not modified"
+// @Failure 500 {object} ErrorResponse
+// @Router /xtz/delegations/feed.atom [get]
+func (h *DelegationHandler) GetDelegationsFeed(ctx iris.Context) {
+	delegations, _, err := h.Service.GetDelegations(ctx.Request().Context(), 1, feedSize, model.DelegationFilter{})
+	if err != nil {
+		h.respondWithServiceError(ctx, "GetDelegationsFeed", err)
+		return
+	}
+
+	body, err := xml.Marshal(buildAtomFeed(delegations, time.Now()))
+	if err != nil {
+		h.respondWithServiceError(ctx, "GetDelegationsFeed", err)
+		return
+	}
+
+	if err := respondWithETag(ctx, append([]byte(xml.Header), body...), contentTypeAtom, h.cacheTTL); err != nil {
+		h.logger(ctx).Error().Err(err).Msg("Error writing delegations feed")
+	}
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"tezos-delegation/internal/mocks"
+	"tezos-delegation/internal/model"
+
+	"github.com/golang/mock/gomock"
+	"github.com/kataras/iris/v12"
+	"github.com/kataras/iris/v12/httptest"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDelegationHandler_GetDelegationsFeed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockDelegationServicePort(ctrl)
+	handler := NewDelegationHandler(service, zerolog.Nop(), HandlerOptions{})
+
+	app := iris.New()
+	app.Get("/xtz/delegations/feed.atom", handler.GetDelegationsFeed)
+	app.Get("/xtz/delegations/{tzktId}", handler.GetDelegationByTzktID)
+	test := httptest.New(t, app)
+
+	t.Run("feed", func(t *testing.T) {
+		delegations := []model.Delegation{
+			{TzktID: 42, Delegator: "tz1new", Amount: 12_500_000, Level: 7, Timestamp: fixedTime()},
+			{TzktID: 41, Delegator: "tz1old", Amount: 100, Level: 6, Timestamp: fixedTime().Add(-time.Hour)},
+		}
+		service.EXPECT().GetDelegations(gomock.Any(), 1, feedSize, model.DelegationFilter{}).Return(delegations, true, nil)
+
+		resp := test.GET("/xtz/delegations/feed.atom").Expect().Status(200)
+		resp.Header("Content-Type").IsEqual(contentTypeAtom)
+
+		var feed atomFeed
+		assert.NoError(t, xml.Unmarshal([]byte(resp.Body().Raw()), &feed))
+		assert.Equal(t, "http://www.w3.org/2005/Atom", feed.XMLName.Space)
+		assert.Equal(t, "feed", feed.XMLName.Local)
+		assert.Equal(t, feedID, feed.ID)
+		assert.Equal(t, "2022-05-05T06:29:14Z", feed.Updated)
+		if !assert.Len(t, feed.Entries, 2) {
+			return
+		}
+
+		entry := feed.Entries[0]
+		assert.Equal(t, feedID+":42", entry.ID)
+		assert.Equal(t, "tz1new delegated 12.500000 tez", entry.Title)
+		assert.Equal(t, "2022-05-05T06:29:14Z", entry.Updated)
+		assert.Equal(t, atomLink{Rel: "alternate", Href: "/xtz/delegations/42"}, entry.Link)
+		assert.Contains(t, entry.Summary, "level 7")
+	})
+
+	t.Run("no delegations", func(t *testing.T) {
+		service.EXPECT().GetDelegations(gomock.Any(), 1, feedSize, model.DelegationFilter{}).Return([]model.Delegation{}, false, nil)
+
+		var feed atomFeed
+		body := test.GET("/xtz/delegations/feed.atom").Expect().Status(200).Body().Raw()
+		assert.NoError(t, xml.Unmarshal([]byte(body), &feed))
+		assert.NotEmpty(t, feed.Updated)
+		assert.Empty(t, feed.Entries)
+	})
+}
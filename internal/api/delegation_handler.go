@@ -1,11 +1,21 @@
 package api
 
 import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"slices"
 	"strconv"
+	"strings"
 	"tezos-delegation/internal/apperrors"
 	"tezos-delegation/internal/model"
 	"tezos-delegation/internal/ports"
+	"tezos-delegation/internal/requestid"
+	"tezos-delegation/internal/services"
 	"time"
 
 	"github.com/kataras/iris/v12"
@@ -15,47 +25,268 @@ import (
 const (
 	defaultPageSize = 50
 	maxPageSize     = 1000
-	cacheTTL        = 30 * time.Second // Cache responses for 30 seconds
+	defaultTopLimit = 10
+	maxTopLimit     = 100
+	cacheTTL        = 30 * time.Second // Default time responses are cached for
 )
 
+// Delegation timestamp formats selected by the timeFormat query parameter
+const (
+	timeFormatRFC3339   = "rfc3339"   // RFC 3339 in UTC, the default
+	timeFormatUnix      = "unix"      // Unix epoch seconds
+	timeFormatUnixMilli = "unixmilli" // Unix epoch milliseconds
+)
+
+// HandlerOptions holds the tunable handler behavior loaded from configuration.
+type HandlerOptions struct {
+	CacheSize int           // Maximum number of cached list responses; 0 disables the response cache
+	CacheTTL  time.Duration // How long responses are cached and may be reused by clients; defaults to cacheTTL
+	MaxYear   int           // Latest year accepted by the year parameter; 0 uses the current year
+}
+
 // DelegationHandler implements DelegationHandlerPort
 type DelegationHandler struct {
-	Service ports.DelegationServicePort
-	Logger  zerolog.Logger
+	Service  ports.DelegationServicePort
+	Logger   zerolog.Logger
+	cache    *responseCache[delegationsPage] // nil when response caching is disabled
+	cacheTTL time.Duration
+	maxYear  int // 0 uses the current year, see model.LatestYear
 }
 
-func NewDelegationHandler(service ports.DelegationServicePort, logger zerolog.Logger) *DelegationHandler {
-	return &DelegationHandler{
-		Service: service,
-		Logger:  logger.With().Str("component", "DelegationHttpHandler").Logger(),
+func NewDelegationHandler(service ports.DelegationServicePort, logger zerolog.Logger, opts HandlerOptions) *DelegationHandler {
+	ttl := opts.CacheTTL
+	if ttl <= 0 {
+		ttl = cacheTTL
+	}
+
+	h := &DelegationHandler{
+		Service:  service,
+		Logger:   logger.With().Str("component", "DelegationHttpHandler").Logger(),
+		cacheTTL: ttl,
+		maxYear:  opts.MaxYear,
 	}
+	if opts.CacheSize > 0 {
+		h.cache = newResponseCache[delegationsPage](opts.CacheSize, ttl)
+	}
+	return h
+}
+
+// logger returns the handler logger scoped to the current request
+func (h *DelegationHandler) logger(ctx iris.Context) *zerolog.Logger {
+	return requestid.Logger(ctx.Request().Context(), &h.Logger)
 }
 
-// respondWithError sends a consistent error response with proper status code
-func respondWithError(ctx iris.Context, status int, message string) {
+// respondWithError sends a consistent error response with proper status code and error code
+func respondWithError(ctx iris.Context, status int, code, message string) {
+	respondWithErrorDetails(ctx, status, code, message, nil)
+}
+
+// respondWithErrorDetails is respondWithError with per-field details; nil details are left out of the body
+func respondWithErrorDetails(ctx iris.Context, status int, code, message string, details []ErrorDetail) {
 	ctx.StatusCode(status)
-	ctx.JSON(iris.Map{"error": message})
+	ctx.JSON(ErrorResponse{Error: message, Code: code, Details: details})
+}
+
+// setRetryAfter sets the Retry-After header to retryAfter in whole seconds, rounded up and at least one,
+// since clients treat 0 as "retry immediately"
+func setRetryAfter(ctx iris.Context, retryAfter time.Duration) {
+	seconds := max(int((retryAfter+time.Second-1)/time.Second), 1)
+	ctx.Header("Retry-After", strconv.Itoa(seconds))
+}
+
+// respondTooManyRequests rejects a rate limited or shed request with 429 and the standard error body,
+// telling the client how long to back off
+func respondTooManyRequests(ctx iris.Context, retryAfter time.Duration) {
+	setRetryAfter(ctx, retryAfter)
+	respondWithError(ctx, http.StatusTooManyRequests, CodeRateLimited, "Too many requests, retry later")
 }
 
 // logAndRespondWithError logs detailed error information but returns sanitized response
-func (h *DelegationHandler) logAndRespondWithError(ctx iris.Context, status int, userMessage, logMessage string, err error) {
+func (h *DelegationHandler) logAndRespondWithError(ctx iris.Context, status int, code, userMessage, logMessage string, details []ErrorDetail, err error) {
 	// Log detailed error for debugging
-	h.Logger.Error().Err(err).Str("user_message", userMessage).Msg(logMessage)
+	h.logger(ctx).Error().Err(err).Str("user_message", userMessage).Str("code", code).Msg(logMessage)
 
 	// Return sanitized message to user
-	respondWithError(ctx, status, userMessage)
+	respondWithErrorDetails(ctx, status, code, userMessage, details)
+}
+
+// dbUnavailableRetryAfter is the Retry-After hint sent with 503 responses while the database is unreachable
+const dbUnavailableRetryAfter = 5 * time.Second
+
+// respondWithServiceError maps a service error to the appropriate HTTP status code and sanitized message
+func (h *DelegationHandler) respondWithServiceError(ctx iris.Context, operation string, err error) {
+	// Determine appropriate HTTP status code and message based on error type
+	var statusCode int
+	var code string
+	var userMessage string
+	var logMessage string
+	var details []ErrorDetail
+	var notFoundErr *apperrors.NotFoundError
+	var validationErr *apperrors.ValidationError
+
+	// Check if it's a validation error from the service
+	if errors.Is(err, services.ErrOffsetTooLarge) {
+		statusCode = http.StatusBadRequest
+		code = CodeOffsetTooLarge
+		userMessage = "Page offset too large: use cursor pagination instead by requesting page 1 with maxId below the last tzkt id seen"
+		logMessage = "Page offset too large in " + operation
+	} else if errors.As(err, &validationErr) {
+		// Validation messages describe the parameter only, so they are safe to pass on
+		statusCode = http.StatusBadRequest
+		code = CodeInvalidRequest
+		userMessage = "Invalid request parameters"
+		logMessage = "Validation error in " + operation
+		details = []ErrorDetail{{Field: validationErr.Field, Message: validationErr.Message}}
+	} else if errors.As(err, &notFoundErr) {
+		// Only the resource type is exposed, not the identifier or underlying cause
+		statusCode = http.StatusNotFound
+		code = CodeNotFound
+		userMessage = notFoundErr.Resource + " not found"
+		logMessage = "Resource not found in " + operation
+	} else if ctx.Request().Context().Err() == context.DeadlineExceeded {
+		// REQUEST_TIMEOUT ran out; the error is whatever the cancelled call failed with
+		statusCode = http.StatusServiceUnavailable
+		code = CodeRequestTimeout
+		userMessage = "Request timed out"
+		logMessage = "Request timed out in " + operation
+	} else if errors.Is(err, services.ErrTzktUnavailable) {
+		statusCode = http.StatusBadGateway
+		code = CodeTzktUnavailable
+		userMessage = "Tzkt temporarily unavailable"
+		logMessage = "Tzkt unavailable in " + operation
+	} else if apperrors.IsDatabaseUnavailableError(err) {
+		// The database connection is down, not the query: tell clients to come back shortly
+		setRetryAfter(ctx, dbUnavailableRetryAfter)
+		statusCode = http.StatusServiceUnavailable
+		code = CodeDBUnavailable
+		userMessage = "Database temporarily unavailable"
+		logMessage = "Database unavailable in " + operation
+	} else if apperrors.IsDatabaseError(err) {
+		statusCode = http.StatusInternalServerError
+		code = CodeDatabaseError
+		userMessage = "Database error"
+		logMessage = "Database error in " + operation
+	} else {
+		statusCode = http.StatusInternalServerError
+		code = CodeInternalError
+		userMessage = "Internal server error"
+		logMessage = "Unexpected error in " + operation
+	}
+
+	h.logAndRespondWithError(ctx, statusCode, code, userMessage, logMessage, details, err)
+}
+
+// formatTimestamp renders t in one of the timeFormat* formats
+func formatTimestamp(t time.Time, timeFormat string) string {
+	switch timeFormat {
+	case timeFormatUnix:
+		return strconv.FormatInt(t.Unix(), 10)
+	case timeFormatUnixMilli:
+		return strconv.FormatInt(t.UnixMilli(), 10)
+	default:
+		return t.UTC().Format(time.RFC3339)
+	}
 }
 
-// toDelegationDto converts a model.Delegation to DelegationDto
-func toDelegationDto(d model.Delegation) DelegationDto {
+// toDelegationDto converts a model.Delegation to DelegationDto, rendering the timestamp in timeFormat
+func toDelegationDto(d model.Delegation, timeFormat string) DelegationDto {
 	return DelegationDto{
-		Timestamp: d.Timestamp.UTC().Format(time.RFC3339),
+		Timestamp: formatTimestamp(d.Timestamp, timeFormat),
 		Amount:    strconv.FormatInt(d.Amount, 10),
 		Delegator: d.Delegator,
 		Level:     strconv.FormatInt(d.Level, 10),
 	}
 }
 
+// toNumericDelegationDto converts a model.Delegation to NumericDelegationDto, rendering the timestamp in timeFormat
+func toNumericDelegationDto(d model.Delegation, timeFormat string) NumericDelegationDto {
+	return NumericDelegationDto{
+		Timestamp: formatTimestamp(d.Timestamp, timeFormat),
+		Amount:    d.Amount,
+		Delegator: d.Delegator,
+		Level:     d.Level,
+	}
+}
+
+// selectDelegationFields keeps only the named fields of d, rendered as by toDelegationDto, or
+// toNumericDelegationDto with numeric
+func selectDelegationFields(d model.Delegation, fields []string, timeFormat string, numeric bool) sparseDelegationDto {
+	dto := toDelegationDto(d, timeFormat)
+	sparse := make(sparseDelegationDto, len(fields))
+	for _, name := range fields {
+		switch name {
+		case "timestamp":
+			sparse[name] = dto.Timestamp
+		case "amount":
+			sparse[name] = dto.Amount
+			if numeric {
+				sparse[name] = d.Amount
+			}
+		case "delegator":
+			sparse[name] = dto.Delegator
+		case "level":
+			sparse[name] = dto.Level
+			if numeric {
+				sparse[name] = d.Level
+			}
+		}
+	}
+	return sparse
+}
+
+// formatTez formats an amount in mutez as a decimal tez string (1 tez = 1,000,000 mutez)
+func formatTez(mutez int64) string {
+	sign := ""
+	abs := uint64(mutez)
+	if mutez < 0 {
+		sign = "-"
+		abs = uint64(-mutez)
+	}
+	return fmt.Sprintf("%s%d.%06d", sign, abs/1_000_000, abs%1_000_000)
+}
+
+// toYearStatsDto converts a model.YearStats to YearStatsDto
+func toYearStatsDto(s model.YearStats) YearStatsDto {
+	return YearStatsDto{
+		Year:           s.Year,
+		Count:          s.Count,
+		TotalAmount:    strconv.FormatInt(s.TotalAmount, 10),
+		TotalAmountTez: formatTez(s.TotalAmount),
+	}
+}
+
+// toMonthStatsDto converts a model.MonthStats to MonthStatsDto
+func toMonthStatsDto(s model.MonthStats) MonthStatsDto {
+	return MonthStatsDto{
+		Month:          s.Month,
+		Count:          s.Count,
+		TotalAmount:    strconv.FormatInt(s.TotalAmount, 10),
+		TotalAmountTez: formatTez(s.TotalAmount),
+	}
+}
+
+// toDelegatorStatsDto converts a model.DelegatorStats to DelegatorStatsDto
+func toDelegatorStatsDto(s model.DelegatorStats) DelegatorStatsDto {
+	return DelegatorStatsDto{
+		Delegator:      s.Delegator,
+		Count:          s.Count,
+		TotalAmount:    strconv.FormatInt(s.TotalAmount, 10),
+		TotalAmountTez: formatTez(s.TotalAmount),
+	}
+}
+
+// toDelegatorSummaryDto converts a model.DelegatorSummary to DelegatorSummaryDto
+func toDelegatorSummaryDto(s model.DelegatorSummary) DelegatorSummaryDto {
+	return DelegatorSummaryDto{
+		Delegator:      s.Delegator,
+		FirstSeen:      s.FirstSeen.UTC().Format(time.RFC3339),
+		LastSeen:       s.LastSeen.UTC().Format(time.RFC3339),
+		Count:          s.Count,
+		TotalAmount:    strconv.FormatInt(s.TotalAmount, 10),
+		TotalAmountTez: formatTez(s.TotalAmount),
+	}
+}
+
 // validatePaginationParams validates and returns page and pageSize parameters
 func (h *DelegationHandler) validatePaginationParams(ctx iris.Context) (int, int, bool) {
 	// Parse page parameter
@@ -64,15 +295,15 @@ func (h *DelegationHandler) validatePaginationParams(ctx iris.Context) (int, int
 		pageStr := ctx.URLParam("page")
 		// Validate string length to prevent resource exhaustion
 		if len(pageStr) > 10 {
-			h.Logger.Warn().Str("page", pageStr).Msg("Page parameter too long")
-			respondWithError(ctx, http.StatusBadRequest, "Invalid page parameter: too long")
+			h.logger(ctx).Warn().Str("page", pageStr).Msg("Page parameter too long")
+			respondWithError(ctx, http.StatusBadRequest, CodeInvalidPage, "Invalid page parameter: too long")
 			return 0, 0, false
 		}
 
 		p, err := ctx.URLParamInt("page")
 		if err != nil || p < 1 {
-			h.Logger.Warn().Str("page", pageStr).Msg("Invalid page parameter")
-			respondWithError(ctx, http.StatusBadRequest, "Invalid page parameter: must be a positive integer")
+			h.logger(ctx).Warn().Str("page", pageStr).Msg("Invalid page parameter")
+			respondWithError(ctx, http.StatusBadRequest, CodeInvalidPage, "Invalid page parameter: must be a positive integer")
 			return 0, 0, false
 		}
 		page = p
@@ -84,15 +315,15 @@ func (h *DelegationHandler) validatePaginationParams(ctx iris.Context) (int, int
 		pageSizeStr := ctx.URLParam("pageSize")
 		// Validate string length to prevent resource exhaustion
 		if len(pageSizeStr) > 10 {
-			h.Logger.Warn().Str("pageSize", pageSizeStr).Msg("PageSize parameter too long")
-			respondWithError(ctx, http.StatusBadRequest, "Invalid pageSize parameter: too long")
+			h.logger(ctx).Warn().Str("pageSize", pageSizeStr).Msg("PageSize parameter too long")
+			respondWithError(ctx, http.StatusBadRequest, CodeInvalidPageSize, "Invalid pageSize parameter: too long")
 			return 0, 0, false
 		}
 
 		ps, err := ctx.URLParamInt("pageSize")
 		if err != nil || ps < 1 || ps > maxPageSize {
-			h.Logger.Warn().Str("pageSize", pageSizeStr).Msg("Invalid pageSize parameter")
-			respondWithError(ctx, http.StatusBadRequest, "Invalid pageSize parameter: must be between 1 and 1000")
+			h.logger(ctx).Warn().Str("pageSize", pageSizeStr).Msg("Invalid pageSize parameter")
+			respondWithError(ctx, http.StatusBadRequest, CodeInvalidPageSize, "Invalid pageSize parameter: must be between 1 and 1000")
 			return 0, 0, false
 		}
 		pageSize = ps
@@ -110,80 +341,940 @@ func (h *DelegationHandler) validateYearParam(ctx iris.Context) (*int, bool) {
 
 	// Validate string length to prevent resource exhaustion
 	if len(yearStr) > 10 {
-		h.Logger.Warn().Str("year", yearStr).Msg("Year parameter too long")
-		respondWithError(ctx, http.StatusBadRequest, "Invalid year parameter: too long")
+		h.logger(ctx).Warn().Str("year", yearStr).Msg("Year parameter too long")
+		respondWithError(ctx, http.StatusBadRequest, CodeInvalidYear, "Invalid year parameter: too long")
 		return nil, false
 	}
 
 	yearInt, err := strconv.Atoi(yearStr)
-	if err != nil || yearInt < 2018 {
-		h.Logger.Warn().Str("year", yearStr).Msg("Invalid year parameter")
-		respondWithError(ctx, http.StatusBadRequest, "Invalid year parameter: must be a valid year from 2018 onwards")
+	maxYear := model.LatestYear(h.maxYear)
+	if err != nil || yearInt < model.MinYear || yearInt > maxYear {
+		h.logger(ctx).Warn().Str("year", yearStr).Msg("Invalid year parameter")
+		respondWithError(ctx, http.StatusBadRequest, CodeInvalidYear, "Invalid year parameter: "+model.YearRangeMessage(maxYear))
 		return nil, false
 	}
 
 	return &yearInt, true
 }
 
+// validateLimitParam validates and returns the limit parameter for top-N queries
+func (h *DelegationHandler) validateLimitParam(ctx iris.Context) (int, bool) {
+	if !ctx.URLParamExists("limit") {
+		return defaultTopLimit, true
+	}
+
+	limitStr := ctx.URLParam("limit")
+	// Validate string length to prevent resource exhaustion
+	if len(limitStr) > 10 {
+		h.logger(ctx).Warn().Str("limit", limitStr).Msg("Limit parameter too long")
+		respondWithError(ctx, http.StatusBadRequest, CodeInvalidLimit, "Invalid limit parameter: too long")
+		return 0, false
+	}
+
+	limit, err := ctx.URLParamInt("limit")
+	if err != nil || limit < 1 || limit > maxTopLimit {
+		h.logger(ctx).Warn().Str("limit", limitStr).Msg("Invalid limit parameter")
+		respondWithError(ctx, http.StatusBadRequest, CodeInvalidLimit, "Invalid limit parameter: must be between 1 and 100")
+		return 0, false
+	}
+
+	return limit, true
+}
+
+// validateMaxIDParam validates and returns the maxId snapshot parameter if provided
+func (h *DelegationHandler) validateMaxIDParam(ctx iris.Context) (*int64, bool) {
+	maxIDStr := ctx.URLParam("maxId")
+	if maxIDStr == "" {
+		return nil, true
+	}
+
+	// Validate string length to prevent resource exhaustion
+	if len(maxIDStr) > 19 {
+		h.logger(ctx).Warn().Str("maxId", maxIDStr).Msg("MaxId parameter too long")
+		respondWithError(ctx, http.StatusBadRequest, CodeInvalidMaxID, "Invalid maxId parameter: too long")
+		return nil, false
+	}
+
+	maxID, err := strconv.ParseInt(maxIDStr, 10, 64)
+	if err != nil || maxID < 0 {
+		h.logger(ctx).Warn().Str("maxId", maxIDStr).Msg("Invalid maxId parameter")
+		respondWithError(ctx, http.StatusBadRequest, CodeInvalidMaxID, "Invalid maxId parameter: must be a non-negative integer")
+		return nil, false
+	}
+
+	return &maxID, true
+}
+
+// validateSinceTzktIDParam validates and returns the sinceTzktId incremental sync parameter if provided
+func (h *DelegationHandler) validateSinceTzktIDParam(ctx iris.Context) (*int64, bool) {
+	sinceStr := ctx.URLParam("sinceTzktId")
+	if sinceStr == "" {
+		return nil, true
+	}
+
+	// Validate string length to prevent resource exhaustion
+	if len(sinceStr) > 19 {
+		h.logger(ctx).Warn().Str("sinceTzktId", sinceStr).Msg("SinceTzktId parameter too long")
+		respondWithError(ctx, http.StatusBadRequest, CodeInvalidSinceTzktID, "Invalid sinceTzktId parameter: too long")
+		return nil, false
+	}
+
+	since, err := strconv.ParseInt(sinceStr, 10, 64)
+	if err != nil || since < 0 {
+		h.logger(ctx).Warn().Str("sinceTzktId", sinceStr).Msg("Invalid sinceTzktId parameter")
+		respondWithError(ctx, http.StatusBadRequest, CodeInvalidSinceTzktID, "Invalid sinceTzktId parameter: must be a non-negative integer")
+		return nil, false
+	}
+
+	return &since, true
+}
+
+// validateMinLevelParam validates and returns the minLevel block level parameter if provided
+func (h *DelegationHandler) validateMinLevelParam(ctx iris.Context) (*int64, bool) {
+	minLevelStr := ctx.URLParam("minLevel")
+	if minLevelStr == "" {
+		return nil, true
+	}
+
+	// Validate string length to prevent resource exhaustion
+	if len(minLevelStr) > 19 {
+		h.logger(ctx).Warn().Str("minLevel", minLevelStr).Msg("MinLevel parameter too long")
+		respondWithError(ctx, http.StatusBadRequest, CodeInvalidMinLevel, "Invalid minLevel parameter: too long")
+		return nil, false
+	}
+
+	minLevel, err := strconv.ParseInt(minLevelStr, 10, 64)
+	if err != nil || minLevel < 0 {
+		h.logger(ctx).Warn().Str("minLevel", minLevelStr).Msg("Invalid minLevel parameter")
+		respondWithError(ctx, http.StatusBadRequest, CodeInvalidMinLevel, "Invalid minLevel parameter: must be a non-negative integer")
+		return nil, false
+	}
+
+	return &minLevel, true
+}
+
+// validateTypeParam validates and returns the operation type parameter if provided
+func (h *DelegationHandler) validateTypeParam(ctx iris.Context) (*string, bool) {
+	opType := ctx.URLParam("type")
+	if opType == "" {
+		return nil, true
+	}
+
+	if opType != model.OperationTypeDelegation && opType != model.OperationTypeOrigination {
+		h.logger(ctx).Warn().Str("type", opType).Msg("Invalid type parameter")
+		respondWithError(ctx, http.StatusBadRequest, CodeInvalidType, "Invalid type parameter: must be delegation or origination")
+		return nil, false
+	}
+
+	return &opType, true
+}
+
+// validateAccountTypeParam validates and returns the delegator account type parameter if provided
+func (h *DelegationHandler) validateAccountTypeParam(ctx iris.Context) (*string, bool) {
+	accountType := ctx.URLParam("accountType")
+	if accountType == "" {
+		return nil, true
+	}
+
+	if accountType != model.AccountTypeImplicit && accountType != model.AccountTypeContract {
+		h.logger(ctx).Warn().Str("accountType", accountType).Msg("Invalid accountType parameter")
+		respondWithError(ctx, http.StatusBadRequest, CodeInvalidAccountType, "Invalid accountType parameter: must be implicit or contract")
+		return nil, false
+	}
+
+	return &accountType, true
+}
+
+// validateFieldsParam validates and returns the fields parameter, a comma-separated subset of delegationFields
+// in canonical order, or nil if absent
+func (h *DelegationHandler) validateFieldsParam(ctx iris.Context) ([]string, bool) {
+	if !ctx.URLParamExists("fields") {
+		return nil, true
+	}
+	fieldsStr := ctx.URLParam("fields")
+
+	// Validate string length to prevent resource exhaustion
+	if len(fieldsStr) > 100 {
+		h.logger(ctx).Warn().Str("fields", fieldsStr).Msg("Fields parameter too long")
+		respondWithError(ctx, http.StatusBadRequest, CodeInvalidFields, "Invalid fields parameter: too long")
+		return nil, false
+	}
+
+	requested := make(map[string]bool)
+	for _, name := range strings.Split(fieldsStr, ",") {
+		name = strings.TrimSpace(name)
+		if !slices.Contains(delegationFields, name) {
+			h.logger(ctx).Warn().Str("fields", fieldsStr).Msg("Invalid fields parameter")
+			respondWithError(ctx, http.StatusBadRequest, CodeInvalidFields, fmt.Sprintf("Invalid fields parameter: %q is not one of %s", name, strings.Join(delegationFields, ", ")))
+			return nil, false
+		}
+		requested[name] = true
+	}
+
+	fields := make([]string, 0, len(requested))
+	for _, name := range delegationFields {
+		if requested[name] {
+			fields = append(fields, name)
+		}
+	}
+	return fields, true
+}
+
+// validateTimeFormatParam validates and returns the timeFormat parameter, defaulting to rfc3339
+func (h *DelegationHandler) validateTimeFormatParam(ctx iris.Context) (string, bool) {
+	timeFormat := ctx.URLParam("timeFormat")
+	switch timeFormat {
+	case "":
+		return timeFormatRFC3339, true
+	case timeFormatRFC3339, timeFormatUnix, timeFormatUnixMilli:
+		return timeFormat, true
+	}
+
+	h.logger(ctx).Warn().Str("timeFormat", timeFormat).Msg("Invalid timeFormat parameter")
+	respondWithError(ctx, http.StatusBadRequest, CodeInvalidTimeFormat, "Invalid timeFormat parameter: must be rfc3339, unix or unixmilli")
+	return "", false
+}
+
+// validateFilterParams validates the filter query parameters shared by the delegation list and export endpoints
+func (h *DelegationHandler) validateFilterParams(ctx iris.Context) (model.DelegationFilter, bool) {
+	// Validate year parameter
+	yearPtr, ok := h.validateYearParam(ctx)
+	if !ok {
+		return model.DelegationFilter{}, false
+	}
+
+	// Validate snapshot max id parameter
+	maxIDPtr, ok := h.validateMaxIDParam(ctx)
+	if !ok {
+		return model.DelegationFilter{}, false
+	}
+
+	// Validate operation type parameter
+	typePtr, ok := h.validateTypeParam(ctx)
+	if !ok {
+		return model.DelegationFilter{}, false
+	}
+
+	// Validate minimum block level parameter
+	minLevelPtr, ok := h.validateMinLevelParam(ctx)
+	if !ok {
+		return model.DelegationFilter{}, false
+	}
+
+	// Validate delegator account type parameter
+	accountTypePtr, ok := h.validateAccountTypeParam(ctx)
+	if !ok {
+		return model.DelegationFilter{}, false
+	}
+
+	// Validate incremental sync parameter
+	sincePtr, ok := h.validateSinceTzktIDParam(ctx)
+	if !ok {
+		return model.DelegationFilter{}, false
+	}
+
+	return model.DelegationFilter{
+		Year:        yearPtr,
+		MaxTzktID:   maxIDPtr,
+		Type:        typePtr,
+		MinLevel:    minLevelPtr,
+		AccountType: accountTypePtr,
+		SinceTzktID: sincePtr,
+	}, true
+}
+
+// validateSnapshotParam validates and returns the snapshot flag
+func (h *DelegationHandler) validateSnapshotParam(ctx iris.Context) (bool, bool) {
+	snapshotStr := ctx.URLParam("snapshot")
+	if snapshotStr == "" {
+		return false, true
+	}
+
+	snapshot, err := strconv.ParseBool(snapshotStr)
+	if err != nil {
+		h.logger(ctx).Warn().Str("snapshot", snapshotStr).Msg("Invalid snapshot parameter")
+		respondWithError(ctx, http.StatusBadRequest, CodeInvalidSnapshot, "Invalid snapshot parameter: must be true or false")
+		return false, false
+	}
+
+	return snapshot, true
+}
+
+// validateLinksParam validates and returns the links flag
+func (h *DelegationHandler) validateLinksParam(ctx iris.Context) (bool, bool) {
+	linksStr := ctx.URLParam("links")
+	if linksStr == "" {
+		return false, true
+	}
+
+	links, err := strconv.ParseBool(linksStr)
+	if err != nil {
+		h.logger(ctx).Warn().Str("links", linksStr).Msg("Invalid links parameter")
+		respondWithError(ctx, http.StatusBadRequest, CodeInvalidLinks, "Invalid links parameter: must be true or false")
+		return false, false
+	}
+
+	return links, true
+}
+
+// validateNumericParam validates the numeric query parameter and reports whether amounts and levels
+// should be rendered as JSON integers instead of strings
+func (h *DelegationHandler) validateNumericParam(ctx iris.Context) (bool, bool) {
+	numericStr := ctx.URLParam("numeric")
+	if numericStr == "" {
+		return false, true
+	}
+
+	numeric, err := strconv.ParseBool(numericStr)
+	if err != nil {
+		h.logger(ctx).Warn().Str("numeric", numericStr).Msg("Invalid numeric parameter")
+		respondWithError(ctx, http.StatusBadRequest, CodeInvalidNumeric, "Invalid numeric parameter: must be true or false")
+		return false, false
+	}
+
+	return numeric, true
+}
+
+// pageURLs returns a function building the relative URL of a delegations page from the request URL,
+// keeping its other query parameters. A snapshot pinned by the request is carried over as maxId,
+// so following the URLs stays on the same snapshot.
+func pageURLs(reqURL *url.URL, snapshotMaxID *int64) func(page int) string {
+	query := reqURL.Query()
+	if snapshotMaxID != nil {
+		query.Del("snapshot")
+		query.Set("maxId", strconv.FormatInt(*snapshotMaxID, 10))
+	}
+	return func(page int) string {
+		query.Set("page", strconv.Itoa(page))
+		return reqURL.Path + "?" + query.Encode()
+	}
+}
+
+// pageLinks builds the links of a delegations page from the request URL, as described for pageURLs
+func pageLinks(reqURL *url.URL, page int, hasNext bool, snapshotMaxID *int64) *PageLinks {
+	link := pageURLs(reqURL, snapshotMaxID)
+	links := &PageLinks{Self: link(page)}
+	if hasNext {
+		links.Next = link(page + 1)
+	}
+	if page > 1 {
+		links.Prev = link(page - 1)
+	}
+	return links
+}
+
+// linkHeader builds an RFC 8288 Link header value with the next, prev and first pages, for clients that
+// follow Link headers. Like the body's _links, next is left out on the last page and prev on the first.
+func linkHeader(reqURL *url.URL, page int, hasNext bool, snapshotMaxID *int64) string {
+	link := pageURLs(reqURL, snapshotMaxID)
+	var links []string
+	if hasNext {
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, link(page+1)))
+	}
+	if page > 1 {
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, link(page-1)))
+	}
+	links = append(links, fmt.Sprintf(`<%s>; rel="first"`, link(1)))
+	return strings.Join(links, ", ")
+}
+
+// delegationsPage is a serialized page of delegations with its Link header, as kept in the response cache
+type delegationsPage struct {
+	body []byte
+	link string
+}
+
 // GetDelegations handles GET /xtz/delegations
 // @Summary Get delegations with pagination and optional year filter
 // @Description Retrieves a paginated list of Tezos delegations with optional year filtering
 // @Tags delegations
-// @Produce json
+// @Produce json,xml,application/x-ndjson
+// @Param Accept header string false "application/xml returns the page as XML; application/x-ndjson streams all matching delegations one per line, ignoring page and pageSize"
 // @Param page query int false "Page number (default: 1)" minimum(1)
 // @Param pageSize query int false "Number of items per page (default: 50, max: 1000)" minimum(1) maximum(1000)
 // @Param year query int false "Filter by year (optional) minimum(2018)"
+// @Param snapshot query bool false "Pin results to the current max Tzkt ID and return it as snapshot_max_id"
+// @Param maxId query int false "Only return delegations with Tzkt ID <= maxId (from a previous snapshot_max_id)" minimum(0)
+// @Param type query string false "Only return operations of this type" Enums(delegation, origination)
+// @Param minLevel query int false "Only return delegations at or after this block level" minimum(0)
+// @Param accountType query string false "Only return delegations from implicit (tz1/tz2/tz3) or contract (KT1) delegators" Enums(implicit, contract)
+// @Param sinceTzktId query int false "Only return delegations with Tzkt ID > sinceTzktId, ordered by ascending Tzkt ID for incremental syncs" minimum(0)
+// @Param fields query string false "Comma-separated fields to return per delegation (timestamp, amount, delegator, level); default all"
+// @Param countOnly query bool false "Return only the number of matching delegations in the X-Total-Count header, with no body"
+// @Param links query bool false "Add _links with the self, next and prev page URLs"
+// @Param timeFormat query string false "Timestamp format (default: rfc3339)" Enums(rfc3339, unix, unixmilli)
+// @Param numeric query bool false "Return amount and level as JSON integers instead of strings"
+// @Param If-None-Match header string false "ETag from a previous response; returns 304 if unchanged"
 // @Success 200 {object} GetDelegationsResponse
+// @Header 200 {string} Link "RFC 8288 links to the next, prev and first pages"
+// @Success 304 "Not modified"
 // @Failure 400 {object} ErrorResponse
+// @Failure 406 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /xtz/delegations [get]
 func (h *DelegationHandler) GetDelegations(ctx iris.Context) {
+	// Clients that only need the total skip the page query and serialization entirely
+	countOnly, ok := h.validateCountOnlyParam(ctx)
+	if !ok {
+		return
+	}
+	if countOnly {
+		h.CountDelegations(ctx)
+		return
+	}
+
+	// The representation depends on the Accept header, so shared caches must key on it
+	ctx.Header("Vary", "Accept")
+	format, ok := negotiateFormat(ctx.GetHeader("Accept"))
+	if !ok {
+		h.logger(ctx).Warn().Str("accept", ctx.GetHeader("Accept")).Msg("Unsupported Accept header")
+		respondWithError(ctx, http.StatusNotAcceptable, CodeNotAcceptable, "Not acceptable: supported media types are application/json, application/xml and application/x-ndjson")
+		return
+	}
+
+	// Bulk consumers can ask for the full result set as NDJSON instead of a page
+	if format == formatNDJSON {
+		h.streamDelegationsNDJSON(ctx)
+		return
+	}
+
 	// Validate pagination parameters
 	page, pageSize, ok := h.validatePaginationParams(ctx)
 	if !ok {
 		return
 	}
 
-	// Validate year parameter
-	yearPtr, ok := h.validateYearParam(ctx)
+	// Validate filter parameters
+	filter, ok := h.validateFilterParams(ctx)
+	if !ok {
+		return
+	}
+
+	// Validate snapshot parameter
+	snapshot, ok := h.validateSnapshotParam(ctx)
+	if !ok {
+		return
+	}
+
+	// Validate field selection
+	fields, ok := h.validateFieldsParam(ctx)
+	if !ok {
+		return
+	}
+
+	// Validate links parameter; the links are built from the request URL
+	links, ok := h.validateLinksParam(ctx)
+	if !ok {
+		return
+	}
+
+	// Validate timestamp format
+	timeFormat, ok := h.validateTimeFormatParam(ctx)
+	if !ok {
+		return
+	}
+
+	// Validate number rendering
+	numeric, ok := h.validateNumericParam(ctx)
 	if !ok {
 		return
 	}
 
+	// Load the page, serving repeated identical queries from the response cache
+	reqCtx := ctx.Request().Context()
+	if h.cache != nil {
+		// The load is shared with concurrent identical requests, so one client disconnecting mustn't cancel it,
+		// but REQUEST_TIMEOUT still bounds it
+		deadline, hasDeadline := reqCtx.Deadline()
+		reqCtx = context.WithoutCancel(reqCtx)
+		if hasDeadline {
+			var cancel context.CancelFunc
+			reqCtx, cancel = context.WithDeadline(reqCtx, deadline)
+			defer cancel()
+		}
+	}
+	reqURL := ctx.Request().URL
+	load := func() (delegationsPage, error) {
+		return h.loadDelegationsPage(reqCtx, page, pageSize, filter, snapshot, fields, reqURL, links, timeFormat, numeric, format)
+	}
+	cacheKey, contentType := "delegations?", contentTypeJSON
+	if format == formatXML {
+		cacheKey, contentType = "delegations.xml?", contentTypeXML
+	}
+	var resp delegationsPage
+	var err error
+	if h.cache != nil {
+		resp, err = h.cache.GetOrLoad(cacheKey+reqURL.Query().Encode(), load)
+	} else {
+		resp, err = load()
+	}
+	if err != nil {
+		h.respondWithServiceError(ctx, "GetDelegations", err)
+		return
+	}
+
+	// Return response, or 304 if the client's cached copy is still current
+	ctx.Header("Link", resp.link)
+	if err := respondWithETag(ctx, resp.body, contentType, h.cacheTTL); err != nil {
+		h.logger(ctx).Error().Err(err).Msg("Error writing delegations response")
+	}
+}
+
+// loadDelegationsPage fetches a page of delegations and returns the GetDelegationsResponse serialized as format,
+// along with the Link header built from reqURL. With a field selection, each delegation only carries the
+// selected fields. With links, the response also includes the page links. Timestamps are rendered in timeFormat,
+// and amounts and levels as integers with numeric.
+func (h *DelegationHandler) loadDelegationsPage(ctx context.Context, page, pageSize int, filter model.DelegationFilter, snapshot bool, fields []string, reqURL *url.URL, links bool, timeFormat string, numeric bool, format responseFormat) (delegationsPage, error) {
+	// Pin a new snapshot to the current max TzktID unless the client passed one back
+	if snapshot && filter.MaxTzktID == nil {
+		maxID, err := h.Service.GetSnapshotMaxID(ctx)
+		if err != nil {
+			return delegationsPage{}, err
+		}
+		filter.MaxTzktID = &maxID
+	}
+
 	// Get delegations from service
-	delegations, err := h.Service.GetDelegations(ctx.Request().Context(), page, pageSize, yearPtr)
+	delegations, hasNext, err := h.Service.GetDelegations(ctx, page, pageSize, filter)
 	if err != nil {
-		// Determine appropriate HTTP status code and message based on error type
-		var statusCode int
-		var userMessage string
-		var logMessage string
-
-		// Check if it's a validation error from the service
-		if apperrors.IsValidationError(err) {
-			statusCode = http.StatusBadRequest
-			userMessage = "Invalid request parameters"
-			logMessage = "Validation error in GetDelegations"
-		} else if apperrors.IsDatabaseError(err) {
-			statusCode = http.StatusInternalServerError
-			userMessage = "Database error"
-			logMessage = "Database error in GetDelegations"
-		} else {
-			statusCode = http.StatusInternalServerError
-			userMessage = "Internal server error"
-			logMessage = "Unexpected error in GetDelegations"
+		return delegationsPage{}, err
+	}
+
+	meta := PageMeta{Page: page, PageSize: pageSize, HasNext: hasNext, HasPrev: page > 1}
+	var bodyLinks *PageLinks
+	if links {
+		bodyLinks = pageLinks(reqURL, page, hasNext, filter.MaxTzktID)
+	}
+
+	// Convert to DTOs
+	var resp any
+	switch {
+	case fields != nil:
+		sparse := make([]sparseDelegationDto, len(delegations))
+		for i, d := range delegations {
+			sparse[i] = selectDelegationFields(d, fields, timeFormat, numeric)
+		}
+		resp = getSparseDelegationsResponse{Data: sparse, Meta: meta, SnapshotMaxID: filter.MaxTzktID, Links: bodyLinks}
+	case numeric:
+		dtos := make([]NumericDelegationDto, len(delegations))
+		for i, d := range delegations {
+			dtos[i] = toNumericDelegationDto(d, timeFormat)
+		}
+		resp = getNumericDelegationsResponse{Data: dtos, Meta: meta, SnapshotMaxID: filter.MaxTzktID, Links: bodyLinks}
+	default:
+		dtos := make([]DelegationDto, len(delegations))
+		for i, d := range delegations {
+			dtos[i] = toDelegationDto(d, timeFormat)
 		}
+		resp = GetDelegationsResponse{Data: dtos, Meta: meta, SnapshotMaxID: filter.MaxTzktID, Links: bodyLinks}
+	}
 
-		h.logAndRespondWithError(ctx, statusCode, userMessage, logMessage, err)
+	result := delegationsPage{link: linkHeader(reqURL, page, hasNext, filter.MaxTzktID)}
+	if format == formatXML {
+		body, err := xml.Marshal(resp)
+		if err != nil {
+			return delegationsPage{}, err
+		}
+		result.body = append([]byte(xml.Header), body...)
+		return result, nil
+	}
+	result.body, err = json.Marshal(resp)
+	return result, err
+}
+
+// streamDelegationsNDJSON streams every delegation matching the filter parameters as NDJSON.
+// Pagination and snapshot parameters don't apply, the stream is read from a single query.
+func (h *DelegationHandler) streamDelegationsNDJSON(ctx iris.Context) {
+	// Validate filter parameters
+	filter, ok := h.validateFilterParams(ctx)
+	if !ok {
 		return
 	}
 
-	// Convert to DTOs
-	dtos := make([]DelegationDto, len(delegations))
-	for i, d := range delegations {
-		dtos[i] = toDelegationDto(d)
+	// Validate timestamp format
+	timeFormat, ok := h.validateTimeFormatParam(ctx)
+	if !ok {
+		return
+	}
+
+	// Validate number rendering
+	numeric, ok := h.validateNumericParam(ctx)
+	if !ok {
+		return
+	}
+
+	h.streamDelegations(ctx, "GetDelegations", filter, newNDJSONStreamWriter(ctx.ResponseWriter(), timeFormat, numeric))
+}
+
+// validateCountOnlyParam validates and returns the countOnly query parameter
+func (h *DelegationHandler) validateCountOnlyParam(ctx iris.Context) (bool, bool) {
+	countOnlyStr := ctx.URLParam("countOnly")
+	if countOnlyStr == "" {
+		return false, true
+	}
+
+	countOnly, err := strconv.ParseBool(countOnlyStr)
+	if err != nil {
+		h.logger(ctx).Warn().Str("countOnly", countOnlyStr).Msg("Invalid countOnly parameter")
+		respondWithError(ctx, http.StatusBadRequest, CodeInvalidCountOnly, "Invalid countOnly parameter: must be true or false")
+		return false, false
+	}
+
+	return countOnly, true
+}
+
+// validateCountModeParam validates the count query parameter and reports whether an approximate count was requested
+func (h *DelegationHandler) validateCountModeParam(ctx iris.Context) (bool, bool) {
+	switch mode := ctx.URLParam("count"); mode {
+	case "", "exact":
+		return false, true
+	case "approx":
+		return true, true
+	default:
+		h.logger(ctx).Warn().Str("count", mode).Msg("Invalid count parameter")
+		respondWithError(ctx, http.StatusBadRequest, CodeInvalidCount, "Invalid count parameter: must be exact or approx")
+		return false, false
+	}
+}
+
+// CountDelegations handles HEAD /xtz/delegations, and GET /xtz/delegations?countOnly=true
+// @Summary Count delegations
+// @Description Returns the number of delegations matching the filters in the X-Total-Count header, with no body.
+// @Description X-Total-Count-Type tells whether the count is exact or an approximate estimate.
+// @Tags delegations
+// @Param year query int false "Filter by year (optional) minimum(2018)"
+// @Param maxId query int false "Only count delegations with Tzkt ID <= maxId" minimum(0)
+// @Param type query string false "Only count operations of this type" Enums(delegation, origination)
+// @Param minLevel query int false "Only count delegations at or after this block level" minimum(0)
+// @Param accountType query string false "Only count delegations from implicit (tz1/tz2/tz3) or contract (KT1) delegators" Enums(implicit, contract)
+// @Param sinceTzktId query int false "Only count delegations with Tzkt ID > sinceTzktId" minimum(0)
+// @Param count query string false "approx returns an instant estimate of the unfiltered total; filtered counts are always exact" Enums(exact, approx)
+// @Success 200 {string} string "Empty body" header(X-Total-Count)
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /xtz/delegations [head]
+func (h *DelegationHandler) CountDelegations(ctx iris.Context) {
+	// Validate filter parameters
+	filter, ok := h.validateFilterParams(ctx)
+	if !ok {
+		return
+	}
+
+	// Validate count mode parameter
+	approx, ok := h.validateCountModeParam(ctx)
+	if !ok {
+		return
+	}
+
+	var count int64
+	var err error
+	exact := true
+	if approx {
+		count, exact, err = h.Service.ApproximateCountDelegations(ctx.Request().Context(), filter)
+	} else {
+		count, err = h.Service.CountDelegations(ctx.Request().Context(), filter)
+	}
+	if err != nil {
+		h.respondWithServiceError(ctx, "CountDelegations", err)
+		return
+	}
+
+	countType := "exact"
+	if !exact {
+		countType = "approximate"
+	}
+	ctx.Header("X-Total-Count", strconv.FormatInt(count, 10))
+	ctx.Header("X-Total-Count-Type", countType)
+	ctx.StatusCode(http.StatusOK)
+}
+
+// validateTzktIDParam validates and returns the tzktId path parameter
+func (h *DelegationHandler) validateTzktIDParam(ctx iris.Context) (int64, bool) {
+	tzktIDStr := ctx.Params().Get("tzktId")
+
+	// Validate string length to prevent resource exhaustion
+	if len(tzktIDStr) > 19 {
+		h.logger(ctx).Warn().Str("tzktId", tzktIDStr).Msg("TzktID parameter too long")
+		respondWithError(ctx, http.StatusBadRequest, CodeInvalidTzktID, "Invalid tzktId parameter: too long")
+		return 0, false
+	}
+
+	tzktID, err := strconv.ParseInt(tzktIDStr, 10, 64)
+	if err != nil || tzktID < 1 {
+		h.logger(ctx).Warn().Str("tzktId", tzktIDStr).Msg("Invalid tzktId parameter")
+		respondWithError(ctx, http.StatusBadRequest, CodeInvalidTzktID, "Invalid tzktId parameter: must be a positive integer")
+		return 0, false
+	}
+
+	return tzktID, true
+}
+
+// GetDelegationByTzktID handles GET /xtz/delegations/{tzktId}
+// @Summary Get a single delegation by its Tzkt operation ID
+// @Description Retrieves one Tezos delegation identified by its Tzkt operation ID
+// @Tags delegations
+// @Produce json
+// @Param tzktId path int true "Tzkt operation ID" minimum(1)
+// @Param timeFormat query string false "Timestamp format (default: rfc3339)" Enums(rfc3339, unix, unixmilli)
+// @Param numeric query bool false "Return amount and level as JSON integers instead of strings"
+// @Success 200 {object} GetDelegationResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /xtz/delegations/{tzktId} [get]
+func (h *DelegationHandler) GetDelegationByTzktID(ctx iris.Context) {
+	// Validate path parameter
+	tzktID, ok := h.validateTzktIDParam(ctx)
+	if !ok {
+		return
+	}
+
+	// Validate timestamp format
+	timeFormat, ok := h.validateTimeFormatParam(ctx)
+	if !ok {
+		return
+	}
+
+	// Validate number rendering
+	numeric, ok := h.validateNumericParam(ctx)
+	if !ok {
+		return
+	}
+
+	// Get delegation from service
+	delegation, err := h.Service.GetDelegationByTzktID(ctx.Request().Context(), tzktID)
+	if err != nil {
+		h.respondWithServiceError(ctx, "GetDelegationByTzktID", err)
+		return
 	}
 
 	// Return response
 	ctx.StatusCode(http.StatusOK)
-	ctx.JSON(GetDelegationsResponse{Data: dtos})
+	if numeric {
+		ctx.JSON(getNumericDelegationResponse{Data: toNumericDelegationDto(*delegation, timeFormat)})
+		return
+	}
+	ctx.JSON(GetDelegationResponse{Data: toDelegationDto(*delegation, timeFormat)})
+}
+
+// DeleteDelegationByTzktID handles DELETE /xtz/delegations/{tzktId}, for correcting data after a reorg
+// or a bad import. The route is only registered when ADMIN_TOKEN is set and requires it as a bearer token.
+// @Summary Delete a delegation by its Tzkt operation ID
+// @Description Deletes one stored delegation. Admin only.
+// @Tags admin
+// @Param tzktId path int true "Tzkt operation ID" minimum(1)
+// @Param Authorization header string true "Bearer <ADMIN_TOKEN>"
+// @Success 204 "Deleted"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /xtz/delegations/{tzktId} [delete]
+func (h *DelegationHandler) DeleteDelegationByTzktID(ctx iris.Context) {
+	// Validate path parameter
+	tzktID, ok := h.validateTzktIDParam(ctx)
+	if !ok {
+		return
+	}
+
+	if err := h.Service.DeleteDelegationByTzktID(ctx.Request().Context(), tzktID); err != nil {
+		h.respondWithServiceError(ctx, "DeleteDelegationByTzktID", err)
+		return
+	}
+
+	ctx.StatusCode(http.StatusNoContent)
+}
+
+// ExportDelegationsCSV handles GET /xtz/delegations.csv
+// @Summary Export delegations as CSV
+// @Description Streams all delegations matching the filters as a CSV attachment, most recent first
+// @Tags delegations
+// @Produce text/csv
+// @Param year query int false "Filter by year (optional) minimum(2018)"
+// @Param maxId query int false "Only return delegations with Tzkt ID <= maxId" minimum(0)
+// @Param type query string false "Only return operations of this type" Enums(delegation, origination)
+// @Param minLevel query int false "Only return delegations at or after this block level" minimum(0)
+// @Param accountType query string false "Only return delegations from implicit (tz1/tz2/tz3) or contract (KT1) delegators" Enums(implicit, contract)
+// @Param sinceTzktId query int false "Only return delegations with Tzkt ID > sinceTzktId, ordered by ascending Tzkt ID for incremental syncs" minimum(0)
+// @Success 200 {string} string "CSV with header timestamp,amount,delegator,level,tzkt_id"
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /xtz/delegations.csv [get]
+func (h *DelegationHandler) ExportDelegationsCSV(ctx iris.Context) {
+	// Validate filter parameters
+	filter, ok := h.validateFilterParams(ctx)
+	if !ok {
+		return
+	}
+
+	h.streamDelegations(ctx, "ExportDelegationsCSV", filter, newCSVStreamWriter(ctx.ResponseWriter()))
+}
+
+// GetStatsByYear handles GET /xtz/delegations/stats/by-year
+// @Summary Get delegation totals by year
+// @Description Returns the number of delegations and total delegated amount for each year, ordered by year
+// @Tags delegations
+// @Produce json
+// @Success 200 {object} GetStatsByYearResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /xtz/delegations/stats/by-year [get]
+func (h *DelegationHandler) GetStatsByYear(ctx iris.Context) {
+	stats, err := h.Service.GetStatsByYear(ctx.Request().Context())
+	if err != nil {
+		h.respondWithServiceError(ctx, "GetStatsByYear", err)
+		return
+	}
+
+	// Convert to DTOs
+	dtos := make([]YearStatsDto, len(stats))
+	for i, s := range stats {
+		dtos[i] = toYearStatsDto(s)
+	}
+
+	ctx.StatusCode(http.StatusOK)
+	ctx.JSON(GetStatsByYearResponse{Data: dtos})
+}
+
+// GetMonthlyStats handles GET /xtz/delegations/stats/monthly
+// @Summary Get delegation totals by month
+// @Description Returns the number of delegations and total delegated amount for each month of a year, including months without delegations
+// @Tags delegations
+// @Produce json
+// @Param year query int true "Year to report minimum(2018)"
+// @Success 200 {object} GetMonthlyStatsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /xtz/delegations/stats/monthly [get]
+func (h *DelegationHandler) GetMonthlyStats(ctx iris.Context) {
+	// Validate year parameter, which is required here
+	yearPtr, ok := h.validateYearParam(ctx)
+	if !ok {
+		return
+	}
+	if yearPtr == nil {
+		h.logger(ctx).Warn().Msg("Missing year parameter")
+		respondWithError(ctx, http.StatusBadRequest, CodeInvalidYear, "Invalid year parameter: required")
+		return
+	}
+
+	stats, err := h.Service.GetMonthlyStats(ctx.Request().Context(), *yearPtr)
+	if err != nil {
+		h.respondWithServiceError(ctx, "GetMonthlyStats", err)
+		return
+	}
+
+	// Convert to DTOs
+	dtos := make([]MonthStatsDto, len(stats))
+	for i, s := range stats {
+		dtos[i] = toMonthStatsDto(s)
+	}
+
+	ctx.StatusCode(http.StatusOK)
+	ctx.JSON(GetMonthlyStatsResponse{Year: *yearPtr, Data: dtos})
+}
+
+// GetTopDelegators handles GET /xtz/delegations/stats/top-delegators
+// @Summary Get top delegators
+// @Description Returns delegators ranked by total delegated amount, optionally for a single year
+// @Tags delegations
+// @Produce json
+// @Param limit query int false "Number of delegators to return (default: 10, max: 100)" minimum(1) maximum(100)
+// @Param year query int false "Filter by year (optional) minimum(2018)"
+// @Success 200 {object} GetTopDelegatorsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /xtz/delegations/stats/top-delegators [get]
+func (h *DelegationHandler) GetTopDelegators(ctx iris.Context) {
+	// Validate limit parameter
+	limit, ok := h.validateLimitParam(ctx)
+	if !ok {
+		return
+	}
+
+	// Validate year parameter
+	yearPtr, ok := h.validateYearParam(ctx)
+	if !ok {
+		return
+	}
+
+	stats, err := h.Service.GetTopDelegators(ctx.Request().Context(), limit, yearPtr)
+	if err != nil {
+		h.respondWithServiceError(ctx, "GetTopDelegators", err)
+		return
+	}
+
+	// Convert to DTOs
+	dtos := make([]DelegatorStatsDto, len(stats))
+	for i, s := range stats {
+		dtos[i] = toDelegatorStatsDto(s)
+	}
+
+	ctx.StatusCode(http.StatusOK)
+	ctx.JSON(GetTopDelegatorsResponse{Data: dtos})
+}
+
+// GetDistinctDelegators handles GET /xtz/delegations/stats/delegators
+// @Summary Count distinct delegators
+// @Description Returns the number of distinct delegators, optionally for a single year
+// @Tags delegations
+// @Produce json
+// @Param year query int false "Filter by year (optional) minimum(2018)"
+// @Success 200 {object} GetDistinctDelegatorsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /xtz/delegations/stats/delegators [get]
+func (h *DelegationHandler) GetDistinctDelegators(ctx iris.Context) {
+	// Validate year parameter
+	yearPtr, ok := h.validateYearParam(ctx)
+	if !ok {
+		return
+	}
+
+	count, err := h.Service.CountDistinctDelegators(ctx.Request().Context(), yearPtr)
+	if err != nil {
+		h.respondWithServiceError(ctx, "GetDistinctDelegators", err)
+		return
+	}
+
+	ctx.StatusCode(http.StatusOK)
+	ctx.JSON(GetDistinctDelegatorsResponse{DistinctDelegators: count})
+}
+
+// validateAddressParam validates and returns the address path parameter
+func (h *DelegationHandler) validateAddressParam(ctx iris.Context) (string, bool) {
+	address := ctx.Params().Get("address")
+	if err := model.ValidateTezosAddress(address); err != nil {
+		h.logger(ctx).Warn().Err(err).Str("address", address).Msg("Invalid address parameter")
+		respondWithError(ctx, http.StatusBadRequest, CodeInvalidAddress, "Invalid address parameter: must be a tz1, tz2, tz3 or KT1 address")
+		return "", false
+	}
+	return address, true
+}
+
+// GetDelegatorSummary handles GET /xtz/delegations/delegator/{address}/summary
+// @Summary Get a delegator summary
+// @Description Returns the first and last delegation times, delegation count and total delegated amount for one delegator
+// @Tags delegations
+// @Produce json
+// @Param address path string true "Delegator address (tz1, tz2, tz3 or KT1)"
+// @Success 200 {object} GetDelegatorSummaryResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /xtz/delegations/delegator/{address}/summary [get]
+func (h *DelegationHandler) GetDelegatorSummary(ctx iris.Context) {
+	// Validate path parameter
+	address, ok := h.validateAddressParam(ctx)
+	if !ok {
+		return
+	}
+
+	summary, err := h.Service.GetDelegatorSummary(ctx.Request().Context(), address)
+	if err != nil {
+		h.respondWithServiceError(ctx, "GetDelegatorSummary", err)
+		return
+	}
+
+	ctx.StatusCode(http.StatusOK)
+	ctx.JSON(GetDelegatorSummaryResponse{Data: toDelegatorSummaryDto(*summary)})
 }
@@ -1,12 +1,18 @@
 package api
 
 import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"strings"
 	"testing"
 	"time"
 
 	"tezos-delegation/internal/apperrors"
 	"tezos-delegation/internal/mocks"
 	"tezos-delegation/internal/model"
+	"tezos-delegation/internal/services"
 
 	"github.com/golang/mock/gomock"
 	"github.com/kataras/iris/v12"
@@ -15,20 +21,23 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+// invalidYearMessage is the error for out-of-range years with the default MaxYear
+var invalidYearMessage = "Invalid year parameter: " + model.YearRangeMessage(time.Now().Year())
+
 func TestDelegationHandler_GetDelegations(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	service := mocks.NewMockDelegationServicePort(ctrl)
 	logger := zerolog.Nop()
-	handler := NewDelegationHandler(service, logger)
+	handler := NewDelegationHandler(service, logger, HandlerOptions{})
 
 	app := iris.New()
 	app.Get("/xtz/delegations", handler.GetDelegations)
 	test := httptest.New(t, app)
 
 	expected := []model.Delegation{{TzktID: 1, Delegator: "tz1", Amount: 100, Level: 1, Timestamp: fixedTime()}}
-	service.EXPECT().GetDelegations(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(expected, nil)
+	service.EXPECT().GetDelegations(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(expected, false, nil)
 
 	resp := test.GET("/xtz/delegations").Expect().Status(200).JSON().Object()
 	resp.Value("data").Array().Value(0).Object().HasValue("delegator", "tz1")
@@ -36,13 +45,145 @@ func TestDelegationHandler_GetDelegations(t *testing.T) {
 	resp.Value("data").Array().Value(0).Object().HasValue("timestamp", "2022-05-05T06:29:14Z")
 }
 
+func TestDelegationHandler_GetDelegations_TimeFormat(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockDelegationServicePort(ctrl)
+	handler := NewDelegationHandler(service, zerolog.Nop(), HandlerOptions{})
+
+	app := iris.New()
+	app.Get("/xtz/delegations", handler.GetDelegations)
+	app.Get("/xtz/delegations/{tzktId}", handler.GetDelegationByTzktID)
+	test := httptest.New(t, app)
+
+	delegation := model.Delegation{TzktID: 1, Delegator: "tz1", Amount: 100, Level: 1, Timestamp: fixedTime().Add(250 * time.Millisecond)}
+	tests := []struct {
+		timeFormat string
+		want       string
+	}{
+		{"", "2022-05-05T06:29:14Z"},
+		{"rfc3339", "2022-05-05T06:29:14Z"},
+		{"unix", "1651732154"},
+		{"unixmilli", "1651732154250"},
+	}
+	for _, tc := range tests {
+		t.Run("list "+tc.timeFormat, func(t *testing.T) {
+			service.EXPECT().GetDelegations(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return([]model.Delegation{delegation}, false, nil)
+			resp := test.GET("/xtz/delegations").WithQuery("timeFormat", tc.timeFormat).Expect().Status(200).JSON().Object()
+			resp.Value("data").Array().Value(0).Object().HasValue("timestamp", tc.want)
+		})
+		t.Run("single "+tc.timeFormat, func(t *testing.T) {
+			service.EXPECT().GetDelegationByTzktID(gomock.Any(), int64(1)).Return(&delegation, nil)
+			resp := test.GET("/xtz/delegations/1").WithQuery("timeFormat", tc.timeFormat).Expect().Status(200).JSON().Object()
+			resp.Value("data").Object().HasValue("timestamp", tc.want)
+		})
+	}
+
+	t.Run("unknown format", func(t *testing.T) {
+		resp := test.GET("/xtz/delegations").WithQuery("timeFormat", "iso").Expect().Status(400).JSON().Object()
+		resp.Value("code").String().IsEqual(CodeInvalidTimeFormat)
+	})
+}
+
+func TestDelegationHandler_GetDelegations_Numeric(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockDelegationServicePort(ctrl)
+	handler := NewDelegationHandler(service, zerolog.Nop(), HandlerOptions{})
+
+	app := iris.New()
+	app.Get("/xtz/delegations", handler.GetDelegations)
+	app.Get("/xtz/delegations/{tzktId}", handler.GetDelegationByTzktID)
+	test := httptest.New(t, app)
+
+	// Beyond float64's exact integer range, so a lossy encoding would show
+	delegation := model.Delegation{TzktID: 1, Delegator: "tz1", Amount: math.MaxInt64, Level: 9007199254740993, Timestamp: fixedTime()}
+	const stringFields = `{"timestamp":"2022-05-05T06:29:14Z","amount":"9223372036854775807","delegator":"tz1","level":"9007199254740993"}`
+	const numericFields = `{"timestamp":"2022-05-05T06:29:14Z","amount":9223372036854775807,"delegator":"tz1","level":9007199254740993}`
+
+	for _, numeric := range []string{"", "false"} {
+		t.Run("strings with numeric="+numeric, func(t *testing.T) {
+			service.EXPECT().GetDelegations(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return([]model.Delegation{delegation}, false, nil)
+			body := test.GET("/xtz/delegations").WithQuery("numeric", numeric).Expect().Status(200).Body().Raw()
+			assert.Contains(t, body, `"data":[`+stringFields+`]`)
+		})
+	}
+
+	t.Run("list", func(t *testing.T) {
+		service.EXPECT().GetDelegations(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return([]model.Delegation{delegation}, false, nil)
+		body := test.GET("/xtz/delegations").WithQuery("numeric", "true").Expect().Status(200).Body().Raw()
+		assert.Contains(t, body, `"data":[`+numericFields+`]`)
+	})
+
+	t.Run("list with fields", func(t *testing.T) {
+		service.EXPECT().GetDelegations(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return([]model.Delegation{delegation}, false, nil)
+		body := test.GET("/xtz/delegations").WithQuery("numeric", "true").WithQuery("fields", "amount,delegator").
+			Expect().Status(200).Body().Raw()
+		assert.Contains(t, body, `"data":[{"amount":9223372036854775807,"delegator":"tz1"}]`)
+	})
+
+	t.Run("single", func(t *testing.T) {
+		service.EXPECT().GetDelegationByTzktID(gomock.Any(), int64(1)).Return(&delegation, nil)
+		body := test.GET("/xtz/delegations/1").WithQuery("numeric", "true").Expect().Status(200).Body().Raw()
+		assert.Contains(t, body, `{"data":`+numericFields+`}`)
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		service.EXPECT().StreamDelegations(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ model.DelegationFilter, fn func(model.Delegation) error) error {
+				return fn(delegation)
+			})
+		test.GET("/xtz/delegations").WithHeader("Accept", "application/x-ndjson").WithQuery("numeric", "true").
+			Expect().Status(200).Body().IsEqual(numericFields + "\n")
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		test.GET("/xtz/delegations").WithQuery("numeric", "yes").Expect().Status(400).JSON().Object().HasValue("code", CodeInvalidNumeric)
+		test.GET("/xtz/delegations/1").WithQuery("numeric", "yes").Expect().Status(400).JSON().Object().HasValue("code", CodeInvalidNumeric)
+	})
+}
+
+func TestDelegationHandler_GetDelegations_PageMeta(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockDelegationServicePort(ctrl)
+	handler := NewDelegationHandler(service, zerolog.Nop(), HandlerOptions{})
+
+	app := iris.New()
+	app.Get("/xtz/delegations", handler.GetDelegations)
+	test := httptest.New(t, app)
+
+	expected := []model.Delegation{{TzktID: 1, Delegator: "tz1", Amount: 100, Level: 1, Timestamp: fixedTime()}}
+
+	t.Run("first page with more", func(t *testing.T) {
+		service.EXPECT().GetDelegations(gomock.Any(), 1, 1, gomock.Any()).Return(expected, true, nil)
+
+		meta := test.GET("/xtz/delegations").WithQuery("page", 1).WithQuery("pageSize", 1).Expect().Status(200).JSON().Object().Value("meta").Object()
+		meta.HasValue("page", 1)
+		meta.HasValue("page_size", 1)
+		meta.HasValue("has_next", true)
+		meta.HasValue("has_prev", false)
+	})
+
+	t.Run("last page", func(t *testing.T) {
+		service.EXPECT().GetDelegations(gomock.Any(), 3, 1, gomock.Any()).Return(expected, false, nil)
+
+		meta := test.GET("/xtz/delegations").WithQuery("page", 3).WithQuery("pageSize", 1).Expect().Status(200).JSON().Object().Value("meta").Object()
+		meta.HasValue("has_next", false)
+		meta.HasValue("has_prev", true)
+	})
+}
+
 func TestDelegationHandler_GetDelegations_OptionalQueryParams(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	service := mocks.NewMockDelegationServicePort(ctrl)
 	logger := zerolog.Nop()
-	handler := NewDelegationHandler(service, logger)
+	handler := NewDelegationHandler(service, logger, HandlerOptions{})
 
 	app := iris.New()
 	app.Get("/xtz/delegations", handler.GetDelegations)
@@ -63,7 +204,7 @@ func TestDelegationHandler_GetDelegations_OptionalQueryParams(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			service.EXPECT().GetDelegations(gomock.Any(), tc.page, gomock.Any(), tc.year).Return(tc.expected, nil)
+			service.EXPECT().GetDelegations(gomock.Any(), tc.page, gomock.Any(), model.DelegationFilter{Year: tc.year}).Return(tc.expected, false, nil)
 			resp := test.GET("/xtz/delegations").WithQueryString(tc.query).Expect().Status(200).JSON().Object()
 			if len(tc.expected) > 0 {
 				resp.Value("data").Array().Value(0).Object().HasValue("delegator", tc.expected[0].Delegator)
@@ -74,13 +215,37 @@ func TestDelegationHandler_GetDelegations_OptionalQueryParams(t *testing.T) {
 	}
 }
 
+func TestDelegationHandler_GetDelegations_MaxYear(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockDelegationServicePort(ctrl)
+	handler := NewDelegationHandler(service, zerolog.Nop(), HandlerOptions{MaxYear: 2030})
+
+	app := iris.New()
+	app.Get("/xtz/delegations", handler.GetDelegations)
+	test := httptest.New(t, app)
+
+	t.Run("max year accepted", func(t *testing.T) {
+		year := 2030
+		service.EXPECT().GetDelegations(gomock.Any(), 1, gomock.Any(), model.DelegationFilter{Year: &year}).Return([]model.Delegation{}, false, nil)
+		test.GET("/xtz/delegations").WithQueryString("year=2030").Expect().Status(200)
+	})
+
+	t.Run("later year rejected", func(t *testing.T) {
+		resp := test.GET("/xtz/delegations").WithQueryString("year=2031").Expect().Status(400).JSON().Object()
+		resp.Value("error").String().IsEqual("Invalid year parameter: must be a valid year between 2018 and 2030")
+		resp.Value("code").String().IsEqual(CodeInvalidYear)
+	})
+}
+
 func TestDelegationHandler_GetDelegations_ErrorConditions(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	service := mocks.NewMockDelegationServicePort(ctrl)
 	logger := zerolog.Nop()
-	handler := NewDelegationHandler(service, logger)
+	handler := NewDelegationHandler(service, logger, HandlerOptions{})
 
 	app := iris.New()
 	app.Get("/xtz/delegations", handler.GetDelegations)
@@ -89,45 +254,81 @@ func TestDelegationHandler_GetDelegations_ErrorConditions(t *testing.T) {
 	t.Run("invalid page", func(t *testing.T) {
 		resp := test.GET("/xtz/delegations").WithQueryString("page=abc").Expect().Status(400).JSON().Object()
 		resp.Value("error").String().IsEqual("Invalid page parameter: must be a positive integer")
+		resp.Value("code").String().IsEqual(CodeInvalidPage)
 	})
 	t.Run("invalid year", func(t *testing.T) {
 		resp := test.GET("/xtz/delegations").WithQueryString("year=bad").Expect().Status(400).JSON().Object()
-		resp.Value("error").String().IsEqual("Invalid year parameter: must be a valid year from 2018 onwards")
+		resp.Value("error").String().IsEqual(invalidYearMessage)
+		resp.Value("code").String().IsEqual(CodeInvalidYear)
 	})
 	t.Run("negative page", func(t *testing.T) {
 		resp := test.GET("/xtz/delegations").WithQueryString("page=-1").Expect().Status(400).JSON().Object()
 		resp.Value("error").String().IsEqual("Invalid page parameter: must be a positive integer")
+		resp.Value("code").String().IsEqual(CodeInvalidPage)
 	})
 	t.Run("negative year", func(t *testing.T) {
 		resp := test.GET("/xtz/delegations").WithQueryString("year=-5").Expect().Status(400).JSON().Object()
-		resp.Value("error").String().IsEqual("Invalid year parameter: must be a valid year from 2018 onwards")
+		resp.Value("error").String().IsEqual(invalidYearMessage)
+		resp.Value("code").String().IsEqual(CodeInvalidYear)
 	})
 	t.Run("year before 2018", func(t *testing.T) {
 		resp := test.GET("/xtz/delegations").WithQueryString("year=2017").Expect().Status(400).JSON().Object()
-		resp.Value("error").String().IsEqual("Invalid year parameter: must be a valid year from 2018 onwards")
+		resp.Value("error").String().IsEqual(invalidYearMessage)
+		resp.Value("code").String().IsEqual(CodeInvalidYear)
 	})
 	t.Run("invalid pageSize", func(t *testing.T) {
 		resp := test.GET("/xtz/delegations").WithQueryString("pageSize=abc").Expect().Status(400).JSON().Object()
 		resp.Value("error").String().IsEqual("Invalid pageSize parameter: must be between 1 and 1000")
+		resp.Value("code").String().IsEqual(CodeInvalidPageSize)
 	})
 	t.Run("pageSize too large", func(t *testing.T) {
 		resp := test.GET("/xtz/delegations").WithQueryString("pageSize=1001").Expect().Status(400).JSON().Object()
 		resp.Value("error").String().IsEqual("Invalid pageSize parameter: must be between 1 and 1000")
+		resp.Value("code").String().IsEqual(CodeInvalidPageSize)
 	})
 	t.Run("pageSize zero", func(t *testing.T) {
 		resp := test.GET("/xtz/delegations").WithQueryString("pageSize=0").Expect().Status(400).JSON().Object()
 		resp.Value("error").String().IsEqual("Invalid pageSize parameter: must be between 1 and 1000")
+		resp.Value("code").String().IsEqual(CodeInvalidPageSize)
 	})
 	t.Run("service database error", func(t *testing.T) {
 		dbErr := apperrors.NewDatabaseError("query", "connection failed")
-		service.EXPECT().GetDelegations(gomock.Any(), 1, gomock.Any(), gomock.Any()).Return(nil, dbErr)
+		service.EXPECT().GetDelegations(gomock.Any(), 1, gomock.Any(), gomock.Any()).Return(nil, false, dbErr)
 		resp := test.GET("/xtz/delegations").Expect().Status(500).JSON().Object()
 		resp.Value("error").String().IsEqual("Database error")
+		resp.Value("code").String().IsEqual(CodeDatabaseError)
+	})
+	t.Run("service database unavailable", func(t *testing.T) {
+		dbErr := apperrors.NewDatabaseUnavailableError(apperrors.NewDatabaseError("query", "connection refused"))
+		service.EXPECT().GetDelegations(gomock.Any(), 1, gomock.Any(), gomock.Any()).Return(nil, false, dbErr)
+		e := test.GET("/xtz/delegations").Expect().Status(503)
+		e.Header("Retry-After").IsEqual("5")
+		resp := e.JSON().Object()
+		resp.Value("error").String().IsEqual("Database temporarily unavailable")
+		resp.Value("code").String().IsEqual(CodeDBUnavailable)
+	})
+	t.Run("service validation error", func(t *testing.T) {
+		service.EXPECT().GetDelegations(gomock.Any(), 1, gomock.Any(), gomock.Any()).Return(nil, false, apperrors.NewValidationError("year", "out of range"))
+		resp := test.GET("/xtz/delegations").Expect().Status(400).JSON().Object()
+		resp.Value("error").String().IsEqual("Invalid request parameters")
+		resp.Value("code").String().IsEqual(CodeInvalidRequest)
+		resp.Value("details").IsEqual([]map[string]any{{"field": "year", "message": "out of range"}})
+	})
+	t.Run("service validation error for pageSize", func(t *testing.T) {
+		pageSizeErr := apperrors.NewValidationError("pageSize", "cannot exceed 1000, got 1001")
+		service.EXPECT().GetDelegations(gomock.Any(), 1, gomock.Any(), gomock.Any()).Return(nil, false, fmt.Errorf("invalid pagination parameters: %w", pageSizeErr))
+		resp := test.GET("/xtz/delegations").Expect().Status(400).JSON().Object()
+		resp.Value("error").String().IsEqual("Invalid request parameters")
+		details := resp.Value("details").Array()
+		details.Length().IsEqual(1)
+		details.Value(0).Object().HasValue("field", "pageSize").HasValue("message", "cannot exceed 1000, got 1001")
 	})
 	t.Run("service general error", func(t *testing.T) {
-		service.EXPECT().GetDelegations(gomock.Any(), 1, gomock.Any(), gomock.Any()).Return(nil, assert.AnError)
+		service.EXPECT().GetDelegations(gomock.Any(), 1, gomock.Any(), gomock.Any()).Return(nil, false, assert.AnError)
 		resp := test.GET("/xtz/delegations").Expect().Status(500).JSON().Object()
 		resp.Value("error").String().IsEqual("Internal server error")
+		resp.Value("code").String().IsEqual(CodeInternalError)
+		resp.NotContainsKey("details")
 	})
 }
 
@@ -137,27 +338,27 @@ func TestDelegationHandler_GetDelegations_EdgeCases(t *testing.T) {
 
 	service := mocks.NewMockDelegationServicePort(ctrl)
 	logger := zerolog.Nop()
-	handler := NewDelegationHandler(service, logger)
+	handler := NewDelegationHandler(service, logger, HandlerOptions{})
 
 	app := iris.New()
 	app.Get("/xtz/delegations", handler.GetDelegations)
 	test := httptest.New(t, app)
 
 	t.Run("empty result", func(t *testing.T) {
-		service.EXPECT().GetDelegations(gomock.Any(), 1, gomock.Any(), gomock.Any()).Return([]model.Delegation{}, nil)
+		service.EXPECT().GetDelegations(gomock.Any(), 1, gomock.Any(), gomock.Any()).Return([]model.Delegation{}, false, nil)
 		resp := test.GET("/xtz/delegations").Expect().Status(200).JSON().Object()
 		resp.Value("data").Array().IsEmpty()
 	})
 
 	t.Run("large page number", func(t *testing.T) {
-		service.EXPECT().GetDelegations(gomock.Any(), 9999, gomock.Any(), gomock.Any()).Return([]model.Delegation{}, nil)
+		service.EXPECT().GetDelegations(gomock.Any(), 9999, gomock.Any(), gomock.Any()).Return([]model.Delegation{}, false, nil)
 		resp := test.GET("/xtz/delegations").WithQueryString("page=9999").Expect().Status(200).JSON().Object()
 		resp.Value("data").Array().IsEmpty()
 	})
 
 	t.Run("year with no data", func(t *testing.T) {
 		year := 2019
-		service.EXPECT().GetDelegations(gomock.Any(), 1, gomock.Any(), &year).Return([]model.Delegation{}, nil)
+		service.EXPECT().GetDelegations(gomock.Any(), 1, gomock.Any(), model.DelegationFilter{Year: &year}).Return([]model.Delegation{}, false, nil)
 		resp := test.GET("/xtz/delegations").WithQueryString("year=2019").Expect().Status(200).JSON().Object()
 		resp.Value("data").Array().IsEmpty()
 	})
@@ -165,7 +366,7 @@ func TestDelegationHandler_GetDelegations_EdgeCases(t *testing.T) {
 	t.Run("valid year 2018", func(t *testing.T) {
 		year := 2018
 		expected := []model.Delegation{{TzktID: 1, Delegator: "tz1", Amount: 100, Level: 1, Timestamp: fixedTime()}}
-		service.EXPECT().GetDelegations(gomock.Any(), 1, gomock.Any(), &year).Return(expected, nil)
+		service.EXPECT().GetDelegations(gomock.Any(), 1, gomock.Any(), model.DelegationFilter{Year: &year}).Return(expected, false, nil)
 		resp := test.GET("/xtz/delegations").WithQueryString("year=2018").Expect().Status(200).JSON().Object()
 		resp.Value("data").Array().Value(0).Object().HasValue("delegator", "tz1")
 	})
@@ -173,7 +374,7 @@ func TestDelegationHandler_GetDelegations_EdgeCases(t *testing.T) {
 	t.Run("valid year 2023", func(t *testing.T) {
 		year := 2023
 		expected := []model.Delegation{{TzktID: 2, Delegator: "tz2", Amount: 200, Level: 2, Timestamp: fixedTime()}}
-		service.EXPECT().GetDelegations(gomock.Any(), 1, gomock.Any(), &year).Return(expected, nil)
+		service.EXPECT().GetDelegations(gomock.Any(), 1, gomock.Any(), model.DelegationFilter{Year: &year}).Return(expected, false, nil)
 		resp := test.GET("/xtz/delegations").WithQueryString("year=2023").Expect().Status(200).JSON().Object()
 		resp.Value("data").Array().Value(0).Object().HasValue("delegator", "tz2")
 	})
@@ -185,7 +386,7 @@ func TestDelegationHandler_GetDelegations_ValidationScenarios(t *testing.T) {
 
 	service := mocks.NewMockDelegationServicePort(ctrl)
 	logger := zerolog.Nop()
-	handler := NewDelegationHandler(service, logger)
+	handler := NewDelegationHandler(service, logger, HandlerOptions{})
 
 	app := iris.New()
 	app.Get("/xtz/delegations", handler.GetDelegations)
@@ -206,6 +407,7 @@ func TestDelegationHandler_GetDelegations_ValidationScenarios(t *testing.T) {
 			t.Run(tc.query, func(t *testing.T) {
 				resp := test.GET("/xtz/delegations").WithQueryString(tc.query).Expect().Status(400).JSON().Object()
 				resp.Value("error").String().IsEqual(tc.expectedMsg)
+				resp.Value("code").String().IsEqual(CodeInvalidPage)
 			})
 		}
 	})
@@ -226,6 +428,7 @@ func TestDelegationHandler_GetDelegations_ValidationScenarios(t *testing.T) {
 			t.Run(tc.query, func(t *testing.T) {
 				resp := test.GET("/xtz/delegations").WithQueryString(tc.query).Expect().Status(400).JSON().Object()
 				resp.Value("error").String().IsEqual(tc.expectedMsg)
+				resp.Value("code").String().IsEqual(CodeInvalidPageSize)
 			})
 		}
 	})
@@ -235,15 +438,16 @@ func TestDelegationHandler_GetDelegations_ValidationScenarios(t *testing.T) {
 			query       string
 			expectedMsg string
 		}{
-			{"year=2017", "Invalid year parameter: must be a valid year from 2018 onwards"},
-			{"year=2016", "Invalid year parameter: must be a valid year from 2018 onwards"},
-			{"year=abc", "Invalid year parameter: must be a valid year from 2018 onwards"},
+			{"year=2017", invalidYearMessage},
+			{"year=2016", invalidYearMessage},
+			{"year=abc", invalidYearMessage},
 		}
 
 		for _, tc := range testCases {
 			t.Run(tc.query, func(t *testing.T) {
 				resp := test.GET("/xtz/delegations").WithQueryString(tc.query).Expect().Status(400).JSON().Object()
 				resp.Value("error").String().IsEqual(tc.expectedMsg)
+				resp.Value("code").String().IsEqual(CodeInvalidYear)
 			})
 		}
 	})
@@ -251,15 +455,1041 @@ func TestDelegationHandler_GetDelegations_ValidationScenarios(t *testing.T) {
 	t.Run("valid year parameter cases", func(t *testing.T) {
 		// Test that empty year parameter is valid (no year filter)
 		expected := []model.Delegation{{TzktID: 1, Delegator: "tz1", Amount: 100, Level: 1, Timestamp: fixedTime()}}
-		service.EXPECT().GetDelegations(gomock.Any(), 1, gomock.Any(), (*int)(nil)).Return(expected, nil)
+		service.EXPECT().GetDelegations(gomock.Any(), 1, gomock.Any(), model.DelegationFilter{}).Return(expected, false, nil)
 
 		resp := test.GET("/xtz/delegations").WithQueryString("year=").Expect().Status(200).JSON().Object()
 		resp.Value("data").Array().Value(0).Object().HasValue("delegator", "tz1")
 	})
 }
 
-func intPtr(i int) *int { return &i }
+func TestDelegationHandler_GetDelegations_Snapshot(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
 
-func fixedTime() time.Time {
-	return time.Date(2022, 5, 5, 6, 29, 14, 0, time.UTC)
+	service := mocks.NewMockDelegationServicePort(ctrl)
+	logger := zerolog.Nop()
+	handler := NewDelegationHandler(service, logger, HandlerOptions{})
+
+	app := iris.New()
+	app.Get("/xtz/delegations", handler.GetDelegations)
+	test := httptest.New(t, app)
+
+	expected := []model.Delegation{{TzktID: 1, Delegator: "tz1", Amount: 100, Level: 1, Timestamp: fixedTime()}}
+
+	t.Run("no snapshot by default", func(t *testing.T) {
+		service.EXPECT().GetDelegations(gomock.Any(), 1, gomock.Any(), model.DelegationFilter{}).Return(expected, false, nil)
+
+		resp := test.GET("/xtz/delegations").Expect().Status(200).JSON().Object()
+		resp.NotContainsKey("snapshot_max_id")
+	})
+
+	t.Run("snapshot pins to current max id", func(t *testing.T) {
+		maxID := int64(500)
+		service.EXPECT().GetSnapshotMaxID(gomock.Any()).Return(maxID, nil)
+		service.EXPECT().GetDelegations(gomock.Any(), 1, gomock.Any(), model.DelegationFilter{MaxTzktID: &maxID}).Return(expected, false, nil)
+
+		resp := test.GET("/xtz/delegations").WithQueryString("snapshot=true").Expect().Status(200).JSON().Object()
+		resp.Value("snapshot_max_id").Number().IsEqual(500)
+	})
+
+	t.Run("maxId passed back by client", func(t *testing.T) {
+		maxID := int64(500)
+		year := 2022
+		service.EXPECT().GetDelegations(gomock.Any(), 2, gomock.Any(), model.DelegationFilter{Year: &year, MaxTzktID: &maxID}).Return(expected, false, nil)
+
+		resp := test.GET("/xtz/delegations").WithQueryString("page=2&year=2022&maxId=500&snapshot=true").Expect().Status(200).JSON().Object()
+		resp.Value("snapshot_max_id").Number().IsEqual(500)
+	})
+
+	t.Run("snapshot lookup error", func(t *testing.T) {
+		service.EXPECT().GetSnapshotMaxID(gomock.Any()).Return(int64(0), apperrors.NewDatabaseError("query", "connection failed"))
+
+		resp := test.GET("/xtz/delegations").WithQueryString("snapshot=true").Expect().Status(500).JSON().Object()
+		resp.Value("error").String().IsEqual("Database error")
+		resp.Value("code").String().IsEqual(CodeDatabaseError)
+	})
+
+	t.Run("invalid maxId", func(t *testing.T) {
+		resp := test.GET("/xtz/delegations").WithQueryString("maxId=-1").Expect().Status(400).JSON().Object()
+		resp.Value("error").String().IsEqual("Invalid maxId parameter: must be a non-negative integer")
+		resp.Value("code").String().IsEqual(CodeInvalidMaxID)
+	})
+
+	t.Run("invalid snapshot", func(t *testing.T) {
+		resp := test.GET("/xtz/delegations").WithQueryString("snapshot=maybe").Expect().Status(400).JSON().Object()
+		resp.Value("error").String().IsEqual("Invalid snapshot parameter: must be true or false")
+		resp.Value("code").String().IsEqual(CodeInvalidSnapshot)
+	})
+}
+
+func TestDelegationHandler_GetDelegations_Links(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockDelegationServicePort(ctrl)
+	logger := zerolog.Nop()
+	handler := NewDelegationHandler(service, logger, HandlerOptions{})
+
+	app := iris.New()
+	app.Get("/xtz/delegations", handler.GetDelegations)
+	test := httptest.New(t, app)
+
+	expected := []model.Delegation{{TzktID: 1, Delegator: "tz1", Amount: 100, Level: 1, Timestamp: fixedTime()}}
+	year := 2022
+
+	t.Run("no links by default", func(t *testing.T) {
+		service.EXPECT().GetDelegations(gomock.Any(), 1, gomock.Any(), gomock.Any()).Return(expected, true, nil)
+
+		resp := test.GET("/xtz/delegations").Expect().Status(200).JSON().Object()
+		resp.NotContainsKey("_links")
+	})
+
+	t.Run("first page", func(t *testing.T) {
+		service.EXPECT().GetDelegations(gomock.Any(), 1, 10, model.DelegationFilter{Year: &year}).Return(expected, true, nil)
+
+		links := test.GET("/xtz/delegations").WithQueryString("year=2022&pageSize=10&links=true").
+			Expect().Status(200).JSON().Object().Value("_links").Object()
+		links.HasValue("self", "/xtz/delegations?links=true&page=1&pageSize=10&year=2022")
+		links.HasValue("next", "/xtz/delegations?links=true&page=2&pageSize=10&year=2022")
+		links.NotContainsKey("prev")
+	})
+
+	t.Run("middle page", func(t *testing.T) {
+		service.EXPECT().GetDelegations(gomock.Any(), 3, 10, model.DelegationFilter{Year: &year}).Return(expected, true, nil)
+
+		links := test.GET("/xtz/delegations").WithQueryString("year=2022&pageSize=10&page=3&links=true").
+			Expect().Status(200).JSON().Object().Value("_links").Object()
+		links.HasValue("self", "/xtz/delegations?links=true&page=3&pageSize=10&year=2022")
+		links.HasValue("next", "/xtz/delegations?links=true&page=4&pageSize=10&year=2022")
+		links.HasValue("prev", "/xtz/delegations?links=true&page=2&pageSize=10&year=2022")
+	})
+
+	t.Run("last page", func(t *testing.T) {
+		service.EXPECT().GetDelegations(gomock.Any(), 4, 10, model.DelegationFilter{Year: &year}).Return(expected, false, nil)
+
+		links := test.GET("/xtz/delegations").WithQueryString("year=2022&pageSize=10&page=4&links=true").
+			Expect().Status(200).JSON().Object().Value("_links").Object()
+		links.HasValue("self", "/xtz/delegations?links=true&page=4&pageSize=10&year=2022")
+		links.NotContainsKey("next")
+		links.HasValue("prev", "/xtz/delegations?links=true&page=3&pageSize=10&year=2022")
+	})
+
+	t.Run("snapshot carried over as maxId", func(t *testing.T) {
+		maxID := int64(500)
+		service.EXPECT().GetSnapshotMaxID(gomock.Any()).Return(maxID, nil)
+		service.EXPECT().GetDelegations(gomock.Any(), 1, gomock.Any(), model.DelegationFilter{MaxTzktID: &maxID}).Return(expected, true, nil)
+
+		links := test.GET("/xtz/delegations").WithQueryString("snapshot=true&links=true").
+			Expect().Status(200).JSON().Object().Value("_links").Object()
+		links.HasValue("next", "/xtz/delegations?links=true&maxId=500&page=2")
+	})
+
+	t.Run("invalid links", func(t *testing.T) {
+		resp := test.GET("/xtz/delegations").WithQueryString("links=maybe").Expect().Status(400).JSON().Object()
+		resp.Value("code").String().IsEqual(CodeInvalidLinks)
+	})
+}
+
+// parseLinkHeader parses an RFC 8288 Link header into its targets by rel
+func parseLinkHeader(t *testing.T, header string) map[string]string {
+	links := map[string]string{}
+	for _, link := range strings.Split(header, ", ") {
+		target, rel, ok := strings.Cut(link, "; ")
+		if !assert.True(t, ok, "malformed link %q", link) ||
+			!assert.True(t, strings.HasPrefix(target, "<") && strings.HasSuffix(target, ">"), "malformed target %q", target) ||
+			!assert.True(t, strings.HasPrefix(rel, `rel="`) && strings.HasSuffix(rel, `"`), "malformed rel %q", rel) {
+			continue
+		}
+		links[strings.TrimSuffix(strings.TrimPrefix(rel, `rel="`), `"`)] = strings.Trim(target, "<>")
+	}
+	return links
+}
+
+func TestDelegationHandler_GetDelegations_LinkHeader(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockDelegationServicePort(ctrl)
+	handler := NewDelegationHandler(service, zerolog.Nop(), HandlerOptions{})
+
+	app := iris.New()
+	app.Get("/xtz/delegations", handler.GetDelegations)
+	test := httptest.New(t, app)
+
+	expected := []model.Delegation{{TzktID: 1, Delegator: "tz1", Amount: 100, Level: 1, Timestamp: fixedTime()}}
+	year := 2022
+
+	t.Run("first page", func(t *testing.T) {
+		service.EXPECT().GetDelegations(gomock.Any(), 1, 10, model.DelegationFilter{Year: &year}).Return(expected, true, nil)
+
+		header := test.GET("/xtz/delegations").WithQueryString("year=2022&pageSize=10").
+			Expect().Status(200).Header("Link").Raw()
+		assert.Equal(t, map[string]string{
+			"next":  "/xtz/delegations?page=2&pageSize=10&year=2022",
+			"first": "/xtz/delegations?page=1&pageSize=10&year=2022",
+		}, parseLinkHeader(t, header))
+	})
+
+	t.Run("middle page", func(t *testing.T) {
+		service.EXPECT().GetDelegations(gomock.Any(), 3, 10, model.DelegationFilter{Year: &year}).Return(expected, true, nil)
+
+		header := test.GET("/xtz/delegations").WithQueryString("year=2022&pageSize=10&page=3").
+			Expect().Status(200).Header("Link").Raw()
+		assert.Equal(t, map[string]string{
+			"next":  "/xtz/delegations?page=4&pageSize=10&year=2022",
+			"prev":  "/xtz/delegations?page=2&pageSize=10&year=2022",
+			"first": "/xtz/delegations?page=1&pageSize=10&year=2022",
+		}, parseLinkHeader(t, header))
+	})
+
+	t.Run("last page", func(t *testing.T) {
+		service.EXPECT().GetDelegations(gomock.Any(), 4, 10, model.DelegationFilter{Year: &year}).Return(expected, false, nil)
+
+		header := test.GET("/xtz/delegations").WithQueryString("year=2022&pageSize=10&page=4").
+			Expect().Status(200).Header("Link").Raw()
+		assert.Equal(t, map[string]string{
+			"prev":  "/xtz/delegations?page=3&pageSize=10&year=2022",
+			"first": "/xtz/delegations?page=1&pageSize=10&year=2022",
+		}, parseLinkHeader(t, header))
+	})
+
+	t.Run("snapshot carried over as maxId", func(t *testing.T) {
+		maxID := int64(500)
+		service.EXPECT().GetSnapshotMaxID(gomock.Any()).Return(maxID, nil)
+		service.EXPECT().GetDelegations(gomock.Any(), 1, gomock.Any(), model.DelegationFilter{MaxTzktID: &maxID}).Return(expected, true, nil)
+
+		header := test.GET("/xtz/delegations").WithQueryString("snapshot=true").
+			Expect().Status(200).Header("Link").Raw()
+		assert.Equal(t, "/xtz/delegations?maxId=500&page=2", parseLinkHeader(t, header)["next"])
+	})
+}
+
+func TestDelegationHandler_GetDelegations_TypeFilter(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockDelegationServicePort(ctrl)
+	logger := zerolog.Nop()
+	handler := NewDelegationHandler(service, logger, HandlerOptions{})
+
+	app := iris.New()
+	app.Get("/xtz/delegations", handler.GetDelegations)
+	test := httptest.New(t, app)
+
+	t.Run("type passed to service", func(t *testing.T) {
+		opType := model.OperationTypeOrigination
+		expected := []model.Delegation{{TzktID: 1, Delegator: "KT1", Amount: 100, Level: 1, Timestamp: fixedTime(), Type: opType}}
+		service.EXPECT().GetDelegations(gomock.Any(), 1, gomock.Any(), model.DelegationFilter{Type: &opType}).Return(expected, false, nil)
+
+		resp := test.GET("/xtz/delegations").WithQueryString("type=origination").Expect().Status(200).JSON().Object()
+		resp.Value("data").Array().Value(0).Object().HasValue("delegator", "KT1")
+	})
+
+	t.Run("invalid type", func(t *testing.T) {
+		resp := test.GET("/xtz/delegations").WithQueryString("type=transaction").Expect().Status(400).JSON().Object()
+		resp.Value("error").String().IsEqual("Invalid type parameter: must be delegation or origination")
+		resp.Value("code").String().IsEqual(CodeInvalidType)
+	})
+}
+
+func TestDelegationHandler_GetDelegations_MinLevelFilter(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockDelegationServicePort(ctrl)
+	logger := zerolog.Nop()
+	handler := NewDelegationHandler(service, logger, HandlerOptions{})
+
+	app := iris.New()
+	app.Get("/xtz/delegations", handler.GetDelegations)
+	test := httptest.New(t, app)
+
+	t.Run("minLevel passed to service with year", func(t *testing.T) {
+		year := 2022
+		minLevel := int64(2338084)
+		expected := []model.Delegation{{TzktID: 1, Delegator: "tz1", Amount: 100, Level: 2338084, Timestamp: fixedTime()}}
+		service.EXPECT().GetDelegations(gomock.Any(), 1, gomock.Any(), model.DelegationFilter{Year: &year, MinLevel: &minLevel}).Return(expected, false, nil)
+
+		resp := test.GET("/xtz/delegations").WithQueryString("year=2022&minLevel=2338084").Expect().Status(200).JSON().Object()
+		resp.Value("data").Array().Value(0).Object().HasValue("level", "2338084")
+	})
+
+	for _, value := range []string{"-1", "abc", "12345678901234567890"} {
+		t.Run("invalid "+value, func(t *testing.T) {
+			resp := test.GET("/xtz/delegations").WithQuery("minLevel", value).Expect().Status(400).JSON().Object()
+			resp.Value("code").String().IsEqual(CodeInvalidMinLevel)
+		})
+	}
+}
+
+func TestDelegationHandler_GetDelegations_AccountTypeFilter(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockDelegationServicePort(ctrl)
+	handler := NewDelegationHandler(service, zerolog.Nop(), HandlerOptions{})
+
+	app := iris.New()
+	app.Get("/xtz/delegations", handler.GetDelegations)
+	test := httptest.New(t, app)
+
+	for _, accountType := range []string{model.AccountTypeImplicit, model.AccountTypeContract} {
+		t.Run(accountType+" passed to service with year", func(t *testing.T) {
+			year := 2022
+			want := accountType
+			service.EXPECT().GetDelegations(gomock.Any(), 1, gomock.Any(), model.DelegationFilter{Year: &year, AccountType: &want}).Return([]model.Delegation{}, false, nil)
+
+			test.GET("/xtz/delegations").WithQuery("year", "2022").WithQuery("accountType", accountType).Expect().Status(200)
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		resp := test.GET("/xtz/delegations").WithQuery("accountType", "KT1").Expect().Status(400).JSON().Object()
+		resp.Value("code").String().IsEqual(CodeInvalidAccountType)
+	})
+}
+
+func TestDelegationHandler_GetDelegations_SinceTzktID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockDelegationServicePort(ctrl)
+	handler := NewDelegationHandler(service, zerolog.Nop(), HandlerOptions{})
+
+	app := iris.New()
+	app.Get("/xtz/delegations", handler.GetDelegations)
+	test := httptest.New(t, app)
+
+	for _, since := range []int64{0, 1098907648} {
+		t.Run(fmt.Sprint("passed to service ", since), func(t *testing.T) {
+			opType := model.OperationTypeDelegation
+			want := since
+			service.EXPECT().GetDelegations(gomock.Any(), 1, gomock.Any(), model.DelegationFilter{Type: &opType, SinceTzktID: &want}).Return([]model.Delegation{}, false, nil)
+
+			test.GET("/xtz/delegations").WithQuery("type", opType).WithQuery("sinceTzktId", since).Expect().Status(200)
+		})
+	}
+
+	for _, value := range []string{"-1", "abc", "12345678901234567890"} {
+		t.Run("invalid "+value, func(t *testing.T) {
+			resp := test.GET("/xtz/delegations").WithQuery("sinceTzktId", value).Expect().Status(400).JSON().Object()
+			resp.Value("code").String().IsEqual(CodeInvalidSinceTzktID)
+		})
+	}
+}
+
+func TestDelegationHandler_GetDelegations_ETag(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockDelegationServicePort(ctrl)
+	logger := zerolog.Nop()
+	handler := NewDelegationHandler(service, logger, HandlerOptions{})
+
+	app := iris.New()
+	app.Get("/xtz/delegations", handler.GetDelegations)
+	test := httptest.New(t, app)
+
+	expected := []model.Delegation{{TzktID: 1, Delegator: "tz1", Amount: 100, Level: 1, Timestamp: fixedTime()}}
+	service.EXPECT().GetDelegations(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(expected, false, nil).Times(3)
+
+	// First request returns the body with an ETag
+	resp := test.GET("/xtz/delegations").Expect().Status(200)
+	etag := resp.Header("ETag").NotEmpty().Raw()
+	resp.Header("Cache-Control").IsEqual("public, max-age=30")
+	resp.JSON().Object().Value("data").Array().Length().IsEqual(1)
+
+	// Repeating it with the ETag returns 304 without a body
+	test.GET("/xtz/delegations").WithHeader("If-None-Match", etag).Expect().
+		Status(304).
+		Header("ETag").IsEqual(etag)
+
+	// A stale ETag gets the full response
+	test.GET("/xtz/delegations").WithHeader("If-None-Match", `W/"stale"`).Expect().
+		Status(200).
+		Header("ETag").IsEqual(etag)
+}
+
+func TestDelegationHandler_GetDelegations_ResponseCache(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockDelegationServicePort(ctrl)
+	logger := zerolog.Nop()
+	handler := NewDelegationHandler(service, logger, HandlerOptions{CacheSize: 10, CacheTTL: time.Minute})
+
+	app := iris.New()
+	app.Get("/xtz/delegations", handler.GetDelegations)
+	test := httptest.New(t, app)
+
+	page1 := []model.Delegation{{TzktID: 1, Delegator: "tz1", Amount: 100, Level: 1, Timestamp: fixedTime()}}
+	page2 := []model.Delegation{{TzktID: 2, Delegator: "tz2", Amount: 200, Level: 2, Timestamp: fixedTime()}}
+	service.EXPECT().GetDelegations(gomock.Any(), 1, defaultPageSize, gomock.Any()).Return(page1, false, nil).Times(1)
+	service.EXPECT().GetDelegations(gomock.Any(), 2, defaultPageSize, gomock.Any()).Return(page2, false, nil).Times(1)
+
+	// Identical queries hit the service once, regardless of parameter order
+	for _, query := range []string{"page=1&year=2022", "year=2022&page=1"} {
+		resp := test.GET("/xtz/delegations").WithQueryString(query).Expect().Status(200)
+		resp.Header("Cache-Control").IsEqual("public, max-age=60")
+		resp.JSON().Object().Value("data").Array().Value(0).Object().HasValue("delegator", "tz1")
+	}
+
+	// A different page is a different cache entry
+	test.GET("/xtz/delegations").WithQueryString("page=2&year=2022").Expect().Status(200).
+		JSON().Object().Value("data").Array().Value(0).Object().HasValue("delegator", "tz2")
+}
+
+func TestDelegationHandler_GetDelegationByTzktID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockDelegationServicePort(ctrl)
+	logger := zerolog.Nop()
+	handler := NewDelegationHandler(service, logger, HandlerOptions{})
+
+	app := iris.New()
+	app.Get("/xtz/delegations/{tzktId}", handler.GetDelegationByTzktID)
+	test := httptest.New(t, app)
+
+	t.Run("found", func(t *testing.T) {
+		expected := &model.Delegation{TzktID: 42, Delegator: "tz1", Amount: 100, Level: 7, Timestamp: fixedTime()}
+		service.EXPECT().GetDelegationByTzktID(gomock.Any(), int64(42)).Return(expected, nil)
+
+		resp := test.GET("/xtz/delegations/42").Expect().Status(200).JSON().Object()
+		resp.Value("data").Object().HasValue("delegator", "tz1")
+		resp.Value("data").Object().HasValue("amount", "100")
+		resp.Value("data").Object().HasValue("level", "7")
+		resp.Value("data").Object().HasValue("timestamp", "2022-05-05T06:29:14Z")
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		notFoundErr := apperrors.NewNotFoundError("delegation", "43")
+		service.EXPECT().GetDelegationByTzktID(gomock.Any(), int64(43)).Return(nil, notFoundErr)
+
+		resp := test.GET("/xtz/delegations/43").Expect().Status(404).JSON().Object()
+		resp.Value("error").String().IsEqual("delegation not found")
+		resp.Value("code").String().IsEqual(CodeNotFound)
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		dbErr := apperrors.NewDatabaseError("query", "connection failed")
+		service.EXPECT().GetDelegationByTzktID(gomock.Any(), int64(44)).Return(nil, dbErr)
+
+		resp := test.GET("/xtz/delegations/44").Expect().Status(500).JSON().Object()
+		resp.Value("error").String().IsEqual("Database error")
+		resp.Value("code").String().IsEqual(CodeDatabaseError)
+	})
+
+	t.Run("invalid tzktId", func(t *testing.T) {
+		testCases := []string{"abc", "0", "-1", "1.5", "99999999999999999999"}
+		for _, tc := range testCases {
+			t.Run(tc, func(t *testing.T) {
+				resp := test.GET("/xtz/delegations/" + tc).Expect().Status(400).JSON().Object()
+				resp.Value("error").String().NotEmpty()
+				resp.Value("code").String().IsEqual(CodeInvalidTzktID)
+			})
+		}
+	})
+}
+
+func TestDelegationHandler_DeleteDelegationByTzktID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockDelegationServicePort(ctrl)
+	handler := NewDelegationHandler(service, zerolog.Nop(), HandlerOptions{})
+
+	app := iris.New()
+	app.Delete("/xtz/delegations/{tzktId}", handler.DeleteDelegationByTzktID)
+	test := httptest.New(t, app)
+
+	t.Run("deleted", func(t *testing.T) {
+		service.EXPECT().DeleteDelegationByTzktID(gomock.Any(), int64(42)).Return(nil)
+
+		test.DELETE("/xtz/delegations/42").Expect().Status(204).NoContent()
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		service.EXPECT().DeleteDelegationByTzktID(gomock.Any(), int64(43)).Return(apperrors.NewNotFoundError("delegation", "43"))
+
+		resp := test.DELETE("/xtz/delegations/43").Expect().Status(404).JSON().Object()
+		resp.Value("error").String().IsEqual("delegation not found")
+		resp.Value("code").String().IsEqual(CodeNotFound)
+	})
+
+	t.Run("invalid tzktId", func(t *testing.T) {
+		test.DELETE("/xtz/delegations/0").Expect().Status(400).JSON().Object().HasValue("code", CodeInvalidTzktID)
+	})
+}
+
+func TestDelegationHandler_CountDelegations(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockDelegationServicePort(ctrl)
+	logger := zerolog.Nop()
+	handler := NewDelegationHandler(service, logger, HandlerOptions{})
+
+	app := iris.New()
+	app.Get("/xtz/delegations", handler.GetDelegations)
+	app.Head("/xtz/delegations", handler.CountDelegations)
+	test := httptest.New(t, app)
+
+	t.Run("head", func(t *testing.T) {
+		year := 2022
+		service.EXPECT().CountDelegations(gomock.Any(), model.DelegationFilter{Year: &year}).Return(int64(1234), nil)
+
+		resp := test.HEAD("/xtz/delegations").WithQuery("year", "2022").Expect().Status(200)
+		resp.Header("X-Total-Count").IsEqual("1234")
+		resp.Body().IsEmpty()
+	})
+
+	t.Run("countOnly", func(t *testing.T) {
+		service.EXPECT().CountDelegations(gomock.Any(), model.DelegationFilter{}).Return(int64(0), nil)
+
+		resp := test.GET("/xtz/delegations").WithQuery("countOnly", "true").Expect().Status(200)
+		resp.Header("X-Total-Count").IsEqual("0")
+		resp.Header("X-Total-Count-Type").IsEqual("exact")
+		resp.Body().IsEmpty()
+	})
+
+	t.Run("approximate", func(t *testing.T) {
+		service.EXPECT().ApproximateCountDelegations(gomock.Any(), model.DelegationFilter{}).Return(int64(98000), false, nil)
+
+		resp := test.HEAD("/xtz/delegations").WithQuery("count", "approx").Expect().Status(200)
+		resp.Header("X-Total-Count").IsEqual("98000")
+		resp.Header("X-Total-Count-Type").IsEqual("approximate")
+	})
+
+	t.Run("approximate falls back to exact", func(t *testing.T) {
+		year := 2022
+		service.EXPECT().ApproximateCountDelegations(gomock.Any(), model.DelegationFilter{Year: &year}).Return(int64(12), true, nil)
+
+		resp := test.GET("/xtz/delegations").WithQuery("countOnly", "true").WithQuery("count", "approx").WithQuery("year", "2022").Expect().Status(200)
+		resp.Header("X-Total-Count").IsEqual("12")
+		resp.Header("X-Total-Count-Type").IsEqual("exact")
+	})
+
+	t.Run("invalid count", func(t *testing.T) {
+		resp := test.GET("/xtz/delegations").WithQuery("countOnly", "true").WithQuery("count", "fast").Expect().Status(400).JSON().Object()
+		resp.Value("code").String().IsEqual(CodeInvalidCount)
+	})
+
+	t.Run("invalid countOnly", func(t *testing.T) {
+		resp := test.GET("/xtz/delegations").WithQuery("countOnly", "maybe").Expect().Status(400).JSON().Object()
+		resp.Value("code").String().IsEqual(CodeInvalidCountOnly)
+	})
+
+	t.Run("invalid filter", func(t *testing.T) {
+		test.HEAD("/xtz/delegations").WithQuery("year", "2017").Expect().Status(400).Header("X-Total-Count").IsEmpty()
+	})
+
+	t.Run("service error", func(t *testing.T) {
+		service.EXPECT().CountDelegations(gomock.Any(), gomock.Any()).Return(int64(0), apperrors.NewDatabaseError("count", "failed"))
+
+		resp := test.GET("/xtz/delegations").WithQuery("countOnly", "true").Expect().Status(500).JSON().Object()
+		resp.Value("code").String().IsEqual(CodeDatabaseError)
+	})
+}
+
+func TestDelegationHandler_GetDelegatorSummary(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockDelegationServicePort(ctrl)
+	logger := zerolog.Nop()
+	handler := NewDelegationHandler(service, logger, HandlerOptions{})
+
+	app := iris.New()
+	app.Get("/xtz/delegations/delegator/{address}/summary", handler.GetDelegatorSummary)
+	test := httptest.New(t, app)
+
+	const address = "tz1a1SAaXRt9yoGMx29rh9FsBF4UzmvojdTL"
+
+	t.Run("found", func(t *testing.T) {
+		summary := &model.DelegatorSummary{
+			Delegator:   address,
+			FirstSeen:   fixedTime().AddDate(0, -1, 0),
+			LastSeen:    fixedTime(),
+			Count:       2,
+			TotalAmount: 1_500_000,
+		}
+		service.EXPECT().GetDelegatorSummary(gomock.Any(), address).Return(summary, nil)
+
+		data := test.GET("/xtz/delegations/delegator/" + address + "/summary").Expect().Status(200).JSON().Object().Value("data").Object()
+		data.HasValue("delegator", address)
+		data.HasValue("first_seen", "2022-04-05T06:29:14Z")
+		data.HasValue("last_seen", "2022-05-05T06:29:14Z")
+		data.HasValue("count", 2)
+		data.HasValue("total_amount", "1500000")
+		data.HasValue("total_amount_tez", "1.500000")
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		service.EXPECT().GetDelegatorSummary(gomock.Any(), address).Return(nil, apperrors.NewNotFoundError("delegator", address))
+
+		resp := test.GET("/xtz/delegations/delegator/" + address + "/summary").Expect().Status(404).JSON().Object()
+		resp.Value("error").String().IsEqual("delegator not found")
+		resp.Value("code").String().IsEqual(CodeNotFound)
+	})
+
+	t.Run("invalid address", func(t *testing.T) {
+		testCases := []string{"tz1", "tz1a1SAaXRt9yoGMx29rh9FsBF4UzmvojdTM", "tz5a1SAaXRt9yoGMx29rh9FsBF4UzmvojdTL"}
+		for _, tc := range testCases {
+			t.Run(tc, func(t *testing.T) {
+				resp := test.GET("/xtz/delegations/delegator/" + tc + "/summary").Expect().Status(400).JSON().Object()
+				resp.Value("code").String().IsEqual(CodeInvalidAddress)
+			})
+		}
+	})
+}
+
+func TestDelegationHandler_ExportDelegationsCSV(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockDelegationServicePort(ctrl)
+	logger := zerolog.Nop()
+	handler := NewDelegationHandler(service, logger, HandlerOptions{})
+
+	app := iris.New()
+	app.Get("/xtz/delegations.csv", handler.ExportDelegationsCSV)
+	test := httptest.New(t, app)
+
+	t.Run("streams rows", func(t *testing.T) {
+		year := 2022
+		rows := []model.Delegation{
+			{TzktID: 2, Delegator: "tz2", Amount: 200, Level: 8, Timestamp: fixedTime()},
+			{TzktID: 1, Delegator: "tz1", Amount: 100, Level: 7, Timestamp: fixedTime()},
+		}
+		service.EXPECT().StreamDelegations(gomock.Any(), model.DelegationFilter{Year: &year}, gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ model.DelegationFilter, fn func(model.Delegation) error) error {
+				for _, d := range rows {
+					if err := fn(d); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+
+		resp := test.GET("/xtz/delegations.csv").WithQuery("year", "2022").Expect().Status(200)
+		resp.Header("Content-Type").HasPrefix("text/csv")
+		resp.Header("Content-Disposition").IsEqual(`attachment; filename="delegations.csv"`)
+		resp.Body().IsEqual("timestamp,amount,delegator,level,tzkt_id\n" +
+			"2022-05-05T06:29:14Z,200,tz2,8,2\n" +
+			"2022-05-05T06:29:14Z,100,tz1,7,1\n")
+	})
+
+	t.Run("empty result", func(t *testing.T) {
+		service.EXPECT().StreamDelegations(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+
+		test.GET("/xtz/delegations.csv").Expect().Status(200).
+			Body().IsEqual("timestamp,amount,delegator,level,tzkt_id\n")
+	})
+
+	t.Run("error before first row", func(t *testing.T) {
+		dbErr := apperrors.NewDatabaseError("stream", "connection failed")
+		service.EXPECT().StreamDelegations(gomock.Any(), gomock.Any(), gomock.Any()).Return(dbErr)
+
+		resp := test.GET("/xtz/delegations.csv").Expect().Status(500).JSON().Object()
+		resp.Value("error").String().IsEqual("Database error")
+		resp.Value("code").String().IsEqual(CodeDatabaseError)
+	})
+
+	t.Run("invalid year", func(t *testing.T) {
+		resp := test.GET("/xtz/delegations.csv").WithQuery("year", "2017").Expect().Status(400).JSON().Object()
+		resp.Value("error").String().NotEmpty()
+		resp.Value("code").String().IsEqual(CodeInvalidYear)
+	})
+}
+
+func TestDelegationHandler_GetDelegations_NDJSON(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockDelegationServicePort(ctrl)
+	logger := zerolog.Nop()
+	handler := NewDelegationHandler(service, logger, HandlerOptions{})
+
+	app := iris.New()
+	app.Get("/xtz/delegations", handler.GetDelegations)
+	test := httptest.New(t, app)
+
+	t.Run("streams one object per line", func(t *testing.T) {
+		year := 2022
+		rows := []model.Delegation{
+			{TzktID: 2, Delegator: "tz2", Amount: 200, Level: 8, Timestamp: fixedTime()},
+			{TzktID: 1, Delegator: "tz1", Amount: 100, Level: 7, Timestamp: fixedTime()},
+		}
+		service.EXPECT().StreamDelegations(gomock.Any(), model.DelegationFilter{Year: &year}, gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ model.DelegationFilter, fn func(model.Delegation) error) error {
+				for _, d := range rows {
+					if err := fn(d); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+
+		resp := test.GET("/xtz/delegations").
+			WithHeader("Accept", "application/x-ndjson").
+			WithQuery("year", "2022").
+			WithQuery("page", "3"). // ignored when streaming
+			Expect().Status(200)
+		resp.Header("Content-Type").HasPrefix("application/x-ndjson")
+		resp.Body().IsEqual(`{"timestamp":"2022-05-05T06:29:14Z","amount":"200","delegator":"tz2","level":"8"}` + "\n" +
+			`{"timestamp":"2022-05-05T06:29:14Z","amount":"100","delegator":"tz1","level":"7"}` + "\n")
+	})
+
+	t.Run("accept with parameters and alternatives", func(t *testing.T) {
+		service.EXPECT().StreamDelegations(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+
+		test.GET("/xtz/delegations").
+			WithHeader("Accept", "application/json;q=0.5, application/x-ndjson;q=1").
+			Expect().Status(200).Body().IsEmpty()
+	})
+
+	t.Run("error before first row", func(t *testing.T) {
+		dbErr := apperrors.NewDatabaseError("stream", "connection failed")
+		service.EXPECT().StreamDelegations(gomock.Any(), gomock.Any(), gomock.Any()).Return(dbErr)
+
+		resp := test.GET("/xtz/delegations").WithHeader("Accept", "application/x-ndjson").
+			Expect().Status(500).JSON().Object()
+		resp.Value("error").String().IsEqual("Database error")
+		resp.Value("code").String().IsEqual(CodeDatabaseError)
+	})
+
+	t.Run("invalid year", func(t *testing.T) {
+		test.GET("/xtz/delegations").WithHeader("Accept", "application/x-ndjson").WithQuery("year", "2017").
+			Expect().Status(400)
+	})
+}
+
+func TestDelegationHandler_GetDelegations_XML(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockDelegationServicePort(ctrl)
+	handler := NewDelegationHandler(service, zerolog.Nop(), HandlerOptions{})
+
+	app := iris.New()
+	app.Get("/xtz/delegations", handler.GetDelegations)
+	test := httptest.New(t, app)
+
+	t.Run("xml document", func(t *testing.T) {
+		expected := []model.Delegation{{TzktID: 1, Delegator: "tz1", Amount: 100, Level: 7, Timestamp: fixedTime()}}
+		service.EXPECT().GetDelegations(gomock.Any(), 2, defaultPageSize, model.DelegationFilter{}).Return(expected, true, nil)
+
+		resp := test.GET("/xtz/delegations").WithHeader("Accept", "application/xml").WithQuery("page", 2).Expect().Status(200)
+		resp.Header("Content-Type").HasPrefix("application/xml")
+		resp.Header("Vary").IsEqual("Accept")
+
+		body := resp.Body().Raw()
+		assert.True(t, strings.HasPrefix(body, xml.Header))
+		var doc GetDelegationsResponse
+		assert.NoError(t, xml.Unmarshal([]byte(body), &doc))
+		assert.Equal(t, "delegations", doc.XMLName.Local)
+		assert.Equal(t, []DelegationDto{{Timestamp: "2022-05-05T06:29:14Z", Amount: "100", Delegator: "tz1", Level: "7"}}, doc.Data)
+		assert.Equal(t, PageMeta{Page: 2, PageSize: defaultPageSize, HasNext: true, HasPrev: true}, doc.Meta)
+	})
+
+	t.Run("json by default", func(t *testing.T) {
+		service.EXPECT().GetDelegations(gomock.Any(), 1, defaultPageSize, model.DelegationFilter{}).Return([]model.Delegation{}, false, nil).Times(2)
+
+		test.GET("/xtz/delegations").Expect().Status(200).Header("Content-Type").HasPrefix("application/json")
+		test.GET("/xtz/delegations").WithHeader("Accept", "*/*").Expect().Status(200).Header("Content-Type").HasPrefix("application/json")
+	})
+
+	t.Run("not acceptable", func(t *testing.T) {
+		resp := test.GET("/xtz/delegations").WithHeader("Accept", "text/html").Expect().Status(406).JSON().Object()
+		resp.Value("code").String().IsEqual(CodeNotAcceptable)
+	})
+}
+
+func TestDelegationHandler_GetDelegations_Fields(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockDelegationServicePort(ctrl)
+	handler := NewDelegationHandler(service, zerolog.Nop(), HandlerOptions{})
+
+	app := iris.New()
+	app.Get("/xtz/delegations", handler.GetDelegations)
+	test := httptest.New(t, app)
+
+	expected := []model.Delegation{{TzktID: 1, Delegator: "tz1", Amount: 100, Level: 7, Timestamp: fixedTime()}}
+
+	t.Run("selected fields only", func(t *testing.T) {
+		service.EXPECT().GetDelegations(gomock.Any(), 1, defaultPageSize, model.DelegationFilter{}).Return(expected, false, nil)
+
+		item := test.GET("/xtz/delegations").WithQuery("fields", "delegator, amount,delegator").
+			Expect().Status(200).JSON().Object().Value("data").Array().Value(0).Object()
+		item.IsEqual(map[string]any{"delegator": "tz1", "amount": "100"})
+	})
+
+	t.Run("selected fields as xml", func(t *testing.T) {
+		service.EXPECT().GetDelegations(gomock.Any(), 1, defaultPageSize, model.DelegationFilter{}).Return(expected, false, nil)
+
+		body := test.GET("/xtz/delegations").WithHeader("Accept", "application/xml").WithQuery("fields", "level,timestamp").
+			Expect().Status(200).Body().Raw()
+		assert.Contains(t, body, "<delegation><timestamp>2022-05-05T06:29:14Z</timestamp><level>7</level></delegation>")
+	})
+
+	t.Run("invalid fields", func(t *testing.T) {
+		for _, fields := range []string{"", "delegator,tzkt_id", "amount,", strings.Repeat("amount,", 20)} {
+			resp := test.GET("/xtz/delegations").WithQuery("fields", fields).Expect().Status(400).JSON().Object()
+			resp.Value("code").String().IsEqual(CodeInvalidFields)
+		}
+	})
+}
+
+func TestDelegationHandler_GetDelegations_OffsetTooLarge(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockDelegationServicePort(ctrl)
+	handler := NewDelegationHandler(service, zerolog.Nop(), HandlerOptions{})
+
+	app := iris.New()
+	app.Get("/xtz/delegations", handler.GetDelegations)
+	test := httptest.New(t, app)
+
+	err := apperrors.NewValidationErrorWithCause("pageNo", "offset too large", services.ErrOffsetTooLarge)
+	service.EXPECT().GetDelegations(gomock.Any(), 1000, gomock.Any(), gomock.Any()).Return(nil, false, err)
+
+	resp := test.GET("/xtz/delegations").WithQuery("page", 1000).Expect().Status(400).JSON().Object()
+	resp.Value("code").String().IsEqual(CodeOffsetTooLarge)
+	resp.Value("error").String().Contains("cursor pagination")
+}
+
+func TestNegotiateFormat(t *testing.T) {
+	cases := []struct {
+		accept string
+		format responseFormat
+		ok     bool
+	}{
+		{"", formatJSON, true},
+		{"*/*", formatJSON, true},
+		{"application/json", formatJSON, true},
+		{"application/xml", formatXML, true},
+		{"Text/XML; charset=utf-8", formatXML, true},
+		{"application/x-ndjson", formatNDJSON, true},
+		{"application/json;q=0.5, application/xml", formatXML, true},
+		{"application/xml, application/json", formatXML, true},
+		{"text/html, application/xml;q=0.9, */*;q=0.8", formatXML, true},
+		{"application/xml;q=0, */*;q=0.1", formatJSON, true},
+		{"text/html", formatJSON, false},
+		{"application/xml;q=0", formatJSON, false},
+		{"application/xml;q=abc", formatJSON, false},
+	}
+	for _, c := range cases {
+		format, ok := negotiateFormat(c.accept)
+		assert.Equal(t, c.ok, ok, c.accept)
+		if c.ok {
+			assert.Equal(t, c.format, format, c.accept)
+		}
+	}
+}
+
+func TestDelegationHandler_GetMonthlyStats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockDelegationServicePort(ctrl)
+	logger := zerolog.Nop()
+	handler := NewDelegationHandler(service, logger, HandlerOptions{})
+
+	app := iris.New()
+	app.Get("/xtz/delegations/{tzktId}", handler.GetDelegationByTzktID)
+	app.Get("/xtz/delegations/stats/monthly", handler.GetMonthlyStats)
+	test := httptest.New(t, app)
+
+	t.Run("stats", func(t *testing.T) {
+		stats := make([]model.MonthStats, 12)
+		for i := range stats {
+			stats[i].Month = i + 1
+		}
+		stats[1] = model.MonthStats{Month: 2, Count: 3, TotalAmount: 1500000}
+		service.EXPECT().GetMonthlyStats(gomock.Any(), 2022).Return(stats, nil)
+
+		resp := test.GET("/xtz/delegations/stats/monthly").WithQuery("year", 2022).Expect().Status(200).JSON().Object()
+		resp.HasValue("year", 2022)
+		data := resp.Value("data").Array()
+		data.Length().IsEqual(12)
+		data.Value(0).Object().HasValue("month", 1).HasValue("count", 0).HasValue("total_amount", "0")
+		data.Value(1).Object().HasValue("month", 2).HasValue("count", 3).HasValue("total_amount_tez", "1.500000")
+		data.Value(11).Object().HasValue("month", 12)
+	})
+
+	for name, query := range map[string]string{"missing year": "", "invalid year": "year=2017", "non-numeric year": "year=abc"} {
+		t.Run(name, func(t *testing.T) {
+			resp := test.GET("/xtz/delegations/stats/monthly").WithQueryString(query).Expect().Status(400).JSON().Object()
+			resp.Value("code").String().IsEqual(CodeInvalidYear)
+		})
+	}
+}
+
+func TestDelegationHandler_GetStatsByYear(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockDelegationServicePort(ctrl)
+	logger := zerolog.Nop()
+	handler := NewDelegationHandler(service, logger, HandlerOptions{})
+
+	app := iris.New()
+	app.Get("/xtz/delegations/{tzktId}", handler.GetDelegationByTzktID)
+	app.Get("/xtz/delegations/stats/by-year", handler.GetStatsByYear)
+	test := httptest.New(t, app)
+
+	t.Run("stats", func(t *testing.T) {
+		stats := []model.YearStats{
+			{Year: 2021, Count: 3, TotalAmount: 1500000},
+			{Year: 2022, Count: 2, TotalAmount: 250},
+		}
+		service.EXPECT().GetStatsByYear(gomock.Any()).Return(stats, nil)
+
+		data := test.GET("/xtz/delegations/stats/by-year").Expect().Status(200).JSON().Object().Value("data").Array()
+		data.Length().IsEqual(2)
+		first := data.Value(0).Object()
+		first.HasValue("year", 2021)
+		first.HasValue("count", 3)
+		first.HasValue("total_amount", "1500000")
+		first.HasValue("total_amount_tez", "1.500000")
+		data.Value(1).Object().HasValue("total_amount_tez", "0.000250")
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		service.EXPECT().GetStatsByYear(gomock.Any()).Return([]model.YearStats{}, nil)
+
+		test.GET("/xtz/delegations/stats/by-year").Expect().Status(200).JSON().Object().Value("data").Array().IsEmpty()
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		dbErr := apperrors.NewDatabaseError("aggregate", "connection failed")
+		service.EXPECT().GetStatsByYear(gomock.Any()).Return(nil, dbErr)
+
+		resp := test.GET("/xtz/delegations/stats/by-year").Expect().Status(500).JSON().Object()
+		resp.Value("error").String().IsEqual("Database error")
+		resp.Value("code").String().IsEqual(CodeDatabaseError)
+	})
+}
+
+func TestDelegationHandler_GetTopDelegators(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockDelegationServicePort(ctrl)
+	logger := zerolog.Nop()
+	handler := NewDelegationHandler(service, logger, HandlerOptions{})
+
+	app := iris.New()
+	app.Get("/xtz/delegations/stats/top-delegators", handler.GetTopDelegators)
+	test := httptest.New(t, app)
+
+	t.Run("defaults", func(t *testing.T) {
+		stats := []model.DelegatorStats{{Delegator: "tz1", Count: 4, TotalAmount: 2500000}}
+		service.EXPECT().GetTopDelegators(gomock.Any(), 10, (*int)(nil)).Return(stats, nil)
+
+		data := test.GET("/xtz/delegations/stats/top-delegators").Expect().Status(200).JSON().Object().Value("data").Array()
+		data.Length().IsEqual(1)
+		first := data.Value(0).Object()
+		first.HasValue("delegator", "tz1")
+		first.HasValue("count", 4)
+		first.HasValue("total_amount", "2500000")
+		first.HasValue("total_amount_tez", "2.500000")
+	})
+
+	t.Run("limit and year", func(t *testing.T) {
+		service.EXPECT().GetTopDelegators(gomock.Any(), 3, intPtr(2022)).Return([]model.DelegatorStats{}, nil)
+
+		test.GET("/xtz/delegations/stats/top-delegators").WithQuery("limit", "3").WithQuery("year", "2022").
+			Expect().Status(200).JSON().Object().Value("data").Array().IsEmpty()
+	})
+
+	t.Run("invalid params", func(t *testing.T) {
+		testCases := map[string]string{
+			"limit=0":           CodeInvalidLimit,
+			"limit=101":         CodeInvalidLimit,
+			"limit=abc":         CodeInvalidLimit,
+			"limit=12345678901": CodeInvalidLimit,
+			"year=2017":         CodeInvalidYear,
+		}
+		for query, code := range testCases {
+			t.Run(query, func(t *testing.T) {
+				resp := test.GET("/xtz/delegations/stats/top-delegators").WithQueryString(query).Expect().Status(400).JSON().Object()
+				resp.Value("error").String().NotEmpty()
+				resp.Value("code").String().IsEqual(code)
+			})
+		}
+	})
+}
+
+func TestDelegationHandler_GetDistinctDelegators(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockDelegationServicePort(ctrl)
+	handler := NewDelegationHandler(service, zerolog.Nop(), HandlerOptions{})
+
+	app := iris.New()
+	app.Get("/xtz/delegations/stats/delegators", handler.GetDistinctDelegators)
+	test := httptest.New(t, app)
+
+	t.Run("all years", func(t *testing.T) {
+		service.EXPECT().CountDistinctDelegators(gomock.Any(), (*int)(nil)).Return(int64(1234), nil)
+
+		test.GET("/xtz/delegations/stats/delegators").Expect().Status(200).JSON().Object().HasValue("distinct_delegators", 1234)
+	})
+
+	t.Run("year", func(t *testing.T) {
+		service.EXPECT().CountDistinctDelegators(gomock.Any(), intPtr(2022)).Return(int64(0), nil)
+
+		test.GET("/xtz/delegations/stats/delegators").WithQuery("year", "2022").
+			Expect().Status(200).JSON().Object().HasValue("distinct_delegators", 0)
+	})
+
+	t.Run("invalid year", func(t *testing.T) {
+		for _, year := range []string{"2017", "abc"} {
+			test.GET("/xtz/delegations/stats/delegators").WithQuery("year", year).
+				Expect().Status(400).JSON().Object().HasValue("code", CodeInvalidYear)
+		}
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		service.EXPECT().CountDistinctDelegators(gomock.Any(), (*int)(nil)).Return(int64(0), apperrors.NewDatabaseError("count distinct delegators", "failed"))
+
+		test.GET("/xtz/delegations/stats/delegators").Expect().Status(500).JSON().Object().HasValue("code", CodeDatabaseError)
+	})
+}
+
+func intPtr(i int) *int { return &i }
+
+func fixedTime() time.Time {
+	return time.Date(2022, 5, 5, 6, 29, 14, 0, time.UTC)
+}
+
+func TestRespondTooManyRequests(t *testing.T) {
+	app := iris.New()
+	app.Get("/limited", func(ctx iris.Context) {
+		retryAfter, _ := time.ParseDuration(ctx.URLParam("after"))
+		respondTooManyRequests(ctx, retryAfter)
+	})
+	test := httptest.New(t, app)
+
+	tests := []struct {
+		after string
+		want  string
+	}{
+		{"3s", "3"},
+		{"1500ms", "2"}, // Rounded up so clients don't come back early
+		{"0s", "1"},     // Never tell clients to retry immediately
+	}
+	for _, tc := range tests {
+		t.Run(tc.after, func(t *testing.T) {
+			e := test.GET("/limited").WithQuery("after", tc.after).Expect().Status(429)
+			e.Header("Retry-After").IsEqual(tc.want)
+			e.JSON().Object().Value("code").String().IsEqual(CodeRateLimited)
+		})
+	}
 }
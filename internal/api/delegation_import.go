@@ -0,0 +1,78 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"tezos-delegation/internal/model"
+
+	"github.com/kataras/iris/v12"
+)
+
+// maxImportBodyBytes bounds the size of an import request body, roughly 50000 delegations
+const maxImportBodyBytes = 10 << 20
+
+// fromImportDelegationDto converts an imported delegation to a model.Delegation
+func fromImportDelegationDto(dto ImportDelegationDto) model.Delegation {
+	return model.Delegation{
+		TzktID:    dto.TzktID,
+		Timestamp: dto.Timestamp.UTC(),
+		Amount:    dto.Amount,
+		Delegator: dto.Delegator,
+		Level:     dto.Level,
+		Type:      dto.Type,
+	}
+}
+
+// toImportDelegationsResponse converts an import summary to its response
+func toImportDelegationsResponse(result model.ImportResult) ImportDelegationsResponse {
+	errs := make([]ImportErrorDto, len(result.Errors))
+	for i, e := range result.Errors {
+		errs[i] = ImportErrorDto{Index: e.Index, TzktID: e.TzktID, Error: e.Message}
+	}
+	return ImportDelegationsResponse{Inserted: result.Inserted, Skipped: result.Skipped, Errors: errs}
+}
+
+// ImportDelegations handles POST /xtz/delegations/import. The route is only registered when
+// IMPORT_ENABLED is set, as it lets any client write delegations.
+// @Summary Import delegations
+// @Description Validates and stores a JSON array of delegations, for seeding test and development databases without the poller. Invalid delegations are reported in errors and not stored; delegations already stored are counted as skipped.
+// @Tags delegations
+// @Accept json
+// @Produce json
+// @Param delegations body []ImportDelegationDto true "Delegations to import"
+// @Success 200 {object} ImportDelegationsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /xtz/delegations/import [post]
+func (h *DelegationHandler) ImportDelegations(ctx iris.Context) {
+	var dtos []ImportDelegationDto
+	body := http.MaxBytesReader(ctx.ResponseWriter(), ctx.Request().Body, maxImportBodyBytes)
+	if err := json.NewDecoder(body).Decode(&dtos); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			h.logger(ctx).Warn().Int64("limit", tooLarge.Limit).Msg("Import body too large")
+			respondWithError(ctx, http.StatusRequestEntityTooLarge, CodeBodyTooLarge, fmt.Sprintf("Request body too large: at most %d bytes", maxImportBodyBytes))
+			return
+		}
+		h.logger(ctx).Warn().Err(err).Msg("Invalid import body")
+		respondWithError(ctx, http.StatusBadRequest, CodeInvalidBody, "Invalid request body: must be a JSON array of delegations")
+		return
+	}
+
+	delegations := make([]model.Delegation, len(dtos))
+	for i, dto := range dtos {
+		delegations[i] = fromImportDelegationDto(dto)
+	}
+
+	result, err := h.Service.ImportDelegations(ctx.Request().Context(), delegations)
+	if err != nil {
+		h.respondWithServiceError(ctx, "ImportDelegations", err)
+		return
+	}
+
+	ctx.StatusCode(http.StatusOK)
+	ctx.JSON(toImportDelegationsResponse(*result))
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"tezos-delegation/internal/apperrors"
+	"tezos-delegation/internal/mocks"
+	"tezos-delegation/internal/model"
+
+	"github.com/golang/mock/gomock"
+	"github.com/kataras/iris/v12"
+	"github.com/kataras/iris/v12/httptest"
+	"github.com/rs/zerolog"
+)
+
+func TestDelegationHandler_ImportDelegations(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockDelegationServicePort(ctrl)
+	handler := NewDelegationHandler(service, zerolog.Nop(), HandlerOptions{})
+
+	app := iris.New()
+	app.Post("/xtz/delegations/import", handler.ImportDelegations)
+	test := httptest.New(t, app)
+
+	t.Run("valid payload", func(t *testing.T) {
+		expected := []model.Delegation{{
+			TzktID:    42,
+			Timestamp: time.Date(2022, 5, 5, 6, 29, 14, 0, time.UTC),
+			Amount:    125896,
+			Delegator: "tz1a1SAaXRt9yoGMx29rh9FsBF4UzmvojdTL",
+			Level:     2338084,
+		}}
+		service.EXPECT().ImportDelegations(gomock.Any(), expected).Return(&model.ImportResult{Inserted: 1, Errors: []model.ImportError{}}, nil)
+
+		body := `[{"tzkt_id": 42, "timestamp": "2022-05-05T08:29:14+02:00", "amount": 125896, "delegator": "tz1a1SAaXRt9yoGMx29rh9FsBF4UzmvojdTL", "level": 2338084}]`
+		resp := test.POST("/xtz/delegations/import").WithHeader("Content-Type", "application/json").WithBytes([]byte(body)).
+			Expect().Status(http.StatusOK).JSON().Object()
+		resp.HasValue("inserted", 1)
+		resp.HasValue("skipped", 0)
+		resp.Value("errors").Array().IsEmpty()
+	})
+
+	t.Run("partially invalid payload", func(t *testing.T) {
+		service.EXPECT().ImportDelegations(gomock.Any(), gomock.Len(2)).Return(&model.ImportResult{
+			Inserted: 0,
+			Skipped:  1,
+			Errors:   []model.ImportError{{Index: 1, TzktID: -1, Message: "validation error for field 'tzkt_id': must be positive, got -1"}},
+		}, nil)
+
+		body := `[{"tzkt_id": 42, "delegator": "tz1a1SAaXRt9yoGMx29rh9FsBF4UzmvojdTL"}, {"tzkt_id": -1, "delegator": "tz1a1SAaXRt9yoGMx29rh9FsBF4UzmvojdTL"}]`
+		resp := test.POST("/xtz/delegations/import").WithBytes([]byte(body)).Expect().Status(http.StatusOK).JSON().Object()
+		resp.HasValue("inserted", 0)
+		resp.HasValue("skipped", 1)
+		resp.Value("errors").Array().Length().IsEqual(1)
+		resp.Value("errors").Array().Value(0).Object().HasValue("index", 1).HasValue("tzkt_id", -1).
+			HasValue("error", "validation error for field 'tzkt_id': must be positive, got -1")
+	})
+
+	t.Run("not a JSON array", func(t *testing.T) {
+		resp := test.POST("/xtz/delegations/import").WithBytes([]byte(`{"tzkt_id": 42}`)).Expect().Status(http.StatusBadRequest).JSON().Object()
+		resp.HasValue("code", CodeInvalidBody)
+		resp.HasValue("error", "Invalid request body: must be a JSON array of delegations")
+	})
+
+	t.Run("body too large", func(t *testing.T) {
+		body := `[{"delegator": "` + strings.Repeat("x", maxImportBodyBytes) + `"}]`
+		test.POST("/xtz/delegations/import").WithBytes([]byte(body)).Expect().Status(http.StatusRequestEntityTooLarge).
+			JSON().Object().HasValue("code", CodeBodyTooLarge)
+	})
+
+	t.Run("insert fails", func(t *testing.T) {
+		service.EXPECT().ImportDelegations(gomock.Any(), gomock.Any()).Return(nil, apperrors.NewDatabaseError("insert delegations", "failed"))
+
+		test.POST("/xtz/delegations/import").WithBytes([]byte(`[]`)).Expect().Status(http.StatusInternalServerError).
+			JSON().Object().HasValue("code", CodeDatabaseError)
+	})
+}
@@ -0,0 +1,166 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"tezos-delegation/internal/model"
+	"time"
+
+	"github.com/kataras/iris/v12"
+)
+
+const (
+	streamFlushEvery = 1000 // Flush streamed responses to the client every N rows
+
+	contentTypeNDJSON = "application/x-ndjson"
+)
+
+// isStreamRequest reports whether the request is for a streamed export: the CSV export, or the
+// delegations list negotiated as NDJSON
+func isStreamRequest(ctx iris.Context) bool {
+	switch ctx.Path() {
+	case "/xtz/delegations.csv":
+		return true
+	case "/xtz/delegations":
+		format, ok := negotiateFormat(ctx.GetHeader("Accept"))
+		return ok && format == formatNDJSON && ctx.Method() == http.MethodGet
+	}
+	return false
+}
+
+// delegationStreamWriter encodes a stream of delegations in a specific format
+type delegationStreamWriter interface {
+	// ContentType returns the Content-Type of the encoded stream
+	ContentType() string
+	// ContentDisposition returns the Content-Disposition header value, or "" to send the stream inline
+	ContentDisposition() string
+	// WriteHeader writes anything that precedes the first row
+	WriteHeader() error
+	// WriteRow encodes a single delegation
+	WriteRow(d model.Delegation) error
+	// Flush writes any buffered data to the underlying writer
+	Flush() error
+}
+
+// streamDelegations streams all delegations matching the filter to the client using sw.
+// The status line and headers are only sent with the first row, so errors before any
+// output still get a regular JSON error response.
+func (h *DelegationHandler) streamDelegations(ctx iris.Context, operation string, filter model.DelegationFilter, sw delegationStreamWriter) {
+	started := false
+	start := func() error {
+		started = true
+		ctx.ContentType(sw.ContentType())
+		if disposition := sw.ContentDisposition(); disposition != "" {
+			ctx.Header("Content-Disposition", disposition)
+		}
+		ctx.StatusCode(http.StatusOK)
+		return sw.WriteHeader()
+	}
+
+	rows := 0
+	err := h.Service.StreamDelegations(ctx.Request().Context(), filter, func(d model.Delegation) error {
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		if err := sw.WriteRow(d); err != nil {
+			return err
+		}
+		rows++
+		// Flush periodically so large result sets reach the client incrementally
+		if rows%streamFlushEvery == 0 {
+			if err := sw.Flush(); err != nil {
+				return err
+			}
+			ctx.ResponseWriter().Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		if !started {
+			h.respondWithServiceError(ctx, operation, err)
+			return
+		}
+		// The status line is already sent; all we can do is log and cut the stream short
+		h.logger(ctx).Error().Err(err).Str("operation", operation).Int("rows", rows).Msg("Error streaming delegations")
+		return
+	}
+
+	// Empty result: still send the header
+	if !started {
+		if err := start(); err != nil {
+			h.logger(ctx).Error().Err(err).Str("operation", operation).Msg("Error writing stream header")
+			return
+		}
+	}
+	if err := sw.Flush(); err != nil {
+		h.logger(ctx).Error().Err(err).Str("operation", operation).Int("rows", rows).Msg("Error flushing stream")
+	}
+}
+
+// csvStreamWriter writes delegations as CSV with a header row
+type csvStreamWriter struct {
+	w *csv.Writer
+}
+
+func newCSVStreamWriter(w io.Writer) *csvStreamWriter {
+	return &csvStreamWriter{w: csv.NewWriter(w)}
+}
+
+// csvHeader is the header row of the CSV export
+var csvHeader = []string{"timestamp", "amount", "delegator", "level", "tzkt_id"}
+
+func (c *csvStreamWriter) ContentType() string { return "text/csv; charset=utf-8" }
+
+func (c *csvStreamWriter) ContentDisposition() string {
+	return `attachment; filename="delegations.csv"`
+}
+
+func (c *csvStreamWriter) WriteHeader() error { return c.w.Write(csvHeader) }
+
+func (c *csvStreamWriter) WriteRow(d model.Delegation) error {
+	return c.w.Write([]string{
+		d.Timestamp.UTC().Format(time.RFC3339),
+		strconv.FormatInt(d.Amount, 10),
+		d.Delegator,
+		strconv.FormatInt(d.Level, 10),
+		strconv.FormatInt(d.TzktID, 10),
+	})
+}
+
+func (c *csvStreamWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// ndjsonStreamWriter writes delegations as newline-delimited JSON, one DelegationDto per line,
+// or NumericDelegationDto with numeric
+type ndjsonStreamWriter struct {
+	enc        *json.Encoder
+	timeFormat string // One of the timeFormat* formats
+	numeric    bool
+}
+
+func newNDJSONStreamWriter(w io.Writer, timeFormat string, numeric bool) *ndjsonStreamWriter {
+	return &ndjsonStreamWriter{enc: json.NewEncoder(w), timeFormat: timeFormat, numeric: numeric}
+}
+
+func (n *ndjsonStreamWriter) ContentType() string { return contentTypeNDJSON }
+
+func (n *ndjsonStreamWriter) ContentDisposition() string { return "" }
+
+func (n *ndjsonStreamWriter) WriteHeader() error { return nil }
+
+// WriteRow encodes d as a single line; json.Encoder terminates each value with a newline
+func (n *ndjsonStreamWriter) WriteRow(d model.Delegation) error {
+	if n.numeric {
+		return n.enc.Encode(toNumericDelegationDto(d, n.timeFormat))
+	}
+	return n.enc.Encode(toDelegationDto(d, n.timeFormat))
+}
+
+func (n *ndjsonStreamWriter) Flush() error { return nil }
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"net/http"
+	"tezos-delegation/internal/model"
+	"time"
+
+	"github.com/kataras/iris/v12"
+)
+
+// toSyncPointDto converts a delegation to the point it marks in the sync, nil staying nil
+func toSyncPointDto(d *model.Delegation) *SyncPointDto {
+	if d == nil {
+		return nil
+	}
+	return &SyncPointDto{TzktID: d.TzktID, Timestamp: d.Timestamp.UTC().Format(time.RFC3339)}
+}
+
+// toSyncStatusDto converts a sync status to its DTO
+func toSyncStatusDto(s model.SyncStatus) SyncStatusDto {
+	dto := SyncStatusDto{Latest: toSyncPointDto(s.Latest), TzktLatest: toSyncPointDto(s.TzktLatest)}
+	if s.HasLag {
+		lagSeconds := int64(s.Lag / time.Second)
+		dto.LagTzktIDs = &s.LagTzktIDs
+		dto.LagSeconds = &lagSeconds
+	}
+	return dto
+}
+
+// GetSyncStatus handles GET /xtz/sync/status
+// @Summary Sync lag behind Tzkt
+// @Description Compares the newest stored delegation with the newest delegation on Tzkt, which is cached for 10 seconds, and reports the lag in Tzkt IDs and seconds. Instances that don't poll Tzkt only report the stored side.
+// @Tags sync
+// @Produce json
+// @Success 200 {object} GetSyncStatusResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 502 {object} ErrorResponse
+// @Router /xtz/sync/status [get]
+func (h *DelegationHandler) GetSyncStatus(ctx iris.Context) {
+	status, err := h.Service.GetSyncStatus(ctx.Request().Context())
+	if err != nil {
+		h.respondWithServiceError(ctx, "GetSyncStatus", err)
+		return
+	}
+
+	ctx.StatusCode(http.StatusOK)
+	ctx.JSON(GetSyncStatusResponse{Data: toSyncStatusDto(*status)})
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"tezos-delegation/internal/apperrors"
+	"tezos-delegation/internal/mocks"
+	"tezos-delegation/internal/model"
+	"tezos-delegation/internal/services"
+
+	"github.com/golang/mock/gomock"
+	"github.com/kataras/iris/v12"
+	"github.com/kataras/iris/v12/httptest"
+	"github.com/rs/zerolog"
+)
+
+func TestDelegationHandler_GetSyncStatus(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockDelegationServicePort(ctrl)
+	handler := NewDelegationHandler(service, zerolog.Nop(), HandlerOptions{})
+
+	app := iris.New()
+	app.Get("/xtz/sync/status", handler.GetSyncStatus)
+	test := httptest.New(t, app)
+
+	latest := &model.Delegation{TzktID: 1098907648, Timestamp: fixedTime()}
+	tzktLatest := &model.Delegation{TzktID: 1098912000, Timestamp: fixedTime().Add(150 * time.Second)}
+
+	t.Run("lag", func(t *testing.T) {
+		service.EXPECT().GetSyncStatus(gomock.Any()).Return(&model.SyncStatus{
+			Latest: latest, TzktLatest: tzktLatest, HasLag: true, LagTzktIDs: 4352, Lag: 150 * time.Second,
+		}, nil)
+
+		data := test.GET("/xtz/sync/status").Expect().Status(http.StatusOK).JSON().Object().Value("data").Object()
+		data.Value("latest").Object().HasValue("tzkt_id", 1098907648).HasValue("timestamp", "2022-05-05T06:29:14Z")
+		data.Value("tzkt_latest").Object().HasValue("tzkt_id", 1098912000).HasValue("timestamp", "2022-05-05T06:31:44Z")
+		data.HasValue("lag_tzkt_ids", 4352)
+		data.HasValue("lag_seconds", 150)
+	})
+
+	t.Run("not polling tzkt", func(t *testing.T) {
+		service.EXPECT().GetSyncStatus(gomock.Any()).Return(&model.SyncStatus{Latest: latest}, nil)
+
+		data := test.GET("/xtz/sync/status").Expect().Status(http.StatusOK).JSON().Object().Value("data").Object()
+		data.Value("latest").Object().HasValue("tzkt_id", 1098907648)
+		data.Value("tzkt_latest").IsNull()
+		data.Value("lag_tzkt_ids").IsNull()
+		data.Value("lag_seconds").IsNull()
+	})
+
+	t.Run("tzkt unavailable", func(t *testing.T) {
+		service.EXPECT().GetSyncStatus(gomock.Any()).Return(nil, fmt.Errorf("%w: %w", services.ErrTzktUnavailable, services.ErrTzktCircuitOpen))
+
+		test.GET("/xtz/sync/status").Expect().Status(http.StatusBadGateway).
+			JSON().Object().HasValue("code", CodeTzktUnavailable)
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		service.EXPECT().GetSyncStatus(gomock.Any()).Return(nil, apperrors.NewDatabaseError("query latest TzktID", "failed"))
+
+		test.GET("/xtz/sync/status").Expect().Status(http.StatusInternalServerError).
+			JSON().Object().HasValue("code", CodeDatabaseError)
+	})
+}
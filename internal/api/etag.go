@@ -0,0 +1,52 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kataras/iris/v12"
+)
+
+// computeETag returns a weak ETag for a serialized response body
+func computeETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `W/"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches reports whether an If-None-Match header value matches etag.
+// Comparison is weak, as required for If-None-Match: the W/ prefix is ignored on both sides.
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	want := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == want {
+			return true
+		}
+	}
+	return false
+}
+
+// respondWithETag writes a serialized body of contentType as a 200 response tagged with an ETag and a maxAge cache lifetime,
+// or a bodiless 304 Not Modified if the client already holds the same representation.
+func respondWithETag(ctx iris.Context, body []byte, contentType string, maxAge time.Duration) error {
+	etag := computeETag(body)
+	ctx.Header("ETag", etag)
+	ctx.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds())))
+
+	if etagMatches(ctx.GetHeader("If-None-Match"), etag) {
+		ctx.StatusCode(http.StatusNotModified)
+		return nil
+	}
+
+	ctx.ContentType(contentType)
+	ctx.StatusCode(http.StatusOK)
+	_, err := ctx.Write(body)
+	return err
+}
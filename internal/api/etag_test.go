@@ -0,0 +1,32 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComputeETag(t *testing.T) {
+	etag := computeETag([]byte(`{"data":[]}`))
+	assert.Regexp(t, `^W/"[0-9a-f]{32}"$`, etag)
+	assert.Equal(t, etag, computeETag([]byte(`{"data":[]}`)))
+	assert.NotEqual(t, etag, computeETag([]byte(`{"data":[1]}`)))
+}
+
+func TestETagMatches(t *testing.T) {
+	etag := `W/"abc"`
+	testCases := []struct {
+		header string
+		match  bool
+	}{
+		{"", false},
+		{`W/"abc"`, true},
+		{`"abc"`, true},
+		{`"other"`, false},
+		{`"other", W/"abc"`, true},
+		{"*", true},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.match, etagMatches(tc.header, etag), tc.header)
+	}
+}
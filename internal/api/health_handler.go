@@ -0,0 +1,72 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"tezos-delegation/internal/ports"
+	"tezos-delegation/internal/requestid"
+	"tezos-delegation/internal/services"
+
+	"github.com/kataras/iris/v12"
+	"github.com/rs/zerolog"
+)
+
+// HealthHandler exposes liveness and readiness probes
+type HealthHandler struct {
+	Service ports.HealthServicePort
+	Logger  zerolog.Logger
+}
+
+func NewHealthHandler(service ports.HealthServicePort, logger zerolog.Logger) *HealthHandler {
+	return &HealthHandler{
+		Service: service,
+		Logger:  logger.With().Str("component", "HealthHttpHandler").Logger(),
+	}
+}
+
+// logger returns the handler logger scoped to the current request
+func (h *HealthHandler) logger(ctx iris.Context) *zerolog.Logger {
+	return requestid.Logger(ctx.Request().Context(), &h.Logger)
+}
+
+// Live handles GET /health
+// @Summary Liveness probe
+// @Tags health
+// @Produce json
+// @Success 200 {object} HealthResponse
+// @Router /health [get]
+func (h *HealthHandler) Live(ctx iris.Context) {
+	ctx.StatusCode(http.StatusOK)
+	ctx.JSON(HealthResponse{Status: "ok", TzktCircuit: h.Service.TzktCircuitState()})
+}
+
+// Ready handles GET /ready
+// @Summary Readiness probe
+// @Description Reports ready once startup has migrated the database, the database is reachable, the delegations table can be queried and the historical sync has finished; the Tzkt circuit state is reported but doesn't affect readiness
+// @Tags health
+// @Produce json
+// @Success 200 {object} HealthResponse
+// @Failure 503 {object} HealthResponse
+// @Router /ready [get]
+func (h *HealthHandler) Ready(ctx iris.Context) {
+	if err := h.Service.CheckReadiness(ctx.Request().Context()); err != nil {
+		reason := "database_unavailable"
+		switch {
+		case errors.Is(err, services.ErrShuttingDown):
+			reason = "shutting_down"
+		case errors.Is(err, services.ErrStartupIncomplete):
+			reason = "starting"
+		case errors.Is(err, services.ErrSchemaNotReady):
+			reason = "schema_not_ready"
+		case errors.Is(err, services.ErrHistoricalSyncIncomplete):
+			reason = "historical_sync_in_progress"
+		}
+		h.logger(ctx).Warn().Err(err).Str("reason", reason).Msg("Service not ready")
+		ctx.StatusCode(http.StatusServiceUnavailable)
+		ctx.JSON(HealthResponse{Status: "not_ready", Reason: reason, TzktCircuit: h.Service.TzktCircuitState()})
+		return
+	}
+
+	ctx.StatusCode(http.StatusOK)
+	ctx.JSON(HealthResponse{Status: "ready", TzktCircuit: h.Service.TzktCircuitState()})
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"fmt"
+	"testing"
+
+	"tezos-delegation/internal/mocks"
+	"tezos-delegation/internal/services"
+
+	"github.com/golang/mock/gomock"
+	"github.com/kataras/iris/v12"
+	"github.com/kataras/iris/v12/httptest"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockHealthServicePort(ctrl)
+	logger := zerolog.Nop()
+	handler := NewHealthHandler(service, logger)
+	service.EXPECT().TzktCircuitState().Return("").AnyTimes()
+
+	app := iris.New()
+	app.Get("/health", handler.Live)
+	app.Get("/ready", handler.Ready)
+	test := httptest.New(t, app)
+
+	t.Run("live", func(t *testing.T) {
+		test.GET("/health").Expect().Status(200).JSON().Object().HasValue("status", "ok")
+	})
+
+	t.Run("ready", func(t *testing.T) {
+		service.EXPECT().CheckReadiness(gomock.Any()).Return(nil)
+		test.GET("/ready").Expect().Status(200).JSON().Object().HasValue("status", "ready")
+	})
+
+	t.Run("historical sync in progress", func(t *testing.T) {
+		service.EXPECT().CheckReadiness(gomock.Any()).Return(services.ErrHistoricalSyncIncomplete)
+		resp := test.GET("/ready").Expect().Status(503).JSON().Object()
+		resp.HasValue("status", "not_ready")
+		resp.HasValue("reason", "historical_sync_in_progress")
+	})
+
+	t.Run("starting", func(t *testing.T) {
+		service.EXPECT().CheckReadiness(gomock.Any()).Return(services.ErrStartupIncomplete)
+		resp := test.GET("/ready").Expect().Status(503).JSON().Object()
+		resp.HasValue("status", "not_ready")
+		resp.HasValue("reason", "starting")
+	})
+
+	t.Run("schema not ready", func(t *testing.T) {
+		service.EXPECT().CheckReadiness(gomock.Any()).Return(fmt.Errorf("%w: %w", services.ErrSchemaNotReady, assert.AnError))
+		resp := test.GET("/ready").Expect().Status(503).JSON().Object()
+		resp.HasValue("status", "not_ready")
+		resp.HasValue("reason", "schema_not_ready")
+	})
+
+	t.Run("shutting down", func(t *testing.T) {
+		service.EXPECT().CheckReadiness(gomock.Any()).Return(services.ErrShuttingDown)
+		resp := test.GET("/ready").Expect().Status(503).JSON().Object()
+		resp.HasValue("status", "not_ready")
+		resp.HasValue("reason", "shutting_down")
+	})
+
+	t.Run("database unavailable", func(t *testing.T) {
+		service.EXPECT().CheckReadiness(gomock.Any()).Return(assert.AnError)
+		resp := test.GET("/ready").Expect().Status(503).JSON().Object()
+		resp.HasValue("status", "not_ready")
+		resp.HasValue("reason", "database_unavailable")
+	})
+}
+
+func TestHealthHandler_TzktCircuit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockHealthServicePort(ctrl)
+	handler := NewHealthHandler(service, zerolog.Nop())
+	service.EXPECT().TzktCircuitState().Return(services.CircuitOpen).Times(2)
+
+	app := iris.New()
+	app.Get("/health", handler.Live)
+	app.Get("/ready", handler.Ready)
+	test := httptest.New(t, app)
+
+	// An open circuit is reported without failing the probes
+	test.GET("/health").Expect().Status(200).JSON().Object().HasValue("tzkt_circuit", "open")
+
+	service.EXPECT().CheckReadiness(gomock.Any()).Return(nil)
+	resp := test.GET("/ready").Expect().Status(200).JSON().Object()
+	resp.HasValue("status", "ready")
+	resp.HasValue("tzkt_circuit", "open")
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"strconv"
+	"strings"
+)
+
+// responseFormat is a representation of the delegations list selected by the Accept header
+type responseFormat int
+
+const (
+	formatJSON responseFormat = iota
+	formatXML
+	formatNDJSON
+)
+
+const (
+	contentTypeJSON = "application/json; charset=utf-8"
+	contentTypeXML  = "application/xml; charset=utf-8"
+)
+
+// acceptedMediaTypes maps the media ranges the delegations list can satisfy to the format served for them
+var acceptedMediaTypes = map[string]responseFormat{
+	"*/*":              formatJSON,
+	"application/*":    formatJSON,
+	"application/json": formatJSON,
+	"application/xml":  formatXML,
+	"text/xml":         formatXML,
+	contentTypeNDJSON:  formatNDJSON,
+}
+
+// negotiateFormat picks the response format for an Accept header value.
+// The supported media range with the highest q value wins, ties going to the one listed first;
+// an empty header means JSON. Returns false if nothing acceptable is supported.
+func negotiateFormat(accept string) (responseFormat, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return formatJSON, true
+	}
+
+	best, bestQ := formatJSON, 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, _ := strings.Cut(part, ";")
+		format, ok := acceptedMediaTypes[strings.ToLower(strings.TrimSpace(mediaType))]
+		if !ok {
+			continue
+		}
+		if q := acceptQuality(params); q > bestQ {
+			best, bestQ = format, q
+		}
+	}
+	return best, bestQ > 0
+}
+
+// acceptQuality returns the q parameter of an Accept media range's parameters, 1 if absent and 0 if malformed
+func acceptQuality(params string) float64 {
+	for _, param := range strings.Split(params, ";") {
+		name, value, _ := strings.Cut(param, "=")
+		if !strings.EqualFold(strings.TrimSpace(name), "q") {
+			continue
+		}
+		q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || q < 0 || q > 1 {
+			return 0
+		}
+		return q
+	}
+	return 1
+}
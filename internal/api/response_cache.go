@@ -0,0 +1,106 @@
+package api
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// responseCache is a size-bounded LRU cache of serialized responses of type V with a fixed TTL.
+// Concurrent misses for the same key are collapsed into a single load, so a popular
+// page expiring doesn't send a burst of identical queries to the database.
+type responseCache[V any] struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	lru        *list.List               // Most recently used at the front
+	entries    map[string]*list.Element // Values are *cacheEntry[V]
+	inflight   map[string]*cacheCall[V]
+	now        func() time.Time
+}
+
+type cacheEntry[V any] struct {
+	key       string
+	value     V
+	expiresAt time.Time
+}
+
+// cacheCall is a load in progress that other requests for the same key wait on
+type cacheCall[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+func newResponseCache[V any](maxEntries int, ttl time.Duration) *responseCache[V] {
+	return &responseCache[V]{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		lru:        list.New(),
+		entries:    make(map[string]*list.Element),
+		inflight:   make(map[string]*cacheCall[V]),
+		now:        time.Now,
+	}
+}
+
+// GetOrLoad returns the cached value for key, calling load to produce it on a miss.
+// Only one load runs per key at a time; concurrent callers share its result.
+// Errors are returned to every waiting caller but never cached.
+func (c *responseCache[V]) GetOrLoad(key string, load func() (V, error)) (V, error) {
+	c.mu.Lock()
+	if el, ok := c.entries[key]; ok {
+		entry := el.Value.(*cacheEntry[V])
+		if c.now().Before(entry.expiresAt) {
+			c.lru.MoveToFront(el)
+			c.mu.Unlock()
+			return entry.value, nil
+		}
+		c.removeElement(el)
+	}
+	if call, ok := c.inflight[key]; ok {
+		c.mu.Unlock()
+		<-call.done
+		return call.value, call.err
+	}
+	call := &cacheCall[V]{done: make(chan struct{})}
+	c.inflight[key] = call
+	c.mu.Unlock()
+
+	// Always release waiters, even if load panics
+	defer func() {
+		c.mu.Lock()
+		delete(c.inflight, key)
+		if call.err == nil {
+			c.add(key, call.value)
+		}
+		c.mu.Unlock()
+		close(call.done)
+	}()
+
+	call.value, call.err = load()
+	return call.value, call.err
+}
+
+// add stores value under key, evicting the least recently used entry if the cache is full. Must hold mu.
+func (c *responseCache[V]) add(key string, value V) {
+	if el, ok := c.entries[key]; ok {
+		c.removeElement(el)
+	}
+	for c.lru.Len() >= c.maxEntries {
+		c.removeElement(c.lru.Back())
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry[V]{key: key, value: value, expiresAt: c.now().Add(c.ttl)})
+}
+
+// removeElement drops an entry from the cache. Must hold mu.
+func (c *responseCache[V]) removeElement(el *list.Element) {
+	c.lru.Remove(el)
+	delete(c.entries, el.Value.(*cacheEntry[V]).key)
+}
+
+// Len returns the number of cached entries, including expired ones not yet evicted
+func (c *responseCache[V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseCache_HitAndExpiry(t *testing.T) {
+	c := newResponseCache[[]byte](10, time.Minute)
+	now := time.Date(2022, 5, 5, 6, 29, 14, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	loads := 0
+	load := func() ([]byte, error) {
+		loads++
+		return []byte("body"), nil
+	}
+
+	body, err := c.GetOrLoad("k", load)
+	assert.NoError(t, err)
+	assert.Equal(t, "body", string(body))
+
+	// Served from cache within the TTL
+	_, _ = c.GetOrLoad("k", load)
+	assert.Equal(t, 1, loads)
+
+	// Reloaded once expired
+	now = now.Add(time.Minute)
+	_, _ = c.GetOrLoad("k", load)
+	assert.Equal(t, 2, loads)
+}
+
+func TestResponseCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := newResponseCache[[]byte](2, time.Minute)
+	load := func(v string) func() ([]byte, error) {
+		return func() ([]byte, error) { return []byte(v), nil }
+	}
+
+	_, _ = c.GetOrLoad("a", load("a"))
+	_, _ = c.GetOrLoad("b", load("b"))
+	_, _ = c.GetOrLoad("a", load("a")) // a becomes most recently used
+	_, _ = c.GetOrLoad("c", load("c")) // evicts b
+	assert.Equal(t, 2, c.Len())
+
+	reloaded := false
+	_, _ = c.GetOrLoad("b", func() ([]byte, error) {
+		reloaded = true
+		return []byte("b"), nil
+	})
+	assert.True(t, reloaded)
+
+	body, _ := c.GetOrLoad("c", func() ([]byte, error) { return nil, errors.New("should be cached") })
+	assert.Equal(t, "c", string(body))
+}
+
+func TestResponseCache_ErrorsNotCached(t *testing.T) {
+	c := newResponseCache[[]byte](10, time.Minute)
+
+	_, err := c.GetOrLoad("k", func() ([]byte, error) { return nil, errors.New("db down") })
+	assert.Error(t, err)
+	assert.Equal(t, 0, c.Len())
+
+	body, err := c.GetOrLoad("k", func() ([]byte, error) { return []byte("ok"), nil })
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", string(body))
+}
+
+func TestResponseCache_SingleFlight(t *testing.T) {
+	c := newResponseCache[[]byte](10, time.Minute)
+
+	var loads int32
+	release := make(chan struct{})
+	load := func() ([]byte, error) {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		return []byte("body"), nil
+	}
+
+	const callers = 10
+	var wg sync.WaitGroup
+	results := make([]string, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			body, _ := c.GetOrLoad("k", load)
+			results[i] = string(body)
+		}(i)
+	}
+
+	// Let every caller reach the cache before the load finishes
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&loads))
+	for _, r := range results {
+		assert.Equal(t, "body", r)
+	}
+}
@@ -1,28 +1,398 @@
 package api
 
 import (
+	"compress/gzip"
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"mime"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"tezos-delegation/internal/requestid"
+	"time"
+
 	"github.com/kataras/iris/v12"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog"
+	"golang.org/x/time/rate"
 )
 
-// securityHeadersMiddleware adds security headers to responses
-func securityHeadersMiddleware() iris.Handler {
+// RouterOptions holds the tunable router behavior loaded from configuration.
+type RouterOptions struct {
+	AccessLogSkipPaths     []string    // Request paths not written to the access log, e.g. probes and metrics scrapes
+	RelaxedSecurityHeaders bool        // Omit the CSP and HSTS headers, for local development over plain HTTP
+	Ready                  func() bool // Reports whether startup has finished; delegation routes answer 503 until it does. nil means always ready
+	MaxURLLength           int         // Longest request URL accepted, in bytes; 0 means unlimited
+	MaxHeaderBytes         int         // Largest total size of the request headers accepted; 0 means unlimited
+	ImportEnabled          bool        // Register POST /xtz/delegations/import, which lets clients write delegations
+	AdminToken             string      // Bearer token required by the admin routes, which are only registered when it is set
+
+	// RequestTimeout is the deadline of each /xtz request, after which it answers 503; 0 means no deadline
+	RequestTimeout time.Duration
+	// MaxConcurrentRequests caps the /xtz requests processed at once, shedding the rest with 503; 0 means unlimited
+	MaxConcurrentRequests int
+	// RateLimit caps the /xtz requests per second from each client IP, rejecting the rest with 429; 0 means unlimited
+	RateLimit int
+	// GzipLevel is the gzip compression level, 1 to 9; 0 uses gzip.DefaultCompression
+	GzipLevel int
+	// GzipContentTypes lists the response media types compressed for clients accepting gzip; empty disables compression
+	GzipContentTypes []string
+}
+
+// securityHeadersMiddleware adds security headers to responses. When relaxed, Content-Security-Policy
+// and Strict-Transport-Security are left out: HSTS is meaningless over plain HTTP and the strict CSP
+// blocks the inline scripts of local tooling.
+func securityHeadersMiddleware(relaxed bool) iris.Handler {
 	return func(ctx iris.Context) {
 		ctx.Header("X-Content-Type-Options", "nosniff")
 		ctx.Header("X-Frame-Options", "DENY")
 		ctx.Header("X-XSS-Protection", "1; mode=block")
 		ctx.Header("Referrer-Policy", "strict-origin-when-cross-origin")
-		ctx.Header("Content-Security-Policy", "default-src 'self'")
-		ctx.Header("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
+		if !relaxed {
+			ctx.Header("Content-Security-Policy", "default-src 'self'")
+			ctx.Header("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
+		}
+
+		ctx.Next()
+	}
+}
+
+// requestSizeMiddleware rejects requests whose URL is longer than maxURLLength bytes with 414, or whose
+// headers add up to more than maxHeaderBytes with 431, before any parameter parsing. Zero disables a limit.
+// This bounds the work a single request can cause; handlers still check individual parameter lengths.
+func requestSizeMiddleware(maxURLLength, maxHeaderBytes int) iris.Handler {
+	return func(ctx iris.Context) {
+		req := ctx.Request()
+		if maxURLLength > 0 && len(req.RequestURI) > maxURLLength {
+			respondWithError(ctx, http.StatusRequestURITooLong, CodeURITooLong, fmt.Sprintf("Request URL too long: at most %d bytes", maxURLLength))
+			return
+		}
+
+		if maxHeaderBytes > 0 {
+			size := 0
+			for name, values := range req.Header {
+				for _, value := range values {
+					size += len(name) + len(value)
+				}
+			}
+			if size > maxHeaderBytes {
+				respondWithError(ctx, http.StatusRequestHeaderFieldsTooLarge, CodeHeadersTooLarge, fmt.Sprintf("Request headers too large: at most %d bytes", maxHeaderBytes))
+				return
+			}
+		}
+
+		ctx.Next()
+	}
+}
+
+// requireJSONMiddleware rejects POST, PUT, PATCH and DELETE requests that carry a body other than
+// application/json with 415, so write endpoints never guess at the body's encoding. Bodiless requests,
+// such as most DELETEs, pass through; handlers reject a missing body themselves.
+func requireJSONMiddleware() iris.Handler {
+	return func(ctx iris.Context) {
+		req := ctx.Request()
+		switch req.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+			if req.ContentLength != 0 {
+				mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+				if err != nil || mediaType != "application/json" {
+					respondWithError(ctx, http.StatusUnsupportedMediaType, CodeUnsupportedMediaType, "Unsupported media type: request body must be application/json")
+					return
+				}
+			}
+		}
+
+		ctx.Next()
+	}
+}
+
+// adminAuthMiddleware answers 401 unless the request carries token as a bearer token. The comparison
+// takes constant time so the token can't be guessed byte by byte from response times.
+func adminAuthMiddleware(token string) iris.Handler {
+	return func(ctx iris.Context) {
+		got, ok := strings.CutPrefix(ctx.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			ctx.Header("WWW-Authenticate", `Bearer realm="admin"`)
+			respondWithError(ctx, http.StatusUnauthorized, CodeUnauthorized, "Missing or invalid admin token")
+			return
+		}
 
 		ctx.Next()
 	}
 }
 
-func RegisterRoutes(app *iris.Application, delegationHandler *DelegationHandler) {
+// requestTimeoutMiddleware puts a deadline of timeout on the request context, which handlers pass on to
+// the service and database, so a slow query is cancelled instead of holding a connection. Only work that
+// watches the context is interrupted: the 503 is written once the handler returns, either by
+// respondWithServiceError or, if the handler gave up without responding, here. Streaming exports are
+// exempt, as large ones legitimately run long.
+func requestTimeoutMiddleware(timeout time.Duration) iris.Handler {
+	return func(ctx iris.Context) {
+		if isStreamRequest(ctx) {
+			ctx.Next()
+			return
+		}
+
+		reqCtx, cancel := context.WithTimeout(ctx.Request().Context(), timeout)
+		defer cancel()
+		ctx.ResetRequest(ctx.Request().WithContext(reqCtx))
+
+		ctx.Next()
+
+		if reqCtx.Err() == context.DeadlineExceeded && ctx.ResponseWriter().Written() < 0 {
+			respondWithError(ctx, http.StatusServiceUnavailable, CodeRequestTimeout, "Request timed out")
+		}
+	}
+}
+
+// rateLimitIdle is how long a client's limiter is kept after its last request
+const rateLimitIdle = 10 * time.Minute
+
+// clientLimiter is the token bucket of one client IP
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// rateLimitMiddleware limits each client IP to limit requests per second, allowing bursts of up to limit
+// requests. Requests over the limit get a 429 with a Retry-After of when the client's next token is due.
+// Limiters of clients idle for rateLimitIdle are dropped, so the map doesn't grow without bound.
+func rateLimitMiddleware(limit int) iris.Handler {
+	var mu sync.Mutex
+	clients := make(map[string]*clientLimiter)
+	lastSweep := time.Now()
 
-	app.Use(securityHeadersMiddleware())
+	return func(ctx iris.Context) {
+		now := time.Now()
+		mu.Lock()
+		if now.Sub(lastSweep) > rateLimitIdle {
+			for ip, client := range clients {
+				if now.Sub(client.lastSeen) > rateLimitIdle {
+					delete(clients, ip)
+				}
+			}
+			lastSweep = now
+		}
+		client, ok := clients[ctx.RemoteAddr()]
+		if !ok {
+			client = &clientLimiter{limiter: rate.NewLimiter(rate.Limit(limit), limit)}
+			clients[ctx.RemoteAddr()] = client
+		}
+		client.lastSeen = now
+		reservation := client.limiter.ReserveN(now, 1)
+		delay := reservation.DelayFrom(now)
+		if delay > 0 {
+			// Rejected requests don't spend a token
+			reservation.CancelAt(now)
+		}
+		mu.Unlock()
 
-	// TODO: Rate limiter
+		if delay > 0 {
+			respondTooManyRequests(ctx, delay)
+			return
+		}
 
-	app.Get("/xtz/delegations", delegationHandler.GetDelegations)
+		ctx.Next()
+	}
+}
+
+// concurrencyLimitMiddleware caps the requests processed at once at limit, to protect the database during
+// traffic spikes. Requests over the cap get a 503 straight away instead of queueing, since a queue would
+// only grow while the spike lasts and every queued request would answer late anyway.
+func concurrencyLimitMiddleware(limit int) iris.Handler {
+	sem := make(chan struct{}, limit)
+	return func(ctx iris.Context) {
+		select {
+		case sem <- struct{}{}:
+			defer func() { <-sem }()
+		default:
+			setRetryAfter(ctx, time.Second)
+			respondWithError(ctx, http.StatusServiceUnavailable, CodeOverloaded, "Server is overloaded, try again shortly")
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// readinessGateMiddleware answers 503 until ready reports true, so queries arriving while startup is
+// still migrating the database don't fail with database errors.
+func readinessGateMiddleware(ready func() bool) iris.Handler {
+	return func(ctx iris.Context) {
+		if !ready() {
+			setRetryAfter(ctx, time.Second)
+			respondWithError(ctx, http.StatusServiceUnavailable, CodeNotReady, "Service is starting, try again shortly")
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// requestIDMiddleware tags each request with an ID, reusing the caller's X-Request-ID when it is valid.
+// The ID is echoed in the response header and stored on the request context, where handler and
+// service loggers pick it up.
+func requestIDMiddleware() iris.Handler {
+	return func(ctx iris.Context) {
+		id := ctx.GetHeader(requestid.Header)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+
+		ctx.Header(requestid.Header, id)
+		ctx.Values().Set(requestid.LogField, id)
+		ctx.ResetRequest(ctx.Request().WithContext(requestid.NewContext(ctx.Request().Context(), id)))
+
+		ctx.Next()
+	}
+}
+
+// accessLogMiddleware logs every completed request at info level with its method, path, final status,
+// latency, response size, client IP and request ID. Requests for paths in skipPaths aren't logged.
+func accessLogMiddleware(logger zerolog.Logger, skipPaths []string) iris.Handler {
+	skip := make(map[string]bool, len(skipPaths))
+	for _, path := range skipPaths {
+		skip[path] = true
+	}
+
+	return func(ctx iris.Context) {
+		if skip[ctx.Path()] {
+			ctx.Next()
+			return
+		}
+
+		start := time.Now()
+		ctx.Next()
+
+		// Written is -1 if nothing was written and 0 if only the status line was
+		bytes := max(ctx.ResponseWriter().Written(), 0)
+		event := logger.Info().
+			Str("method", ctx.Method()).
+			Str("path", ctx.Path()).
+			Int("status", ctx.GetStatusCode()).
+			Dur("latency", time.Since(start)).
+			Int("bytes", bytes).
+			Str("client_ip", ctx.RemoteAddr())
+		if id := ctx.Values().GetString(requestid.LogField); id != "" {
+			event = event.Str(requestid.LogField, id)
+		}
+		event.Msg("HTTP request")
+	}
+}
+
+// recoveryMiddleware turns a panicking handler into a 500 with the standard error body, logging the panic
+// and stack trace with the request ID. If the response was already started it can only be cut short.
+func recoveryMiddleware(logger zerolog.Logger) iris.Handler {
+	return func(ctx iris.Context) {
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			// http.ErrAbortHandler is the deliberate way to abort a response; let the server handle it
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+
+			requestid.Logger(ctx.Request().Context(), &logger).Error().
+				Str("panic", fmt.Sprint(p)).
+				Str("stack", string(debug.Stack())).
+				Str("method", ctx.Method()).
+				Str("path", ctx.Path()).
+				Msg("Recovered from handler panic")
+
+			if ctx.ResponseWriter().Written() < 0 {
+				respondWithError(ctx, http.StatusInternalServerError, CodeInternalError, "Internal server error")
+			}
+			ctx.StopExecution()
+		}()
+
+		ctx.Next()
+	}
+}
+
+// routeMethods lists the methods checked when building the Allow header of a 405 response
+var routeMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+	http.MethodPatch, http.MethodDelete, http.MethodOptions,
+}
+
+// registerErrorHandlers replaces iris's plain text 404 and 405 responses with the standard JSON error body.
+// Requests for a known path with an unregistered method get a 405 whose Allow header lists the methods
+// the path does accept, instead of a 404.
+func registerErrorHandlers(app *iris.Application) {
+	app.Configure(iris.WithFireMethodNotAllowed)
+
+	app.OnErrorCode(http.StatusNotFound, func(ctx iris.Context) {
+		respondWithError(ctx, http.StatusNotFound, CodeNotFound, "Resource not found")
+	})
+	app.OnErrorCode(http.StatusMethodNotAllowed, func(ctx iris.Context) {
+		// iris sets Allow to the first matching method only; replace it with all of them
+		var allowed []string
+		for _, method := range routeMethods {
+			if ctx.RouteExists(method, ctx.Path()) {
+				allowed = append(allowed, method)
+			}
+		}
+		ctx.ResponseWriter().Header().Set("Allow", strings.Join(allowed, ", "))
+		respondWithError(ctx, http.StatusMethodNotAllowed, CodeMethodNotAllowed, fmt.Sprintf("Method %s not allowed", ctx.Method()))
+	})
+}
+
+func RegisterRoutes(app *iris.Application, delegationHandler *DelegationHandler, healthHandler *HealthHandler, logger zerolog.Logger, opts RouterOptions) {
+
+	if len(opts.GzipContentTypes) > 0 {
+		level := opts.GzipLevel
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+		app.WrapRouter(gzipRouterWrapper(level, opts.GzipContentTypes))
+	}
+
+	// Registered ahead of routing so unmatched requests are logged too
+	app.UseRouter(accessLogMiddleware(logger.With().Str("component", "AccessLog").Logger(), opts.AccessLogSkipPaths))
+	app.UseRouter(recoveryMiddleware(logger.With().Str("component", "Recovery").Logger()))
+	app.UseRouter(requestSizeMiddleware(opts.MaxURLLength, opts.MaxHeaderBytes))
+	app.Use(requestIDMiddleware())
+	app.Use(securityHeadersMiddleware(opts.RelaxedSecurityHeaders))
+	app.Use(requireJSONMiddleware())
+	registerErrorHandlers(app)
+
+	app.Get("/health", healthHandler.Live)
+	app.Get("/ready", healthHandler.Ready)
+	app.Get("/metrics", iris.FromStd(promhttp.Handler()))
+
+	var xtzMiddleware []iris.Handler
+	if opts.Ready != nil {
+		xtzMiddleware = append(xtzMiddleware, readinessGateMiddleware(opts.Ready))
+	}
+	if opts.RateLimit > 0 {
+		xtzMiddleware = append(xtzMiddleware, rateLimitMiddleware(opts.RateLimit))
+	}
+	if opts.MaxConcurrentRequests > 0 {
+		xtzMiddleware = append(xtzMiddleware, concurrencyLimitMiddleware(opts.MaxConcurrentRequests))
+	}
+	if opts.RequestTimeout > 0 {
+		xtzMiddleware = append(xtzMiddleware, requestTimeoutMiddleware(opts.RequestTimeout))
+	}
+	xtz := app.Party("/xtz", xtzMiddleware...)
+	xtz.Get("/delegations", delegationHandler.GetDelegations)
+	xtz.Head("/delegations", delegationHandler.CountDelegations)
+	xtz.Get("/delegations.csv", delegationHandler.ExportDelegationsCSV)
+	xtz.Get("/delegations/feed.atom", delegationHandler.GetDelegationsFeed)
+	xtz.Get("/delegations/{tzktId}", delegationHandler.GetDelegationByTzktID)
+	xtz.Get("/delegations/stats/by-year", delegationHandler.GetStatsByYear)
+	xtz.Get("/delegations/stats/monthly", delegationHandler.GetMonthlyStats)
+	xtz.Get("/delegations/stats/top-delegators", delegationHandler.GetTopDelegators)
+	xtz.Get("/delegations/stats/delegators", delegationHandler.GetDistinctDelegators)
+	xtz.Get("/delegations/delegator/{address}/summary", delegationHandler.GetDelegatorSummary)
+	xtz.Get("/sync/status", delegationHandler.GetSyncStatus)
+	if opts.ImportEnabled {
+		xtz.Post("/delegations/import", delegationHandler.ImportDelegations)
+	}
+	if opts.AdminToken != "" {
+		xtz.Delete("/delegations/{tzktId}", adminAuthMiddleware(opts.AdminToken), delegationHandler.DeleteDelegationByTzktID)
+	}
 }
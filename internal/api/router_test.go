@@ -0,0 +1,429 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"tezos-delegation/internal/apperrors"
+	"tezos-delegation/internal/mocks"
+	"tezos-delegation/internal/model"
+	"tezos-delegation/internal/requestid"
+
+	"github.com/golang/mock/gomock"
+	"github.com/kataras/iris/v12"
+	"github.com/kataras/iris/v12/httptest"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	app := iris.New()
+	app.Use(requestIDMiddleware())
+	app.Get("/echo", func(ctx iris.Context) {
+		// Both the iris context and the request context carry the ID
+		assert.Equal(t, ctx.Values().GetString(requestid.LogField), requestid.FromContext(ctx.Request().Context()))
+		ctx.WriteString(requestid.FromContext(ctx.Request().Context()))
+	})
+	test := httptest.New(t, app)
+
+	t.Run("reuses incoming id", func(t *testing.T) {
+		resp := test.GET("/echo").WithHeader(requestid.Header, "abc-123").Expect().Status(http.StatusOK)
+		resp.Header(requestid.Header).IsEqual("abc-123")
+		resp.Body().IsEqual("abc-123")
+	})
+
+	t.Run("generates id when missing", func(t *testing.T) {
+		resp := test.GET("/echo").Expect().Status(http.StatusOK)
+		id := resp.Header(requestid.Header).NotEmpty().Raw()
+		assert.Len(t, id, 36)
+		resp.Body().IsEqual(id)
+	})
+
+	t.Run("replaces invalid id", func(t *testing.T) {
+		resp := test.GET("/echo").WithHeader(requestid.Header, "bad id").Expect().Status(http.StatusOK)
+		resp.Header(requestid.Header).NotEqual("bad id")
+	})
+}
+
+func TestAccessLogMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	app := iris.New()
+	app.UseRouter(accessLogMiddleware(zerolog.New(&buf), []string{"/health"}))
+	app.Use(requestIDMiddleware())
+	app.Get("/fail", func(ctx iris.Context) {
+		respondWithError(ctx, http.StatusBadRequest, CodeInvalidYear, "Invalid year parameter")
+	})
+	app.Get("/health", func(ctx iris.Context) {
+		ctx.StatusCode(http.StatusOK)
+	})
+	test := httptest.New(t, app)
+
+	t.Run("records final status of an error response", func(t *testing.T) {
+		buf.Reset()
+		test.GET("/fail").WithHeader(requestid.Header, "abc-123").Expect().Status(http.StatusBadRequest)
+
+		var entry map[string]interface{}
+		assert.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+		assert.Equal(t, "HTTP request", entry["message"])
+		assert.Equal(t, "GET", entry["method"])
+		assert.Equal(t, "/fail", entry["path"])
+		assert.Equal(t, float64(http.StatusBadRequest), entry["status"])
+		assert.Equal(t, "abc-123", entry[requestid.LogField])
+		assert.Greater(t, entry["bytes"], float64(0))
+		assert.Contains(t, entry, "latency")
+		assert.Contains(t, entry, "client_ip")
+	})
+
+	t.Run("records unmatched routes", func(t *testing.T) {
+		buf.Reset()
+		test.GET("/missing").Expect().Status(http.StatusNotFound)
+
+		var entry map[string]interface{}
+		assert.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+		assert.Equal(t, float64(http.StatusNotFound), entry["status"])
+	})
+
+	t.Run("skips configured paths", func(t *testing.T) {
+		buf.Reset()
+		test.GET("/health").Expect().Status(http.StatusOK)
+		assert.Empty(t, buf.String())
+	})
+}
+
+func TestRecoveryMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	app := iris.New()
+	app.UseRouter(recoveryMiddleware(zerolog.New(&buf)))
+	app.Use(requestIDMiddleware())
+	app.Get("/panic", func(ctx iris.Context) {
+		var d *DelegationDto
+		ctx.WriteString(d.Delegator) // nil pointer dereference
+	})
+	app.Get("/ok", func(ctx iris.Context) {
+		ctx.WriteString("ok")
+	})
+	test := httptest.New(t, app)
+
+	resp := test.GET("/panic").WithHeader(requestid.Header, "abc-123").Expect().Status(http.StatusInternalServerError)
+	resp.JSON().Object().IsEqual(map[string]interface{}{"error": "Internal server error", "code": CodeInternalError})
+
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "abc-123", entry[requestid.LogField])
+	assert.Contains(t, entry["panic"], "nil pointer dereference")
+	assert.Contains(t, entry["stack"], "TestRecoveryMiddleware")
+
+	// The server keeps serving after a panic
+	test.GET("/ok").Expect().Status(http.StatusOK).Body().IsEqual("ok")
+}
+
+func TestErrorHandlers(t *testing.T) {
+	app := iris.New()
+	registerErrorHandlers(app)
+	app.Get("/items", func(ctx iris.Context) { ctx.StatusCode(http.StatusOK) })
+	app.Head("/items", func(ctx iris.Context) { ctx.StatusCode(http.StatusOK) })
+	app.Get("/items/{id}", func(ctx iris.Context) {
+		respondWithError(ctx, http.StatusNotFound, CodeNotFound, "Item not found")
+	})
+	test := httptest.New(t, app)
+
+	t.Run("unknown path", func(t *testing.T) {
+		resp := test.GET("/unknown").Expect().Status(http.StatusNotFound)
+		resp.Header("Content-Type").Contains("application/json")
+		body := resp.JSON().Object()
+		body.Value("error").String().IsEqual("Resource not found")
+		body.Value("code").String().IsEqual(CodeNotFound)
+	})
+
+	t.Run("wrong method", func(t *testing.T) {
+		resp := test.POST("/items").Expect().Status(http.StatusMethodNotAllowed)
+		resp.Header("Allow").IsEqual("GET, HEAD")
+		body := resp.JSON().Object()
+		body.Value("error").String().IsEqual("Method POST not allowed")
+		body.Value("code").String().IsEqual(CodeMethodNotAllowed)
+	})
+
+	t.Run("handler error body is kept", func(t *testing.T) {
+		test.GET("/items/42").Expect().Status(http.StatusNotFound).
+			JSON().Object().Value("error").String().IsEqual("Item not found")
+	})
+}
+
+func TestSecurityHeadersMiddleware(t *testing.T) {
+	newApp := func(relaxed bool) *iris.Application {
+		app := iris.New()
+		app.Use(securityHeadersMiddleware(relaxed))
+		app.Get("/ping", func(ctx iris.Context) { ctx.StatusCode(http.StatusOK) })
+		return app
+	}
+
+	t.Run("strict", func(t *testing.T) {
+		resp := httptest.New(t, newApp(false)).GET("/ping").Expect().Status(http.StatusOK)
+		resp.Header("Content-Security-Policy").IsEqual("default-src 'self'")
+		resp.Header("Strict-Transport-Security").IsEqual("max-age=31536000; includeSubDomains")
+		resp.Header("X-Content-Type-Options").IsEqual("nosniff")
+	})
+
+	t.Run("relaxed", func(t *testing.T) {
+		resp := httptest.New(t, newApp(true)).GET("/ping").Expect().Status(http.StatusOK)
+		resp.Headers().NotContainsKey("Content-Security-Policy")
+		resp.Headers().NotContainsKey("Strict-Transport-Security")
+		resp.Header("X-Content-Type-Options").IsEqual("nosniff")
+		resp.Header("X-Frame-Options").IsEqual("DENY")
+	})
+}
+
+func TestRequireJSONMiddleware(t *testing.T) {
+	app := iris.New()
+	app.Use(requireJSONMiddleware())
+	ok := func(ctx iris.Context) { ctx.StatusCode(http.StatusOK) }
+	app.Post("/xtz/delegations/import", ok)
+	app.Delete("/xtz/delegations/{tzktId}", ok)
+	app.Get("/xtz/delegations", ok)
+	test := httptest.New(t, app)
+
+	t.Run("form body rejected", func(t *testing.T) {
+		resp := test.POST("/xtz/delegations/import").WithFormField("tzkt_id", "42").Expect().Status(http.StatusUnsupportedMediaType)
+		resp.JSON().Object().HasValue("code", CodeUnsupportedMediaType).
+			HasValue("error", "Unsupported media type: request body must be application/json")
+	})
+
+	t.Run("missing content type rejected", func(t *testing.T) {
+		test.POST("/xtz/delegations/import").WithBytes([]byte(`[]`)).Expect().Status(http.StatusUnsupportedMediaType)
+	})
+
+	t.Run("json accepted", func(t *testing.T) {
+		test.POST("/xtz/delegations/import").WithHeader("Content-Type", "application/json; charset=utf-8").WithBytes([]byte(`[]`)).
+			Expect().Status(http.StatusOK)
+	})
+
+	t.Run("bodiless delete accepted", func(t *testing.T) {
+		test.DELETE("/xtz/delegations/42").Expect().Status(http.StatusOK)
+	})
+
+	t.Run("reads not checked", func(t *testing.T) {
+		test.GET("/xtz/delegations").WithHeader("Content-Type", "text/plain").Expect().Status(http.StatusOK)
+	})
+}
+
+func TestAdminAuthMiddleware(t *testing.T) {
+	const token = "0123456789abcdef0123"
+	app := iris.New()
+	app.Delete("/xtz/delegations/{tzktId}", adminAuthMiddleware(token), func(ctx iris.Context) { ctx.StatusCode(http.StatusNoContent) })
+	test := httptest.New(t, app)
+
+	t.Run("missing token", func(t *testing.T) {
+		resp := test.DELETE("/xtz/delegations/42").Expect().Status(http.StatusUnauthorized)
+		resp.Header("WWW-Authenticate").IsEqual(`Bearer realm="admin"`)
+		resp.JSON().Object().HasValue("code", CodeUnauthorized)
+	})
+
+	t.Run("wrong token", func(t *testing.T) {
+		test.DELETE("/xtz/delegations/42").WithHeader("Authorization", "Bearer wrong").
+			Expect().Status(http.StatusUnauthorized).JSON().Object().HasValue("code", CodeUnauthorized)
+	})
+
+	t.Run("valid token", func(t *testing.T) {
+		test.DELETE("/xtz/delegations/42").WithHeader("Authorization", "Bearer "+token).Expect().Status(http.StatusNoContent)
+	})
+}
+
+func TestReadinessGateMiddleware(t *testing.T) {
+	var ready atomic.Bool
+	app := iris.New()
+	app.Use(readinessGateMiddleware(ready.Load))
+	app.Get("/xtz/delegations", func(ctx iris.Context) { ctx.StatusCode(http.StatusOK) })
+	test := httptest.New(t, app)
+
+	t.Run("not ready", func(t *testing.T) {
+		resp := test.GET("/xtz/delegations").Expect().Status(http.StatusServiceUnavailable)
+		resp.Header("Retry-After").IsEqual("1")
+		resp.JSON().Object().HasValue("code", CodeNotReady)
+	})
+
+	t.Run("ready", func(t *testing.T) {
+		ready.Store(true)
+		test.GET("/xtz/delegations").Expect().Status(http.StatusOK)
+	})
+}
+
+func TestRequestTimeoutMiddleware(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockDelegationServicePort(ctrl)
+	handler := NewDelegationHandler(service, zerolog.Nop(), HandlerOptions{})
+	app := iris.New()
+	app.Use(requestTimeoutMiddleware(20 * time.Millisecond))
+	app.Get("/xtz/delegations", handler.GetDelegations)
+	app.Get("/xtz/delegations.csv", handler.ExportDelegationsCSV)
+	app.Get("/xtz/stalled", func(ctx iris.Context) { <-ctx.Request().Context().Done() })
+	test := httptest.New(t, app)
+
+	t.Run("slow service", func(t *testing.T) {
+		// The service only returns once the deadline cancels its query
+		service.EXPECT().GetDelegations(gomock.Any(), 1, 50, gomock.Any()).DoAndReturn(
+			func(ctx context.Context, pageNo, pageSize int, filter model.DelegationFilter) ([]model.Delegation, bool, error) {
+				<-ctx.Done()
+				return nil, false, apperrors.NewDatabaseErrorWithCause("list delegations", "canceled", ctx.Err())
+			})
+
+		resp := test.GET("/xtz/delegations").Expect().Status(http.StatusServiceUnavailable)
+		resp.JSON().Object().HasValue("code", CodeRequestTimeout).HasValue("error", "Request timed out")
+	})
+
+	t.Run("handler gives up without responding", func(t *testing.T) {
+		test.GET("/xtz/stalled").Expect().Status(http.StatusServiceUnavailable).
+			JSON().Object().HasValue("code", CodeRequestTimeout)
+	})
+
+	t.Run("fast service", func(t *testing.T) {
+		service.EXPECT().GetDelegations(gomock.Any(), 1, 50, gomock.Any()).Return([]model.Delegation{}, false, nil)
+		test.GET("/xtz/delegations").Expect().Status(http.StatusOK)
+	})
+
+	t.Run("streaming export exempt", func(t *testing.T) {
+		service.EXPECT().StreamDelegations(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, filter model.DelegationFilter, fn func(model.Delegation) error) error {
+				_, hasDeadline := ctx.Deadline()
+				assert.False(t, hasDeadline)
+				return nil
+			})
+		test.GET("/xtz/delegations.csv").Expect().Status(http.StatusOK)
+	})
+}
+
+func TestRequestTimeoutMiddleware_Cached(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockDelegationServicePort(ctrl)
+	handler := NewDelegationHandler(service, zerolog.Nop(), HandlerOptions{CacheSize: 10, CacheTTL: time.Minute})
+	app := iris.New()
+	app.Use(requestTimeoutMiddleware(20 * time.Millisecond))
+	app.Get("/xtz/delegations", handler.GetDelegations)
+	test := httptest.New(t, app)
+
+	// The cached load is detached from client disconnects but must keep the request's deadline
+	service.EXPECT().GetDelegations(gomock.Any(), 1, 50, gomock.Any()).DoAndReturn(
+		func(ctx context.Context, pageNo, pageSize int, filter model.DelegationFilter) ([]model.Delegation, bool, error) {
+			_, hasDeadline := ctx.Deadline()
+			assert.True(t, hasDeadline)
+			<-ctx.Done()
+			return nil, false, apperrors.NewDatabaseErrorWithCause("list delegations", "canceled", ctx.Err())
+		})
+
+	test.GET("/xtz/delegations").Expect().Status(http.StatusServiceUnavailable).
+		JSON().Object().HasValue("code", CodeRequestTimeout)
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockDelegationServicePort(ctrl)
+	healthService := mocks.NewMockHealthServicePort(ctrl)
+	healthService.EXPECT().TzktCircuitState().Return("").AnyTimes()
+	app := iris.New()
+	RegisterRoutes(app, NewDelegationHandler(service, zerolog.Nop(), HandlerOptions{}), NewHealthHandler(healthService, zerolog.Nop()),
+		zerolog.Nop(), RouterOptions{RateLimit: 2})
+	test := httptest.New(t, app)
+
+	// The burst allows as many requests as the per-second limit
+	service.EXPECT().GetDelegations(gomock.Any(), 1, 50, gomock.Any()).Times(2).Return([]model.Delegation{}, false, nil)
+	for range 2 {
+		test.GET("/xtz/delegations").Expect().Status(http.StatusOK)
+	}
+
+	t.Run("over the limit", func(t *testing.T) {
+		resp := test.GET("/xtz/delegations").Expect().Status(http.StatusTooManyRequests)
+		resp.Header("Retry-After").IsEqual("1")
+		resp.JSON().Object().HasValue("code", CodeRateLimited)
+	})
+
+	t.Run("health bypasses limiter", func(t *testing.T) {
+		test.GET("/health").Expect().Status(http.StatusOK)
+	})
+}
+
+func TestConcurrencyLimitMiddleware(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := mocks.NewMockDelegationServicePort(ctrl)
+	healthService := mocks.NewMockHealthServicePort(ctrl)
+	healthService.EXPECT().TzktCircuitState().Return("").AnyTimes()
+	app := iris.New()
+	RegisterRoutes(app, NewDelegationHandler(service, zerolog.Nop(), HandlerOptions{}), NewHealthHandler(healthService, zerolog.Nop()),
+		zerolog.Nop(), RouterOptions{MaxConcurrentRequests: 2})
+	test := httptest.New(t, app)
+
+	// Saturate the limiter with two requests blocked in the service until release is closed
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	service.EXPECT().GetDelegations(gomock.Any(), 1, 50, gomock.Any()).Times(2).DoAndReturn(
+		func(ctx context.Context, pageNo, pageSize int, filter model.DelegationFilter) ([]model.Delegation, bool, error) {
+			entered <- struct{}{}
+			<-release
+			return []model.Delegation{}, false, nil
+		})
+	done := make(chan struct{})
+	for range 2 {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			test.GET("/xtz/delegations").Expect().Status(http.StatusOK)
+		}()
+	}
+	<-entered
+	<-entered
+
+	t.Run("overflow request shed", func(t *testing.T) {
+		resp := test.GET("/xtz/delegations/stats/by-year").Expect().Status(http.StatusServiceUnavailable)
+		resp.Header("Retry-After").IsEqual("1")
+		resp.JSON().Object().HasValue("code", CodeOverloaded)
+	})
+
+	t.Run("health bypasses limiter", func(t *testing.T) {
+		test.GET("/health").Expect().Status(http.StatusOK)
+	})
+
+	close(release)
+	<-done
+	<-done
+
+	t.Run("slots freed", func(t *testing.T) {
+		service.EXPECT().GetDelegations(gomock.Any(), 1, 50, gomock.Any()).Return([]model.Delegation{}, false, nil)
+		test.GET("/xtz/delegations").Expect().Status(http.StatusOK)
+	})
+}
+
+func TestRequestSizeMiddleware(t *testing.T) {
+	app := iris.New()
+	app.UseRouter(requestSizeMiddleware(64, 1024))
+	app.Get("/xtz/delegations", func(ctx iris.Context) { ctx.StatusCode(http.StatusOK) })
+	test := httptest.New(t, app)
+
+	t.Run("within limits", func(t *testing.T) {
+		test.GET("/xtz/delegations").WithQuery("page", "2").Expect().Status(http.StatusOK)
+	})
+
+	t.Run("over-length query string", func(t *testing.T) {
+		resp := test.GET("/xtz/delegations").WithQuery("year", strings.Repeat("9", 64)).Expect().Status(http.StatusRequestURITooLong)
+		resp.JSON().Object().HasValue("code", CodeURITooLong)
+	})
+
+	t.Run("oversized headers", func(t *testing.T) {
+		resp := test.GET("/xtz/delegations").WithHeader("X-Padding", strings.Repeat("a", 1024)).Expect().Status(http.StatusRequestHeaderFieldsTooLarge)
+		resp.JSON().Object().HasValue("code", CodeHeadersTooLarge)
+	})
+
+	t.Run("unmatched routes are guarded too", func(t *testing.T) {
+		test.GET("/" + strings.Repeat("x", 100)).Expect().Status(http.StatusRequestURITooLong)
+	})
+}
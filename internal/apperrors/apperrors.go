@@ -10,6 +10,7 @@ var (
 	ErrValidation    = errors.New("validation error")
 	ErrNotFound      = errors.New("not found")
 	ErrDatabase      = errors.New("database error")
+	ErrUnavailable   = errors.New("database unavailable")
 	ErrExternalAPI   = errors.New("external API error")
 	ErrConfiguration = errors.New("configuration error")
 	ErrInternal      = errors.New("internal error")
@@ -97,6 +98,83 @@ func IsDatabaseError(err error) bool {
 	return errors.As(err, &dbErr)
 }
 
+// DatabaseUnavailableError marks a database error caused by a lost or refused connection
+// rather than by the query itself, so callers can tell an outage from a failing statement
+type DatabaseUnavailableError struct {
+	Err error
+}
+
+func (e *DatabaseUnavailableError) Error() string {
+	return fmt.Sprintf("database unavailable: %v", e.Err)
+}
+
+func (e *DatabaseUnavailableError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrUnavailable, so errors.Is keeps matching the sentinel
+func (e *DatabaseUnavailableError) Is(target error) bool {
+	return target == ErrUnavailable
+}
+
+// NewDatabaseUnavailableError wraps a database error as a connection-level failure.
+// The wrapped error is kept, so IsDatabaseError still matches a wrapped DatabaseError.
+func NewDatabaseUnavailableError(err error) error {
+	return &DatabaseUnavailableError{Err: err}
+}
+
+// IsDatabaseUnavailableError checks if an error is a database unavailable error
+func IsDatabaseUnavailableError(err error) bool {
+	var unavailableErr *DatabaseUnavailableError
+	return errors.As(err, &unavailableErr)
+}
+
+// ConfigurationError represents a missing or invalid configuration setting
+type ConfigurationError struct {
+	Key     string // Environment variable at fault; empty when the error concerns several
+	Message string
+	Err     error
+}
+
+func (e *ConfigurationError) Error() string {
+	if e.Key != "" {
+		return fmt.Sprintf("configuration error: invalid %s: %s", e.Key, e.Message)
+	}
+	return fmt.Sprintf("configuration error: %s", e.Message)
+}
+
+func (e *ConfigurationError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrConfiguration, so errors.Is keeps matching the sentinel
+func (e *ConfigurationError) Is(target error) bool {
+	return target == ErrConfiguration
+}
+
+// NewConfigurationError creates a new configuration error
+func NewConfigurationError(key, message string) error {
+	return &ConfigurationError{
+		Key:     key,
+		Message: message,
+	}
+}
+
+// NewConfigurationErrorWithCause creates a new configuration error with a cause
+func NewConfigurationErrorWithCause(key, message string, cause error) error {
+	return &ConfigurationError{
+		Key:     key,
+		Message: message,
+		Err:     cause,
+	}
+}
+
+// IsConfigurationError checks if an error is a configuration error
+func IsConfigurationError(err error) bool {
+	var configErr *ConfigurationError
+	return errors.As(err, &configErr)
+}
+
 // ExternalAPIError represents an external API error
 type ExternalAPIError struct {
 	Service   string
@@ -140,3 +218,49 @@ func IsExternalAPIError(err error) bool {
 	var apiErr *ExternalAPIError
 	return errors.As(err, &apiErr)
 }
+
+// NotFoundError represents a missing resource
+type NotFoundError struct {
+	Resource   string
+	Identifier string
+	Err        error
+}
+
+func (e *NotFoundError) Error() string {
+	if e.Identifier != "" {
+		return fmt.Sprintf("%s not found: %s", e.Resource, e.Identifier)
+	}
+	return fmt.Sprintf("%s not found", e.Resource)
+}
+
+func (e *NotFoundError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrNotFound, so errors.Is keeps matching the sentinel
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// NewNotFoundError creates a new not found error
+func NewNotFoundError(resource, identifier string) error {
+	return &NotFoundError{
+		Resource:   resource,
+		Identifier: identifier,
+	}
+}
+
+// NewNotFoundErrorWithCause creates a new not found error with a cause
+func NewNotFoundErrorWithCause(resource, identifier string, cause error) error {
+	return &NotFoundError{
+		Resource:   resource,
+		Identifier: identifier,
+		Err:        cause,
+	}
+}
+
+// IsNotFoundError checks if an error is a not found error
+func IsNotFoundError(err error) bool {
+	var notFoundErr *NotFoundError
+	return errors.As(err, &notFoundErr)
+}
@@ -2,6 +2,7 @@ package apperrors
 
 import (
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -51,6 +52,17 @@ func TestDatabaseError(t *testing.T) {
 	})
 }
 
+func TestDatabaseUnavailableError(t *testing.T) {
+	dbErr := NewDatabaseError("query", "connection refused")
+	err := NewDatabaseUnavailableError(dbErr)
+	assert.Contains(t, err.Error(), "database unavailable: database error during query: connection refused")
+	assert.True(t, IsDatabaseUnavailableError(err))
+	assert.True(t, IsDatabaseError(err))
+	assert.True(t, errors.Is(err, ErrUnavailable))
+	assert.Equal(t, dbErr, errors.Unwrap(err))
+	assert.False(t, IsDatabaseUnavailableError(dbErr))
+}
+
 func TestExternalAPIError(t *testing.T) {
 	t.Run("new external API error", func(t *testing.T) {
 		err := NewExternalAPIError("tzkt", "GET", "rate limited")
@@ -73,6 +85,62 @@ func TestExternalAPIError(t *testing.T) {
 	})
 }
 
+func TestNotFoundError(t *testing.T) {
+	t.Run("new not found error", func(t *testing.T) {
+		err := NewNotFoundError("delegation", "42")
+		assert.Error(t, err)
+		assert.Equal(t, "delegation not found: 42", err.Error())
+		assert.Equal(t, "delegation not found", NewNotFoundError("delegation", "").Error())
+	})
+
+	t.Run("new not found error with cause", func(t *testing.T) {
+		cause := errors.New("no rows")
+		err := NewNotFoundErrorWithCause("delegation", "42", cause)
+		assert.Equal(t, cause, errors.Unwrap(err))
+		assert.ErrorIs(t, err, cause)
+	})
+
+	t.Run("is not found error", func(t *testing.T) {
+		err := fmt.Errorf("wrapped: %w", NewNotFoundError("delegation", "42"))
+		assert.True(t, IsNotFoundError(err))
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.False(t, IsNotFoundError(ErrNotFound))
+		assert.False(t, IsNotFoundError(errors.New("other error")))
+
+		var notFoundErr *NotFoundError
+		assert.True(t, errors.As(err, &notFoundErr))
+		assert.Equal(t, "delegation", notFoundErr.Resource)
+	})
+}
+
+func TestConfigurationError(t *testing.T) {
+	t.Run("new configuration error", func(t *testing.T) {
+		err := NewConfigurationError("SERVER_PORT", "must be a number between 1 and 65535, got \"abc\"")
+		assert.Equal(t, `configuration error: invalid SERVER_PORT: must be a number between 1 and 65535, got "abc"`, err.Error())
+		assert.Equal(t, "configuration error: missing required environment variables: [POSTGRES_DB]",
+			NewConfigurationError("", "missing required environment variables: [POSTGRES_DB]").Error())
+	})
+
+	t.Run("new configuration error with cause", func(t *testing.T) {
+		cause := errors.New("no such file or directory")
+		err := NewConfigurationErrorWithCause("POSTGRES_SSLKEY", cause.Error(), cause)
+		assert.Equal(t, cause, errors.Unwrap(err))
+		assert.ErrorIs(t, err, cause)
+	})
+
+	t.Run("is configuration error", func(t *testing.T) {
+		err := fmt.Errorf("wrapped: %w", NewConfigurationError("TIMEZONE", "unknown time zone"))
+		assert.True(t, IsConfigurationError(err))
+		assert.ErrorIs(t, err, ErrConfiguration)
+		assert.False(t, IsConfigurationError(ErrConfiguration))
+		assert.False(t, IsConfigurationError(errors.New("other error")))
+
+		var configErr *ConfigurationError
+		assert.True(t, errors.As(err, &configErr))
+		assert.Equal(t, "TIMEZONE", configErr.Key)
+	})
+}
+
 func TestErrorConstants(t *testing.T) {
 	assert.NotNil(t, ErrValidation)
 	assert.NotNil(t, ErrNotFound)
@@ -2,24 +2,686 @@ package config
 
 import (
 	"fmt"
+	"math"
+	"mime"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
+	"tezos-delegation/internal/apperrors"
+	"time"
+	_ "time/tzdata" // The final image has no zoneinfo, so TIMEZONE needs the embedded database
 
 	"github.com/joho/godotenv"
 )
 
+// minAdminTokenLength is the shortest ADMIN_TOKEN accepted
+const minAdminTokenLength = 16
+
+// TzktTransportConfig holds the connection pool and timeout settings of the HTTP client the poller uses for Tzkt.
+type TzktTransportConfig struct {
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+	TLSHandshakeTimeout time.Duration
+	RequestTimeout      time.Duration
+}
+
 type Config struct {
 	DBUrl      string
 	ServerPort string
 	Env        string
 	SSLMode    string
+
+	// TLS files passed to Postgres for certificate verification and client certificate auth; empty when unset
+	SSLRootCert string
+	SSLCert     string
+	SSLKey      string
+
+	// DBAutoMigrate applies pending schema migrations at startup
+	DBAutoMigrate bool
+	// DBInsertChunkSize is the number of rows written per INSERT statement
+	DBInsertChunkSize int
+	// DBReadRetries is how many times a read query that lost its connection is retried
+	DBReadRetries int
+	// SlowQueryThreshold is how long a read query may take before it is logged as slow
+	SlowQueryThreshold time.Duration
+
+	// Logging; values are validated by the logger setup, which falls back to info/json
+	LogLevel  string
+	LogFormat string
+	// LogSampling keeps only one in LogSampleRate request-path debug and info logs; warnings and errors are always kept
+	LogSampling   bool
+	LogSampleRate int
+
+	// PollerEnabled runs the Tzkt poller; disable it on read-only replicas
+	PollerEnabled bool
+	// PollerLockRetryInterval is how often an instance that doesn't hold the poll lock retries it
+	PollerLockRetryInterval time.Duration
+
+	PollerVerifyInserts     bool
+	PollerHistoricalWorkers int
+	TzktRateLimit           float64
+	TzktPageSize            int    // Operations requested per Tzkt page, at most Tzkt's maximum of 1000
+	PollerInsertBatchSize   int    // Rows stored per transaction when splitting a fetched page; 0 stores each page at once
+	TzktAPIKey              string // Sent to Tzkt as a bearer token when set; log it with GetMaskedTzktAPIKey
+	TzktBaseURL             string // Root of the Tzkt API, without a trailing slash
+	PollerTrackOriginations bool
+	PollerUpsertMode        string // "ignore" keeps stored rows on a TzktID conflict, "update" overwrites changed fields
+	PollerAnalyzeAfterSync  string // "off", or "analyze" or "vacuum" to refresh planner statistics after the historical sync
+
+	// PollerSkipEmptyDelegator drops operations Tzkt returns without a delegator address instead of storing them
+	PollerSkipEmptyDelegator bool
+
+	// Tzkt request retry budget
+	PollerMaxRetries     int
+	PollerInitialBackoff time.Duration
+	PollerMaxTotalWait   time.Duration
+
+	// Tzkt circuit breaker: consecutive failed fetches that open it, and how long it stays open
+	PollerBreakerThreshold int
+	PollerBreakerCooldown  time.Duration
+
+	// TzktTransport tunes the poller's HTTP connection pool and timeouts
+	TzktTransport TzktTransportConfig
+
+	ResponseCacheSize int
+	ResponseCacheTTL  time.Duration
+
+	// MaxOffset caps the (page-1)*pageSize offset of delegation list queries
+	MaxOffset int
+	// MaxYear is the latest year accepted by year filters; 0 means the current year
+	MaxYear int
+	// Timezone is the time zone year filters and yearly and monthly stats are evaluated in
+	Timezone *time.Location
+
+	// AccessLogSkipPaths lists request paths left out of the HTTP access log
+	AccessLogSkipPaths []string
+	// SecurityHeadersStrict sends the Content-Security-Policy and Strict-Transport-Security headers
+	SecurityHeadersStrict bool
+	// MaxURLLength and MaxHeaderBytes bound the request line URL and the total size of the request headers
+	MaxURLLength   int
+	MaxHeaderBytes int
+	// RequestTimeout is the deadline of each /xtz request, streamed exports excepted
+	RequestTimeout time.Duration
+	// MaxConcurrentRequests caps the /xtz requests processed at once; 0 means unlimited
+	MaxConcurrentRequests int
+	// RateLimit caps the /xtz requests per second from each client IP; 0 means unlimited
+	RateLimit int
+	// GzipLevel and GzipContentTypes control response compression; no content types disables it
+	GzipLevel        int
+	GzipContentTypes []string
+	// ImportEnabled exposes POST /xtz/delegations/import for seeding test and development databases
+	ImportEnabled bool
+	// AdminToken enables the admin endpoints, which require it as a bearer token; never logged
+	AdminToken string
+
+	// Graceful shutdown budgets for draining the poller and in-flight HTTP requests
+	ShutdownPollerTimeout time.Duration
+	ShutdownHTTPTimeout   time.Duration
+	// ShutdownDrainDelay is how long /ready reports 503 before shutdown starts, so load balancers stop routing to us
+	ShutdownDrainDelay time.Duration
+
+	// logLevelFromEnv records whether LOG_LEVEL was set in the process environment rather than by .env
+	logLevelFromEnv bool
 }
 
 // LoadConfig loads configuration from environment variables.
-// Returns an error if required environment variables are missing.
+// The database connection is taken from DATABASE_URL when set, otherwise it is built
+// from the individual POSTGRES_* variables.
+// Returns an apperrors.ConfigurationError if required environment variables are missing or invalid.
 func LoadConfig() (*Config, error) {
+	// .env never overrides the environment, so note where LOG_LEVEL comes from for ReloadLogLevel
+	_, logLevelFromEnv := os.LookupEnv("LOG_LEVEL")
 	_ = godotenv.Load()
 
+	// Determine SSL mode based on environment
+	sslMode := os.Getenv("POSTGRES_SSLMODE")
+	if sslMode == "" {
+		if os.Getenv("APP_ENV") == "production" {
+			sslMode = "require"
+		} else {
+			sslMode = "disable"
+		}
+	}
+
+	// TLS files are checked up front so a wrong path fails at startup rather than on first connect
+	sslFiles, err := loadSSLFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	// Build database connection string, preferring a single URL as injected by PaaS platforms
+	var dsn string
+	if databaseURL := os.Getenv("DATABASE_URL"); databaseURL != "" {
+		dsn, sslMode, err = buildDSNFromURL(databaseURL, sslMode, sslFiles)
+	} else {
+		dsn, err = buildDSNFromVars(sslMode, sslFiles)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{
+		DBUrl:       dsn,
+		ServerPort:  os.Getenv("SERVER_PORT"),
+		Env:         os.Getenv("APP_ENV"),
+		SSLMode:     sslMode,
+		SSLRootCert: sslFiles["sslrootcert"],
+		SSLCert:     sslFiles["sslcert"],
+		SSLKey:      sslFiles["sslkey"],
+		LogLevel:    strings.ToLower(os.Getenv("LOG_LEVEL")),
+		LogFormat:   strings.ToLower(os.Getenv("LOG_FORMAT")),
+
+		logLevelFromEnv: logLevelFromEnv,
+	}
+
+	// Set defaults
+	if cfg.ServerPort == "" {
+		cfg.ServerPort = "3000"
+	}
+	if err := validatePort(cfg.ServerPort); err != nil {
+		return nil, err
+	}
+	if cfg.Env == "" {
+		cfg.Env = "development"
+	}
+	if cfg.LogLevel == "" {
+		cfg.LogLevel = "info"
+	}
+	if cfg.LogFormat == "" {
+		cfg.LogFormat = "json"
+	}
+
+	logSampling, err := getEnvBool("LOG_SAMPLING", false)
+	if err != nil {
+		return nil, err
+	}
+	cfg.LogSampling = logSampling
+
+	logSampleRate, err := getEnvInt("LOG_SAMPLE_RATE", 10, 1, 10000)
+	if err != nil {
+		return nil, err
+	}
+	cfg.LogSampleRate = logSampleRate
+
+	autoMigrate, err := getEnvBool("DB_AUTO_MIGRATE", true)
+	if err != nil {
+		return nil, err
+	}
+	cfg.DBAutoMigrate = autoMigrate
+
+	// At most 10922 rows fit in one statement under the Postgres limit of 65535 bind parameters
+	insertChunkSize, err := getEnvInt("DB_INSERT_CHUNK_SIZE", 500, 1, 10922)
+	if err != nil {
+		return nil, err
+	}
+	cfg.DBInsertChunkSize = insertChunkSize
+
+	readRetries, err := getEnvInt("DB_READ_RETRIES", 2, 0, 10)
+	if err != nil {
+		return nil, err
+	}
+	cfg.DBReadRetries = readRetries
+
+	slowQueryThreshold, err := getEnvDuration("SLOW_QUERY_THRESHOLD", 500*time.Millisecond)
+	if err != nil {
+		return nil, err
+	}
+	cfg.SlowQueryThreshold = slowQueryThreshold
+
+	// Poller options
+	pollerEnabled, err := getEnvBool("POLLER_ENABLED", true)
+	if err != nil {
+		return nil, err
+	}
+	cfg.PollerEnabled = pollerEnabled
+
+	verifyInserts, err := getEnvBool("POLLER_VERIFY_INSERTS", false)
+	if err != nil {
+		return nil, err
+	}
+	cfg.PollerVerifyInserts = verifyInserts
+
+	historicalWorkers, err := getEnvInt("POLLER_HISTORICAL_WORKERS", 4, 1, 16)
+	if err != nil {
+		return nil, err
+	}
+	cfg.PollerHistoricalWorkers = historicalWorkers
+
+	tzktRateLimit, err := getEnvPositiveFloat("TZKT_RATE_LIMIT", 10)
+	if err != nil {
+		return nil, err
+	}
+	cfg.TzktRateLimit = tzktRateLimit
+
+	tzktPageSize, err := getEnvInt("TZKT_PAGE_SIZE", 1000, 1, 1000)
+	if err != nil {
+		return nil, err
+	}
+	cfg.TzktPageSize = tzktPageSize
+
+	insertBatchSize, err := getEnvInt("POLLER_INSERT_BATCH_SIZE", 0, 0, 1000)
+	if err != nil {
+		return nil, err
+	}
+	cfg.PollerInsertBatchSize = insertBatchSize
+	cfg.TzktAPIKey = strings.TrimSpace(os.Getenv("TZKT_API_KEY"))
+
+	tzktBaseURL, err := loadTzktBaseURL()
+	if err != nil {
+		return nil, err
+	}
+	cfg.TzktBaseURL = tzktBaseURL
+
+	trackOriginations, err := getEnvBool("POLLER_TRACK_ORIGINATIONS", false)
+	if err != nil {
+		return nil, err
+	}
+	cfg.PollerTrackOriginations = trackOriginations
+
+	skipEmptyDelegator, err := getEnvBool("POLLER_SKIP_EMPTY_DELEGATOR", true)
+	if err != nil {
+		return nil, err
+	}
+	cfg.PollerSkipEmptyDelegator = skipEmptyDelegator
+
+	cfg.PollerUpsertMode = strings.ToLower(strings.TrimSpace(os.Getenv("POLLER_UPSERT_MODE")))
+	switch cfg.PollerUpsertMode {
+	case "":
+		cfg.PollerUpsertMode = "ignore"
+	case "ignore", "update":
+	default:
+		return nil, apperrors.NewConfigurationError("POLLER_UPSERT_MODE", fmt.Sprintf("must be ignore or update, got %q", cfg.PollerUpsertMode))
+	}
+
+	cfg.PollerAnalyzeAfterSync = strings.ToLower(strings.TrimSpace(os.Getenv("POLLER_ANALYZE_AFTER_SYNC")))
+	switch cfg.PollerAnalyzeAfterSync {
+	case "":
+		cfg.PollerAnalyzeAfterSync = "off"
+	case "off", "analyze", "vacuum":
+	default:
+		return nil, apperrors.NewConfigurationError("POLLER_ANALYZE_AFTER_SYNC", fmt.Sprintf("must be off, analyze or vacuum, got %q", cfg.PollerAnalyzeAfterSync))
+	}
+
+	// Retry budget; the backoff doubles on each retry, so keep both bounds modest
+	maxRetries, err := getEnvInt("POLLER_MAX_RETRIES", 5, 1, 20)
+	if err != nil {
+		return nil, err
+	}
+	cfg.PollerMaxRetries = maxRetries
+
+	initialBackoff, err := getEnvDuration("POLLER_INITIAL_BACKOFF", time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if initialBackoff > time.Minute {
+		return nil, apperrors.NewConfigurationError("POLLER_INITIAL_BACKOFF", fmt.Sprintf("must be at most 1m, got %s", initialBackoff))
+	}
+	cfg.PollerInitialBackoff = initialBackoff
+
+	maxTotalWait, err := getEnvDuration("POLLER_MAX_TOTAL_WAIT", 2*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	if maxTotalWait < initialBackoff || maxTotalWait > time.Hour {
+		return nil, apperrors.NewConfigurationError("POLLER_MAX_TOTAL_WAIT", fmt.Sprintf("must be between POLLER_INITIAL_BACKOFF (%s) and 1h, got %s", initialBackoff, maxTotalWait))
+	}
+	cfg.PollerMaxTotalWait = maxTotalWait
+
+	breakerThreshold, err := getEnvInt("POLLER_BREAKER_THRESHOLD", 5, 1, 100)
+	if err != nil {
+		return nil, err
+	}
+	cfg.PollerBreakerThreshold = breakerThreshold
+
+	breakerCooldown, err := getEnvDuration("POLLER_BREAKER_COOLDOWN", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	if breakerCooldown > time.Hour {
+		return nil, apperrors.NewConfigurationError("POLLER_BREAKER_COOLDOWN", fmt.Sprintf("must be at most 1h, got %s", breakerCooldown))
+	}
+	cfg.PollerBreakerCooldown = breakerCooldown
+
+	lockRetryInterval, err := getEnvDuration("POLLER_LOCK_RETRY_INTERVAL", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if lockRetryInterval > 10*time.Minute {
+		return nil, apperrors.NewConfigurationError("POLLER_LOCK_RETRY_INTERVAL", fmt.Sprintf("must be at most 10m, got %s", lockRetryInterval))
+	}
+	cfg.PollerLockRetryInterval = lockRetryInterval
+
+	tzktTransport, err := loadTzktTransport()
+	if err != nil {
+		return nil, err
+	}
+	cfg.TzktTransport = tzktTransport
+
+	// Response cache options
+	responseCacheSize, err := getEnvInt("RESPONSE_CACHE_SIZE", 1000, 0, 100000)
+	if err != nil {
+		return nil, err
+	}
+	cfg.ResponseCacheSize = responseCacheSize
+
+	responseCacheTTL, err := getEnvDuration("RESPONSE_CACHE_TTL", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	cfg.ResponseCacheTTL = responseCacheTTL
+
+	maxOffset, err := getEnvInt("MAX_OFFSET", 100000, 1000, 100000000)
+	if err != nil {
+		return nil, err
+	}
+	cfg.MaxOffset = maxOffset
+
+	// Unset stays 0, so the current year is looked up when a year is validated rather than once at startup
+	maxYear, err := getEnvInt("MAX_YEAR", 0, 2018, 9999)
+	if err != nil {
+		return nil, err
+	}
+	cfg.MaxYear = maxYear
+
+	timezone, err := loadTimezone()
+	if err != nil {
+		return nil, err
+	}
+	cfg.Timezone = timezone
+
+	// Access log options; set explicitly empty to log every path
+	cfg.AccessLogSkipPaths = []string{"/health", "/metrics"}
+	if skipPaths, ok := os.LookupEnv("ACCESS_LOG_SKIP_PATHS"); ok {
+		cfg.AccessLogSkipPaths = splitList(skipPaths)
+	}
+
+	// Disable for local development over plain HTTP
+	strictHeaders, err := getEnvBool("SECURITY_HEADERS_STRICT", true)
+	if err != nil {
+		return nil, err
+	}
+	cfg.SecurityHeadersStrict = strictHeaders
+
+	maxURLLength, err := getEnvInt("MAX_URL_LENGTH", 2048, 256, 65536)
+	if err != nil {
+		return nil, err
+	}
+	cfg.MaxURLLength = maxURLLength
+
+	maxHeaderBytes, err := getEnvInt("MAX_HEADER_BYTES", 8192, 1024, 1<<20)
+	if err != nil {
+		return nil, err
+	}
+	cfg.MaxHeaderBytes = maxHeaderBytes
+
+	requestTimeout, err := getEnvDuration("REQUEST_TIMEOUT", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if requestTimeout > 10*time.Minute {
+		return nil, apperrors.NewConfigurationError("REQUEST_TIMEOUT", fmt.Sprintf("must be at most 10m, got %s", requestTimeout))
+	}
+	cfg.RequestTimeout = requestTimeout
+
+	maxConcurrentRequests, err := getEnvInt("MAX_CONCURRENT_REQUESTS", 0, 0, 100000)
+	if err != nil {
+		return nil, err
+	}
+	cfg.MaxConcurrentRequests = maxConcurrentRequests
+
+	rateLimit, err := getEnvInt("RATE_LIMIT", 0, 0, 100000)
+	if err != nil {
+		return nil, err
+	}
+	cfg.RateLimit = rateLimit
+
+	gzipLevel, err := getEnvInt("GZIP_LEVEL", 6, 1, 9)
+	if err != nil {
+		return nil, err
+	}
+	cfg.GzipLevel = gzipLevel
+
+	// Set explicitly empty to disable compression
+	cfg.GzipContentTypes = []string{"application/json", "text/csv"}
+	if contentTypes, ok := os.LookupEnv("GZIP_CONTENT_TYPES"); ok {
+		cfg.GzipContentTypes = splitList(contentTypes)
+	}
+	for i, contentType := range cfg.GzipContentTypes {
+		mediaType, params, err := mime.ParseMediaType(contentType)
+		if err != nil || len(params) > 0 || !strings.Contains(mediaType, "/") {
+			return nil, apperrors.NewConfigurationError("GZIP_CONTENT_TYPES", fmt.Sprintf("must be a comma-separated list of media types without parameters, got %q", contentType))
+		}
+		cfg.GzipContentTypes[i] = mediaType
+	}
+
+	// Never enable in production: the import endpoint is unauthenticated
+	importEnabled, err := getEnvBool("IMPORT_ENABLED", false)
+	if err != nil {
+		return nil, err
+	}
+	cfg.ImportEnabled = importEnabled
+
+	// Short tokens are too easy to guess for endpoints that delete data
+	cfg.AdminToken = strings.TrimSpace(os.Getenv("ADMIN_TOKEN"))
+	if cfg.AdminToken != "" && len(cfg.AdminToken) < minAdminTokenLength {
+		return nil, apperrors.NewConfigurationError("ADMIN_TOKEN", fmt.Sprintf("must be at least %d characters", minAdminTokenLength))
+	}
+
+	// Shutdown options
+	shutdownPollerTimeout, err := getEnvDuration("SHUTDOWN_POLLER_TIMEOUT", 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	cfg.ShutdownPollerTimeout = shutdownPollerTimeout
+
+	shutdownHTTPTimeout, err := getEnvDuration("SHUTDOWN_HTTP_TIMEOUT", 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	cfg.ShutdownHTTPTimeout = shutdownHTTPTimeout
+
+	// Unset means no drain; match it to the load balancer's health check interval times its failure threshold
+	shutdownDrainDelay, err := getEnvDuration("SHUTDOWN_DRAIN_DELAY", 0)
+	if err != nil {
+		return nil, err
+	}
+	if shutdownDrainDelay > 5*time.Minute {
+		return nil, apperrors.NewConfigurationError("SHUTDOWN_DRAIN_DELAY", fmt.Sprintf("must be at most 5m, got %s", shutdownDrainDelay))
+	}
+	cfg.ShutdownDrainDelay = shutdownDrainDelay
+
+	return cfg, nil
+}
+
+// validatePort checks that port is a TCP port number in 1-65535.
+// Returns an apperrors.ConfigurationError otherwise.
+func validatePort(port string) error {
+	n, err := strconv.Atoi(port)
+	if err != nil || n < 1 || n > 65535 {
+		return apperrors.NewConfigurationError("SERVER_PORT", fmt.Sprintf("must be a number between 1 and 65535, got %q", port))
+	}
+	return nil
+}
+
+// ReloadLogLevel reads LOG_LEVEL again for a running service, with the same precedence as LoadConfig:
+// a value set in the process environment wins, and as that can't be changed from outside it is kept;
+// otherwise the .env file is read again. Defaults to info.
+func (c *Config) ReloadLogLevel() string {
+	if c.logLevelFromEnv {
+		return c.LogLevel
+	}
+	var level string
+	if env, err := godotenv.Read(); err == nil {
+		level = env["LOG_LEVEL"]
+	}
+	if level = strings.ToLower(level); level == "" {
+		return "info"
+	}
+	return level
+}
+
+// splitList splits a comma-separated list, trimming whitespace and dropping empty entries
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// getEnvBool reads a boolean environment variable, returning def if it is unset.
+// Returns an error if the value can't be parsed as a boolean.
+func getEnvBool(key string, def bool) (bool, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return def, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, apperrors.NewConfigurationError(key, fmt.Sprintf("must be a boolean, got %q", value))
+	}
+	return b, nil
+}
+
+// loadTimezone reads TIMEZONE, an IANA time zone name such as Europe/Berlin, defaulting to UTC.
+// "Local" is rejected since it depends on the host and has no name Postgres understands.
+func loadTimezone() (*time.Location, error) {
+	name := strings.TrimSpace(os.Getenv("TIMEZONE"))
+	if name == "" {
+		return time.UTC, nil
+	}
+	if name == "Local" {
+		return nil, apperrors.NewConfigurationError("TIMEZONE", fmt.Sprintf("must be an IANA time zone name such as UTC or Europe/Berlin, got %q", name))
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, apperrors.NewConfigurationError("TIMEZONE", fmt.Sprintf("must be an IANA time zone name such as UTC or Europe/Berlin, got %q", name))
+	}
+	return loc, nil
+}
+
+// getEnvInt reads an integer environment variable in [min, max], returning def if it is unset.
+// Returns an error if the value can't be parsed or is out of range.
+func getEnvInt(key string, def, min, max int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min || n > max {
+		return 0, apperrors.NewConfigurationError(key, fmt.Sprintf("must be an integer between %d and %d, got %q", min, max, value))
+	}
+	return n, nil
+}
+
+// getEnvPositiveFloat reads a positive float environment variable, returning def if it is unset.
+// Returns an error if the value can't be parsed or isn't positive.
+func getEnvPositiveFloat(key string, def float64) (float64, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return def, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f <= 0 || math.IsInf(f, 0) {
+		return 0, apperrors.NewConfigurationError(key, fmt.Sprintf("must be a positive number, got %q", value))
+	}
+	return f, nil
+}
+
+// getEnvDuration reads a positive duration environment variable such as "30s", returning def if it is unset.
+// Returns an error if the value can't be parsed or isn't positive.
+func getEnvDuration(key string, def time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, apperrors.NewConfigurationError(key, fmt.Sprintf("must be a positive duration such as 30s, got %q", value))
+	}
+	return d, nil
+}
+
+// loadTzktBaseURL reads TZKT_BASE_URL, the root of the Tzkt API the operation endpoints are appended to,
+// e.g. a self-hosted Tzkt. The trailing slash is dropped.
+func loadTzktBaseURL() (string, error) {
+	value := strings.TrimSpace(os.Getenv("TZKT_BASE_URL"))
+	if value == "" {
+		return "https://api.tzkt.io/v1", nil
+	}
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.RawQuery != "" {
+		return "", apperrors.NewConfigurationError("TZKT_BASE_URL", fmt.Sprintf("must be an http or https URL without a query, got %q", value))
+	}
+	return strings.TrimRight(value, "/"), nil
+}
+
+// loadTzktTransport reads the Tzkt HTTP client settings, defaulting to values suited to a single Tzkt host
+func loadTzktTransport() (TzktTransportConfig, error) {
+	var t TzktTransportConfig
+	var err error
+	if t.MaxIdleConns, err = getEnvInt("TZKT_MAX_IDLE_CONNS", 100, 1, 10000); err != nil {
+		return t, err
+	}
+	if t.MaxIdleConnsPerHost, err = getEnvInt("TZKT_MAX_IDLE_CONNS_PER_HOST", 10, 1, 1000); err != nil {
+		return t, err
+	}
+	if t.IdleConnTimeout, err = getEnvDuration("TZKT_IDLE_CONN_TIMEOUT", 90*time.Second); err != nil {
+		return t, err
+	}
+	if t.TLSHandshakeTimeout, err = getEnvDuration("TZKT_TLS_HANDSHAKE_TIMEOUT", 10*time.Second); err != nil {
+		return t, err
+	}
+	if t.RequestTimeout, err = getEnvDuration("TZKT_REQUEST_TIMEOUT", 30*time.Second); err != nil {
+		return t, err
+	}
+	return t, nil
+}
+
+// sslFileVars maps each Postgres TLS file parameter to the environment variable that sets it, in DSN order
+var sslFileVars = []struct{ param, env string }{
+	{"sslrootcert", "POSTGRES_SSLROOTCERT"},
+	{"sslcert", "POSTGRES_SSLCERT"},
+	{"sslkey", "POSTGRES_SSLKEY"},
+}
+
+// loadSSLFiles returns the TLS file paths set in the environment, keyed by Postgres parameter.
+// Returns an apperrors.ConfigurationError if a path is set but isn't an existing file.
+func loadSSLFiles() (map[string]string, error) {
+	files := make(map[string]string)
+	for _, v := range sslFileVars {
+		path := strings.TrimSpace(os.Getenv(v.env))
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, apperrors.NewConfigurationErrorWithCause(v.env, err.Error(), err)
+		}
+		if info.IsDir() {
+			return nil, apperrors.NewConfigurationError(v.env, path+" is a directory")
+		}
+		files[v.param] = path
+	}
+	return files, nil
+}
+
+// quoteDSNValue quotes a key/value DSN value if it is empty or contains spaces, quotes or backslashes
+func quoteDSNValue(value string) string {
+	if value != "" && !strings.ContainsAny(value, ` '\`) {
+		return value
+	}
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
+
+// buildDSNFromVars builds a key/value DSN from the individual POSTGRES_* environment variables,
+// adding any TLS files from sslFiles.
+// Returns an error listing any missing required variables.
+func buildDSNFromVars(sslMode string, sslFiles map[string]string) (string, error) {
 	// Validate required environment variables
 	requiredVars := map[string]string{
 		"POSTGRES_HOST":     os.Getenv("POSTGRES_HOST"),
@@ -38,17 +700,7 @@ func LoadConfig() (*Config, error) {
 	}
 
 	if len(missingVars) > 0 {
-		return nil, fmt.Errorf("missing required environment variables: %v", missingVars)
-	}
-
-	// Determine SSL mode based on environment
-	sslMode := os.Getenv("POSTGRES_SSLMODE")
-	if sslMode == "" {
-		if os.Getenv("APP_ENV") == "production" {
-			sslMode = "require"
-		} else {
-			sslMode = "disable"
-		}
+		return "", apperrors.NewConfigurationError("", fmt.Sprintf("missing required environment variables: %v", missingVars))
 	}
 
 	// Build database connection string with SSL configuration
@@ -60,39 +712,182 @@ func LoadConfig() (*Config, error) {
 		requiredVars["POSTGRES_DB"],
 		sslMode,
 	)
+	for _, v := range sslFileVars {
+		if path, ok := sslFiles[v.param]; ok {
+			dsn += " " + v.param + "=" + quoteDSNValue(path)
+		}
+	}
+	return dsn, nil
+}
 
-	cfg := &Config{
-		DBUrl:      dsn,
-		ServerPort: os.Getenv("SERVER_PORT"),
-		Env:        os.Getenv("APP_ENV"),
-		SSLMode:    sslMode,
+// buildDSNFromURL validates a postgres:// connection URL and returns it along with the effective SSL mode.
+// An sslmode query parameter in the URL takes precedence; otherwise defaultSSLMode is added to the URL.
+// TLS files from sslFiles are added the same way, unless the URL already sets them.
+func buildDSNFromURL(databaseURL, defaultSSLMode string, sslFiles map[string]string) (string, string, error) {
+	u, err := url.Parse(databaseURL)
+	if err != nil {
+		// Don't include the raw URL in the error, it contains the password
+		return "", "", apperrors.NewConfigurationError("DATABASE_URL", "failed to parse connection URL")
+	}
+	if u.Scheme != "postgres" && u.Scheme != "postgresql" {
+		return "", "", apperrors.NewConfigurationError("DATABASE_URL", fmt.Sprintf("scheme must be postgres or postgresql, got %q", u.Scheme))
+	}
+	if u.Host == "" {
+		return "", "", apperrors.NewConfigurationError("DATABASE_URL", "missing host")
+	}
+	if strings.TrimPrefix(u.Path, "/") == "" {
+		return "", "", apperrors.NewConfigurationError("DATABASE_URL", "missing database name")
 	}
 
-	// Set defaults
-	if cfg.ServerPort == "" {
-		cfg.ServerPort = "3000"
+	query := u.Query()
+	modified := false
+	sslMode := query.Get("sslmode")
+	if sslMode == "" {
+		sslMode = defaultSSLMode
+		query.Set("sslmode", sslMode)
+		modified = true
 	}
-	if cfg.Env == "" {
-		cfg.Env = "development"
+	for _, v := range sslFileVars {
+		if path, ok := sslFiles[v.param]; ok && query.Get(v.param) == "" {
+			query.Set(v.param, path)
+			modified = true
+		}
+	}
+	if modified {
+		u.RawQuery = query.Encode()
 	}
 
-	return cfg, nil
+	return u.String(), sslMode, nil
+}
+
+// GetMaskedTzktAPIKey returns the Tzkt API key masked for logging. No part of the key is kept, only its
+// length, which is enough to tell a truncated key apart
+func (c *Config) GetMaskedTzktAPIKey() string {
+	if c.TzktAPIKey == "" {
+		return ""
+	}
+	return fmt.Sprintf("*** (%d characters)", len(c.TzktAPIKey))
+}
+
+// dsnSecretKeys are the connection parameters masked by GetMaskedDBUrl
+var dsnSecretKeys = []string{"password", "sslpassword"}
+
+// maskDSNParam returns a key=value connection parameter with the value masked if the key is a secret
+func maskDSNParam(param string) string {
+	key, _, ok := strings.Cut(param, "=")
+	if !ok {
+		return param
+	}
+	for _, secret := range dsnSecretKeys {
+		if strings.EqualFold(key, secret) {
+			return key + "=***"
+		}
+	}
+	return param
 }
 
-// GetMaskedDBUrl returns the database URL with password masked for logging
+// GetMaskedDBUrl returns the database URL with password masked for logging.
+// Passwords in the URL userinfo and password or sslpassword parameters are masked.
 func (c *Config) GetMaskedDBUrl() string {
 	if c.DBUrl == "" {
 		return ""
 	}
 
-	// Simple masking - replace password with asterisks
+	// URL form: replace the userinfo password and secret query parameters with asterisks
+	if strings.Contains(c.DBUrl, "://") {
+		u, err := url.Parse(c.DBUrl)
+		if err != nil {
+			return "***"
+		}
+		// Mask the raw query by hand, url.Values.Encode would reorder it and percent-encode the asterisks
+		params := strings.Split(u.RawQuery, "&")
+		for i, param := range params {
+			params[i] = maskDSNParam(param)
+		}
+		u.RawQuery = strings.Join(params, "&")
+		if _, hasPassword := u.User.Password(); !hasPassword {
+			return u.String()
+		}
+		// Rebuild the userinfo by hand, url.UserPassword would percent-encode the asterisks
+		username := url.User(u.User.Username()).String()
+		u.User = nil
+		return strings.Replace(u.String(), "://", "://"+username+":***@", 1)
+	}
+
+	// Key/value form: simple masking - replace secret values with asterisks
 	parts := strings.Split(c.DBUrl, " ")
 	for i, part := range parts {
-		if strings.HasPrefix(part, "password=") {
-			parts[i] = "password=***"
-			break
-		}
+		parts[i] = maskDSNParam(part)
 	}
 
 	return strings.Join(parts, " ")
 }
+
+// LogFields returns the effective configuration as a flat map for a single structured log event,
+// with the database password and Tzkt API key masked
+func (c *Config) LogFields() map[string]any {
+	fields := map[string]any{
+		"db_url":                    c.GetMaskedDBUrl(),
+		"db_auto_migrate":           c.DBAutoMigrate,
+		"db_insert_chunk_size":      c.DBInsertChunkSize,
+		"db_read_retries":           c.DBReadRetries,
+		"slow_query_threshold":      c.SlowQueryThreshold.String(),
+		"server_port":               c.ServerPort,
+		"env":                       c.Env,
+		"ssl_mode":                  c.SSLMode,
+		"ssl_root_cert":             c.SSLRootCert,
+		"ssl_cert":                  c.SSLCert,
+		"ssl_key":                   c.SSLKey,
+		"log_level":                 c.LogLevel,
+		"log_format":                c.LogFormat,
+		"log_sampling":              c.LogSampling,
+		"log_sample_rate":           c.LogSampleRate,
+		"poller_enabled":            c.PollerEnabled,
+		"poller_verify_inserts":     c.PollerVerifyInserts,
+		"poller_historical_workers": c.PollerHistoricalWorkers,
+		"poller_track_originations": c.PollerTrackOriginations,
+		"poller_upsert_mode":        c.PollerUpsertMode,
+		"poller_analyze_after_sync": c.PollerAnalyzeAfterSync,
+		"poller_skip_no_delegator":  c.PollerSkipEmptyDelegator,
+		"poller_max_retries":        c.PollerMaxRetries,
+		"poller_initial_backoff":    c.PollerInitialBackoff.String(),
+		"poller_max_total_wait":     c.PollerMaxTotalWait.String(),
+		"poller_breaker_threshold":  c.PollerBreakerThreshold,
+		"poller_breaker_cooldown":   c.PollerBreakerCooldown.String(),
+		"poller_lock_retry":         c.PollerLockRetryInterval.String(),
+		"tzkt_rate_limit":           c.TzktRateLimit,
+		"tzkt_base_url":             c.TzktBaseURL,
+		"tzkt_page_size":            c.TzktPageSize,
+		"poller_insert_batch_size":  c.PollerInsertBatchSize,
+		"response_cache_size":       c.ResponseCacheSize,
+		"response_cache_ttl":        c.ResponseCacheTTL.String(),
+		"max_offset":                c.MaxOffset,
+		"max_year":                  c.MaxYear,
+		"timezone":                  c.Timezone.String(),
+		"access_log_skip_paths":     c.AccessLogSkipPaths,
+		"security_headers_strict":   c.SecurityHeadersStrict,
+		"max_url_length":            c.MaxURLLength,
+		"max_header_bytes":          c.MaxHeaderBytes,
+		"request_timeout":           c.RequestTimeout.String(),
+		"max_concurrent_requests":   c.MaxConcurrentRequests,
+		"rate_limit":                c.RateLimit,
+		"gzip_level":                c.GzipLevel,
+		"gzip_content_types":        c.GzipContentTypes,
+		"import_enabled":            c.ImportEnabled,
+		"admin_enabled":             c.AdminToken != "",
+		"shutdown_poller_timeout":   c.ShutdownPollerTimeout.String(),
+		"shutdown_http_timeout":     c.ShutdownHTTPTimeout.String(),
+		"shutdown_drain_delay":      c.ShutdownDrainDelay.String(),
+
+		// Tzkt HTTP client tuning
+		"tzkt_max_idle_conns":          c.TzktTransport.MaxIdleConns,
+		"tzkt_max_idle_conns_per_host": c.TzktTransport.MaxIdleConnsPerHost,
+		"tzkt_idle_conn_timeout":       c.TzktTransport.IdleConnTimeout.String(),
+		"tzkt_tls_handshake_timeout":   c.TzktTransport.TLSHandshakeTimeout.String(),
+		"tzkt_request_timeout":         c.TzktTransport.RequestTimeout.String(),
+	}
+	if c.TzktAPIKey != "" {
+		fields["tzkt_api_key"] = c.GetMaskedTzktAPIKey()
+	}
+	return fields
+}
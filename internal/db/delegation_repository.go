@@ -3,103 +3,610 @@ package db
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
 	"tezos-delegation/internal/apperrors"
+	"tezos-delegation/internal/metrics"
 	"tezos-delegation/internal/model"
 	"tezos-delegation/internal/ports"
+	"tezos-delegation/internal/requestid"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/rs/zerolog"
+)
+
+// Conflict handling modes for InsertDelegations when a TzktID is already stored
+const (
+	// UpsertIgnore keeps the stored row unchanged
+	UpsertIgnore = "ignore"
+	// UpsertUpdate overwrites the stored timestamp, amount, delegator and level when Tzkt reports different values
+	UpsertUpdate = "update"
 )
 
+// DelegationRepositoryOptions tunes the repository. Zero values use the defaults.
+type DelegationRepositoryOptions struct {
+	UpsertMode      string // UpsertIgnore or UpsertUpdate; anything else means UpsertIgnore
+	InsertChunkSize int    // Rows per INSERT statement in InsertDelegations; 0 or above MaxInsertChunkSize uses MaxInsertChunkSize
+	// ReadRetries is how many times a read query that failed with a connection error is retried; 0 disables retries
+	ReadRetries int
+	// ReadRetryBackoff is the delay before the first read retry, doubled after each; 0 uses defaultReadRetryBackoff
+	ReadRetryBackoff time.Duration
+	// Location is the time zone year and month boundaries are evaluated in; nil means UTC
+	Location *time.Location
+	// SlowQueryThreshold is how long a read query may take before it is logged and counted as slow; 0 disables it
+	SlowQueryThreshold time.Duration
+	// Logger receives the slow query warnings; the zero value discards them
+	Logger zerolog.Logger
+}
+
+// defaultReadRetryBackoff is the delay before the first read retry when ReadRetryBackoff is unset
+const defaultReadRetryBackoff = 100 * time.Millisecond
+
+// pollLockKey identifies the advisory lock held by the instance running the poller
+const pollLockKey int64 = 0x74657a6f73 // "tezos"
+
 // DelegationRepository implements DelegationRepositoryPort
 type DelegationRepository struct {
-	db *sql.DB
+	db   ports.DatabasePort
+	opts DelegationRepositoryOptions
+
+	// pollLockConn is the session holding the poll lock, nil when not held. Advisory locks belong to
+	// a session, so the connection is kept out of the pool until the lock is released.
+	pollLockMu   sync.Mutex
+	pollLockConn *sql.Conn
+}
+
+// Ensure DelegationRepository implements DelegationRepositoryPort, and *sql.DB the DatabasePort it runs on
+var (
+	_ ports.DelegationRepositoryPort = (*DelegationRepository)(nil)
+	_ ports.DatabasePort             = (*sql.DB)(nil)
+)
+
+func NewDelegationRepository(db ports.DatabasePort, opts DelegationRepositoryOptions) *DelegationRepository {
+	return &DelegationRepository{db: db, opts: opts}
+}
+
+// location returns the time zone year and month boundaries are evaluated in
+func (r *DelegationRepository) location() *time.Location {
+	if r.opts.Location != nil {
+		return r.opts.Location
+	}
+	return time.UTC
+}
+
+// wrapDBError wraps a driver error as an apperrors.DatabaseError, additionally marking it
+// as apperrors.DatabaseUnavailableError when the connection rather than the query failed
+func wrapDBError(operation, message string, err error) error {
+	dbErr := apperrors.NewDatabaseErrorWithCause(operation, message, err)
+	if isConnectionError(err) {
+		return apperrors.NewDatabaseUnavailableError(dbErr)
+	}
+	return dbErr
+}
+
+// isConnectionError reports whether err means the database could not be reached or dropped the connection
+func isConnectionError(err error) bool {
+	if errors.Is(err, sql.ErrConnDone) || errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	// SQLSTATE class 08 is connection exception; 57P01-57P03 are server shutdown and startup
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "57P01", "57P02", "57P03":
+			return true
+		}
+		return pqErr.Code.Class() == "08"
+	}
+	return false
+}
+
+// withReadRetry runs a read-only query, retrying it up to ReadRetries times with a short doubling backoff
+// while it fails with a connection error, e.g. a connection reset by a network blip. Any other error, or
+// cancellation of ctx, ends the retries. Writes must not go through it: a write whose connection failed
+// may still have been applied.
+func (r *DelegationRepository) withReadRetry(ctx context.Context, query func() error) error {
+	backoff := r.opts.ReadRetryBackoff
+	if backoff <= 0 {
+		backoff = defaultReadRetryBackoff
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := query()
+		if err == nil || attempt >= r.opts.ReadRetries || !isConnectionError(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// queryRowWithRetry runs a single-row read named name and scans it into dest, retrying connection errors
+func (r *DelegationRepository) queryRowWithRetry(ctx context.Context, name, query string, args []interface{}, dest ...interface{}) error {
+	return r.withReadRetry(ctx, func() error {
+		defer r.observeQuery(ctx, name, args, time.Now())
+		return r.db.QueryRowContext(ctx, query, args...).Scan(dest...)
+	})
 }
 
-// Ensure DelegationRepository implements DelegationRepositoryPort
-var _ ports.DelegationRepositoryPort = (*DelegationRepository)(nil)
+// queryWithRetry starts a multi-row read named name, retrying connection errors. Errors while iterating
+// the returned rows aren't retried, since part of the result may already have been consumed.
+func (r *DelegationRepository) queryWithRetry(ctx context.Context, name, query string, args ...interface{}) (*sql.Rows, error) {
+	var rows *sql.Rows
+	err := r.withReadRetry(ctx, func() (err error) {
+		defer r.observeQuery(ctx, name, args, time.Now())
+		rows, err = r.db.QueryContext(ctx, query, args...)
+		return err
+	})
+	return rows, err
+}
 
-func NewDelegationRepository(db *sql.DB) *DelegationRepository {
-	return &DelegationRepository{db: db}
+// maxLoggedArgLen bounds each bound parameter written to a slow query warning
+const maxLoggedArgLen = 64
+
+// observeQuery logs a warning and counts the query in slow_queries_total if the attempt started at start
+// took longer than SlowQueryThreshold. For multi-row reads this is the time until the first rows were ready.
+func (r *DelegationRepository) observeQuery(ctx context.Context, name string, args []interface{}, start time.Time) {
+	elapsed := time.Since(start)
+	if r.opts.SlowQueryThreshold <= 0 || elapsed <= r.opts.SlowQueryThreshold {
+		return
+	}
+	metrics.DBSlowQueries.WithLabelValues(name).Inc()
+	requestid.Logger(ctx, &r.opts.Logger).Warn().
+		Str("query", name).
+		Dur("duration", elapsed).
+		Dur("threshold", r.opts.SlowQueryThreshold).
+		Strs("args", sanitizeArgs(args)).
+		Msg("Slow query")
+}
+
+// sanitizeArgs renders bound parameters for logging: arrays are reduced to their length and long values
+// are truncated, so a large IN list or text value can't flood the logs
+func sanitizeArgs(args []interface{}) []string {
+	out := make([]string, len(args))
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case *pq.Int64Array:
+			out[i] = fmt.Sprintf("[%d values]", len(*v))
+		case time.Time:
+			out[i] = v.UTC().Format(time.RFC3339)
+		default:
+			s := fmt.Sprint(v)
+			if len(s) > maxLoggedArgLen {
+				s = s[:maxLoggedArgLen] + "..."
+			}
+			out[i] = s
+		}
+	}
+	return out
+}
+
+const (
+	// insertColumnsPerRow is the number of bind parameters each delegation uses in a multi-row insert
+	insertColumnsPerRow = 6
+	// MaxInsertChunkSize keeps a single multi-row insert under Postgres's limit of 65535 bind parameters
+	MaxInsertChunkSize = 65535 / insertColumnsPerRow
+)
+
+// insertChunkSize returns the number of rows written per INSERT statement
+func (r *DelegationRepository) insertChunkSize() int {
+	if r.opts.InsertChunkSize > 0 && r.opts.InsertChunkSize < MaxInsertChunkSize {
+		return r.opts.InsertChunkSize
+	}
+	return MaxInsertChunkSize
 }
 
 // InsertDelegations inserts multiple delegations into the database in a transaction.
-// Returns an error if the transaction fails or if any delegation insertion fails.
-func (r *DelegationRepository) InsertDelegations(delegations []*model.Delegation) (err error) {
+// Rows are written with multi-row INSERT statements of InsertChunkSize rows, which also keeps
+// each statement under the Postgres bind parameter limit, instead of one round-trip per delegation.
+// All chunks share the transaction, so a batch is stored entirely or not at all; cancelling ctx aborts it.
+// Rows whose TzktID already exists are skipped, or with UpsertUpdate overwritten if any field changed.
+// Returns the number of rows actually written, which is lower than len(delegations)
+// when some were skipped by ON CONFLICT; rows that were updated count as written.
+// Returns an error if the transaction fails or if any delegation insertion fails. Failures are never retried here.
+func (r *DelegationRepository) InsertDelegations(ctx context.Context, delegations []*model.Delegation) (inserted int64, err error) {
 	if len(delegations) == 0 {
-		return nil
+		return 0, nil
+	}
+
+	// Validate before touching the database
+	for i, d := range delegations {
+		if d == nil {
+			return 0, apperrors.NewValidationError("delegation", fmt.Sprintf("delegation at index %d is nil", i))
+		}
 	}
 
 	// Start transaction
-	tx, err := r.db.Begin()
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		return apperrors.NewDatabaseErrorWithCause("begin transaction", "failed to begin transaction", err)
+		return 0, wrapDBError("begin transaction", "failed to begin transaction", err)
 	}
 
 	// Ensure transaction is rolled back on error or panic
 	defer func() {
 		if p := recover(); p != nil {
+			inserted = 0
 			if rbErr := tx.Rollback(); rbErr != nil {
 				err = fmt.Errorf("panic occurred and rollback failed: %v, rollback error: %w", p, rbErr)
 			} else {
 				err = fmt.Errorf("panic occurred: %v", p)
 			}
 		} else if err != nil {
-			if rbErr := tx.Rollback(); rbErr != nil {
+			inserted = 0
+			// ErrTxDone means database/sql already rolled back because ctx was cancelled
+			if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
 				err = fmt.Errorf("rollback failed after error: %w, rollback error: %w", err, rbErr)
 			}
 		}
 	}()
 
-	// Prepare statement
-	const query = `INSERT INTO delegations (tzkt_id, timestamp, amount, delegator, level) VALUES ($1, $2, $3, $4, $5) ON CONFLICT (tzkt_id) DO NOTHING`
-	stmt, err := tx.Prepare(query)
-	if err != nil {
-		return apperrors.NewDatabaseErrorWithCause("prepare statement", "failed to prepare insert statement", err)
+	// Insert in chunks, one statement per chunk
+	chunkSize := r.insertChunkSize()
+	for start := 0; start < len(delegations); start += chunkSize {
+		end := min(start+chunkSize, len(delegations))
+		query, args := buildInsertQuery(delegations[start:end], r.opts.UpsertMode == UpsertUpdate)
+
+		var res sql.Result
+		res, err = tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return 0, wrapDBError("insert delegations", fmt.Sprintf("failed to insert delegations at index %d-%d (TzktID: %d-%d)", start, end-1, delegations[start].TzktID, delegations[end-1].TzktID), err)
+		}
+
+		// Count rows actually written (rows skipped by ON CONFLICT aren't counted)
+		var affected int64
+		affected, err = res.RowsAffected()
+		if err != nil {
+			return 0, wrapDBError("insert delegations", fmt.Sprintf("failed to get rows affected at index %d-%d", start, end-1), err)
+		}
+		inserted += affected
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return 0, wrapDBError("commit transaction", "failed to commit transaction", err)
 	}
-	defer stmt.Close()
 
-	// Insert each delegation
+	return inserted, nil
+}
+
+// InsertDelegationsBestEffort inserts multiple delegations like InsertDelegations, but skips rows that fail
+// instead of losing the batch, e.g. malformed data met during a backfill. Each chunk runs under a savepoint;
+// when a chunk fails it is rolled back to the savepoint and its rows are retried one at a time to isolate the bad ones.
+// Returns the number of rows written and one error per skipped row; the written rows are committed.
+// Connection errors and failures of the transaction itself still roll back the whole batch and are returned as err.
+func (r *DelegationRepository) InsertDelegationsBestEffort(ctx context.Context, delegations []*model.Delegation) (inserted int64, failures []error, err error) {
+	if len(delegations) == 0 {
+		return 0, nil, nil
+	}
+
+	// Validate before touching the database
 	for i, d := range delegations {
 		if d == nil {
-			return apperrors.NewValidationError("delegation", fmt.Sprintf("delegation at index %d is nil", i))
+			return 0, nil, apperrors.NewValidationError("delegation", fmt.Sprintf("delegation at index %d is nil", i))
 		}
+	}
 
-		_, err = stmt.Exec(d.TzktID, d.Timestamp, d.Amount, d.Delegator, d.Level)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, nil, wrapDBError("begin transaction", "failed to begin transaction", err)
+	}
+
+	// Ensure transaction is rolled back on error or panic
+	defer func() {
+		if p := recover(); p != nil {
+			inserted, failures = 0, nil
+			if rbErr := tx.Rollback(); rbErr != nil {
+				err = fmt.Errorf("panic occurred and rollback failed: %v, rollback error: %w", p, rbErr)
+			} else {
+				err = fmt.Errorf("panic occurred: %v", p)
+			}
+		} else if err != nil {
+			inserted, failures = 0, nil
+			if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+				err = fmt.Errorf("rollback failed after error: %w, rollback error: %w", err, rbErr)
+			}
+		}
+	}()
+
+	chunkSize := r.insertChunkSize()
+	for start := 0; start < len(delegations); start += chunkSize {
+		end := min(start+chunkSize, len(delegations))
+
+		n, chunkErr, err := r.insertWithSavepoint(ctx, tx, delegations[start:end])
 		if err != nil {
-			return apperrors.NewDatabaseErrorWithCause("insert delegation", fmt.Sprintf("failed to insert delegation at index %d (TzktID: %d)", i, d.TzktID), err)
+			return 0, nil, wrapDBError("insert delegations", fmt.Sprintf("failed to insert delegations at index %d-%d (TzktID: %d-%d)", start, end-1, delegations[start].TzktID, delegations[end-1].TzktID), err)
+		}
+		if chunkErr == nil {
+			inserted += n
+			continue
+		}
+
+		// Retry the chunk row by row so only the bad rows are skipped
+		for i := start; i < end; i++ {
+			n, rowErr, err := r.insertWithSavepoint(ctx, tx, delegations[i:i+1])
+			if err != nil {
+				return 0, nil, wrapDBError("insert delegations", fmt.Sprintf("failed to insert delegation at index %d (TzktID: %d)", i, delegations[i].TzktID), err)
+			}
+			if rowErr != nil {
+				failures = append(failures, wrapDBError("insert delegation", fmt.Sprintf("skipped delegation at index %d (TzktID: %d)", i, delegations[i].TzktID), rowErr))
+				continue
+			}
+			inserted += n
 		}
 	}
 
-	// Commit transaction
 	if err = tx.Commit(); err != nil {
-		return apperrors.NewDatabaseErrorWithCause("commit transaction", "failed to commit transaction", err)
+		return 0, nil, wrapDBError("commit transaction", "failed to commit transaction", err)
 	}
 
-	return nil
+	return inserted, failures, nil
+}
+
+// insertWithSavepoint writes rows with one INSERT statement under a savepoint and returns the number of rows written.
+// If the statement fails, the transaction is rolled back to the savepoint so it stays usable, and the failure is returned
+// as rowErr. err is set when the transaction can't continue: the savepoint itself failed or the connection was lost.
+func (r *DelegationRepository) insertWithSavepoint(ctx context.Context, tx *sql.Tx, rows []*model.Delegation) (inserted int64, rowErr, err error) {
+	if _, err = tx.ExecContext(ctx, `SAVEPOINT insert_rows`); err != nil {
+		return 0, nil, err
+	}
+
+	query, args := buildInsertQuery(rows, r.opts.UpsertMode == UpsertUpdate)
+	res, rowErr := tx.ExecContext(ctx, query, args...)
+	if rowErr == nil {
+		inserted, rowErr = res.RowsAffected()
+	}
+	if rowErr != nil {
+		if isConnectionError(rowErr) || ctx.Err() != nil {
+			return 0, nil, rowErr
+		}
+		if _, err = tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT insert_rows`); err != nil {
+			return 0, nil, fmt.Errorf("rollback to savepoint failed after error: %w, rollback error: %w", rowErr, err)
+		}
+		return 0, rowErr, nil
+	}
+
+	if _, err = tx.ExecContext(ctx, `RELEASE SAVEPOINT insert_rows`); err != nil {
+		return 0, nil, err
+	}
+	return inserted, nil, nil
+}
+
+// buildInsertQuery builds a single multi-row INSERT for the given delegations and its bind arguments.
+// With updateOnConflict, existing rows are updated, but only when a value differs so repeated syncs don't rewrite them.
+func buildInsertQuery(delegations []*model.Delegation, updateOnConflict bool) (string, []interface{}) {
+	var b strings.Builder
+	args := make([]interface{}, 0, len(delegations)*insertColumnsPerRow)
+
+	b.WriteString(`INSERT INTO delegations (tzkt_id, timestamp, amount, delegator, level, type) VALUES `)
+	for i, d := range delegations {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		// Operations without an explicit type are delegations
+		opType := d.Type
+		if opType == "" {
+			opType = model.OperationTypeDelegation
+		}
+		n := i * insertColumnsPerRow
+		fmt.Fprintf(&b, "($%d, $%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5, n+6)
+		args = append(args, d.TzktID, d.Timestamp, d.Amount, d.Delegator, d.Level, opType)
+	}
+	if updateOnConflict {
+		b.WriteString(` ON CONFLICT (tzkt_id) DO UPDATE SET timestamp = EXCLUDED.timestamp, amount = EXCLUDED.amount, delegator = EXCLUDED.delegator, level = EXCLUDED.level` +
+			` WHERE (delegations.timestamp, delegations.amount, delegations.delegator, delegations.level) IS DISTINCT FROM (EXCLUDED.timestamp, EXCLUDED.amount, EXCLUDED.delegator, EXCLUDED.level)`)
+	} else {
+		b.WriteString(` ON CONFLICT (tzkt_id) DO NOTHING`)
+	}
+
+	return b.String(), args
 }
 
 // GetLatestTzktID retrieves the highest TzktID from the database.
 // Returns 0 if no delegations exist.
 func (r *DelegationRepository) GetLatestTzktID(ctx context.Context) (int64, error) {
 	var tzktID int64
-	err := r.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(tzkt_id), 0) FROM delegations").Scan(&tzktID)
+	err := r.queryRowWithRetry(ctx, "GetLatestTzktID", "SELECT COALESCE(MAX(tzkt_id), 0) FROM delegations", nil, &tzktID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return 0, nil // No delegations exist
 		}
-		return 0, apperrors.NewDatabaseErrorWithCause("query latest TzktID", "failed to get latest TzktID", err)
+		return 0, wrapDBError("query latest TzktID", "failed to get latest TzktID", err)
 	}
 	return tzktID, nil
 }
 
+// GetByTzktID retrieves a single delegation by its Tzkt operation ID.
+// Returns an apperrors.NotFoundError if no delegation matches.
+func (r *DelegationRepository) GetByTzktID(ctx context.Context, tzktID int64) (*model.Delegation, error) {
+	var d model.Delegation
+	err := r.queryRowWithRetry(
+		ctx, "GetByTzktID",
+		`SELECT id, timestamp, amount, delegator, level, tzkt_id, type
+		 FROM delegations
+		 WHERE tzkt_id = $1`,
+		[]interface{}{tzktID},
+		&d.ID, &d.Timestamp, &d.Amount, &d.Delegator, &d.Level, &d.TzktID, &d.Type,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperrors.NewNotFoundErrorWithCause("delegation", strconv.FormatInt(tzktID, 10), err)
+		}
+		return nil, wrapDBError("query delegation by TzktID", fmt.Sprintf("failed to get delegation with TzktID %d", tzktID), err)
+	}
+	return &d, nil
+}
+
+// DeleteByTzktID deletes the delegation with the given Tzkt operation ID, for correcting data after
+// a reorg or a bad import. Returns the number of rows deleted, which is 0 if no delegation matched.
+func (r *DelegationRepository) DeleteByTzktID(ctx context.Context, tzktID int64) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM delegations WHERE tzkt_id = $1`, tzktID)
+	if err != nil {
+		return 0, wrapDBError("delete delegation by TzktID", fmt.Sprintf("failed to delete delegation with TzktID %d", tzktID), err)
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, wrapDBError("delete delegation by TzktID", fmt.Sprintf("failed to get rows deleted for TzktID %d", tzktID), err)
+	}
+	return deleted, nil
+}
+
+// CountByTzktIDs returns how many of the given TzktIDs are stored in the database.
+func (r *DelegationRepository) CountByTzktIDs(ctx context.Context, tzktIDs []int64) (int64, error) {
+	if len(tzktIDs) == 0 {
+		return 0, nil
+	}
+
+	var count int64
+	err := r.queryRowWithRetry(ctx, "CountByTzktIDs", "SELECT COUNT(*) FROM delegations WHERE tzkt_id = ANY($1)", []interface{}{pq.Array(tzktIDs)}, &count)
+	if err != nil {
+		return 0, wrapDBError("count delegations by TzktIDs", fmt.Sprintf("failed to count %d delegations by TzktID", len(tzktIDs)), err)
+	}
+	return count, nil
+}
+
+// MaxTzktIDBatch caps the number of TzktIDs GetByTzktIDs looks up at once
+const MaxTzktIDBatch = 1000
+
+// GetByTzktIDs retrieves the stored delegations among the given Tzkt operation IDs in a single query,
+// ordered by TzktID. IDs that aren't stored are simply absent from the result, so comparing the two
+// shows which operations are missing. At most MaxTzktIDBatch IDs can be looked up at once.
+func (r *DelegationRepository) GetByTzktIDs(ctx context.Context, tzktIDs []int64) ([]model.Delegation, error) {
+	if len(tzktIDs) > MaxTzktIDBatch {
+		return nil, apperrors.NewValidationError("tzktIDs", fmt.Sprintf("cannot exceed %d ids, got %d", MaxTzktIDBatch, len(tzktIDs)))
+	}
+	result := []model.Delegation{}
+	if len(tzktIDs) == 0 {
+		return result, nil
+	}
+
+	rows, err := r.queryWithRetry(
+		ctx, "GetByTzktIDs",
+		`SELECT id, timestamp, amount, delegator, level, tzkt_id, type FROM delegations WHERE tzkt_id = ANY($1) ORDER BY tzkt_id`,
+		pq.Array(tzktIDs),
+	)
+	if err != nil {
+		return nil, wrapDBError("query delegations by TzktIDs", fmt.Sprintf("failed to get %d delegations by TzktID", len(tzktIDs)), err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var d model.Delegation
+		if err := rows.Scan(&d.ID, &d.Timestamp, &d.Amount, &d.Delegator, &d.Level, &d.TzktID, &d.Type); err != nil {
+			return nil, wrapDBError("scan delegation row", "failed to scan delegation row", err)
+		}
+		result = append(result, d)
+	}
+
+	// Check for iteration errors
+	if err := rows.Err(); err != nil {
+		return nil, wrapDBError("iterate rows", "error during row iteration", err)
+	}
+
+	return result, nil
+}
+
 var ErrNoDelegations = errors.New("no delegations found")
 
-// ListDelegations retrieves delegations with pagination and optional year filtering.
-// Returns ErrNoDelegations if no delegations match the criteria.
-func (r *DelegationRepository) ListDelegations(ctx context.Context, limit, offset int, year *int) ([]model.Delegation, error) {
-	var rows *sql.Rows
-	var err error
+// yearBounds returns the start of year and of the following year in loc, as UTC times
+// to compare with the stored UTC timestamps
+func yearBounds(year int, loc *time.Location) (time.Time, time.Time) {
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+	return start.UTC(), start.AddDate(1, 0, 0).UTC()
+}
+
+// buildFilterClause builds the WHERE clause and its positional arguments for the given filter.
+// Returns an empty clause if no filter criteria are set.
+func buildFilterClause(filter model.DelegationFilter, loc *time.Location) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	if filter.Year != nil {
+		// Half-open range rather than EXTRACT(YEAR ...) so the timestamp index can be used
+		start, end := yearBounds(*filter.Year, loc)
+		args = append(args, start, end)
+		conditions = append(conditions, fmt.Sprintf("timestamp >= $%d AND timestamp < $%d", len(args)-1, len(args)))
+	}
+	if filter.MaxTzktID != nil {
+		args = append(args, *filter.MaxTzktID)
+		conditions = append(conditions, fmt.Sprintf("tzkt_id <= $%d", len(args)))
+	}
+	if filter.Type != nil {
+		args = append(args, *filter.Type)
+		conditions = append(conditions, fmt.Sprintf("type = $%d", len(args)))
+	}
+	if filter.MinLevel != nil {
+		args = append(args, *filter.MinLevel)
+		conditions = append(conditions, fmt.Sprintf("level >= $%d", len(args)))
+	}
+	if filter.SinceTzktID != nil {
+		args = append(args, *filter.SinceTzktID)
+		conditions = append(conditions, fmt.Sprintf("tzkt_id > $%d", len(args)))
+	}
+	if filter.AccountType != nil {
+		// Smart contracts have KT1 addresses, implicit accounts tz1, tz2 or tz3
+		switch *filter.AccountType {
+		case model.AccountTypeContract:
+			conditions = append(conditions, "delegator LIKE 'KT1%'")
+		case model.AccountTypeImplicit:
+			conditions = append(conditions, "delegator NOT LIKE 'KT1%'")
+		}
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// orderClause returns the ORDER BY clause for listing delegations matching filter: most recent first,
+// or ascending by tzkt_id for incremental syncs with SinceTzktID, so clients can page forward deterministically
+func orderClause(filter model.DelegationFilter) string {
+	if filter.SinceTzktID != nil {
+		return ` ORDER BY tzkt_id ASC`
+	}
+	return ` ORDER BY timestamp DESC, tzkt_id DESC`
+}
+
+// validateFilter checks the filter values the queries can't handle, for every query that builds its WHERE
+// clause with buildFilterClause
+func validateFilter(filter model.DelegationFilter) error {
+	if filter.Year != nil && *filter.Year < model.MinYear {
+		return apperrors.NewValidationError("year", fmt.Sprintf("must be a valid year from %d onwards, got %d", model.MinYear, *filter.Year))
+	}
+	if filter.MaxTzktID != nil && *filter.MaxTzktID < 0 {
+		return apperrors.NewValidationError("maxTzktID", fmt.Sprintf("must be non-negative, got %d", *filter.MaxTzktID))
+	}
+	if filter.MinLevel != nil && *filter.MinLevel < 0 {
+		return apperrors.NewValidationError("minLevel", fmt.Sprintf("must be non-negative, got %d", *filter.MinLevel))
+	}
+	if filter.SinceTzktID != nil && *filter.SinceTzktID < 0 {
+		return apperrors.NewValidationError("sinceTzktID", fmt.Sprintf("must be non-negative, got %d", *filter.SinceTzktID))
+	}
+	return nil
+}
 
+// ListDelegations retrieves delegations with pagination and optional filtering.
+// Returns ErrNoDelegations if no delegations match the criteria.
+func (r *DelegationRepository) ListDelegations(ctx context.Context, limit, offset int, filter model.DelegationFilter) ([]model.Delegation, error) {
 	// Validate parameters
 	if limit <= 0 {
 		return nil, apperrors.NewValidationError("limit", fmt.Sprintf("must be positive, got %d", limit))
@@ -107,35 +614,19 @@ func (r *DelegationRepository) ListDelegations(ctx context.Context, limit, offse
 	if offset < 0 {
 		return nil, apperrors.NewValidationError("offset", fmt.Sprintf("must be non-negative, got %d", offset))
 	}
-
-	// Build query based on whether year filter is provided
-	if year != nil {
-		if *year < 2018 {
-			return nil, apperrors.NewValidationError("year", fmt.Sprintf("must be a valid year from 2018 onwards, got %d", *year))
-		}
-
-		rows, err = r.db.QueryContext(
-			ctx,
-			`SELECT id, timestamp, amount, delegator, level, tzkt_id 
-			 FROM delegations 
-			 WHERE EXTRACT(YEAR FROM timestamp) = $1 
-			 ORDER BY timestamp DESC, tzkt_id DESC 
-			 LIMIT $2 OFFSET $3`,
-			*year, limit, offset,
-		)
-	} else {
-		rows, err = r.db.QueryContext(
-			ctx,
-			`SELECT id, timestamp, amount, delegator, level, tzkt_id 
-			 FROM delegations 
-			 ORDER BY timestamp DESC, tzkt_id DESC 
-			 LIMIT $1 OFFSET $2`,
-			limit, offset,
-		)
+	if err := validateFilter(filter); err != nil {
+		return nil, err
 	}
 
+	// Build query based on which filters are provided
+	where, args := buildFilterClause(filter, r.location())
+	query := `SELECT id, timestamp, amount, delegator, level, tzkt_id, type FROM delegations` + where + orderClause(filter) +
+		fmt.Sprintf(` LIMIT $%d OFFSET $%d`, len(args)+1, len(args)+2)
+	args = append(args, limit, offset)
+
+	rows, err := r.queryWithRetry(ctx, "ListDelegations", query, args...)
 	if err != nil {
-		return nil, apperrors.NewDatabaseErrorWithCause("query delegations", "failed to query delegations", err)
+		return nil, wrapDBError("query delegations", "failed to query delegations", err)
 	}
 	defer rows.Close()
 
@@ -143,15 +634,15 @@ func (r *DelegationRepository) ListDelegations(ctx context.Context, limit, offse
 	var result []model.Delegation
 	for rows.Next() {
 		var d model.Delegation
-		if err := rows.Scan(&d.ID, &d.Timestamp, &d.Amount, &d.Delegator, &d.Level, &d.TzktID); err != nil {
-			return nil, apperrors.NewDatabaseErrorWithCause("scan delegation row", "failed to scan delegation row", err)
+		if err := rows.Scan(&d.ID, &d.Timestamp, &d.Amount, &d.Delegator, &d.Level, &d.TzktID, &d.Type); err != nil {
+			return nil, wrapDBError("scan delegation row", "failed to scan delegation row", err)
 		}
 		result = append(result, d)
 	}
 
 	// Check for scan errors
 	if err = rows.Err(); err != nil {
-		return nil, apperrors.NewDatabaseErrorWithCause("iterate rows", "error during row iteration", err)
+		return nil, wrapDBError("iterate rows", "error during row iteration", err)
 	}
 
 	// Return error if no results found
@@ -161,3 +652,326 @@ func (r *DelegationRepository) ListDelegations(ctx context.Context, limit, offse
 
 	return result, nil
 }
+
+// StreamDelegations calls fn for each delegation matching the filter, in the same order as ListDelegations,
+// without materializing the full result set. Iteration stops at the first error returned by fn,
+// which is returned unchanged. Cancelling ctx aborts the query and closes the rows.
+func (r *DelegationRepository) StreamDelegations(ctx context.Context, filter model.DelegationFilter, fn func(model.Delegation) error) error {
+	if err := validateFilter(filter); err != nil {
+		return err
+	}
+
+	where, args := buildFilterClause(filter, r.location())
+	query := `SELECT id, timestamp, amount, delegator, level, tzkt_id, type FROM delegations` + where + orderClause(filter)
+
+	rows, err := r.queryWithRetry(ctx, "StreamDelegations", query, args...)
+	if err != nil {
+		return wrapDBError("stream delegations", "failed to query delegations", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		// Stop as soon as the caller goes away; the deferred Close releases the connection
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var d model.Delegation
+		if err := rows.Scan(&d.ID, &d.Timestamp, &d.Amount, &d.Delegator, &d.Level, &d.TzktID, &d.Type); err != nil {
+			return wrapDBError("scan delegation row", "failed to scan delegation row", err)
+		}
+		if err := fn(d); err != nil {
+			return err
+		}
+	}
+
+	// Check for iteration errors, including context cancellation mid-stream
+	if err := rows.Err(); err != nil {
+		return wrapDBError("iterate rows", "error during row iteration", err)
+	}
+
+	return nil
+}
+
+// CountDelegations returns the number of delegations matching the filter.
+func (r *DelegationRepository) CountDelegations(ctx context.Context, filter model.DelegationFilter) (int64, error) {
+	if err := validateFilter(filter); err != nil {
+		return 0, err
+	}
+
+	where, args := buildFilterClause(filter, r.location())
+	var count int64
+	if err := r.queryRowWithRetry(ctx, "CountDelegations", `SELECT COUNT(*) FROM delegations`+where, args, &count); err != nil {
+		return 0, wrapDBError("count delegations", "failed to count delegations", err)
+	}
+	return count, nil
+}
+
+// ApproximateCount returns the planner's estimate of the number of rows in the delegations table, read from
+// pg_class.reltuples without scanning the table. The estimate is refreshed by VACUUM and ANALYZE, so it lags
+// recent inserts. Returns -1 if the table hasn't been vacuumed or analyzed yet and there is no estimate.
+func (r *DelegationRepository) ApproximateCount(ctx context.Context) (int64, error) {
+	const query = `SELECT reltuples::bigint FROM pg_class WHERE oid = 'delegations'::regclass`
+
+	var count int64
+	if err := r.queryRowWithRetry(ctx, "ApproximateCount", query, nil, &count); err != nil {
+		return 0, wrapDBError("approximate count", "failed to estimate delegation count", err)
+	}
+	return count, nil
+}
+
+// localTimestamp converts the stored UTC timestamp to wall time in the time zone given by the
+// placeholder, so EXTRACT doesn't depend on the column type or session time zone
+func localTimestamp(placeholder string) string {
+	return `timestamp AT TIME ZONE 'UTC' AT TIME ZONE ` + placeholder
+}
+
+// AggregateByYear returns the number of delegations and the total delegated amount per calendar year
+// in the configured time zone, ordered by year. Returns an empty slice if there are no delegations.
+func (r *DelegationRepository) AggregateByYear(ctx context.Context) ([]model.YearStats, error) {
+	query := `SELECT EXTRACT(YEAR FROM ` + localTimestamp("$1") + `)::int AS year, COUNT(*), COALESCE(SUM(amount), 0) FROM delegations GROUP BY year ORDER BY year`
+
+	rows, err := r.queryWithRetry(ctx, "AggregateByYear", query, r.location().String())
+	if err != nil {
+		return nil, wrapDBError("aggregate by year", "failed to aggregate delegations by year", err)
+	}
+	defer rows.Close()
+
+	stats := []model.YearStats{}
+	for rows.Next() {
+		var s model.YearStats
+		if err := rows.Scan(&s.Year, &s.Count, &s.TotalAmount); err != nil {
+			return nil, wrapDBError("scan year stats row", "failed to scan year stats row", err)
+		}
+		stats = append(stats, s)
+	}
+
+	// Check for iteration errors
+	if err := rows.Err(); err != nil {
+		return nil, wrapDBError("iterate rows", "error during row iteration", err)
+	}
+
+	return stats, nil
+}
+
+// AggregateByMonth returns the number of delegations and the total delegated amount per month of the given year
+// in the configured time zone, ordered by month. Months without delegations are left out.
+func (r *DelegationRepository) AggregateByMonth(ctx context.Context, year int) ([]model.MonthStats, error) {
+	if year < model.MinYear {
+		return nil, apperrors.NewValidationError("year", fmt.Sprintf("must be a valid year from %d onwards, got %d", model.MinYear, year))
+	}
+
+	where, args := buildFilterClause(model.DelegationFilter{Year: &year}, r.location())
+	args = append(args, r.location().String())
+	query := `SELECT EXTRACT(MONTH FROM ` + localTimestamp(fmt.Sprintf("$%d", len(args))) + `)::int AS month, COUNT(*), COALESCE(SUM(amount), 0) FROM delegations` + where +
+		` GROUP BY month ORDER BY month`
+
+	rows, err := r.queryWithRetry(ctx, "AggregateByMonth", query, args...)
+	if err != nil {
+		return nil, wrapDBError("aggregate by month", fmt.Sprintf("failed to aggregate delegations by month for %d", year), err)
+	}
+	defer rows.Close()
+
+	stats := []model.MonthStats{}
+	for rows.Next() {
+		var s model.MonthStats
+		if err := rows.Scan(&s.Month, &s.Count, &s.TotalAmount); err != nil {
+			return nil, wrapDBError("scan month stats row", "failed to scan month stats row", err)
+		}
+		stats = append(stats, s)
+	}
+
+	// Check for iteration errors
+	if err := rows.Err(); err != nil {
+		return nil, wrapDBError("iterate rows", "error during row iteration", err)
+	}
+
+	return stats, nil
+}
+
+// AggregateTopDelegators returns up to limit delegators ranked by total delegated amount,
+// optionally restricted to a single year. Returns an empty slice if there are no delegations.
+func (r *DelegationRepository) AggregateTopDelegators(ctx context.Context, limit int, year *int) ([]model.DelegatorStats, error) {
+	// Validate parameters
+	if limit <= 0 {
+		return nil, apperrors.NewValidationError("limit", fmt.Sprintf("must be positive, got %d", limit))
+	}
+	filter := model.DelegationFilter{Year: year}
+	if err := validateFilter(filter); err != nil {
+		return nil, err
+	}
+
+	where, args := buildFilterClause(filter, r.location())
+	args = append(args, limit)
+	query := `SELECT delegator, COUNT(*), SUM(amount) FROM delegations` + where +
+		fmt.Sprintf(` GROUP BY delegator ORDER BY SUM(amount) DESC, delegator LIMIT $%d`, len(args))
+
+	rows, err := r.queryWithRetry(ctx, "AggregateTopDelegators", query, args...)
+	if err != nil {
+		return nil, wrapDBError("aggregate top delegators", "failed to aggregate top delegators", err)
+	}
+	defer rows.Close()
+
+	stats := []model.DelegatorStats{}
+	for rows.Next() {
+		var s model.DelegatorStats
+		if err := rows.Scan(&s.Delegator, &s.Count, &s.TotalAmount); err != nil {
+			return nil, wrapDBError("scan delegator stats row", "failed to scan delegator stats row", err)
+		}
+		stats = append(stats, s)
+	}
+
+	// Check for iteration errors
+	if err := rows.Err(); err != nil {
+		return nil, wrapDBError("iterate rows", "error during row iteration", err)
+	}
+
+	return stats, nil
+}
+
+// CountDistinctDelegators returns the number of distinct delegators, optionally only counting delegations
+// made in year. The (delegator, timestamp) index lets Postgres answer it with an index-only scan.
+func (r *DelegationRepository) CountDistinctDelegators(ctx context.Context, year *int) (int64, error) {
+	filter := model.DelegationFilter{Year: year}
+	if err := validateFilter(filter); err != nil {
+		return 0, err
+	}
+
+	where, args := buildFilterClause(filter, r.location())
+	var count int64
+	if err := r.queryRowWithRetry(ctx, "CountDistinctDelegators", `SELECT COUNT(DISTINCT delegator) FROM delegations`+where, args, &count); err != nil {
+		return 0, wrapDBError("count distinct delegators", "failed to count distinct delegators", err)
+	}
+	return count, nil
+}
+
+// GetDelegatorSummary returns the first and last delegation times, count and total amount for one delegator.
+// Returns an apperrors.NotFoundError if the delegator has no delegations.
+func (r *DelegationRepository) GetDelegatorSummary(ctx context.Context, delegator string) (*model.DelegatorSummary, error) {
+	s := model.DelegatorSummary{Delegator: delegator}
+	err := r.queryRowWithRetry(
+		ctx, "GetDelegatorSummary",
+		`SELECT MIN(timestamp), MAX(timestamp), COUNT(*), SUM(amount)
+		 FROM delegations
+		 WHERE delegator = $1
+		 GROUP BY delegator`,
+		[]interface{}{delegator},
+		&s.FirstSeen, &s.LastSeen, &s.Count, &s.TotalAmount,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperrors.NewNotFoundErrorWithCause("delegator", delegator, err)
+		}
+		return nil, wrapDBError("summarize delegator", fmt.Sprintf("failed to summarize delegator %s", delegator), err)
+	}
+	return &s, nil
+}
+
+// TryAcquirePollLock tries to take the session-level advisory lock that elects the polling instance,
+// without waiting. Returns true if this repository holds the lock, including when it already did and
+// its session is still alive. A lost session releases the lock in Postgres, so it is then retried.
+func (r *DelegationRepository) TryAcquirePollLock(ctx context.Context) (bool, error) {
+	r.pollLockMu.Lock()
+	defer r.pollLockMu.Unlock()
+
+	if r.pollLockConn != nil {
+		if err := r.pollLockConn.PingContext(ctx); err == nil {
+			return true, nil
+		}
+		r.pollLockConn.Close()
+		r.pollLockConn = nil
+	}
+
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return false, wrapDBError("acquire poll lock", "failed to get a connection for the poll lock", err)
+	}
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, pollLockKey).Scan(&acquired); err != nil {
+		conn.Close()
+		return false, wrapDBError("acquire poll lock", "failed to try the poll lock", err)
+	}
+	if !acquired {
+		conn.Close()
+		return false, nil
+	}
+	r.pollLockConn = conn
+	return true, nil
+}
+
+// ReleasePollLock releases the poll lock if this repository holds it, returning its connection to the pool.
+func (r *DelegationRepository) ReleasePollLock(ctx context.Context) error {
+	r.pollLockMu.Lock()
+	defer r.pollLockMu.Unlock()
+
+	if r.pollLockConn == nil {
+		return nil
+	}
+	conn := r.pollLockConn
+	r.pollLockConn = nil
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, pollLockKey); err != nil {
+		// Discard the session rather than pooling it, since ending it is what releases the lock then
+		conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+		return wrapDBError("release poll lock", "failed to release the poll lock", err)
+	}
+	return nil
+}
+
+// AnalyzeDelegations refreshes the planner statistics of the delegations table with ANALYZE, or with vacuum,
+// VACUUM ANALYZE, which also reclaims dead rows. VACUUM can't run in a transaction, so neither is wrapped in one.
+func (r *DelegationRepository) AnalyzeDelegations(ctx context.Context, vacuum bool) error {
+	query := `ANALYZE delegations`
+	if vacuum {
+		query = `VACUUM ANALYZE delegations`
+	}
+	if _, err := r.db.ExecContext(ctx, query); err != nil {
+		return wrapDBError("analyze delegations", "failed to analyze delegations", err)
+	}
+	return nil
+}
+
+// CheckSchema verifies the delegations table can be queried, catching a database whose migrations failed
+// or were never run, which a connection ping alone doesn't notice. An empty table passes.
+func (r *DelegationRepository) CheckSchema(ctx context.Context) error {
+	var one int
+	err := r.queryRowWithRetry(ctx, "CheckSchema", `SELECT 1 FROM delegations LIMIT 1`, nil, &one)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return wrapDBError("check schema", "delegations table not queryable", err)
+	}
+	return nil
+}
+
+// GetSyncState retrieves the poller's persisted sync state.
+// Returns a zero-value state if the poller has not recorded any progress yet.
+func (r *DelegationRepository) GetSyncState(ctx context.Context) (*model.SyncState, error) {
+	var state model.SyncState
+	var lastPollAt sql.NullTime
+	err := r.queryRowWithRetry(
+		ctx, "GetSyncState",
+		`SELECT last_tzkt_id, last_poll_at, historical_complete FROM sync_state WHERE id = 1`,
+		nil,
+		&state.LastTzktID, &lastPollAt, &state.HistoricalComplete,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return &model.SyncState{}, nil // No progress recorded yet
+		}
+		return nil, wrapDBError("query sync state", "failed to get sync state", err)
+	}
+	if lastPollAt.Valid {
+		state.LastPollAt = lastPollAt.Time
+	}
+	return &state, nil
+}
+
+// UpdateSyncState persists the poller's sync state, creating the row if it does not exist.
+func (r *DelegationRepository) UpdateSyncState(ctx context.Context, state model.SyncState) error {
+	const query = `INSERT INTO sync_state (id, last_tzkt_id, last_poll_at, historical_complete) VALUES (1, $1, $2, $3)
+		ON CONFLICT (id) DO UPDATE SET last_tzkt_id = EXCLUDED.last_tzkt_id, last_poll_at = EXCLUDED.last_poll_at, historical_complete = EXCLUDED.historical_complete`
+	_, err := r.db.ExecContext(ctx, query, state.LastTzktID, state.LastPollAt, state.HistoricalComplete)
+	if err != nil {
+		return wrapDBError("update sync state", "failed to update sync state", err)
+	}
+	return nil
+}
@@ -1,15 +1,27 @@
 package db
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
 	"regexp"
+	"syscall"
 	"testing"
 	"time"
 
+	"tezos-delegation/internal/apperrors"
+	"tezos-delegation/internal/metrics"
 	"tezos-delegation/internal/model"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -25,25 +37,288 @@ func setupMockDB(t *testing.T) (*sql.DB, sqlmock.Sqlmock, func()) {
 func TestInsertDelegations(t *testing.T) {
 	db, mock, cleanup := setupMockDB(t)
 	defer cleanup()
-	repo := NewDelegationRepository(db)
+	repo := NewDelegationRepository(db, DelegationRepositoryOptions{})
 	delegations := []*model.Delegation{{TzktID: 1, Timestamp: fixedTime(), Amount: 100, Delegator: "tz1", Level: 1}}
 
 	mock.ExpectBegin()
-	mock.ExpectPrepare(regexp.QuoteMeta(`INSERT INTO delegations (tzkt_id, timestamp, amount, delegator, level) VALUES ($1, $2, $3, $4, $5) ON CONFLICT (tzkt_id) DO NOTHING`)).
-		ExpectExec().
-		WithArgs(delegations[0].TzktID, delegations[0].Timestamp, delegations[0].Amount, delegations[0].Delegator, delegations[0].Level).
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO delegations (tzkt_id, timestamp, amount, delegator, level, type) VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (tzkt_id) DO NOTHING`)).
+		WithArgs(delegations[0].TzktID, delegations[0].Timestamp, delegations[0].Amount, delegations[0].Delegator, delegations[0].Level, model.OperationTypeDelegation).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
 
-	err := repo.InsertDelegations(delegations)
+	inserted, err := repo.InsertDelegations(context.Background(), delegations)
 	assert.NoError(t, err)
+	assert.Equal(t, int64(1), inserted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInsertDelegations_SkipsExisting(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	repo := NewDelegationRepository(db, DelegationRepositoryOptions{})
+	delegations := []*model.Delegation{
+		{TzktID: 1, Timestamp: fixedTime(), Amount: 100, Delegator: "tz1", Level: 1},
+		{TzktID: 2, Timestamp: fixedTime(), Amount: 200, Delegator: "KT1", Level: 2, Type: model.OperationTypeOrigination},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO delegations (tzkt_id, timestamp, amount, delegator, level, type) VALUES ($1, $2, $3, $4, $5, $6), ($7, $8, $9, $10, $11, $12) ON CONFLICT (tzkt_id) DO NOTHING`)).
+		WithArgs(int64(1), fixedTime(), int64(100), "tz1", int64(1), "delegation", int64(2), fixedTime(), int64(200), "KT1", int64(2), "origination").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	inserted, err := repo.InsertDelegations(context.Background(), delegations)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), inserted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInsertDelegations_UpsertModes(t *testing.T) {
+	delegations := []*model.Delegation{{TzktID: 1, Timestamp: fixedTime(), Amount: 150, Delegator: "tz1", Level: 1}}
+	const insert = `INSERT INTO delegations (tzkt_id, timestamp, amount, delegator, level, type) VALUES ($1, $2, $3, $4, $5, $6)`
+
+	testCases := []struct {
+		name     string
+		mode     string
+		conflict string
+	}{
+		{"ignore", UpsertIgnore, ` ON CONFLICT (tzkt_id) DO NOTHING`},
+		{"update", UpsertUpdate, ` ON CONFLICT (tzkt_id) DO UPDATE SET timestamp = EXCLUDED.timestamp, amount = EXCLUDED.amount, delegator = EXCLUDED.delegator, level = EXCLUDED.level` +
+			` WHERE (delegations.timestamp, delegations.amount, delegations.delegator, delegations.level) IS DISTINCT FROM (EXCLUDED.timestamp, EXCLUDED.amount, EXCLUDED.delegator, EXCLUDED.level)`},
+		{"unknown falls back to ignore", "merge", ` ON CONFLICT (tzkt_id) DO NOTHING`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, cleanup := setupMockDB(t)
+			defer cleanup()
+			repo := NewDelegationRepository(db, DelegationRepositoryOptions{UpsertMode: tc.mode})
+
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(insert+tc.conflict)+"$").
+				WithArgs(int64(1), fixedTime(), int64(150), "tz1", int64(1), "delegation").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+
+			written, err := repo.InsertDelegations(context.Background(), delegations)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(1), written)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestInsertDelegations_Chunked(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	repo := NewDelegationRepository(db, DelegationRepositoryOptions{})
+
+	delegations := make([]*model.Delegation, MaxInsertChunkSize+1)
+	for i := range delegations {
+		delegations[i] = &model.Delegation{TzktID: int64(i + 1), Timestamp: fixedTime(), Amount: 100, Delegator: "tz1", Level: 1}
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO delegations`)).WillReturnResult(sqlmock.NewResult(0, int64(MaxInsertChunkSize)))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO delegations (tzkt_id, timestamp, amount, delegator, level, type) VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT`)).
+		WithArgs(int64(MaxInsertChunkSize+1), fixedTime(), int64(100), "tz1", int64(1), "delegation").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	inserted, err := repo.InsertDelegations(context.Background(), delegations)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(MaxInsertChunkSize+1), inserted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInsertDelegations_ChunkSize(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	repo := NewDelegationRepository(db, DelegationRepositoryOptions{InsertChunkSize: 2})
+
+	delegations := make([]*model.Delegation, 5)
+	for i := range delegations {
+		delegations[i] = &model.Delegation{TzktID: int64(i + 1), Timestamp: fixedTime(), Amount: 100, Delegator: "tz1", Level: 1}
+	}
+
+	// Five rows in chunks of two: three statements in one transaction
+	mock.ExpectBegin()
+	for _, ids := range [][]int64{{1, 2}, {3, 4}, {5}} {
+		var args []driver.Value
+		for _, id := range ids {
+			args = append(args, id, fixedTime(), int64(100), "tz1", int64(1), "delegation")
+		}
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO delegations`)).WithArgs(args...).
+			WillReturnResult(sqlmock.NewResult(0, int64(len(ids))))
+	}
+	mock.ExpectCommit()
+
+	inserted, err := repo.InsertDelegations(context.Background(), delegations)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), inserted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInsertDelegations_ContextCancelled(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	repo := NewDelegationRepository(db, DelegationRepositoryOptions{InsertChunkSize: 1})
+	delegations := []*model.Delegation{
+		{TzktID: 1, Timestamp: fixedTime(), Amount: 100, Delegator: "tz1", Level: 1},
+		{TzktID: 2, Timestamp: fixedTime(), Amount: 100, Delegator: "tz1", Level: 1},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The first chunk is written, then the context is cancelled while the second one hangs
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO delegations`)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO delegations`)).WillDelayFor(time.Second).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectRollback()
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	inserted, err := repo.InsertDelegations(ctx, delegations)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.True(t, apperrors.IsDatabaseError(err))
+	assert.Equal(t, int64(0), inserted)
+	// database/sql rolls the transaction back from its own goroutine once ctx is cancelled
+	assert.Eventually(t, func() bool { return mock.ExpectationsWereMet() == nil }, time.Second, 10*time.Millisecond)
+}
+
+func TestInsertDelegations_NilDelegation(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	repo := NewDelegationRepository(db, DelegationRepositoryOptions{})
+	delegations := []*model.Delegation{{TzktID: 1, Timestamp: fixedTime(), Amount: 100, Delegator: "tz1", Level: 1}, nil}
+
+	inserted, err := repo.InsertDelegations(context.Background(), delegations)
+	assert.True(t, apperrors.IsValidationError(err))
+	assert.Equal(t, int64(0), inserted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInsertDelegations_RollbackOnError(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	repo := NewDelegationRepository(db, DelegationRepositoryOptions{})
+	delegations := []*model.Delegation{{TzktID: 1, Timestamp: fixedTime(), Amount: 100, Delegator: "tz1", Level: 1}}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO delegations`)).
+		WillReturnError(sql.ErrConnDone)
+	mock.ExpectRollback()
+
+	inserted, err := repo.InsertDelegations(context.Background(), delegations)
+	assert.True(t, apperrors.IsDatabaseError(err))
+	assert.Equal(t, int64(0), inserted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// poisonBatch returns three delegations whose second row the database rejects
+func poisonBatch() ([]*model.Delegation, *pq.Error) {
+	delegations := make([]*model.Delegation, 3)
+	for i := range delegations {
+		delegations[i] = &model.Delegation{TzktID: int64(i + 1), Timestamp: fixedTime(), Amount: 100, Delegator: "tz1", Level: 1}
+	}
+	return delegations, &pq.Error{Code: "22003", Message: "value out of range for type bigint"}
+}
+
+func TestInsertDelegations_PoisonRowFailsBatch(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	repo := NewDelegationRepository(db, DelegationRepositoryOptions{})
+	delegations, poison := poisonBatch()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO delegations`)).WillReturnError(poison)
+	mock.ExpectRollback()
+
+	inserted, err := repo.InsertDelegations(context.Background(), delegations)
+	assert.True(t, apperrors.IsDatabaseError(err))
+	assert.ErrorIs(t, err, poison)
+	assert.Equal(t, int64(0), inserted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInsertDelegationsBestEffort_SkipsPoisonRow(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	repo := NewDelegationRepository(db, DelegationRepositoryOptions{})
+	delegations, poison := poisonBatch()
+	rowArgs := func(id int64) []driver.Value {
+		return []driver.Value{id, fixedTime(), int64(100), "tz1", int64(1), "delegation"}
+	}
+
+	// The chunk fails, is rolled back to its savepoint and retried row by row
+	mock.ExpectBegin()
+	mock.ExpectExec(`SAVEPOINT insert_rows`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO delegations`)).WillReturnError(poison)
+	mock.ExpectExec(`ROLLBACK TO SAVEPOINT insert_rows`).WillReturnResult(sqlmock.NewResult(0, 0))
+	for _, id := range []int64{1, 2, 3} {
+		mock.ExpectExec(`SAVEPOINT insert_rows`).WillReturnResult(sqlmock.NewResult(0, 0))
+		insert := mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO delegations`)).WithArgs(rowArgs(id)...)
+		if id == 2 {
+			insert.WillReturnError(poison)
+			mock.ExpectExec(`ROLLBACK TO SAVEPOINT insert_rows`).WillReturnResult(sqlmock.NewResult(0, 0))
+			continue
+		}
+		insert.WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`RELEASE SAVEPOINT insert_rows`).WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+	mock.ExpectCommit()
+
+	inserted, failures, err := repo.InsertDelegationsBestEffort(context.Background(), delegations)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), inserted)
+	if assert.Len(t, failures, 1) {
+		assert.True(t, apperrors.IsDatabaseError(failures[0]))
+		assert.ErrorIs(t, failures[0], poison)
+		assert.Contains(t, failures[0].Error(), "TzktID: 2")
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInsertDelegationsBestEffort_NoFailures(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	repo := NewDelegationRepository(db, DelegationRepositoryOptions{})
+	delegations, _ := poisonBatch()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SAVEPOINT insert_rows`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO delegations`)).WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec(`RELEASE SAVEPOINT insert_rows`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	inserted, failures, err := repo.InsertDelegationsBestEffort(context.Background(), delegations)
+	assert.NoError(t, err)
+	assert.Empty(t, failures)
+	assert.Equal(t, int64(3), inserted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInsertDelegationsBestEffort_ConnectionErrorFailsBatch(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	repo := NewDelegationRepository(db, DelegationRepositoryOptions{})
+	delegations, _ := poisonBatch()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SAVEPOINT insert_rows`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO delegations`)).WillReturnError(driver.ErrBadConn)
+	mock.ExpectRollback()
+
+	inserted, failures, err := repo.InsertDelegationsBestEffort(context.Background(), delegations)
+	assert.True(t, apperrors.IsDatabaseUnavailableError(err))
+	assert.Empty(t, failures)
+	assert.Equal(t, int64(0), inserted)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestGetLatestTzktID(t *testing.T) {
 	db, mock, cleanup := setupMockDB(t)
 	defer cleanup()
-	repo := NewDelegationRepository(db)
+	repo := NewDelegationRepository(db, DelegationRepositoryOptions{})
 	ctx := context.Background()
 
 	mock.ExpectQuery(regexp.QuoteMeta("SELECT COALESCE(MAX(tzkt_id), 0) FROM delegations")).
@@ -58,22 +333,997 @@ func TestGetLatestTzktID(t *testing.T) {
 func TestListDelegations(t *testing.T) {
 	db, mock, cleanup := setupMockDB(t)
 	defer cleanup()
-	repo := NewDelegationRepository(db)
+	repo := NewDelegationRepository(db, DelegationRepositoryOptions{})
 	ctx := context.Background()
 
-	rows := sqlmock.NewRows([]string{"id", "timestamp", "amount", "delegator", "level", "tzkt_id"}).
-		AddRow(1, fixedTime(), 100, "tz1", 1, 1)
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, timestamp, amount, delegator, level, tzkt_id FROM delegations ORDER BY timestamp DESC, tzkt_id DESC LIMIT $1 OFFSET $2`)).
+	rows := sqlmock.NewRows([]string{"id", "timestamp", "amount", "delegator", "level", "tzkt_id", "type"}).
+		AddRow(1, fixedTime(), 100, "tz1", 1, 1, "delegation")
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, timestamp, amount, delegator, level, tzkt_id, type FROM delegations ORDER BY timestamp DESC, tzkt_id DESC LIMIT $1 OFFSET $2`)).
 		WithArgs(10, 0).
 		WillReturnRows(rows)
 
-	delegations, err := repo.ListDelegations(ctx, 10, 0, nil)
+	delegations, err := repo.ListDelegations(ctx, 10, 0, model.DelegationFilter{})
 	assert.NoError(t, err)
 	assert.Len(t, delegations, 1)
 	assert.Equal(t, "tz1", delegations[0].Delegator)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestListDelegations_WithFilters(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	repo := NewDelegationRepository(db, DelegationRepositoryOptions{})
+	ctx := context.Background()
+
+	year := 2022
+	maxID := int64(500)
+	rows := sqlmock.NewRows([]string{"id", "timestamp", "amount", "delegator", "level", "tzkt_id", "type"}).
+		AddRow(1, fixedTime(), 100, "tz1", 1, 1, "delegation")
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, timestamp, amount, delegator, level, tzkt_id, type FROM delegations WHERE timestamp >= $1 AND timestamp < $2 AND tzkt_id <= $3 ORDER BY timestamp DESC, tzkt_id DESC LIMIT $4 OFFSET $5`)).
+		WithArgs(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), maxID, 10, 20).
+		WillReturnRows(rows)
+
+	delegations, err := repo.ListDelegations(ctx, 10, 20, model.DelegationFilter{Year: &year, MaxTzktID: &maxID})
+	assert.NoError(t, err)
+	assert.Len(t, delegations, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListDelegations_TypeFilter(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	repo := NewDelegationRepository(db, DelegationRepositoryOptions{})
+	ctx := context.Background()
+
+	opType := model.OperationTypeOrigination
+	rows := sqlmock.NewRows([]string{"id", "timestamp", "amount", "delegator", "level", "tzkt_id", "type"}).
+		AddRow(1, fixedTime(), 100, "KT1", 1, 1, "origination")
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, timestamp, amount, delegator, level, tzkt_id, type FROM delegations WHERE type = $1 ORDER BY timestamp DESC, tzkt_id DESC LIMIT $2 OFFSET $3`)).
+		WithArgs(opType, 10, 0).
+		WillReturnRows(rows)
+
+	delegations, err := repo.ListDelegations(ctx, 10, 0, model.DelegationFilter{Type: &opType})
+	assert.NoError(t, err)
+	assert.Len(t, delegations, 1)
+	assert.Equal(t, model.OperationTypeOrigination, delegations[0].Type)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListDelegations_MinLevelFilter(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	repo := NewDelegationRepository(db, DelegationRepositoryOptions{})
+	ctx := context.Background()
+
+	year := 2022
+	minLevel := int64(2338084)
+	rows := sqlmock.NewRows([]string{"id", "timestamp", "amount", "delegator", "level", "tzkt_id", "type"}).
+		AddRow(1, fixedTime(), 100, "tz1", 2338084, 1, "delegation")
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, timestamp, amount, delegator, level, tzkt_id, type FROM delegations WHERE timestamp >= $1 AND timestamp < $2 AND level >= $3 ORDER BY timestamp DESC, tzkt_id DESC LIMIT $4 OFFSET $5`)).
+		WithArgs(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), minLevel, 10, 0).
+		WillReturnRows(rows)
+
+	delegations, err := repo.ListDelegations(ctx, 10, 0, model.DelegationFilter{Year: &year, MinLevel: &minLevel})
+	assert.NoError(t, err)
+	assert.Len(t, delegations, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	negative := int64(-1)
+	_, err = repo.ListDelegations(ctx, 10, 0, model.DelegationFilter{MinLevel: &negative})
+	assert.True(t, apperrors.IsValidationError(err))
+}
+
+func TestListDelegations_AccountTypeFilter(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	repo := NewDelegationRepository(db, DelegationRepositoryOptions{})
+	ctx := context.Background()
+
+	tests := []struct {
+		accountType string
+		condition   string
+	}{
+		{model.AccountTypeContract, "delegator LIKE 'KT1%'"},
+		{model.AccountTypeImplicit, "delegator NOT LIKE 'KT1%'"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.accountType, func(t *testing.T) {
+			// Composes with the other filters, and adds no bind parameter of its own
+			opType := model.OperationTypeDelegation
+			rows := sqlmock.NewRows([]string{"id", "timestamp", "amount", "delegator", "level", "tzkt_id", "type"}).
+				AddRow(1, fixedTime(), 100, "KT1", 1, 1, "delegation")
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, timestamp, amount, delegator, level, tzkt_id, type FROM delegations WHERE type = $1 AND `+tc.condition+` ORDER BY timestamp DESC, tzkt_id DESC LIMIT $2 OFFSET $3`)).
+				WithArgs(opType, 10, 0).
+				WillReturnRows(rows)
+
+			accountType := tc.accountType
+			delegations, err := repo.ListDelegations(ctx, 10, 0, model.DelegationFilter{Type: &opType, AccountType: &accountType})
+			assert.NoError(t, err)
+			assert.Len(t, delegations, 1)
+		})
+	}
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListDelegations_SinceTzktIDFilter(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	repo := NewDelegationRepository(db, DelegationRepositoryOptions{})
+	ctx := context.Background()
+
+	// Composes with the other filters and switches to ascending tzkt_id order
+	opType := model.OperationTypeDelegation
+	since := int64(0)
+	rows := sqlmock.NewRows([]string{"id", "timestamp", "amount", "delegator", "level", "tzkt_id", "type"}).
+		AddRow(1, fixedTime(), 100, "tz1", 1, 1, "delegation").
+		AddRow(2, fixedTime(), 200, "tz2", 2, 2, "delegation")
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, timestamp, amount, delegator, level, tzkt_id, type FROM delegations WHERE type = $1 AND tzkt_id > $2 ORDER BY tzkt_id ASC LIMIT $3 OFFSET $4`)).
+		WithArgs(opType, since, 10, 0).
+		WillReturnRows(rows)
+
+	delegations, err := repo.ListDelegations(ctx, 10, 0, model.DelegationFilter{Type: &opType, SinceTzktID: &since})
+	assert.NoError(t, err)
+	if assert.Len(t, delegations, 2) {
+		assert.Equal(t, int64(1), delegations[0].TzktID)
+		assert.Equal(t, int64(2), delegations[1].TzktID)
+	}
+
+	// Streaming uses the same order
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, timestamp, amount, delegator, level, tzkt_id, type FROM delegations WHERE tzkt_id > $1 ORDER BY tzkt_id ASC`)).
+		WithArgs(int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "timestamp", "amount", "delegator", "level", "tzkt_id", "type"}))
+	since = 2
+	assert.NoError(t, repo.StreamDelegations(ctx, model.DelegationFilter{SinceTzktID: &since}, func(model.Delegation) error { return nil }))
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	negative := int64(-1)
+	_, err = repo.ListDelegations(ctx, 10, 0, model.DelegationFilter{SinceTzktID: &negative})
+	assert.True(t, apperrors.IsValidationError(err))
+}
+
+func TestListDelegations_ErrorClassification(t *testing.T) {
+	query := regexp.QuoteMeta(`SELECT id, timestamp, amount, delegator, level, tzkt_id, type FROM delegations`)
+
+	t.Run("connection failure is unavailable", func(t *testing.T) {
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+		repo := NewDelegationRepository(db, DelegationRepositoryOptions{})
+
+		mock.ExpectQuery(query).WillReturnError(&net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED})
+		_, err := repo.ListDelegations(context.Background(), 10, 0, model.DelegationFilter{})
+		assert.True(t, apperrors.IsDatabaseUnavailableError(err))
+		assert.True(t, apperrors.IsDatabaseError(err))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("query failure is not unavailable", func(t *testing.T) {
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+		repo := NewDelegationRepository(db, DelegationRepositoryOptions{})
+
+		mock.ExpectQuery(query).WillReturnError(&pq.Error{Code: "42P01", Message: `relation "delegations" does not exist`})
+		_, err := repo.ListDelegations(context.Background(), 10, 0, model.DelegationFilter{})
+		assert.False(t, apperrors.IsDatabaseUnavailableError(err))
+		assert.True(t, apperrors.IsDatabaseError(err))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestIsConnectionError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"conn done", sql.ErrConnDone, true},
+		{"bad conn", fmt.Errorf("query: %w", driver.ErrBadConn), true},
+		{"unexpected eof", io.ErrUnexpectedEOF, true},
+		{"network error", &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}, true},
+		{"connection exception", &pq.Error{Code: "08006"}, true},
+		{"admin shutdown", &pq.Error{Code: "57P01"}, true},
+		{"undefined table", &pq.Error{Code: "42P01"}, false},
+		{"unique violation", &pq.Error{Code: "23505"}, false},
+		{"no rows", sql.ErrNoRows, false},
+		{"other", errors.New("boom"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isConnectionError(tt.err))
+		})
+	}
+}
+
+func TestCountDelegations(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	repo := NewDelegationRepository(db, DelegationRepositoryOptions{})
+	ctx := context.Background()
+
+	t.Run("unfiltered", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM delegations`)).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(7))
+
+		count, err := repo.CountDelegations(ctx, model.DelegationFilter{})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(7), count)
+	})
+
+	t.Run("filtered", func(t *testing.T) {
+		opType := model.OperationTypeDelegation
+		maxID := int64(500)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM delegations WHERE tzkt_id <= $1 AND type = $2`)).
+			WithArgs(maxID, opType).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+		count, err := repo.CountDelegations(ctx, model.DelegationFilter{MaxTzktID: &maxID, Type: &opType})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), count)
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM delegations`)).WillReturnError(sql.ErrConnDone)
+
+		_, err := repo.CountDelegations(ctx, model.DelegationFilter{})
+		assert.True(t, apperrors.IsDatabaseUnavailableError(err))
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestApproximateCount(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	repo := NewDelegationRepository(db, DelegationRepositoryOptions{})
+	ctx := context.Background()
+	query := regexp.QuoteMeta(`SELECT reltuples::bigint FROM pg_class WHERE oid = 'delegations'::regclass`)
+
+	t.Run("estimate", func(t *testing.T) {
+		mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"reltuples"}).AddRow(812345))
+
+		count, err := repo.ApproximateCount(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(812345), count)
+	})
+
+	t.Run("never analyzed", func(t *testing.T) {
+		mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"reltuples"}).AddRow(-1))
+
+		count, err := repo.ApproximateCount(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(-1), count)
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		mock.ExpectQuery(query).WillReturnError(assert.AnError)
+
+		_, err := repo.ApproximateCount(ctx)
+		assert.True(t, apperrors.IsDatabaseError(err))
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStreamDelegations(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	repo := NewDelegationRepository(db, DelegationRepositoryOptions{})
+	ctx := context.Background()
+
+	year := 2022
+	rows := sqlmock.NewRows([]string{"id", "timestamp", "amount", "delegator", "level", "tzkt_id", "type"}).
+		AddRow(2, fixedTime(), 200, "tz2", 2, 2, "delegation").
+		AddRow(1, fixedTime(), 100, "tz1", 1, 1, "delegation")
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, timestamp, amount, delegator, level, tzkt_id, type FROM delegations WHERE timestamp >= $1 AND timestamp < $2 ORDER BY timestamp DESC, tzkt_id DESC`)).
+		WithArgs(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)).
+		WillReturnRows(rows)
+
+	var got []string
+	err := repo.StreamDelegations(ctx, model.DelegationFilter{Year: &year}, func(d model.Delegation) error {
+		got = append(got, d.Delegator)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"tz2", "tz1"}, got)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStreamDelegations_CallbackError(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	repo := NewDelegationRepository(db, DelegationRepositoryOptions{})
+	ctx := context.Background()
+
+	rows := sqlmock.NewRows([]string{"id", "timestamp", "amount", "delegator", "level", "tzkt_id", "type"}).
+		AddRow(2, fixedTime(), 200, "tz2", 2, 2, "delegation").
+		AddRow(1, fixedTime(), 100, "tz1", 1, 1, "delegation")
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, timestamp, amount, delegator, level, tzkt_id, type FROM delegations ORDER BY timestamp DESC, tzkt_id DESC`)).
+		WillReturnRows(rows)
+
+	stop := errors.New("client gone")
+	calls := 0
+	err := repo.StreamDelegations(ctx, model.DelegationFilter{}, func(d model.Delegation) error {
+		calls++
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, calls)
+}
+
+func TestStreamDelegations_ContextCancelled(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	repo := NewDelegationRepository(db, DelegationRepositoryOptions{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rows := sqlmock.NewRows([]string{"id", "timestamp", "amount", "delegator", "level", "tzkt_id", "type"}).
+		AddRow(2, fixedTime(), 200, "tz2", 2, 2, "delegation").
+		AddRow(1, fixedTime(), 100, "tz1", 1, 1, "delegation")
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, timestamp, amount, delegator, level, tzkt_id, type FROM delegations ORDER BY timestamp DESC, tzkt_id DESC`)).
+		WillReturnRows(rows).
+		RowsWillBeClosed()
+
+	calls := 0
+	err := repo.StreamDelegations(ctx, model.DelegationFilter{}, func(d model.Delegation) error {
+		calls++
+		cancel() // client disconnects after the first row
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, calls)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReadRetry(t *testing.T) {
+	query := regexp.QuoteMeta("SELECT COALESCE(MAX(tzkt_id), 0) FROM delegations")
+	connReset := &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+	opts := DelegationRepositoryOptions{ReadRetries: 2, ReadRetryBackoff: time.Millisecond}
+
+	t.Run("succeeds after a transient failure", func(t *testing.T) {
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+		repo := NewDelegationRepository(db, opts)
+
+		mock.ExpectQuery(query).WillReturnError(connReset)
+		mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"tzkt_id"}).AddRow(42))
+
+		id, err := repo.GetLatestTzktID(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, int64(42), id)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("gives up after the configured retries", func(t *testing.T) {
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+		repo := NewDelegationRepository(db, opts)
+
+		for range 3 {
+			mock.ExpectQuery(query).WillReturnError(connReset)
+		}
+
+		_, err := repo.GetLatestTzktID(context.Background())
+		assert.True(t, apperrors.IsDatabaseUnavailableError(err))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("query errors are not retried", func(t *testing.T) {
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+		repo := NewDelegationRepository(db, opts)
+
+		mock.ExpectQuery(query).WillReturnError(&pq.Error{Code: "42P01", Message: "relation does not exist"})
+
+		_, err := repo.GetLatestTzktID(context.Background())
+		assert.True(t, apperrors.IsDatabaseError(err))
+		assert.False(t, apperrors.IsDatabaseUnavailableError(err))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("multi-row read", func(t *testing.T) {
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+		repo := NewDelegationRepository(db, opts)
+
+		list := regexp.QuoteMeta(`SELECT id, timestamp, amount, delegator, level, tzkt_id, type FROM delegations`)
+		mock.ExpectQuery(list).WillReturnError(io.ErrUnexpectedEOF)
+		mock.ExpectQuery(list).WillReturnRows(sqlmock.NewRows([]string{"id", "timestamp", "amount", "delegator", "level", "tzkt_id", "type"}).
+			AddRow(1, fixedTime(), 100, "tz1", 1, 1, "delegation"))
+
+		delegations, err := repo.ListDelegations(context.Background(), 10, 0, model.DelegationFilter{})
+		assert.NoError(t, err)
+		assert.Len(t, delegations, 1)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("writes are not retried", func(t *testing.T) {
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+		repo := NewDelegationRepository(db, opts)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO delegations`)).WillReturnError(connReset)
+		mock.ExpectRollback()
+
+		_, err := repo.InsertDelegations(context.Background(), []*model.Delegation{{TzktID: 1, Timestamp: fixedTime(), Amount: 100, Delegator: "tz1", Level: 1}})
+		assert.True(t, apperrors.IsDatabaseUnavailableError(err))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGetByTzktID(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	repo := NewDelegationRepository(db, DelegationRepositoryOptions{})
+	ctx := context.Background()
+
+	query := regexp.QuoteMeta(`SELECT id, timestamp, amount, delegator, level, tzkt_id, type FROM delegations WHERE tzkt_id = $1`)
+
+	t.Run("found", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"id", "timestamp", "amount", "delegator", "level", "tzkt_id", "type"}).
+			AddRow(1, fixedTime(), 100, "tz1", 1, 42, "delegation")
+		mock.ExpectQuery(query).WithArgs(int64(42)).WillReturnRows(rows)
+
+		delegation, err := repo.GetByTzktID(ctx, 42)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(42), delegation.TzktID)
+		assert.Equal(t, "tz1", delegation.Delegator)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mock.ExpectQuery(query).WithArgs(int64(43)).WillReturnError(sql.ErrNoRows)
+
+		delegation, err := repo.GetByTzktID(ctx, 43)
+		assert.Nil(t, delegation)
+		assert.True(t, apperrors.IsNotFoundError(err))
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		mock.ExpectQuery(query).WithArgs(int64(44)).WillReturnError(sql.ErrConnDone)
+
+		delegation, err := repo.GetByTzktID(ctx, 44)
+		assert.Nil(t, delegation)
+		assert.True(t, apperrors.IsDatabaseError(err))
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteByTzktID(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	repo := NewDelegationRepository(db, DelegationRepositoryOptions{})
+	ctx := context.Background()
+
+	query := regexp.QuoteMeta(`DELETE FROM delegations WHERE tzkt_id = $1`)
+
+	t.Run("deleted", func(t *testing.T) {
+		mock.ExpectExec(query).WithArgs(int64(42)).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		deleted, err := repo.DeleteByTzktID(ctx, 42)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), deleted)
+	})
+
+	t.Run("no match", func(t *testing.T) {
+		mock.ExpectExec(query).WithArgs(int64(43)).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		deleted, err := repo.DeleteByTzktID(ctx, 43)
+		assert.NoError(t, err)
+		assert.Zero(t, deleted)
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		mock.ExpectExec(query).WithArgs(int64(44)).WillReturnError(sql.ErrConnDone)
+
+		_, err := repo.DeleteByTzktID(ctx, 44)
+		assert.True(t, apperrors.IsDatabaseError(err))
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetDelegatorSummary(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	repo := NewDelegationRepository(db, DelegationRepositoryOptions{})
+	ctx := context.Background()
+
+	const address = "tz1a1SAaXRt9yoGMx29rh9FsBF4UzmvojdTL"
+	query := regexp.QuoteMeta(`SELECT MIN(timestamp), MAX(timestamp), COUNT(*), SUM(amount) FROM delegations WHERE delegator = $1 GROUP BY delegator`)
+
+	t.Run("found", func(t *testing.T) {
+		first := fixedTime().AddDate(-1, 0, 0)
+		rows := sqlmock.NewRows([]string{"min", "max", "count", "sum"}).AddRow(first, fixedTime(), 3, 1500)
+		mock.ExpectQuery(query).WithArgs(address).WillReturnRows(rows)
+
+		summary, err := repo.GetDelegatorSummary(ctx, address)
+		assert.NoError(t, err)
+		assert.Equal(t, &model.DelegatorSummary{Delegator: address, FirstSeen: first, LastSeen: fixedTime(), Count: 3, TotalAmount: 1500}, summary)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mock.ExpectQuery(query).WithArgs(address).WillReturnRows(sqlmock.NewRows([]string{"min", "max", "count", "sum"}))
+
+		summary, err := repo.GetDelegatorSummary(ctx, address)
+		assert.Nil(t, summary)
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		mock.ExpectQuery(query).WithArgs(address).WillReturnError(&pq.Error{Code: "42P01"})
+
+		summary, err := repo.GetDelegatorSummary(ctx, address)
+		assert.Nil(t, summary)
+		assert.True(t, apperrors.IsDatabaseError(err))
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCountByTzktIDs(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	repo := NewDelegationRepository(db, DelegationRepositoryOptions{})
+	ctx := context.Background()
+
+	ids := []int64{1, 2, 3}
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM delegations WHERE tzkt_id = ANY($1)")).
+		WithArgs(pq.Array(ids)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+	count, err := repo.CountByTzktIDs(ctx, ids)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	count, err = repo.CountByTzktIDs(ctx, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetByTzktIDs(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	repo := NewDelegationRepository(db, DelegationRepositoryOptions{})
+	ctx := context.Background()
+
+	t.Run("returns the stored ones", func(t *testing.T) {
+		ids := []int64{3, 1, 2}
+		rows := sqlmock.NewRows([]string{"id", "timestamp", "amount", "delegator", "level", "tzkt_id", "type"}).
+			AddRow(10, fixedTime(), 100, "tz1", 1, 1, "delegation").
+			AddRow(12, fixedTime(), 300, "tz3", 3, 3, "delegation")
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, timestamp, amount, delegator, level, tzkt_id, type FROM delegations WHERE tzkt_id = ANY($1) ORDER BY tzkt_id`)).
+			WithArgs(pq.Array(ids)).
+			WillReturnRows(rows)
+
+		delegations, err := repo.GetByTzktIDs(ctx, ids)
+		assert.NoError(t, err)
+		if assert.Len(t, delegations, 2) {
+			assert.Equal(t, int64(1), delegations[0].TzktID)
+			assert.Equal(t, int64(3), delegations[1].TzktID)
+			assert.Equal(t, "tz3", delegations[1].Delegator)
+		}
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		delegations, err := repo.GetByTzktIDs(ctx, nil)
+		assert.NoError(t, err)
+		assert.Empty(t, delegations)
+	})
+
+	t.Run("too many ids", func(t *testing.T) {
+		_, err := repo.GetByTzktIDs(ctx, make([]int64, MaxTzktIDBatch+1))
+		assert.True(t, apperrors.IsValidationError(err))
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAggregateByMonth(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	repo := NewDelegationRepository(db, DelegationRepositoryOptions{})
+	ctx := context.Background()
+
+	rows := sqlmock.NewRows([]string{"month", "count", "coalesce"}).
+		AddRow(2, 3, "1500000").
+		AddRow(11, 1, "250")
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXTRACT(MONTH FROM timestamp AT TIME ZONE 'UTC' AT TIME ZONE $3)::int AS month, COUNT(*), COALESCE(SUM(amount), 0) FROM delegations WHERE timestamp >= $1 AND timestamp < $2 GROUP BY month ORDER BY month`)).
+		WithArgs(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), "UTC").
+		WillReturnRows(rows)
+
+	stats, err := repo.AggregateByMonth(ctx, 2022)
+	assert.NoError(t, err)
+	assert.Equal(t, []model.MonthStats{
+		{Month: 2, Count: 3, TotalAmount: 1500000},
+		{Month: 11, Count: 1, TotalAmount: 250},
+	}, stats)
+
+	_, err = repo.AggregateByMonth(ctx, 2017)
+	assert.True(t, apperrors.IsValidationError(err))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAggregateByYear(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	repo := NewDelegationRepository(db, DelegationRepositoryOptions{})
+	ctx := context.Background()
+
+	query := regexp.QuoteMeta(`SELECT EXTRACT(YEAR FROM timestamp AT TIME ZONE 'UTC' AT TIME ZONE $1)::int AS year, COUNT(*), COALESCE(SUM(amount), 0) FROM delegations GROUP BY year ORDER BY year`)
+
+	t.Run("rows", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"year", "count", "coalesce"}).
+			AddRow(2021, 3, "1500000").
+			AddRow(2022, 2, "250")
+		mock.ExpectQuery(query).WithArgs("UTC").WillReturnRows(rows)
+
+		stats, err := repo.AggregateByYear(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []model.YearStats{
+			{Year: 2021, Count: 3, TotalAmount: 1500000},
+			{Year: 2022, Count: 2, TotalAmount: 250},
+		}, stats)
+	})
+
+	t.Run("empty table", func(t *testing.T) {
+		mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"year", "count", "coalesce"}))
+
+		stats, err := repo.AggregateByYear(ctx)
+		assert.NoError(t, err)
+		assert.NotNil(t, stats)
+		assert.Empty(t, stats)
+	})
+
+	t.Run("query error", func(t *testing.T) {
+		mock.ExpectQuery(query).WillReturnError(sql.ErrConnDone)
+
+		stats, err := repo.AggregateByYear(ctx)
+		assert.Nil(t, stats)
+		assert.True(t, apperrors.IsDatabaseError(err))
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTimezone(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	assert.NoError(t, err)
+	repo := NewDelegationRepository(db, DelegationRepositoryOptions{Location: berlin})
+	ctx := context.Background()
+
+	// Berlin's 2022 runs from 23:00 UTC on New Year's Eve 2021 to 23:00 UTC on New Year's Eve 2022
+	start := time.Date(2021, 12, 31, 23, 0, 0, 0, time.UTC)
+	end := time.Date(2022, 12, 31, 23, 0, 0, 0, time.UTC)
+
+	t.Run("year filter", func(t *testing.T) {
+		year := 2022
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM delegations WHERE timestamp >= $1 AND timestamp < $2`)).
+			WithArgs(start, end).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+		_, err := repo.CountDelegations(ctx, model.DelegationFilter{Year: &year})
+		assert.NoError(t, err)
+	})
+
+	t.Run("stats by year", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(`EXTRACT(YEAR FROM timestamp AT TIME ZONE 'UTC' AT TIME ZONE $1)`)).
+			WithArgs("Europe/Berlin").
+			WillReturnRows(sqlmock.NewRows([]string{"year", "count", "coalesce"}))
+
+		_, err := repo.AggregateByYear(ctx)
+		assert.NoError(t, err)
+	})
+
+	t.Run("stats by month", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(`EXTRACT(MONTH FROM timestamp AT TIME ZONE 'UTC' AT TIME ZONE $3)`)).
+			WithArgs(start, end, "Europe/Berlin").
+			WillReturnRows(sqlmock.NewRows([]string{"month", "count", "coalesce"}))
+
+		_, err := repo.AggregateByMonth(ctx, 2022)
+		assert.NoError(t, err)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAggregateTopDelegators(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	repo := NewDelegationRepository(db, DelegationRepositoryOptions{})
+	ctx := context.Background()
+
+	t.Run("all years", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"delegator", "count", "sum"}).
+			AddRow("tz1", 4, "900").
+			AddRow("tz2", 1, "500")
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT delegator, COUNT(*), SUM(amount) FROM delegations GROUP BY delegator ORDER BY SUM(amount) DESC, delegator LIMIT $1`)).
+			WithArgs(10).
+			WillReturnRows(rows)
+
+		stats, err := repo.AggregateTopDelegators(ctx, 10, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []model.DelegatorStats{
+			{Delegator: "tz1", Count: 4, TotalAmount: 900},
+			{Delegator: "tz2", Count: 1, TotalAmount: 500},
+		}, stats)
+	})
+
+	t.Run("year filter", func(t *testing.T) {
+		year := 2022
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT delegator, COUNT(*), SUM(amount) FROM delegations WHERE timestamp >= $1 AND timestamp < $2 GROUP BY delegator ORDER BY SUM(amount) DESC, delegator LIMIT $3`)).
+			WithArgs(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), 5).
+			WillReturnRows(sqlmock.NewRows([]string{"delegator", "count", "sum"}))
+
+		stats, err := repo.AggregateTopDelegators(ctx, 5, &year)
+		assert.NoError(t, err)
+		assert.NotNil(t, stats)
+		assert.Empty(t, stats)
+	})
+
+	t.Run("invalid limit", func(t *testing.T) {
+		stats, err := repo.AggregateTopDelegators(ctx, 0, nil)
+		assert.Nil(t, stats)
+		assert.True(t, apperrors.IsValidationError(err))
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCountDistinctDelegators(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	repo := NewDelegationRepository(db, DelegationRepositoryOptions{})
+	ctx := context.Background()
+
+	t.Run("all years", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(DISTINCT delegator) FROM delegations`)).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1234))
+
+		count, err := repo.CountDistinctDelegators(ctx, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1234), count)
+	})
+
+	t.Run("year filter", func(t *testing.T) {
+		year := 2022
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(DISTINCT delegator) FROM delegations WHERE timestamp >= $1 AND timestamp < $2`)).
+			WithArgs(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+		count, err := repo.CountDistinctDelegators(ctx, &year)
+		assert.NoError(t, err)
+		assert.Zero(t, count)
+	})
+
+	t.Run("invalid year", func(t *testing.T) {
+		year := 2017
+		_, err := repo.CountDistinctDelegators(ctx, &year)
+		assert.True(t, apperrors.IsValidationError(err))
+	})
+
+	t.Run("query error", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(DISTINCT delegator) FROM delegations`)).WillReturnError(errors.New("boom"))
+
+		_, err := repo.CountDistinctDelegators(ctx, nil)
+		assert.True(t, apperrors.IsDatabaseError(err))
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCheckSchema(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	repo := NewDelegationRepository(db, DelegationRepositoryOptions{})
+	ctx := context.Background()
+
+	query := regexp.QuoteMeta(`SELECT 1 FROM delegations LIMIT 1`)
+
+	t.Run("table with rows", func(t *testing.T) {
+		mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"?column?"}).AddRow(1))
+		assert.NoError(t, repo.CheckSchema(ctx))
+	})
+
+	t.Run("empty table", func(t *testing.T) {
+		mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"?column?"}))
+		assert.NoError(t, repo.CheckSchema(ctx))
+	})
+
+	t.Run("table missing", func(t *testing.T) {
+		mock.ExpectQuery(query).WillReturnError(&pq.Error{Code: "42P01", Message: `relation "delegations" does not exist`})
+		err := repo.CheckSchema(ctx)
+		assert.True(t, apperrors.IsDatabaseError(err))
+		assert.False(t, apperrors.IsDatabaseUnavailableError(err))
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAnalyzeDelegations(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	repo := NewDelegationRepository(db, DelegationRepositoryOptions{})
+	ctx := context.Background()
+
+	t.Run("analyze", func(t *testing.T) {
+		mock.ExpectExec(`^ANALYZE delegations$`).WillReturnResult(sqlmock.NewResult(0, 0))
+		assert.NoError(t, repo.AnalyzeDelegations(ctx, false))
+	})
+
+	t.Run("vacuum", func(t *testing.T) {
+		mock.ExpectExec(`^VACUUM ANALYZE delegations$`).WillReturnResult(sqlmock.NewResult(0, 0))
+		assert.NoError(t, repo.AnalyzeDelegations(ctx, true))
+	})
+
+	t.Run("error", func(t *testing.T) {
+		mock.ExpectExec(`^ANALYZE delegations$`).WillReturnError(errors.New("permission denied"))
+		assert.True(t, apperrors.IsDatabaseError(repo.AnalyzeDelegations(ctx, false)))
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetSyncState(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	repo := NewDelegationRepository(db, DelegationRepositoryOptions{})
+	ctx := context.Background()
+
+	query := regexp.QuoteMeta(`SELECT last_tzkt_id, last_poll_at, historical_complete FROM sync_state WHERE id = 1`)
+
+	t.Run("existing state", func(t *testing.T) {
+		mock.ExpectQuery(query).
+			WillReturnRows(sqlmock.NewRows([]string{"last_tzkt_id", "last_poll_at", "historical_complete"}).AddRow(42, fixedTime(), true))
+
+		state, err := repo.GetSyncState(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(42), state.LastTzktID)
+		assert.Equal(t, fixedTime(), state.LastPollAt)
+		assert.True(t, state.HistoricalComplete)
+	})
+
+	t.Run("no state recorded", func(t *testing.T) {
+		mock.ExpectQuery(query).WillReturnError(sql.ErrNoRows)
+
+		state, err := repo.GetSyncState(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, &model.SyncState{}, state)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateSyncState(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	repo := NewDelegationRepository(db, DelegationRepositoryOptions{})
+	ctx := context.Background()
+
+	state := model.SyncState{LastTzktID: 42, LastPollAt: fixedTime(), HistoricalComplete: true}
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO sync_state (id, last_tzkt_id, last_poll_at, historical_complete) VALUES (1, $1, $2, $3) ON CONFLICT (id) DO UPDATE`)).
+		WithArgs(state.LastTzktID, state.LastPollAt, state.HistoricalComplete).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := repo.UpdateSyncState(ctx, state)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPollLock(t *testing.T) {
+	t.Run("acquired, then held until released", func(t *testing.T) {
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+		repo := NewDelegationRepository(db, DelegationRepositoryOptions{})
+		ctx := context.Background()
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT pg_try_advisory_lock($1)`)).WithArgs(pollLockKey).
+			WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_lock"}).AddRow(true))
+		mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_unlock($1)`)).WithArgs(pollLockKey).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		held, err := repo.TryAcquirePollLock(ctx)
+		assert.NoError(t, err)
+		assert.True(t, held)
+
+		// Already held: only the session is checked, the lock isn't requested again
+		held, err = repo.TryAcquirePollLock(ctx)
+		assert.NoError(t, err)
+		assert.True(t, held)
+
+		assert.NoError(t, repo.ReleasePollLock(ctx))
+		assert.NoError(t, repo.ReleasePollLock(ctx))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("held by another instance", func(t *testing.T) {
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+		repo := NewDelegationRepository(db, DelegationRepositoryOptions{})
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT pg_try_advisory_lock($1)`)).WithArgs(pollLockKey).
+			WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_lock"}).AddRow(false))
+
+		held, err := repo.TryAcquirePollLock(context.Background())
+		assert.NoError(t, err)
+		assert.False(t, held)
+		// Nothing to release
+		assert.NoError(t, repo.ReleasePollLock(context.Background()))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("query fails", func(t *testing.T) {
+		db, mock, cleanup := setupMockDB(t)
+		defer cleanup()
+		repo := NewDelegationRepository(db, DelegationRepositoryOptions{})
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT pg_try_advisory_lock($1)`)).WillReturnError(errors.New("boom"))
+
+		held, err := repo.TryAcquirePollLock(context.Background())
+		assert.False(t, held)
+		assert.True(t, apperrors.IsDatabaseError(err))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestSlowQueryLogging(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	var logs bytes.Buffer
+	repo := NewDelegationRepository(db, DelegationRepositoryOptions{
+		SlowQueryThreshold: 20 * time.Millisecond,
+		Logger:             zerolog.New(&logs),
+	})
+	ctx := context.Background()
+	slowQueries := metrics.DBSlowQueries.WithLabelValues("CountByTzktIDs")
+
+	t.Run("slow query", func(t *testing.T) {
+		logs.Reset()
+		before := testutil.ToFloat64(slowQueries)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM delegations WHERE tzkt_id = ANY($1)`)).
+			WillDelayFor(50 * time.Millisecond).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+		_, err := repo.CountByTzktIDs(ctx, []int64{1, 2})
+		assert.NoError(t, err)
+		assert.Equal(t, before+1, testutil.ToFloat64(slowQueries))
+		assert.Contains(t, logs.String(), `"level":"warn"`)
+		assert.Contains(t, logs.String(), `"query":"CountByTzktIDs"`)
+		assert.Contains(t, logs.String(), `"message":"Slow query"`)
+		// The ID list is summarized rather than logged in full
+		assert.Contains(t, logs.String(), `"args":["[2 values]"]`)
+	})
+
+	t.Run("fast query", func(t *testing.T) {
+		logs.Reset()
+		before := testutil.ToFloat64(slowQueries)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM delegations WHERE tzkt_id = ANY($1)`)).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+		_, err := repo.CountByTzktIDs(ctx, []int64{1, 2})
+		assert.NoError(t, err)
+		assert.Equal(t, before, testutil.ToFloat64(slowQueries))
+		assert.Empty(t, logs.String())
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSanitizeArgs(t *testing.T) {
+	long := make([]byte, 100)
+	for i := range long {
+		long[i] = 'x'
+	}
+	args := []interface{}{int64(42), fixedTime(), pq.Array([]int64{1, 2, 3}), string(long)}
+	assert.Equal(t, []string{"42", "2022-05-05T06:29:14Z", "[3 values]", string(long[:maxLoggedArgLen]) + "..."}, sanitizeArgs(args))
+}
+
 // fixedTime returns a constant time.Time for use in tests
 func fixedTime() time.Time {
 	return time.Date(2022, 5, 5, 6, 29, 14, 0, time.UTC)
@@ -0,0 +1,137 @@
+package db
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"tezos-delegation/internal/apperrors"
+	"tezos-delegation/internal/ports"
+)
+
+// migrationsFS holds the SQL migrations applied by Migrate, named NNNN_description.sql
+//
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// migrationLockID is the advisory lock key serializing migrations across service instances
+const migrationLockID = 7283410051
+
+// migration is a single versioned schema change
+type migration struct {
+	Version int64
+	Name    string
+	SQL     string
+}
+
+// Migrate applies any pending embedded schema migrations in version order.
+// Each migration runs in its own transaction together with its schema_migrations record,
+// so a failed migration leaves the schema at the previous version.
+// Returns the number of migrations applied.
+func Migrate(ctx context.Context, db ports.DatabasePort) (int, error) {
+	migrations, err := loadMigrations(migrationsFS)
+	if err != nil {
+		return 0, err
+	}
+	return runMigrations(ctx, db, migrations)
+}
+
+// loadMigrations reads and orders the *.sql migrations in the migrations directory of fsys
+func loadMigrations(fsys fs.FS) ([]migration, error) {
+	files, err := fs.Glob(fsys, "migrations/*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+
+	migrations := make([]migration, 0, len(files))
+	seen := make(map[int64]string)
+	for _, file := range files {
+		name := strings.TrimSuffix(path.Base(file), ".sql")
+		prefix, _, ok := strings.Cut(name, "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if !ok || err != nil || version < 1 {
+			return nil, fmt.Errorf("invalid migration file name %q: must be NNNN_description.sql", file)
+		}
+		if other, dup := seen[version]; dup {
+			return nil, fmt.Errorf("duplicate migration version %d: %s and %s", version, other, file)
+		}
+		seen[version] = file
+
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", file, err)
+		}
+		migrations = append(migrations, migration{Version: version, Name: name, SQL: string(content)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// runMigrations applies the migrations that aren't recorded in schema_migrations yet
+func runMigrations(ctx context.Context, db ports.DatabasePort, migrations []migration) (int, error) {
+	const createTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT (NOW() AT TIME ZONE 'UTC')
+	)`
+	if _, err := db.ExecContext(ctx, createTable); err != nil {
+		return 0, apperrors.NewDatabaseErrorWithCause("create schema_migrations", "failed to create schema_migrations table", err)
+	}
+
+	applied := 0
+	for _, m := range migrations {
+		ok, err := applyMigration(ctx, db, m)
+		if err != nil {
+			return applied, err
+		}
+		if ok {
+			applied++
+		}
+	}
+	return applied, nil
+}
+
+// applyMigration runs a single migration unless it has already been applied.
+// Returns whether the migration was applied by this call.
+func applyMigration(ctx context.Context, db ports.DatabasePort, m migration) (applied bool, err error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, apperrors.NewDatabaseErrorWithCause("begin transaction", "failed to begin migration transaction", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	// Serialize with other instances migrating at the same time; released on commit or rollback
+	if _, err = tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, migrationLockID); err != nil {
+		return false, apperrors.NewDatabaseErrorWithCause("lock migrations", "failed to acquire migration lock", err)
+	}
+
+	var exists bool
+	if err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`, m.Version).Scan(&exists); err != nil {
+		return false, apperrors.NewDatabaseErrorWithCause("check migration", fmt.Sprintf("failed to check migration %s", m.Name), err)
+	}
+	if exists {
+		err = tx.Commit()
+		return false, err
+	}
+
+	if _, err = tx.ExecContext(ctx, m.SQL); err != nil {
+		return false, apperrors.NewDatabaseErrorWithCause("apply migration", fmt.Sprintf("failed to apply migration %s", m.Name), err)
+	}
+	if _, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, m.Version, m.Name); err != nil {
+		return false, apperrors.NewDatabaseErrorWithCause("record migration", fmt.Sprintf("failed to record migration %s", m.Name), err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return false, apperrors.NewDatabaseErrorWithCause("commit transaction", fmt.Sprintf("failed to commit migration %s", m.Name), err)
+	}
+	return true, nil
+}
@@ -0,0 +1,111 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"testing/fstest"
+
+	"tezos-delegation/internal/apperrors"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	t.Run("embedded", func(t *testing.T) {
+		migrations, err := loadMigrations(migrationsFS)
+		assert.NoError(t, err)
+		assert.GreaterOrEqual(t, len(migrations), 2)
+		assert.Equal(t, int64(1), migrations[0].Version)
+		assert.Equal(t, "0001_create_delegations", migrations[0].Name)
+		assert.Contains(t, migrations[0].SQL, "CREATE TABLE IF NOT EXISTS delegations")
+		for i := 1; i < len(migrations); i++ {
+			assert.Greater(t, migrations[i].Version, migrations[i-1].Version)
+		}
+	})
+
+	t.Run("ordered by version", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"migrations/0010_later.sql":  {Data: []byte("SELECT 10")},
+			"migrations/0002_second.sql": {Data: []byte("SELECT 2")},
+		}
+		migrations, err := loadMigrations(fsys)
+		assert.NoError(t, err)
+		assert.Equal(t, []migration{
+			{Version: 2, Name: "0002_second", SQL: "SELECT 2"},
+			{Version: 10, Name: "0010_later", SQL: "SELECT 10"},
+		}, migrations)
+	})
+
+	t.Run("invalid name", func(t *testing.T) {
+		_, err := loadMigrations(fstest.MapFS{"migrations/create.sql": {Data: []byte("SELECT 1")}})
+		assert.Error(t, err)
+	})
+
+	t.Run("duplicate version", func(t *testing.T) {
+		_, err := loadMigrations(fstest.MapFS{
+			"migrations/0001_a.sql": {Data: []byte("SELECT 1")},
+			"migrations/0001_b.sql": {Data: []byte("SELECT 1")},
+		})
+		assert.Error(t, err)
+	})
+}
+
+func TestRunMigrations(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	migrations := []migration{
+		{Version: 1, Name: "0001_first", SQL: "CREATE TABLE first (id INT)"},
+		{Version: 2, Name: "0002_second", SQL: "CREATE TABLE second (id INT)"},
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta(`CREATE TABLE IF NOT EXISTS schema_migrations`)).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// First migration is already applied
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_xact_lock($1)`)).WithArgs(migrationLockID).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`)).WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectCommit()
+
+	// Second migration is applied and recorded
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_xact_lock($1)`)).WithArgs(migrationLockID).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`)).WithArgs(int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectExec(regexp.QuoteMeta(`CREATE TABLE second (id INT)`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`)).WithArgs(int64(2), "0002_second").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	applied, err := runMigrations(ctx, db, migrations)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, applied)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRunMigrations_RollbackOnError(t *testing.T) {
+	db, mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	migrations := []migration{{Version: 1, Name: "0001_broken", SQL: "CREATE TABLE broken ("}}
+
+	mock.ExpectExec(regexp.QuoteMeta(`CREATE TABLE IF NOT EXISTS schema_migrations`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_xact_lock($1)`)).WithArgs(migrationLockID).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS`)).WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectExec(regexp.QuoteMeta(`CREATE TABLE broken (`)).WillReturnError(sql.ErrConnDone)
+	mock.ExpectRollback()
+
+	applied, err := runMigrations(ctx, db, migrations)
+	assert.Equal(t, 0, applied)
+	assert.True(t, apperrors.IsDatabaseError(err))
+	assert.Contains(t, err.Error(), "0001_broken")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
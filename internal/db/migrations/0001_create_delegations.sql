@@ -0,0 +1,25 @@
+-- Delegation operations synced from the Tzkt API
+CREATE TABLE IF NOT EXISTS delegations (
+    id SERIAL PRIMARY KEY,              -- Surrogate primary key for internal use
+    tzkt_id BIGINT UNIQUE NOT NULL,     -- Unique identifier from the Tzkt API to prevent duplicates
+    timestamp TIMESTAMP NOT NULL,       -- UTC timestamp of the delegation operation
+    amount BIGINT NOT NULL,             -- Amount delegated (in mutez, 1 tez = 1,000,000 mutez)
+    delegator TEXT NOT NULL,            -- Sender's (delegator's) address
+    level BIGINT NOT NULL               -- Block height of the delegation
+);
+
+-- Constraints for data integrity; guarded so databases created from schema.sql migrate cleanly
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'chk_amount_non_negative') THEN
+        ALTER TABLE delegations ADD CONSTRAINT chk_amount_non_negative CHECK (amount >= 0);
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'chk_level_non_negative') THEN
+        ALTER TABLE delegations ADD CONSTRAINT chk_level_non_negative CHECK (level >= 0);
+    END IF;
+END
+$$;
+
+-- Indexes for paging and year filtering
+CREATE INDEX IF NOT EXISTS idx_timestamp_tzkt_id_desc ON delegations (timestamp DESC, tzkt_id DESC);
+CREATE INDEX IF NOT EXISTS idx_year_timestamp_tzkt_id_desc ON delegations (EXTRACT(YEAR FROM timestamp), timestamp DESC, tzkt_id DESC);
@@ -0,0 +1,7 @@
+-- Poller sync state (single row) so restarts can resume and report progress
+CREATE TABLE IF NOT EXISTS sync_state (
+    id SMALLINT PRIMARY KEY DEFAULT 1 CHECK (id = 1),   -- Enforces a single row
+    last_tzkt_id BIGINT NOT NULL DEFAULT 0,             -- Highest Tzkt ID processed by the poller
+    last_poll_at TIMESTAMP,                             -- UTC time of the last successful batch
+    historical_complete BOOLEAN NOT NULL DEFAULT FALSE  -- Whether the initial historical sync has finished
+);
@@ -0,0 +1,4 @@
+-- The year filter is a half-open timestamp range, served by the (timestamp, tzkt_id) index;
+-- the EXTRACT(YEAR ...) expression index no longer matches any query
+CREATE INDEX IF NOT EXISTS idx_timestamp_tzkt_id_desc ON delegations (timestamp DESC, tzkt_id DESC);
+DROP INDEX IF EXISTS idx_year_timestamp_tzkt_id_desc;
@@ -0,0 +1,3 @@
+-- Operation type, so originations that set a delegate can be stored alongside delegations
+ALTER TABLE delegations ADD COLUMN IF NOT EXISTS type TEXT NOT NULL DEFAULT 'delegation';
+CREATE INDEX IF NOT EXISTS idx_type_timestamp_tzkt_id_desc ON delegations (type, timestamp DESC, tzkt_id DESC);
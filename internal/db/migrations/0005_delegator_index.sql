@@ -0,0 +1,3 @@
+-- Per-delegator lookups and distinct delegator counts; timestamp is included so year-filtered
+-- counts and delegator summaries can be answered from the index alone
+CREATE INDEX IF NOT EXISTS idx_delegator_timestamp ON delegations (delegator, timestamp);
@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Poller metrics
+var (
+	// PollerDuplicateSkips counts fetched delegations that were not inserted because they already existed
+	PollerDuplicateSkips = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "poller_duplicate_skips",
+		Help: "Number of fetched delegations skipped on insert because their tzkt_id was already stored.",
+	})
+
+	// PollerEmptyDelegatorsSkipped counts fetched operations dropped because they had no delegator address
+	PollerEmptyDelegatorsSkipped = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "poller_empty_delegators_skipped",
+		Help: "Number of fetched operations not stored because Tzkt returned them without a delegator address.",
+	})
+
+	// PollerReorgsSuspected counts fetched operations whose level or timestamp differs from the stored row
+	PollerReorgsSuspected = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "poller_reorgs_suspected",
+		Help: "Number of fetched operations already stored with a different level or timestamp, suggesting a chain reorg.",
+	})
+
+	// PollerInsertVerificationFailures counts delegations missing on read-back after a successful insert
+	PollerInsertVerificationFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "poller_insert_verification_failures",
+		Help: "Number of inserted delegations that were missing when read back for verification.",
+	})
+
+	// PollerInsertFailures counts delegations skipped because they failed to insert in best-effort mode
+	PollerInsertFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "poller_insert_failures",
+		Help: "Number of delegations skipped by a best-effort backfill because they failed to insert.",
+	})
+
+	// PollerTzktCircuitState reports the Tzkt circuit breaker state: 0 closed, 1 half-open, 2 open
+	PollerTzktCircuitState = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "poller_tzkt_circuit_state",
+		Help: "State of the circuit breaker around Tzkt calls: 0 closed, 1 half-open, 2 open.",
+	})
+
+	// PollerLastTzktID reports the highest Tzkt operation ID the poller has stored
+	PollerLastTzktID = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "poller_last_tzkt_id",
+		Help: "Highest Tzkt operation ID stored by the poller.",
+	})
+
+	// PollerHistoricalBatches counts batches stored during the historical sync
+	PollerHistoricalBatches = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "poller_historical_batches",
+		Help: "Number of batches stored during the historical sync.",
+	})
+
+	// PollerHistoricalInserted counts delegations inserted during the historical sync
+	PollerHistoricalInserted = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "poller_historical_delegations_inserted",
+		Help: "Number of delegations inserted during the historical sync.",
+	})
+
+	// PollerHistoricalComplete is 1 once the historical sync has finished, including in an earlier run
+	PollerHistoricalComplete = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "poller_historical_sync_complete",
+		Help: "Whether the historical sync has finished: 1 complete, 0 in progress.",
+	})
+
+	// PollerHistoricalDuration reports how long this process took to finish the historical sync
+	PollerHistoricalDuration = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "poller_historical_sync_duration_seconds",
+		Help: "Time this process spent on the historical sync before catching up; 0 if it had already finished in an earlier run.",
+	})
+
+	// PollerTzktCircuitTrips counts how often the Tzkt circuit breaker opened
+	PollerTzktCircuitTrips = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "poller_tzkt_circuit_trips",
+		Help: "Number of times the circuit breaker around Tzkt calls opened after repeated failures.",
+	})
+)
+
+// Database metrics
+var (
+	// DBSlowQueries counts read queries slower than SLOW_QUERY_THRESHOLD, by repository method
+	DBSlowQueries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "slow_queries_total",
+		Help: "Number of read queries that took longer than SLOW_QUERY_THRESHOLD.",
+	}, []string{"query"})
+)
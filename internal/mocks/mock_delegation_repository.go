@@ -35,6 +35,199 @@ func (m *MockDelegationRepositoryPort) EXPECT() *MockDelegationRepositoryPortMoc
 	return m.recorder
 }
 
+// AggregateByMonth mocks base method.
+func (m *MockDelegationRepositoryPort) AggregateByMonth(arg0 context.Context, arg1 int) ([]model.MonthStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AggregateByMonth", arg0, arg1)
+	ret0, _ := ret[0].([]model.MonthStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AggregateByMonth indicates an expected call of AggregateByMonth.
+func (mr *MockDelegationRepositoryPortMockRecorder) AggregateByMonth(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AggregateByMonth", reflect.TypeOf((*MockDelegationRepositoryPort)(nil).AggregateByMonth), arg0, arg1)
+}
+
+// AggregateByYear mocks base method.
+func (m *MockDelegationRepositoryPort) AggregateByYear(arg0 context.Context) ([]model.YearStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AggregateByYear", arg0)
+	ret0, _ := ret[0].([]model.YearStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AggregateByYear indicates an expected call of AggregateByYear.
+func (mr *MockDelegationRepositoryPortMockRecorder) AggregateByYear(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AggregateByYear", reflect.TypeOf((*MockDelegationRepositoryPort)(nil).AggregateByYear), arg0)
+}
+
+// AggregateTopDelegators mocks base method.
+func (m *MockDelegationRepositoryPort) AggregateTopDelegators(arg0 context.Context, arg1 int, arg2 *int) ([]model.DelegatorStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AggregateTopDelegators", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]model.DelegatorStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AggregateTopDelegators indicates an expected call of AggregateTopDelegators.
+func (mr *MockDelegationRepositoryPortMockRecorder) AggregateTopDelegators(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AggregateTopDelegators", reflect.TypeOf((*MockDelegationRepositoryPort)(nil).AggregateTopDelegators), arg0, arg1, arg2)
+}
+
+// AnalyzeDelegations mocks base method.
+func (m *MockDelegationRepositoryPort) AnalyzeDelegations(arg0 context.Context, arg1 bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AnalyzeDelegations", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AnalyzeDelegations indicates an expected call of AnalyzeDelegations.
+func (mr *MockDelegationRepositoryPortMockRecorder) AnalyzeDelegations(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AnalyzeDelegations", reflect.TypeOf((*MockDelegationRepositoryPort)(nil).AnalyzeDelegations), arg0, arg1)
+}
+
+// ApproximateCount mocks base method.
+func (m *MockDelegationRepositoryPort) ApproximateCount(arg0 context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApproximateCount", arg0)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ApproximateCount indicates an expected call of ApproximateCount.
+func (mr *MockDelegationRepositoryPortMockRecorder) ApproximateCount(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApproximateCount", reflect.TypeOf((*MockDelegationRepositoryPort)(nil).ApproximateCount), arg0)
+}
+
+// CheckSchema mocks base method.
+func (m *MockDelegationRepositoryPort) CheckSchema(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckSchema", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckSchema indicates an expected call of CheckSchema.
+func (mr *MockDelegationRepositoryPortMockRecorder) CheckSchema(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckSchema", reflect.TypeOf((*MockDelegationRepositoryPort)(nil).CheckSchema), arg0)
+}
+
+// CountByTzktIDs mocks base method.
+func (m *MockDelegationRepositoryPort) CountByTzktIDs(arg0 context.Context, arg1 []int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountByTzktIDs", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountByTzktIDs indicates an expected call of CountByTzktIDs.
+func (mr *MockDelegationRepositoryPortMockRecorder) CountByTzktIDs(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountByTzktIDs", reflect.TypeOf((*MockDelegationRepositoryPort)(nil).CountByTzktIDs), arg0, arg1)
+}
+
+// CountDelegations mocks base method.
+func (m *MockDelegationRepositoryPort) CountDelegations(arg0 context.Context, arg1 model.DelegationFilter) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountDelegations", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountDelegations indicates an expected call of CountDelegations.
+func (mr *MockDelegationRepositoryPortMockRecorder) CountDelegations(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountDelegations", reflect.TypeOf((*MockDelegationRepositoryPort)(nil).CountDelegations), arg0, arg1)
+}
+
+// CountDistinctDelegators mocks base method.
+func (m *MockDelegationRepositoryPort) CountDistinctDelegators(arg0 context.Context, arg1 *int) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountDistinctDelegators", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountDistinctDelegators indicates an expected call of CountDistinctDelegators.
+func (mr *MockDelegationRepositoryPortMockRecorder) CountDistinctDelegators(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountDistinctDelegators", reflect.TypeOf((*MockDelegationRepositoryPort)(nil).CountDistinctDelegators), arg0, arg1)
+}
+
+// DeleteByTzktID mocks base method.
+func (m *MockDelegationRepositoryPort) DeleteByTzktID(arg0 context.Context, arg1 int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteByTzktID", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteByTzktID indicates an expected call of DeleteByTzktID.
+func (mr *MockDelegationRepositoryPortMockRecorder) DeleteByTzktID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteByTzktID", reflect.TypeOf((*MockDelegationRepositoryPort)(nil).DeleteByTzktID), arg0, arg1)
+}
+
+// GetByTzktID mocks base method.
+func (m *MockDelegationRepositoryPort) GetByTzktID(arg0 context.Context, arg1 int64) (*model.Delegation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByTzktID", arg0, arg1)
+	ret0, _ := ret[0].(*model.Delegation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByTzktID indicates an expected call of GetByTzktID.
+func (mr *MockDelegationRepositoryPortMockRecorder) GetByTzktID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByTzktID", reflect.TypeOf((*MockDelegationRepositoryPort)(nil).GetByTzktID), arg0, arg1)
+}
+
+// GetByTzktIDs mocks base method.
+func (m *MockDelegationRepositoryPort) GetByTzktIDs(arg0 context.Context, arg1 []int64) ([]model.Delegation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByTzktIDs", arg0, arg1)
+	ret0, _ := ret[0].([]model.Delegation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByTzktIDs indicates an expected call of GetByTzktIDs.
+func (mr *MockDelegationRepositoryPortMockRecorder) GetByTzktIDs(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByTzktIDs", reflect.TypeOf((*MockDelegationRepositoryPort)(nil).GetByTzktIDs), arg0, arg1)
+}
+
+// GetDelegatorSummary mocks base method.
+func (m *MockDelegationRepositoryPort) GetDelegatorSummary(arg0 context.Context, arg1 string) (*model.DelegatorSummary, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDelegatorSummary", arg0, arg1)
+	ret0, _ := ret[0].(*model.DelegatorSummary)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDelegatorSummary indicates an expected call of GetDelegatorSummary.
+func (mr *MockDelegationRepositoryPortMockRecorder) GetDelegatorSummary(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDelegatorSummary", reflect.TypeOf((*MockDelegationRepositoryPort)(nil).GetDelegatorSummary), arg0, arg1)
+}
+
 // GetLatestTzktID mocks base method.
 func (m *MockDelegationRepositoryPort) GetLatestTzktID(arg0 context.Context) (int64, error) {
 	m.ctrl.T.Helper()
@@ -50,22 +243,54 @@ func (mr *MockDelegationRepositoryPortMockRecorder) GetLatestTzktID(arg0 interfa
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLatestTzktID", reflect.TypeOf((*MockDelegationRepositoryPort)(nil).GetLatestTzktID), arg0)
 }
 
+// GetSyncState mocks base method.
+func (m *MockDelegationRepositoryPort) GetSyncState(arg0 context.Context) (*model.SyncState, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSyncState", arg0)
+	ret0, _ := ret[0].(*model.SyncState)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSyncState indicates an expected call of GetSyncState.
+func (mr *MockDelegationRepositoryPortMockRecorder) GetSyncState(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSyncState", reflect.TypeOf((*MockDelegationRepositoryPort)(nil).GetSyncState), arg0)
+}
+
 // InsertDelegations mocks base method.
-func (m *MockDelegationRepositoryPort) InsertDelegations(arg0 []*model.Delegation) error {
+func (m *MockDelegationRepositoryPort) InsertDelegations(arg0 context.Context, arg1 []*model.Delegation) (int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "InsertDelegations", arg0)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "InsertDelegations", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // InsertDelegations indicates an expected call of InsertDelegations.
-func (mr *MockDelegationRepositoryPortMockRecorder) InsertDelegations(arg0 interface{}) *gomock.Call {
+func (mr *MockDelegationRepositoryPortMockRecorder) InsertDelegations(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertDelegations", reflect.TypeOf((*MockDelegationRepositoryPort)(nil).InsertDelegations), arg0, arg1)
+}
+
+// InsertDelegationsBestEffort mocks base method.
+func (m *MockDelegationRepositoryPort) InsertDelegationsBestEffort(arg0 context.Context, arg1 []*model.Delegation) (int64, []error, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertDelegationsBestEffort", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].([]error)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// InsertDelegationsBestEffort indicates an expected call of InsertDelegationsBestEffort.
+func (mr *MockDelegationRepositoryPortMockRecorder) InsertDelegationsBestEffort(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertDelegations", reflect.TypeOf((*MockDelegationRepositoryPort)(nil).InsertDelegations), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertDelegationsBestEffort", reflect.TypeOf((*MockDelegationRepositoryPort)(nil).InsertDelegationsBestEffort), arg0, arg1)
 }
 
 // ListDelegations mocks base method.
-func (m *MockDelegationRepositoryPort) ListDelegations(arg0 context.Context, arg1, arg2 int, arg3 *int) ([]model.Delegation, error) {
+func (m *MockDelegationRepositoryPort) ListDelegations(arg0 context.Context, arg1, arg2 int, arg3 model.DelegationFilter) ([]model.Delegation, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "ListDelegations", arg0, arg1, arg2, arg3)
 	ret0, _ := ret[0].([]model.Delegation)
@@ -78,3 +303,60 @@ func (mr *MockDelegationRepositoryPortMockRecorder) ListDelegations(arg0, arg1,
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDelegations", reflect.TypeOf((*MockDelegationRepositoryPort)(nil).ListDelegations), arg0, arg1, arg2, arg3)
 }
+
+// ReleasePollLock mocks base method.
+func (m *MockDelegationRepositoryPort) ReleasePollLock(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleasePollLock", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleasePollLock indicates an expected call of ReleasePollLock.
+func (mr *MockDelegationRepositoryPortMockRecorder) ReleasePollLock(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleasePollLock", reflect.TypeOf((*MockDelegationRepositoryPort)(nil).ReleasePollLock), arg0)
+}
+
+// StreamDelegations mocks base method.
+func (m *MockDelegationRepositoryPort) StreamDelegations(arg0 context.Context, arg1 model.DelegationFilter, arg2 func(model.Delegation) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StreamDelegations", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StreamDelegations indicates an expected call of StreamDelegations.
+func (mr *MockDelegationRepositoryPortMockRecorder) StreamDelegations(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamDelegations", reflect.TypeOf((*MockDelegationRepositoryPort)(nil).StreamDelegations), arg0, arg1, arg2)
+}
+
+// TryAcquirePollLock mocks base method.
+func (m *MockDelegationRepositoryPort) TryAcquirePollLock(arg0 context.Context) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TryAcquirePollLock", arg0)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TryAcquirePollLock indicates an expected call of TryAcquirePollLock.
+func (mr *MockDelegationRepositoryPortMockRecorder) TryAcquirePollLock(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TryAcquirePollLock", reflect.TypeOf((*MockDelegationRepositoryPort)(nil).TryAcquirePollLock), arg0)
+}
+
+// UpdateSyncState mocks base method.
+func (m *MockDelegationRepositoryPort) UpdateSyncState(arg0 context.Context, arg1 model.SyncState) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSyncState", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateSyncState indicates an expected call of UpdateSyncState.
+func (mr *MockDelegationRepositoryPortMockRecorder) UpdateSyncState(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSyncState", reflect.TypeOf((*MockDelegationRepositoryPort)(nil).UpdateSyncState), arg0, arg1)
+}
@@ -35,13 +35,89 @@ func (m *MockDelegationServicePort) EXPECT() *MockDelegationServicePortMockRecor
 	return m.recorder
 }
 
+// ApproximateCountDelegations mocks base method.
+func (m *MockDelegationServicePort) ApproximateCountDelegations(arg0 context.Context, arg1 model.DelegationFilter) (int64, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApproximateCountDelegations", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ApproximateCountDelegations indicates an expected call of ApproximateCountDelegations.
+func (mr *MockDelegationServicePortMockRecorder) ApproximateCountDelegations(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApproximateCountDelegations", reflect.TypeOf((*MockDelegationServicePort)(nil).ApproximateCountDelegations), arg0, arg1)
+}
+
+// CountDelegations mocks base method.
+func (m *MockDelegationServicePort) CountDelegations(arg0 context.Context, arg1 model.DelegationFilter) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountDelegations", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountDelegations indicates an expected call of CountDelegations.
+func (mr *MockDelegationServicePortMockRecorder) CountDelegations(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountDelegations", reflect.TypeOf((*MockDelegationServicePort)(nil).CountDelegations), arg0, arg1)
+}
+
+// CountDistinctDelegators mocks base method.
+func (m *MockDelegationServicePort) CountDistinctDelegators(arg0 context.Context, arg1 *int) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountDistinctDelegators", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountDistinctDelegators indicates an expected call of CountDistinctDelegators.
+func (mr *MockDelegationServicePortMockRecorder) CountDistinctDelegators(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountDistinctDelegators", reflect.TypeOf((*MockDelegationServicePort)(nil).CountDistinctDelegators), arg0, arg1)
+}
+
+// DeleteDelegationByTzktID mocks base method.
+func (m *MockDelegationServicePort) DeleteDelegationByTzktID(arg0 context.Context, arg1 int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteDelegationByTzktID", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteDelegationByTzktID indicates an expected call of DeleteDelegationByTzktID.
+func (mr *MockDelegationServicePortMockRecorder) DeleteDelegationByTzktID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteDelegationByTzktID", reflect.TypeOf((*MockDelegationServicePort)(nil).DeleteDelegationByTzktID), arg0, arg1)
+}
+
+// GetDelegationByTzktID mocks base method.
+func (m *MockDelegationServicePort) GetDelegationByTzktID(arg0 context.Context, arg1 int64) (*model.Delegation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDelegationByTzktID", arg0, arg1)
+	ret0, _ := ret[0].(*model.Delegation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDelegationByTzktID indicates an expected call of GetDelegationByTzktID.
+func (mr *MockDelegationServicePortMockRecorder) GetDelegationByTzktID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDelegationByTzktID", reflect.TypeOf((*MockDelegationServicePort)(nil).GetDelegationByTzktID), arg0, arg1)
+}
+
 // GetDelegations mocks base method.
-func (m *MockDelegationServicePort) GetDelegations(arg0 context.Context, arg1, arg2 int, arg3 *int) ([]model.Delegation, error) {
+func (m *MockDelegationServicePort) GetDelegations(arg0 context.Context, arg1, arg2 int, arg3 model.DelegationFilter) ([]model.Delegation, bool, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetDelegations", arg0, arg1, arg2, arg3)
 	ret0, _ := ret[0].([]model.Delegation)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
 }
 
 // GetDelegations indicates an expected call of GetDelegations.
@@ -49,3 +125,122 @@ func (mr *MockDelegationServicePortMockRecorder) GetDelegations(arg0, arg1, arg2
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDelegations", reflect.TypeOf((*MockDelegationServicePort)(nil).GetDelegations), arg0, arg1, arg2, arg3)
 }
+
+// GetDelegatorSummary mocks base method.
+func (m *MockDelegationServicePort) GetDelegatorSummary(arg0 context.Context, arg1 string) (*model.DelegatorSummary, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDelegatorSummary", arg0, arg1)
+	ret0, _ := ret[0].(*model.DelegatorSummary)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDelegatorSummary indicates an expected call of GetDelegatorSummary.
+func (mr *MockDelegationServicePortMockRecorder) GetDelegatorSummary(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDelegatorSummary", reflect.TypeOf((*MockDelegationServicePort)(nil).GetDelegatorSummary), arg0, arg1)
+}
+
+// GetMonthlyStats mocks base method.
+func (m *MockDelegationServicePort) GetMonthlyStats(arg0 context.Context, arg1 int) ([]model.MonthStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMonthlyStats", arg0, arg1)
+	ret0, _ := ret[0].([]model.MonthStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMonthlyStats indicates an expected call of GetMonthlyStats.
+func (mr *MockDelegationServicePortMockRecorder) GetMonthlyStats(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMonthlyStats", reflect.TypeOf((*MockDelegationServicePort)(nil).GetMonthlyStats), arg0, arg1)
+}
+
+// GetSnapshotMaxID mocks base method.
+func (m *MockDelegationServicePort) GetSnapshotMaxID(arg0 context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSnapshotMaxID", arg0)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSnapshotMaxID indicates an expected call of GetSnapshotMaxID.
+func (mr *MockDelegationServicePortMockRecorder) GetSnapshotMaxID(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSnapshotMaxID", reflect.TypeOf((*MockDelegationServicePort)(nil).GetSnapshotMaxID), arg0)
+}
+
+// GetStatsByYear mocks base method.
+func (m *MockDelegationServicePort) GetStatsByYear(arg0 context.Context) ([]model.YearStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStatsByYear", arg0)
+	ret0, _ := ret[0].([]model.YearStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStatsByYear indicates an expected call of GetStatsByYear.
+func (mr *MockDelegationServicePortMockRecorder) GetStatsByYear(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStatsByYear", reflect.TypeOf((*MockDelegationServicePort)(nil).GetStatsByYear), arg0)
+}
+
+// GetSyncStatus mocks base method.
+func (m *MockDelegationServicePort) GetSyncStatus(arg0 context.Context) (*model.SyncStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSyncStatus", arg0)
+	ret0, _ := ret[0].(*model.SyncStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSyncStatus indicates an expected call of GetSyncStatus.
+func (mr *MockDelegationServicePortMockRecorder) GetSyncStatus(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSyncStatus", reflect.TypeOf((*MockDelegationServicePort)(nil).GetSyncStatus), arg0)
+}
+
+// GetTopDelegators mocks base method.
+func (m *MockDelegationServicePort) GetTopDelegators(arg0 context.Context, arg1 int, arg2 *int) ([]model.DelegatorStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTopDelegators", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]model.DelegatorStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTopDelegators indicates an expected call of GetTopDelegators.
+func (mr *MockDelegationServicePortMockRecorder) GetTopDelegators(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTopDelegators", reflect.TypeOf((*MockDelegationServicePort)(nil).GetTopDelegators), arg0, arg1, arg2)
+}
+
+// ImportDelegations mocks base method.
+func (m *MockDelegationServicePort) ImportDelegations(arg0 context.Context, arg1 []model.Delegation) (*model.ImportResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ImportDelegations", arg0, arg1)
+	ret0, _ := ret[0].(*model.ImportResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ImportDelegations indicates an expected call of ImportDelegations.
+func (mr *MockDelegationServicePortMockRecorder) ImportDelegations(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportDelegations", reflect.TypeOf((*MockDelegationServicePort)(nil).ImportDelegations), arg0, arg1)
+}
+
+// StreamDelegations mocks base method.
+func (m *MockDelegationServicePort) StreamDelegations(arg0 context.Context, arg1 model.DelegationFilter, arg2 func(model.Delegation) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StreamDelegations", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StreamDelegations indicates an expected call of StreamDelegations.
+func (mr *MockDelegationServicePortMockRecorder) StreamDelegations(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamDelegations", reflect.TypeOf((*MockDelegationServicePort)(nil).StreamDelegations), arg0, arg1, arg2)
+}
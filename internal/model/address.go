@@ -0,0 +1,74 @@
+package model
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"tezos-delegation/internal/apperrors"
+)
+
+const (
+	tezosAddressLength = 36 // Base58 characters in every supported address
+	tezosHashLength    = 20 // Bytes of public key or contract hash encoded in an address
+	base58Alphabet     = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+)
+
+// tezosAddressPrefixes maps each supported address prefix to the version bytes it encodes
+var tezosAddressPrefixes = map[string][]byte{
+	"tz1": {6, 161, 159}, // ed25519 implicit account
+	"tz2": {6, 161, 161}, // secp256k1 implicit account
+	"tz3": {6, 161, 164}, // p256 implicit account
+	"KT1": {2, 90, 121},  // originated contract
+}
+
+// ValidateTezosAddress checks that s is a well-formed tz1, tz2, tz3 or KT1 address:
+// the prefix, the base58 length and alphabet, and the base58check checksum.
+// It doesn't check that the account exists on chain.
+func ValidateTezosAddress(s string) error {
+	if len(s) != tezosAddressLength {
+		return apperrors.NewValidationError("address", fmt.Sprintf("must be %d characters, got %d", tezosAddressLength, len(s)))
+	}
+	version, ok := tezosAddressPrefixes[s[:3]]
+	if !ok {
+		return apperrors.NewValidationError("address", fmt.Sprintf("must start with tz1, tz2, tz3 or KT1, got %q", s[:3]))
+	}
+
+	decoded, err := decodeBase58(s)
+	if err != nil {
+		return apperrors.NewValidationErrorWithCause("address", "must be base58 encoded", err)
+	}
+	if len(decoded) != len(version)+tezosHashLength+4 || !bytes.HasPrefix(decoded, version) {
+		return apperrors.NewValidationError("address", "invalid encoded payload")
+	}
+
+	payload, checksum := decoded[:len(decoded)-4], decoded[len(decoded)-4:]
+	first := sha256.Sum256(payload)
+	second := sha256.Sum256(first[:])
+	if !bytes.Equal(second[:4], checksum) {
+		return apperrors.NewValidationError("address", "invalid checksum")
+	}
+	return nil
+}
+
+// decodeBase58 decodes a Bitcoin-alphabet base58 string, keeping leading zero bytes
+func decodeBase58(s string) ([]byte, error) {
+	n := new(big.Int)
+	radix := big.NewInt(58)
+	for _, c := range s {
+		digit := strings.IndexRune(base58Alphabet, c)
+		if digit < 0 {
+			return nil, fmt.Errorf("invalid base58 character %q", c)
+		}
+		n.Mul(n, radix)
+		n.Add(n, big.NewInt(int64(digit)))
+	}
+
+	leadingZeros := 0
+	for leadingZeros < len(s) && s[leadingZeros] == base58Alphabet[0] {
+		leadingZeros++
+	}
+	return append(make([]byte, leadingZeros), n.Bytes()...), nil
+}
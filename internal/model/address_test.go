@@ -0,0 +1,46 @@
+package model
+
+import (
+	"testing"
+
+	"tezos-delegation/internal/apperrors"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateTezosAddress(t *testing.T) {
+	valid := map[string]string{
+		"tz1":               "tz1a1SAaXRt9yoGMx29rh9FsBF4UzmvojdTL",
+		"tz1 zero hash":     "tz1Ke2h7sDdakHJQh8WX4Z372du1KChsksyU",
+		"tz2":               "tz28KFsN3RPHiWGF2rd3ScbnDdFhZc4eQm3K",
+		"tz3":               "tz3LL4pgwHWq78iYT7hJSa4A2z9DLSBZKozx",
+		"KT1":               "KT1JejNYjmQYh8yw95u5kfQDRuxJcaUPjUnf",
+		"KT1 max hash byte": "KT1XvNYseNDJJ6Kw27qhSEDF8ys8JhDopzfG",
+	}
+	for name, address := range valid {
+		t.Run("valid "+name, func(t *testing.T) {
+			assert.NoError(t, ValidateTezosAddress(address))
+		})
+	}
+
+	invalid := map[string]string{
+		"empty":                "",
+		"too short":            "tz1a1SAaXRt9yoGMx29rh9FsBF4UzmvojdT",
+		"too long":             "tz1a1SAaXRt9yoGMx29rh9FsBF4UzmvojdTLL",
+		"unknown prefix":       "tz5a1SAaXRt9yoGMx29rh9FsBF4UzmvojdTL",
+		"lowercase prefix":     "kt1JejNYjmQYh8yw95u5kfQDRuxJcaUPjUnf",
+		"non-base58 character": "tz1a1SAaXRt9yoGMx29rh9FsBF4UzmvojdT0",
+		"ambiguous character":  "tz1a1SAaXRt9yoGMx29rh9FsBF4UzmvojdTl",
+		"bad checksum":         "tz1a1SAaXRt9yoGMx29rh9FsBF4UzmvojdTM",
+		"swapped characters":   "tz1a1SAaXRt9yoGMx29rh9FsBF4UzmvjodTL",
+		"prefix of other type": "tz2a1SAaXRt9yoGMx29rh9FsBF4UzmvojdTL",
+		"whitespace":           " tz1a1SAaXRt9yoGMx29rh9FsBF4UzmvojdT",
+	}
+	for name, address := range invalid {
+		t.Run("invalid "+name, func(t *testing.T) {
+			err := ValidateTezosAddress(address)
+			assert.Error(t, err)
+			assert.True(t, apperrors.IsValidationError(err))
+		})
+	}
+}
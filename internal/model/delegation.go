@@ -1,6 +1,24 @@
 package model
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
+
+// MinYear is the first calendar year with delegations served by the API
+const MinYear = 2018
+
+// Operation types stored in the delegations table
+const (
+	OperationTypeDelegation  = "delegation"  // Delegation operation
+	OperationTypeOrigination = "origination" // Contract origination that sets a delegate
+)
+
+// Delegator account types, told apart by address prefix
+const (
+	AccountTypeImplicit = "implicit" // tz1, tz2 and tz3 addresses
+	AccountTypeContract = "contract" // KT1 smart contract addresses
+)
 
 type Delegation struct {
 	ID        int       `db:"id"`
@@ -9,4 +27,92 @@ type Delegation struct {
 	Amount    int64     `db:"amount"`
 	Delegator string    `db:"delegator"`
 	Level     int64     `db:"level"`
+	Type      string    `db:"type"` // OperationTypeDelegation or OperationTypeOrigination
+}
+
+// DelegationFilter holds the optional criteria used when listing delegations.
+// A nil field means the criterion is not applied.
+type DelegationFilter struct {
+	Year        *int    // Only delegations made in this calendar year
+	MaxTzktID   *int64  // Only delegations with tzkt_id <= MaxTzktID, pinning results to a snapshot
+	Type        *string // Only operations of this type
+	MinLevel    *int64  // Only delegations included at or after this block level
+	AccountType *string // Only delegations from this kind of delegator account
+	SinceTzktID *int64  // Only delegations with tzkt_id > SinceTzktID; results are then ordered by ascending tzkt_id
+}
+
+// LatestYear returns the latest year accepted by year filters: maxYear, or the current year when it is 0.
+// The current year is looked up on each call, so a long-running service accepts a new year from January 1.
+func LatestYear(maxYear int) int {
+	if maxYear > 0 {
+		return maxYear
+	}
+	return time.Now().Year()
+}
+
+// YearRangeMessage describes the accepted year range. The handler and the service both reject
+// out-of-range years with it, so a year is refused the same way whichever layer catches it.
+func YearRangeMessage(maxYear int) string {
+	return fmt.Sprintf("must be a valid year between %d and %d", MinYear, maxYear)
+}
+
+// YearStats aggregates delegations made in a single calendar year.
+type YearStats struct {
+	Year        int   `db:"year"`
+	Count       int64 `db:"count"`
+	TotalAmount int64 `db:"total_amount"` // Sum of delegated amounts in mutez
+}
+
+// MonthStats aggregates delegations made in a single calendar month.
+type MonthStats struct {
+	Month       int   `db:"month"` // 1 (January) to 12 (December)
+	Count       int64 `db:"count"`
+	TotalAmount int64 `db:"total_amount"` // Sum of delegated amounts in mutez
+}
+
+// DelegatorStats aggregates the delegations made by a single delegator.
+type DelegatorStats struct {
+	Delegator   string `db:"delegator"`
+	Count       int64  `db:"count"`
+	TotalAmount int64  `db:"total_amount"` // Sum of delegated amounts in mutez
+}
+
+// DelegatorSummary rolls up every delegation made by a single delegator.
+type DelegatorSummary struct {
+	Delegator   string    `db:"delegator"`
+	FirstSeen   time.Time `db:"first_seen"` // Timestamp of the delegator's earliest delegation
+	LastSeen    time.Time `db:"last_seen"`  // Timestamp of the delegator's latest delegation
+	Count       int64     `db:"count"`
+	TotalAmount int64     `db:"total_amount"` // Sum of delegated amounts in mutez
+}
+
+// SyncState tracks the poller's progress syncing delegations from Tzkt.
+type SyncState struct {
+	LastTzktID         int64     `db:"last_tzkt_id"`        // Highest Tzkt ID processed by the poller
+	LastPollAt         time.Time `db:"last_poll_at"`        // Time of the last successful batch
+	HistoricalComplete bool      `db:"historical_complete"` // Whether the initial historical sync has finished
+}
+
+// SyncStatus compares the newest stored delegation with the newest one on Tzkt, to show how far behind
+// the sync is. The lag fields are only set when both delegations are known.
+type SyncStatus struct {
+	Latest     *Delegation   // Newest stored delegation; nil if none is stored
+	TzktLatest *Delegation   // Newest delegation on Tzkt; nil if this instance doesn't poll Tzkt
+	HasLag     bool          // Whether LagTzktIDs and Lag are set
+	LagTzktIDs int64         // Gap in Tzkt ID space between Latest and TzktLatest, at least 0; not an operation count
+	Lag        time.Duration // Time between the timestamps of Latest and TzktLatest, at least 0
+}
+
+// ImportResult summarizes a bulk import of delegations.
+type ImportResult struct {
+	Inserted int64         // Delegations written to the database
+	Skipped  int64         // Valid delegations not written because their TzktID is already stored
+	Errors   []ImportError // Delegations rejected by validation, none of which are written
+}
+
+// ImportError describes why a single imported delegation was rejected.
+type ImportError struct {
+	Index   int    // Position of the delegation in the imported batch
+	TzktID  int64  // TzktID of the rejected delegation as submitted
+	Message string // Validation failure
 }
@@ -2,6 +2,8 @@ package ports
 
 import (
 	"context"
+	"database/sql"
+	"net/http"
 	"tezos-delegation/internal/model"
 )
 
@@ -9,16 +11,54 @@ import (
 
 // DelegationRepositoryPort defines the contract for delegation data persistence
 type DelegationRepositoryPort interface {
-	InsertDelegations(delegations []*model.Delegation) error
+	InsertDelegations(ctx context.Context, delegations []*model.Delegation) (int64, error)
+	InsertDelegationsBestEffort(ctx context.Context, delegations []*model.Delegation) (int64, []error, error)
 	GetLatestTzktID(ctx context.Context) (int64, error)
-	ListDelegations(ctx context.Context, limit, offset int, year *int) ([]model.Delegation, error)
+	ListDelegations(ctx context.Context, limit, offset int, filter model.DelegationFilter) ([]model.Delegation, error)
+	StreamDelegations(ctx context.Context, filter model.DelegationFilter, fn func(model.Delegation) error) error
+	CountDelegations(ctx context.Context, filter model.DelegationFilter) (int64, error)
+	ApproximateCount(ctx context.Context) (int64, error)
+	GetByTzktID(ctx context.Context, tzktID int64) (*model.Delegation, error)
+	DeleteByTzktID(ctx context.Context, tzktID int64) (int64, error)
+	CountByTzktIDs(ctx context.Context, tzktIDs []int64) (int64, error)
+	GetByTzktIDs(ctx context.Context, tzktIDs []int64) ([]model.Delegation, error)
+	AggregateByYear(ctx context.Context) ([]model.YearStats, error)
+	AggregateByMonth(ctx context.Context, year int) ([]model.MonthStats, error)
+	AggregateTopDelegators(ctx context.Context, limit int, year *int) ([]model.DelegatorStats, error)
+	GetDelegatorSummary(ctx context.Context, delegator string) (*model.DelegatorSummary, error)
+	CountDistinctDelegators(ctx context.Context, year *int) (int64, error)
+	GetSyncState(ctx context.Context) (*model.SyncState, error)
+	UpdateSyncState(ctx context.Context, state model.SyncState) error
+	CheckSchema(ctx context.Context) error
+	AnalyzeDelegations(ctx context.Context, vacuum bool) error
+	TryAcquirePollLock(ctx context.Context) (bool, error)
+	ReleasePollLock(ctx context.Context) error
 }
 
 // Service Ports
 
 // DelegationServicePort defines the contract for delegation business logic
 type DelegationServicePort interface {
-	GetDelegations(ctx context.Context, pageNo, pageSize int, year *int) ([]model.Delegation, error)
+	GetDelegations(ctx context.Context, pageNo, pageSize int, filter model.DelegationFilter) ([]model.Delegation, bool, error)
+	GetSnapshotMaxID(ctx context.Context) (int64, error)
+	StreamDelegations(ctx context.Context, filter model.DelegationFilter, fn func(model.Delegation) error) error
+	CountDelegations(ctx context.Context, filter model.DelegationFilter) (int64, error)
+	ApproximateCountDelegations(ctx context.Context, filter model.DelegationFilter) (int64, bool, error)
+	GetDelegationByTzktID(ctx context.Context, tzktID int64) (*model.Delegation, error)
+	DeleteDelegationByTzktID(ctx context.Context, tzktID int64) error
+	GetStatsByYear(ctx context.Context) ([]model.YearStats, error)
+	GetMonthlyStats(ctx context.Context, year int) ([]model.MonthStats, error)
+	GetTopDelegators(ctx context.Context, limit int, year *int) ([]model.DelegatorStats, error)
+	GetDelegatorSummary(ctx context.Context, delegator string) (*model.DelegatorSummary, error)
+	CountDistinctDelegators(ctx context.Context, year *int) (int64, error)
+	ImportDelegations(ctx context.Context, delegations []model.Delegation) (*model.ImportResult, error)
+	GetSyncStatus(ctx context.Context) (*model.SyncStatus, error)
+}
+
+// HealthServicePort defines the contract for liveness and readiness checks
+type HealthServicePort interface {
+	CheckReadiness(ctx context.Context) error
+	TzktCircuitState() string
 }
 
 // PollerServicePort defines the contract for the data polling service
@@ -36,12 +76,19 @@ type DelegationHandlerPort interface {
 
 // Infrastructure Ports
 
-// DatabasePort defines the contract for database connections
+// DatabasePort defines the contract for the database connection pool used by the repository and migrations.
+// *sql.DB implements it.
 type DatabasePort interface {
+	PingContext(ctx context.Context) error
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+	Conn(ctx context.Context) (*sql.Conn, error)
 	Close() error
 }
 
-// HTTPClientPort defines the contract for HTTP clients
+// HTTPClientPort defines the contract for the HTTP client the poller calls Tzkt with. *http.Client implements it.
 type HTTPClientPort interface {
-	Do(req interface{}) (interface{}, error)
+	Do(req *http.Request) (*http.Response, error)
 }
@@ -0,0 +1,62 @@
+// Package requestid carries a per-request correlation ID through context.Context
+// so that logs written while serving a request can be tied back to it.
+package requestid
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// Header is the HTTP header used to pass the request ID in and out of the service
+const Header = "X-Request-ID"
+
+// LogField is the log field name the request ID is written under
+const LogField = "request_id"
+
+// maxLength bounds client-supplied request IDs so they can't bloat logs
+const maxLength = 128
+
+type contextKey struct{}
+
+// New generates a new random request ID
+func New() string {
+	return uuid.NewString()
+}
+
+// Valid reports whether a client-supplied request ID is safe to reuse:
+// non-empty, at most 128 characters and made of printable ASCII only.
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// NewContext returns a copy of ctx carrying the request ID
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID stored in ctx, or "" if there is none
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Logger returns base with a request_id field added when ctx carries a request ID,
+// otherwise base itself.
+func Logger(ctx context.Context, base *zerolog.Logger) *zerolog.Logger {
+	id := FromContext(ctx)
+	if id == "" {
+		return base
+	}
+	l := base.With().Str(LogField, id).Logger()
+	return &l
+}
@@ -0,0 +1,54 @@
+package requestid
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewContext(t *testing.T) {
+	ctx := NewContext(context.Background(), "abc-123")
+	assert.Equal(t, "abc-123", FromContext(ctx))
+	assert.Equal(t, "", FromContext(context.Background()))
+}
+
+func TestNew(t *testing.T) {
+	id := New()
+	assert.Len(t, id, 36)
+	assert.True(t, Valid(id))
+	assert.NotEqual(t, id, New())
+}
+
+func TestValid(t *testing.T) {
+	testCases := []struct {
+		id    string
+		valid bool
+	}{
+		{"abc-123", true},
+		{"", false},
+		{"has space", false},
+		{"line\nbreak", false},
+		{"ünïcode", false},
+		{strings.Repeat("a", 128), true},
+		{strings.Repeat("a", 129), false},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.valid, Valid(tc.id), tc.id)
+	}
+}
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	base := zerolog.New(&buf)
+
+	Logger(context.Background(), &base).Info().Msg("no id")
+	assert.NotContains(t, buf.String(), LogField)
+
+	buf.Reset()
+	Logger(NewContext(context.Background(), "abc-123"), &base).Info().Msg("with id")
+	assert.Contains(t, buf.String(), `"request_id":"abc-123"`)
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrTzktCircuitOpen is returned instead of calling Tzkt while the circuit breaker is open.
+var ErrTzktCircuitOpen = errors.New("tzkt circuit breaker open")
+
+// Circuit breaker states, as reported by PollerService.CircuitState
+const (
+	CircuitClosed   = "closed"    // Calls flow normally
+	CircuitOpen     = "open"      // Calls are skipped until the cooldown ends
+	CircuitHalfOpen = "half_open" // A single probe call decides whether to close or reopen
+)
+
+// circuitBreaker stops calls to a failing dependency. After threshold consecutive failures it opens
+// and rejects calls for cooldown, then lets a single probe through: success closes it, failure reopens it.
+// A nil *circuitBreaker allows every call.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+	// onStateChange is called with the lock held whenever the state changes; it must not call back into the breaker
+	onStateChange func(from, to string)
+
+	mu       sync.Mutex
+	state    string
+	failures int       // Consecutive failures while closed
+	openedAt time.Time // When the breaker last opened
+	probing  bool      // Whether the half-open probe is in flight
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration, onStateChange func(from, to string)) *circuitBreaker {
+	return &circuitBreaker{
+		threshold:     threshold,
+		cooldown:      cooldown,
+		now:           time.Now,
+		onStateChange: onStateChange,
+		state:         CircuitClosed,
+	}
+}
+
+// Allow returns ErrTzktCircuitOpen if a call must be skipped. Once the cooldown has passed,
+// the first caller is let through as the half-open probe. Every allowed call must be followed
+// by Success, Failure or Release.
+func (b *circuitBreaker) Allow() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return ErrTzktCircuitOpen
+		}
+		b.setState(CircuitHalfOpen)
+		b.probing = true
+		return nil
+	case CircuitHalfOpen:
+		if b.probing {
+			return ErrTzktCircuitOpen
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// Success records a successful call, closing the breaker
+func (b *circuitBreaker) Success() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.probing = false
+	b.setState(CircuitClosed)
+}
+
+// Failure records a failed call, opening the breaker after threshold consecutive failures or a failed probe
+func (b *circuitBreaker) Failure() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	b.failures++
+	if b.state == CircuitHalfOpen || b.failures >= b.threshold {
+		b.failures = 0
+		b.openedAt = b.now()
+		b.setState(CircuitOpen)
+	}
+}
+
+// Release ends an allowed call whose outcome says nothing about the dependency, e.g. because it was cancelled
+func (b *circuitBreaker) Release() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+// State returns the current state, CircuitClosed for a nil breaker
+func (b *circuitBreaker) State() string {
+	if b == nil {
+		return CircuitClosed
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+// RetryIn returns how long until an open breaker lets a probe through, or 0 if calls may be attempted now
+func (b *circuitBreaker) RetryIn() time.Duration {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != CircuitOpen {
+		return 0
+	}
+	return max(0, b.cooldown-b.now().Sub(b.openedAt))
+}
+
+// setState moves to state, notifying onStateChange. Must be called with the lock held.
+func (b *circuitBreaker) setState(state string) {
+	if state == b.state {
+		return
+	}
+	from := b.state
+	b.state = state
+	if b.onStateChange != nil {
+		b.onStateChange(from, state)
+	}
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var transitions []string
+	b := newCircuitBreaker(3, time.Minute, func(from, to string) { transitions = append(transitions, from+"->"+to) })
+	b.now = func() time.Time { return now }
+
+	// Failures below the threshold, or interrupted by a success, keep it closed
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, b.Allow())
+		b.Failure()
+	}
+	assert.NoError(t, b.Allow())
+	b.Success()
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, b.Allow())
+		b.Failure()
+	}
+	assert.Equal(t, CircuitClosed, b.State())
+
+	// The third consecutive failure opens it
+	assert.NoError(t, b.Allow())
+	b.Failure()
+	assert.Equal(t, CircuitOpen, b.State())
+	assert.ErrorIs(t, b.Allow(), ErrTzktCircuitOpen)
+	assert.Equal(t, time.Minute, b.RetryIn())
+
+	now = now.Add(30 * time.Second)
+	assert.ErrorIs(t, b.Allow(), ErrTzktCircuitOpen)
+	assert.Equal(t, 30*time.Second, b.RetryIn())
+
+	// After the cooldown a single probe goes through; a failed probe reopens it immediately
+	now = now.Add(30 * time.Second)
+	assert.NoError(t, b.Allow())
+	assert.Equal(t, CircuitHalfOpen, b.State())
+	assert.ErrorIs(t, b.Allow(), ErrTzktCircuitOpen)
+	b.Failure()
+	assert.Equal(t, CircuitOpen, b.State())
+	assert.Equal(t, time.Minute, b.RetryIn())
+
+	// A cancelled probe lets the next caller probe instead
+	now = now.Add(time.Minute)
+	assert.NoError(t, b.Allow())
+	b.Release()
+	assert.Equal(t, CircuitHalfOpen, b.State())
+	assert.NoError(t, b.Allow())
+
+	// A successful probe closes it
+	b.Success()
+	assert.Equal(t, CircuitClosed, b.State())
+	assert.Equal(t, time.Duration(0), b.RetryIn())
+	assert.NoError(t, b.Allow())
+
+	assert.Equal(t, []string{
+		"closed->open",
+		"open->half_open",
+		"half_open->open",
+		"open->half_open",
+		"half_open->closed",
+	}, transitions)
+}
+
+func TestCircuitBreaker_Nil(t *testing.T) {
+	var b *circuitBreaker
+	assert.NoError(t, b.Allow())
+	b.Failure()
+	b.Success()
+	b.Release()
+	assert.Equal(t, CircuitClosed, b.State())
+	assert.Equal(t, time.Duration(0), b.RetryIn())
+}
@@ -4,30 +4,60 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 	"tezos-delegation/internal/apperrors"
 	"tezos-delegation/internal/db"
 	"tezos-delegation/internal/model"
 	"tezos-delegation/internal/ports"
+	"tezos-delegation/internal/requestid"
 
 	"github.com/rs/zerolog"
 )
 
+// ErrOffsetTooLarge is wrapped by the validation error GetDelegations returns for pages beyond the maximum offset
+var ErrOffsetTooLarge = errors.New("page offset too large")
+
+// ErrTzktUnavailable is wrapped by the error GetSyncStatus returns when Tzkt can't be reached
+var ErrTzktUnavailable = errors.New("tzkt unavailable")
+
+// defaultMaxOffset is the deepest row offset GetDelegations serves when DelegationServiceOptions.MaxOffset is unset
+const defaultMaxOffset = 100000
+
+// DelegationServiceOptions holds the tunable service behavior loaded from configuration.
+type DelegationServiceOptions struct {
+	MaxOffset int // Largest (pageNo-1)*pageSize offset served, bounding how much Postgres scans to skip rows; 0 uses the default
+	MaxYear   int // Latest year accepted by year filters; 0 uses the current year
+
+	// TzktLatest returns the newest delegation on Tzkt for sync status; nil when this instance doesn't poll Tzkt
+	TzktLatest func(ctx context.Context) (*model.Delegation, error)
+}
+
 // DelegationService implements DelegationServicePort
 type DelegationService struct {
 	Repo   ports.DelegationRepositoryPort
 	Logger zerolog.Logger
+	opts   DelegationServiceOptions
 }
 
 // Ensure DelegationService implements DelegationServicePort
 var _ ports.DelegationServicePort = (*DelegationService)(nil)
 
-func NewDelegationService(repo ports.DelegationRepositoryPort, logger zerolog.Logger) *DelegationService {
+func NewDelegationService(repo ports.DelegationRepositoryPort, logger zerolog.Logger, opts DelegationServiceOptions) *DelegationService {
+	if opts.MaxOffset <= 0 {
+		opts.MaxOffset = defaultMaxOffset
+	}
 	return &DelegationService{
 		Repo:   repo,
 		Logger: logger.With().Str("component", "DelegationService").Logger(),
+		opts:   opts,
 	}
 }
 
+// logger returns the service logger scoped to the request carried by ctx
+func (s *DelegationService) logger(ctx context.Context) *zerolog.Logger {
+	return requestid.Logger(ctx, &s.Logger)
+}
+
 // validatePaginationParams validates pagination parameters
 func (s *DelegationService) validatePaginationParams(pageNo, pageSize int) error {
 	if pageNo < 1 {
@@ -39,50 +69,440 @@ func (s *DelegationService) validatePaginationParams(pageNo, pageSize int) error
 	if pageSize > 1000 {
 		return apperrors.NewValidationError("pageSize", fmt.Sprintf("cannot exceed 1000, got %d", pageSize))
 	}
+	// Deep offsets make Postgres scan and discard every skipped row
+	if offset := (pageNo - 1) * pageSize; offset > s.opts.MaxOffset {
+		return apperrors.NewValidationErrorWithCause("pageNo", fmt.Sprintf("offset %d exceeds the maximum of %d; use cursor pagination instead by requesting page 1 with maxId below the last tzkt id seen", offset, s.opts.MaxOffset), ErrOffsetTooLarge)
+	}
 	return nil
 }
 
 // validateYearParam validates the year parameter if provided
 func (s *DelegationService) validateYearParam(year *int) error {
 	if year != nil {
-		if *year < 2018 || *year > 2100 {
-			return apperrors.NewValidationError("year", fmt.Sprintf("must be between 2018 and 2100, got %d", *year))
+		if maxYear := model.LatestYear(s.opts.MaxYear); *year < model.MinYear || *year > maxYear {
+			return apperrors.NewValidationError("year", fmt.Sprintf("%s, got %d", model.YearRangeMessage(maxYear), *year))
 		}
 	}
 	return nil
 }
 
-// GetDelegations returns delegations with pagination and optional year filter.
+// validateMaxTzktIDParam validates the snapshot max TzktID parameter if provided
+func (s *DelegationService) validateMaxTzktIDParam(maxTzktID *int64) error {
+	if maxTzktID != nil && *maxTzktID < 0 {
+		return apperrors.NewValidationError("maxTzktID", fmt.Sprintf("must be non-negative, got %d", *maxTzktID))
+	}
+	return nil
+}
+
+// validateSinceTzktIDParam validates the incremental sync sinceTzktID parameter if provided
+func (s *DelegationService) validateSinceTzktIDParam(sinceTzktID *int64) error {
+	if sinceTzktID != nil && *sinceTzktID < 0 {
+		return apperrors.NewValidationError("sinceTzktID", fmt.Sprintf("must be non-negative, got %d", *sinceTzktID))
+	}
+	return nil
+}
+
+// validateMinLevelParam validates the minimum block level parameter if provided
+func (s *DelegationService) validateMinLevelParam(minLevel *int64) error {
+	if minLevel != nil && *minLevel < 0 {
+		return apperrors.NewValidationError("minLevel", fmt.Sprintf("must be non-negative, got %d", *minLevel))
+	}
+	return nil
+}
+
+// validateTypeParam validates the operation type parameter if provided
+func (s *DelegationService) validateTypeParam(opType *string) error {
+	if opType != nil && *opType != model.OperationTypeDelegation && *opType != model.OperationTypeOrigination {
+		return apperrors.NewValidationError("type", fmt.Sprintf("must be %q or %q, got %q", model.OperationTypeDelegation, model.OperationTypeOrigination, *opType))
+	}
+	return nil
+}
+
+// validateAccountTypeParam validates the delegator account type parameter if provided
+func (s *DelegationService) validateAccountTypeParam(accountType *string) error {
+	if accountType != nil && *accountType != model.AccountTypeImplicit && *accountType != model.AccountTypeContract {
+		return apperrors.NewValidationError("accountType", fmt.Sprintf("must be %q or %q, got %q", model.AccountTypeImplicit, model.AccountTypeContract, *accountType))
+	}
+	return nil
+}
+
+// validateFilter validates the filter parameters shared by the list, stream and count queries. The error
+// names the first invalid parameter and wraps its ValidationError.
+func (s *DelegationService) validateFilter(filter model.DelegationFilter) error {
+	checks := []struct {
+		param string
+		err   error
+	}{
+		{"year", s.validateYearParam(filter.Year)},
+		{"maxTzktID", s.validateMaxTzktIDParam(filter.MaxTzktID)},
+		{"sinceTzktID", s.validateSinceTzktIDParam(filter.SinceTzktID)},
+		{"type", s.validateTypeParam(filter.Type)},
+		{"minLevel", s.validateMinLevelParam(filter.MinLevel)},
+		{"accountType", s.validateAccountTypeParam(filter.AccountType)},
+	}
+	for _, check := range checks {
+		if check.err != nil {
+			return fmt.Errorf("invalid %s parameter: %w", check.param, check.err)
+		}
+	}
+	return nil
+}
+
+// GetDelegations returns delegations with pagination and optional filtering, and whether a next page exists.
+// One extra row is fetched to detect the next page without a COUNT query; it is trimmed from the result.
 // Validates input parameters and handles repository errors appropriately.
-func (s *DelegationService) GetDelegations(ctx context.Context, pageNo, pageSize int, year *int) ([]model.Delegation, error) {
+func (s *DelegationService) GetDelegations(ctx context.Context, pageNo, pageSize int, filter model.DelegationFilter) ([]model.Delegation, bool, error) {
 	// Validate pagination parameters
 	if err := s.validatePaginationParams(pageNo, pageSize); err != nil {
-		s.Logger.Warn().Err(err).Int("pageNo", pageNo).Int("pageSize", pageSize).Msg("Invalid pagination parameters")
-		return nil, fmt.Errorf("invalid pagination parameters: %w", err)
+		s.logger(ctx).Warn().Err(err).Int("pageNo", pageNo).Int("pageSize", pageSize).Msg("Invalid pagination parameters")
+		return nil, false, fmt.Errorf("invalid pagination parameters: %w", err)
 	}
 
-	// Validate year parameter
-	if err := s.validateYearParam(year); err != nil {
-		s.Logger.Warn().Err(err).Interface("year", year).Msg("Invalid year parameter")
-		return nil, fmt.Errorf("invalid year parameter: %w", err)
+	// Validate filter parameters
+	if err := s.validateFilter(filter); err != nil {
+		s.logger(ctx).Warn().Err(err).Interface("filter", filter).Msg("Invalid filter parameters")
+		return nil, false, err
 	}
 
 	// Calculate offset
 	offset := (pageNo - 1) * pageSize
 
-	// Get delegations from repository
-	delegations, err := s.Repo.ListDelegations(ctx, pageSize, offset, year)
+	// Get delegations from repository, plus one row to tell whether a next page exists
+	delegations, err := s.Repo.ListDelegations(ctx, pageSize+1, offset, filter)
 	if err != nil {
 		// Handle specific repository errors
 		if errors.Is(err, db.ErrNoDelegations) {
-			s.Logger.Info().Int("pageNo", pageNo).Int("pageSize", pageSize).Interface("year", year).Msg("No delegations found")
-			return []model.Delegation{}, nil
+			s.logger(ctx).Info().Int("pageNo", pageNo).Int("pageSize", pageSize).Interface("filter", filter).Msg("No delegations found")
+			return []model.Delegation{}, false, nil
+		}
+
+		s.logger(ctx).Error().Err(err).Int("pageNo", pageNo).Int("pageSize", pageSize).Interface("filter", filter).Msg("Repository error in GetDelegations")
+		return nil, false, fmt.Errorf("failed to retrieve delegations: %w", err)
+	}
+
+	hasNext := len(delegations) > pageSize
+	if hasNext {
+		delegations = delegations[:pageSize]
+	}
+
+	s.logger(ctx).Debug().Int("count", len(delegations)).Bool("hasNext", hasNext).Int("pageNo", pageNo).Int("pageSize", pageSize).Interface("filter", filter).Msg("Retrieved delegations")
+	return delegations, hasNext, nil
+}
+
+// StreamDelegations calls fn for each delegation matching the filter without loading them all into memory.
+// Validates the filter and returns fn's error unchanged if it aborts the stream.
+func (s *DelegationService) StreamDelegations(ctx context.Context, filter model.DelegationFilter, fn func(model.Delegation) error) error {
+	// Validate filter parameters
+	if err := s.validateFilter(filter); err != nil {
+		s.logger(ctx).Warn().Err(err).Interface("filter", filter).Msg("Invalid filter parameters")
+		return err
+	}
+
+	count := 0
+	err := s.Repo.StreamDelegations(ctx, filter, func(d model.Delegation) error {
+		count++
+		return fn(d)
+	})
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("streamed", count).Interface("filter", filter).Msg("Error streaming delegations")
+		return fmt.Errorf("failed to stream delegations: %w", err)
+	}
+
+	s.logger(ctx).Debug().Int("count", count).Interface("filter", filter).Msg("Streamed delegations")
+	return nil
+}
+
+// CountDelegations returns the number of delegations matching the filter.
+func (s *DelegationService) CountDelegations(ctx context.Context, filter model.DelegationFilter) (int64, error) {
+	// Validate filter parameters
+	if err := s.validateFilter(filter); err != nil {
+		s.logger(ctx).Warn().Err(err).Interface("filter", filter).Msg("Invalid filter parameters")
+		return 0, err
+	}
+
+	count, err := s.Repo.CountDelegations(ctx, filter)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Interface("filter", filter).Msg("Repository error in CountDelegations")
+		return 0, fmt.Errorf("failed to count delegations: %w", err)
+	}
+
+	s.logger(ctx).Debug().Int64("count", count).Interface("filter", filter).Msg("Counted delegations")
+	return count, nil
+}
+
+// ApproximateCountDelegations returns a quick estimate of the number of delegations matching the filter,
+// for callers that only need a rough total. Only the unfiltered total can be estimated; with any filter,
+// or before the table has statistics, it falls back to the exact count. exact reports which one was returned.
+func (s *DelegationService) ApproximateCountDelegations(ctx context.Context, filter model.DelegationFilter) (count int64, exact bool, err error) {
+	if filter != (model.DelegationFilter{}) {
+		count, err := s.CountDelegations(ctx, filter)
+		return count, true, err
+	}
+
+	estimate, err := s.Repo.ApproximateCount(ctx)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Msg("Repository error in ApproximateCountDelegations")
+		return 0, false, fmt.Errorf("failed to estimate delegation count: %w", err)
+	}
+	if estimate < 0 {
+		s.logger(ctx).Debug().Msg("No delegation count estimate yet, counting exactly")
+		count, err := s.CountDelegations(ctx, filter)
+		return count, true, err
+	}
+
+	s.logger(ctx).Debug().Int64("count", estimate).Msg("Estimated delegation count")
+	return estimate, false, nil
+}
+
+// GetSnapshotMaxID returns the highest TzktID currently stored.
+// Clients pass it back as a filter to pin paged results to a consistent snapshot.
+func (s *DelegationService) GetSnapshotMaxID(ctx context.Context) (int64, error) {
+	maxID, err := s.Repo.GetLatestTzktID(ctx)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Msg("Repository error in GetSnapshotMaxID")
+		return 0, fmt.Errorf("failed to retrieve snapshot max id: %w", err)
+	}
+	return maxID, nil
+}
+
+// GetStatsByYear returns delegation counts and total delegated amounts per calendar year, ordered by year.
+func (s *DelegationService) GetStatsByYear(ctx context.Context) ([]model.YearStats, error) {
+	stats, err := s.Repo.AggregateByYear(ctx)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Msg("Repository error in GetStatsByYear")
+		return nil, fmt.Errorf("failed to aggregate delegations by year: %w", err)
+	}
+
+	s.logger(ctx).Debug().Int("years", len(stats)).Msg("Retrieved stats by year")
+	return stats, nil
+}
+
+// GetMonthlyStats returns delegation counts and total delegated amounts for each of the 12 months of year,
+// in order. Months without delegations are included with zero totals, so the series has no gaps.
+func (s *DelegationService) GetMonthlyStats(ctx context.Context, year int) ([]model.MonthStats, error) {
+	// Validate year parameter
+	if err := s.validateYearParam(&year); err != nil {
+		s.logger(ctx).Warn().Err(err).Int("year", year).Msg("Invalid year parameter")
+		return nil, fmt.Errorf("invalid year parameter: %w", err)
+	}
+
+	stats, err := s.Repo.AggregateByMonth(ctx, year)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("year", year).Msg("Repository error in GetMonthlyStats")
+		return nil, fmt.Errorf("failed to aggregate delegations by month: %w", err)
+	}
+
+	// Fill in the months the database returned no rows for
+	months := make([]model.MonthStats, 12)
+	for i := range months {
+		months[i].Month = i + 1
+	}
+	for _, m := range stats {
+		if m.Month >= 1 && m.Month <= 12 {
+			months[m.Month-1] = m
 		}
+	}
+
+	s.logger(ctx).Debug().Int("year", year).Int("months_with_data", len(stats)).Msg("Retrieved monthly stats")
+	return months, nil
+}
 
-		s.Logger.Error().Err(err).Int("pageNo", pageNo).Int("pageSize", pageSize).Interface("year", year).Msg("Repository error in GetDelegations")
-		return nil, fmt.Errorf("failed to retrieve delegations: %w", err)
+// GetTopDelegators returns up to limit delegators ranked by total delegated amount, optionally for a single year.
+func (s *DelegationService) GetTopDelegators(ctx context.Context, limit int, year *int) ([]model.DelegatorStats, error) {
+	// Validate limit parameter
+	if limit < 1 || limit > 100 {
+		err := apperrors.NewValidationError("limit", fmt.Sprintf("must be between 1 and 100, got %d", limit))
+		s.logger(ctx).Warn().Err(err).Int("limit", limit).Msg("Invalid limit parameter")
+		return nil, fmt.Errorf("invalid limit parameter: %w", err)
 	}
 
-	s.Logger.Debug().Int("count", len(delegations)).Int("pageNo", pageNo).Int("pageSize", pageSize).Interface("year", year).Msg("Retrieved delegations")
-	return delegations, nil
+	// Validate year parameter
+	if err := s.validateYearParam(year); err != nil {
+		s.logger(ctx).Warn().Err(err).Interface("year", year).Msg("Invalid year parameter")
+		return nil, fmt.Errorf("invalid year parameter: %w", err)
+	}
+
+	stats, err := s.Repo.AggregateTopDelegators(ctx, limit, year)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("limit", limit).Interface("year", year).Msg("Repository error in GetTopDelegators")
+		return nil, fmt.Errorf("failed to aggregate top delegators: %w", err)
+	}
+
+	s.logger(ctx).Debug().Int("count", len(stats)).Int("limit", limit).Interface("year", year).Msg("Retrieved top delegators")
+	return stats, nil
+}
+
+// CountDistinctDelegators returns the number of distinct delegators, optionally for a single year.
+func (s *DelegationService) CountDistinctDelegators(ctx context.Context, year *int) (int64, error) {
+	if err := s.validateYearParam(year); err != nil {
+		s.logger(ctx).Warn().Err(err).Interface("year", year).Msg("Invalid year parameter")
+		return 0, fmt.Errorf("invalid year parameter: %w", err)
+	}
+
+	count, err := s.Repo.CountDistinctDelegators(ctx, year)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Interface("year", year).Msg("Repository error in CountDistinctDelegators")
+		return 0, fmt.Errorf("failed to count distinct delegators: %w", err)
+	}
+	return count, nil
+}
+
+// GetDelegatorSummary returns the delegation rollup for a single delegator address.
+// Returns an apperrors.NotFoundError if the delegator has no delegations.
+func (s *DelegationService) GetDelegatorSummary(ctx context.Context, delegator string) (*model.DelegatorSummary, error) {
+	if err := model.ValidateTezosAddress(delegator); err != nil {
+		s.logger(ctx).Warn().Err(err).Str("delegator", delegator).Msg("Invalid delegator address")
+		return nil, fmt.Errorf("invalid delegator address: %w", err)
+	}
+
+	summary, err := s.Repo.GetDelegatorSummary(ctx, delegator)
+	if err != nil {
+		if apperrors.IsNotFoundError(err) {
+			s.logger(ctx).Info().Str("delegator", delegator).Msg("Delegator not found")
+			return nil, err
+		}
+
+		s.logger(ctx).Error().Err(err).Str("delegator", delegator).Msg("Repository error in GetDelegatorSummary")
+		return nil, fmt.Errorf("failed to summarize delegator: %w", err)
+	}
+
+	return summary, nil
+}
+
+// GetDelegationByTzktID returns a single delegation identified by its Tzkt operation ID.
+// Returns an apperrors.NotFoundError if the delegation does not exist.
+func (s *DelegationService) GetDelegationByTzktID(ctx context.Context, tzktID int64) (*model.Delegation, error) {
+	if tzktID < 1 {
+		err := apperrors.NewValidationError("tzktID", fmt.Sprintf("must be positive, got %d", tzktID))
+		s.logger(ctx).Warn().Err(err).Int64("tzktID", tzktID).Msg("Invalid tzktID parameter")
+		return nil, fmt.Errorf("invalid tzktID parameter: %w", err)
+	}
+
+	delegation, err := s.Repo.GetByTzktID(ctx, tzktID)
+	if err != nil {
+		if apperrors.IsNotFoundError(err) {
+			s.logger(ctx).Info().Int64("tzktID", tzktID).Msg("Delegation not found")
+			return nil, err
+		}
+
+		s.logger(ctx).Error().Err(err).Int64("tzktID", tzktID).Msg("Repository error in GetDelegationByTzktID")
+		return nil, fmt.Errorf("failed to retrieve delegation: %w", err)
+	}
+
+	return delegation, nil
+}
+
+// DeleteDelegationByTzktID deletes a single delegation identified by its Tzkt operation ID.
+// Returns an apperrors.NotFoundError if the delegation does not exist.
+func (s *DelegationService) DeleteDelegationByTzktID(ctx context.Context, tzktID int64) error {
+	if tzktID < 1 {
+		err := apperrors.NewValidationError("tzktID", fmt.Sprintf("must be positive, got %d", tzktID))
+		s.logger(ctx).Warn().Err(err).Int64("tzktID", tzktID).Msg("Invalid tzktID parameter")
+		return fmt.Errorf("invalid tzktID parameter: %w", err)
+	}
+
+	deleted, err := s.Repo.DeleteByTzktID(ctx, tzktID)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int64("tzktID", tzktID).Msg("Repository error in DeleteDelegationByTzktID")
+		return fmt.Errorf("failed to delete delegation: %w", err)
+	}
+	if deleted == 0 {
+		s.logger(ctx).Info().Int64("tzktID", tzktID).Msg("Delegation to delete not found")
+		return apperrors.NewNotFoundError("delegation", strconv.FormatInt(tzktID, 10))
+	}
+
+	s.logger(ctx).Info().Int64("tzktID", tzktID).Msg("Deleted delegation")
+	return nil
+}
+
+// validateImportedDelegation checks one delegation of an import batch, defaulting an empty type to a delegation
+func validateImportedDelegation(d *model.Delegation) error {
+	if d.TzktID < 1 {
+		return apperrors.NewValidationError("tzkt_id", fmt.Sprintf("must be positive, got %d", d.TzktID))
+	}
+	if err := model.ValidateTezosAddress(d.Delegator); err != nil {
+		return err
+	}
+	if d.Amount < 0 {
+		return apperrors.NewValidationError("amount", fmt.Sprintf("must not be negative, got %d", d.Amount))
+	}
+	if d.Level < 0 {
+		return apperrors.NewValidationError("level", fmt.Sprintf("must not be negative, got %d", d.Level))
+	}
+	if d.Type == "" {
+		d.Type = model.OperationTypeDelegation
+	}
+	if d.Type != model.OperationTypeDelegation && d.Type != model.OperationTypeOrigination {
+		return apperrors.NewValidationError("type", fmt.Sprintf("must be %q or %q, got %q", model.OperationTypeDelegation, model.OperationTypeOrigination, d.Type))
+	}
+	return nil
+}
+
+// ImportDelegations validates delegations and inserts the valid ones in a single transaction, for seeding
+// a database without running the poller. Invalid delegations, and repeats of a TzktID earlier in the
+// batch, are reported in the result's Errors rather than failing the import; delegations already stored
+// are counted as skipped. An error is returned only if the insert itself fails, in which case nothing is stored.
+func (s *DelegationService) ImportDelegations(ctx context.Context, delegations []model.Delegation) (*model.ImportResult, error) {
+	result := &model.ImportResult{Errors: []model.ImportError{}}
+	valid := make([]*model.Delegation, 0, len(delegations))
+	seen := make(map[int64]bool, len(delegations))
+	for i := range delegations {
+		d := &delegations[i]
+		err := validateImportedDelegation(d)
+		if err == nil && seen[d.TzktID] {
+			err = apperrors.NewValidationError("tzkt_id", fmt.Sprintf("duplicate of an earlier delegation in the batch: %d", d.TzktID))
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, model.ImportError{Index: i, TzktID: d.TzktID, Message: err.Error()})
+			continue
+		}
+		seen[d.TzktID] = true
+		valid = append(valid, d)
+	}
+
+	inserted, err := s.Repo.InsertDelegations(ctx, valid)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("count", len(valid)).Msg("Repository error in ImportDelegations")
+		return nil, fmt.Errorf("failed to import delegations: %w", err)
+	}
+	result.Inserted = inserted
+	result.Skipped = int64(len(valid)) - inserted
+
+	s.logger(ctx).Info().Int64("inserted", result.Inserted).Int64("skipped", result.Skipped).Int("rejected", len(result.Errors)).Msg("Imported delegations")
+	return result, nil
+}
+
+// GetSyncStatus compares the newest stored delegation with the newest one on Tzkt. Without a TzktLatest
+// option, as on read-only replicas, only the stored side is reported.
+func (s *DelegationService) GetSyncStatus(ctx context.Context) (*model.SyncStatus, error) {
+	status := &model.SyncStatus{}
+	latestID, err := s.Repo.GetLatestTzktID(ctx)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Msg("Repository error in GetSyncStatus")
+		return nil, fmt.Errorf("failed to retrieve latest tzkt id: %w", err)
+	}
+	if latestID > 0 {
+		status.Latest, err = s.Repo.GetByTzktID(ctx, latestID)
+		// A delegation deleted in between only leaves the stored side unknown
+		if err != nil && !apperrors.IsNotFoundError(err) {
+			s.logger(ctx).Error().Err(err).Int64("tzktID", latestID).Msg("Repository error in GetSyncStatus")
+			return nil, fmt.Errorf("failed to retrieve latest delegation: %w", err)
+		}
+	}
+
+	if s.opts.TzktLatest == nil {
+		return status, nil
+	}
+	status.TzktLatest, err = s.opts.TzktLatest(ctx)
+	if err != nil {
+		s.logger(ctx).Warn().Err(err).Msg("Tzkt error in GetSyncStatus")
+		return nil, fmt.Errorf("%w: %w", ErrTzktUnavailable, err)
+	}
+
+	if status.Latest != nil {
+		status.HasLag = true
+		status.LagTzktIDs = max(status.TzktLatest.TzktID-status.Latest.TzktID, 0)
+		status.Lag = max(status.TzktLatest.Timestamp.Sub(status.Latest.Timestamp), 0)
+	}
+	return status, nil
 }
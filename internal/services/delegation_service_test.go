@@ -1,12 +1,15 @@
 package services
 
 import (
+	"bytes"
 	"context"
 	"testing"
 	"time"
 
+	"tezos-delegation/internal/apperrors"
 	"tezos-delegation/internal/mocks"
 	"tezos-delegation/internal/model"
+	"tezos-delegation/internal/requestid"
 
 	"tezos-delegation/internal/db"
 
@@ -21,16 +24,16 @@ func TestDelegationService_GetDelegations(t *testing.T) {
 
 	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
 	logger := zerolog.Nop()
-	service := NewDelegationService(repo, logger)
+	service := NewDelegationService(repo, logger, DelegationServiceOptions{})
 
 	ctx := context.Background()
 	pageNo, pageSize := 1, 10
 	var year *int = nil
 
 	expected := []model.Delegation{{TzktID: 1, Delegator: "tz1", Amount: 100, Level: 1, Timestamp: fixedTime()}}
-	repo.EXPECT().ListDelegations(ctx, pageSize, 0, year).Return(expected, nil)
+	repo.EXPECT().ListDelegations(ctx, pageSize+1, 0, model.DelegationFilter{Year: year}).Return(expected, nil)
 
-	result, err := service.GetDelegations(ctx, pageNo, pageSize, year)
+	result, _, err := service.GetDelegations(ctx, pageNo, pageSize, model.DelegationFilter{Year: year})
 	assert.NoError(t, err)
 	assert.Equal(t, expected, result)
 }
@@ -41,7 +44,7 @@ func TestDelegationService_GetDelegations_InvalidPagination(t *testing.T) {
 
 	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
 	logger := zerolog.Nop()
-	service := NewDelegationService(repo, logger)
+	service := NewDelegationService(repo, logger, DelegationServiceOptions{})
 	ctx := context.Background()
 	var year *int = nil
 
@@ -57,7 +60,7 @@ func TestDelegationService_GetDelegations_InvalidPagination(t *testing.T) {
 
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
-			_, err := service.GetDelegations(ctx, c.pageNo, c.pageSz, year)
+			_, _, err := service.GetDelegations(ctx, c.pageNo, c.pageSz, model.DelegationFilter{Year: year})
 			assert.Error(t, err)
 			assert.True(t, err != nil && err.Error() != "", "should return a validation error")
 		})
@@ -70,7 +73,7 @@ func TestDelegationService_GetDelegations_InvalidYear(t *testing.T) {
 
 	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
 	logger := zerolog.Nop()
-	service := NewDelegationService(repo, logger)
+	service := NewDelegationService(repo, logger, DelegationServiceOptions{})
 	ctx := context.Background()
 	pageNo, pageSize := 1, 10
 
@@ -78,27 +81,85 @@ func TestDelegationService_GetDelegations_InvalidYear(t *testing.T) {
 	for _, y := range badYears {
 		year := y
 		t.Run("year invalid", func(t *testing.T) {
-			_, err := service.GetDelegations(ctx, pageNo, pageSize, &year)
+			_, _, err := service.GetDelegations(ctx, pageNo, pageSize, model.DelegationFilter{Year: &year})
 			assert.Error(t, err)
 			assert.True(t, err != nil && err.Error() != "", "should return a validation error")
 		})
 	}
 }
 
+func TestDelegationService_MaxYear(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+	service := NewDelegationService(repo, zerolog.Nop(), DelegationServiceOptions{MaxYear: 2030})
+	ctx := context.Background()
+
+	t.Run("max year accepted", func(t *testing.T) {
+		year := 2030
+		repo.EXPECT().CountDelegations(ctx, model.DelegationFilter{Year: &year}).Return(int64(0), nil)
+		_, err := service.CountDelegations(ctx, model.DelegationFilter{Year: &year})
+		assert.NoError(t, err)
+	})
+
+	t.Run("later year rejected with the handler's message", func(t *testing.T) {
+		year := 2031
+		_, err := service.CountDelegations(ctx, model.DelegationFilter{Year: &year})
+		assert.True(t, apperrors.IsValidationError(err))
+		assert.ErrorContains(t, err, "must be a valid year between 2018 and 2030, got 2031")
+	})
+}
+
+func TestDelegationService_GetDelegations_InvalidType(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+	service := NewDelegationService(repo, zerolog.Nop(), DelegationServiceOptions{})
+	ctx := context.Background()
+
+	opType := "transaction"
+	_, _, err := service.GetDelegations(ctx, 1, 10, model.DelegationFilter{Type: &opType})
+	assert.True(t, apperrors.IsValidationError(err))
+
+	err = service.StreamDelegations(ctx, model.DelegationFilter{Type: &opType}, func(model.Delegation) error { return nil })
+	assert.True(t, apperrors.IsValidationError(err))
+}
+
+func TestDelegationService_InvalidSinceTzktID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+	service := NewDelegationService(repo, zerolog.Nop(), DelegationServiceOptions{})
+	ctx := context.Background()
+
+	since := int64(-1)
+	_, _, err := service.GetDelegations(ctx, 1, 10, model.DelegationFilter{SinceTzktID: &since})
+	assert.True(t, apperrors.IsValidationError(err))
+
+	err = service.StreamDelegations(ctx, model.DelegationFilter{SinceTzktID: &since}, func(model.Delegation) error { return nil })
+	assert.True(t, apperrors.IsValidationError(err))
+
+	_, err = service.CountDelegations(ctx, model.DelegationFilter{SinceTzktID: &since})
+	assert.True(t, apperrors.IsValidationError(err))
+}
+
 func TestDelegationService_GetDelegations_NoDelegations(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
 	logger := zerolog.Nop()
-	service := NewDelegationService(repo, logger)
+	service := NewDelegationService(repo, logger, DelegationServiceOptions{})
 	ctx := context.Background()
 	pageNo, pageSize := 1, 10
 	var year *int = nil
 
-	repo.EXPECT().ListDelegations(ctx, pageSize, 0, year).Return(nil, db.ErrNoDelegations)
+	repo.EXPECT().ListDelegations(ctx, pageSize+1, 0, model.DelegationFilter{Year: year}).Return(nil, db.ErrNoDelegations)
 
-	result, err := service.GetDelegations(ctx, pageNo, pageSize, year)
+	result, _, err := service.GetDelegations(ctx, pageNo, pageSize, model.DelegationFilter{Year: year})
 	assert.NoError(t, err)
 	assert.Empty(t, result)
 }
@@ -109,14 +170,14 @@ func TestDelegationService_GetDelegations_RepoError(t *testing.T) {
 
 	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
 	logger := zerolog.Nop()
-	service := NewDelegationService(repo, logger)
+	service := NewDelegationService(repo, logger, DelegationServiceOptions{})
 	ctx := context.Background()
 	pageNo, pageSize := 1, 10
 	var year *int = nil
 
-	repo.EXPECT().ListDelegations(ctx, pageSize, 0, year).Return(nil, assert.AnError)
+	repo.EXPECT().ListDelegations(ctx, pageSize+1, 0, model.DelegationFilter{Year: year}).Return(nil, assert.AnError)
 
-	result, err := service.GetDelegations(ctx, pageNo, pageSize, year)
+	result, _, err := service.GetDelegations(ctx, pageNo, pageSize, model.DelegationFilter{Year: year})
 	assert.Error(t, err)
 	assert.Nil(t, result)
 }
@@ -127,15 +188,15 @@ func TestDelegationService_GetDelegations_ValidYear(t *testing.T) {
 
 	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
 	logger := zerolog.Nop()
-	service := NewDelegationService(repo, logger)
+	service := NewDelegationService(repo, logger, DelegationServiceOptions{})
 	ctx := context.Background()
 	pageNo, pageSize := 1, 10
 	year := 2022
 
 	expected := []model.Delegation{{TzktID: 2, Delegator: "tz2", Amount: 200, Level: 2, Timestamp: fixedTime()}}
-	repo.EXPECT().ListDelegations(ctx, pageSize, 0, &year).Return(expected, nil)
+	repo.EXPECT().ListDelegations(ctx, pageSize+1, 0, model.DelegationFilter{Year: &year}).Return(expected, nil)
 
-	result, err := service.GetDelegations(ctx, pageNo, pageSize, &year)
+	result, _, err := service.GetDelegations(ctx, pageNo, pageSize, model.DelegationFilter{Year: &year})
 	assert.NoError(t, err)
 	assert.Equal(t, expected, result)
 }
@@ -146,19 +207,660 @@ func TestDelegationService_GetDelegations_PaginationOffset(t *testing.T) {
 
 	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
 	logger := zerolog.Nop()
-	service := NewDelegationService(repo, logger)
+	service := NewDelegationService(repo, logger, DelegationServiceOptions{})
 	ctx := context.Background()
 	pageNo, pageSize := 3, 5
 	var year *int = nil
 
 	expected := []model.Delegation{{TzktID: 3, Delegator: "tz3", Amount: 300, Level: 3, Timestamp: fixedTime()}}
-	repo.EXPECT().ListDelegations(ctx, pageSize, 10, year).Return(expected, nil)
+	repo.EXPECT().ListDelegations(ctx, pageSize+1, 10, model.DelegationFilter{Year: year}).Return(expected, nil)
 
-	result, err := service.GetDelegations(ctx, pageNo, pageSize, year)
+	result, _, err := service.GetDelegations(ctx, pageNo, pageSize, model.DelegationFilter{Year: year})
 	assert.NoError(t, err)
 	assert.Equal(t, expected, result)
 }
 
+func TestDelegationService_GetDelegations_HasNext(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+	service := NewDelegationService(repo, zerolog.Nop(), DelegationServiceOptions{})
+	ctx := context.Background()
+	pageSize := 3
+
+	rows := func(n int) []model.Delegation {
+		delegations := make([]model.Delegation, n)
+		for i := range delegations {
+			delegations[i] = model.Delegation{TzktID: int64(100 - i), Delegator: "tz1", Amount: 100, Level: 1, Timestamp: fixedTime()}
+		}
+		return delegations
+	}
+
+	t.Run("exactly page size rows", func(t *testing.T) {
+		repo.EXPECT().ListDelegations(ctx, pageSize+1, 0, model.DelegationFilter{}).Return(rows(pageSize), nil)
+
+		result, hasNext, err := service.GetDelegations(ctx, 1, pageSize, model.DelegationFilter{})
+		assert.NoError(t, err)
+		assert.False(t, hasNext)
+		assert.Equal(t, rows(pageSize), result)
+	})
+
+	t.Run("extra row is trimmed", func(t *testing.T) {
+		repo.EXPECT().ListDelegations(ctx, pageSize+1, 0, model.DelegationFilter{}).Return(rows(pageSize+1), nil)
+
+		result, hasNext, err := service.GetDelegations(ctx, 1, pageSize, model.DelegationFilter{})
+		assert.NoError(t, err)
+		assert.True(t, hasNext)
+		assert.Equal(t, rows(pageSize), result)
+	})
+
+	t.Run("short last page", func(t *testing.T) {
+		repo.EXPECT().ListDelegations(ctx, pageSize+1, 3, model.DelegationFilter{}).Return(rows(1), nil)
+
+		result, hasNext, err := service.GetDelegations(ctx, 2, pageSize, model.DelegationFilter{})
+		assert.NoError(t, err)
+		assert.False(t, hasNext)
+		assert.Len(t, result, 1)
+	})
+}
+
+func TestDelegationService_GetDelegations_MaxOffset(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+	service := NewDelegationService(repo, zerolog.Nop(), DelegationServiceOptions{MaxOffset: 1000})
+	ctx := context.Background()
+
+	t.Run("offset at the limit", func(t *testing.T) {
+		repo.EXPECT().ListDelegations(ctx, 101, 1000, model.DelegationFilter{}).Return([]model.Delegation{}, nil)
+
+		_, _, err := service.GetDelegations(ctx, 11, 100, model.DelegationFilter{})
+		assert.NoError(t, err)
+	})
+
+	t.Run("offset beyond the limit", func(t *testing.T) {
+		_, _, err := service.GetDelegations(ctx, 12, 100, model.DelegationFilter{})
+		assert.True(t, apperrors.IsValidationError(err))
+		assert.ErrorIs(t, err, ErrOffsetTooLarge)
+		assert.Contains(t, err.Error(), "cursor pagination")
+	})
+
+	t.Run("default limit", func(t *testing.T) {
+		service := NewDelegationService(repo, zerolog.Nop(), DelegationServiceOptions{})
+		_, _, err := service.GetDelegations(ctx, 100000000, 1000, model.DelegationFilter{})
+		assert.ErrorIs(t, err, ErrOffsetTooLarge)
+	})
+}
+
+func TestDelegationService_GetDelegations_Snapshot(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+	logger := zerolog.Nop()
+	service := NewDelegationService(repo, logger, DelegationServiceOptions{})
+	ctx := context.Background()
+
+	t.Run("snapshot max id", func(t *testing.T) {
+		repo.EXPECT().GetLatestTzktID(ctx).Return(int64(500), nil)
+
+		maxID, err := service.GetSnapshotMaxID(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(500), maxID)
+	})
+
+	t.Run("pinned to max id", func(t *testing.T) {
+		maxID := int64(500)
+		filter := model.DelegationFilter{MaxTzktID: &maxID}
+		expected := []model.Delegation{{TzktID: 499, Delegator: "tz1", Amount: 100, Level: 1, Timestamp: fixedTime()}}
+		repo.EXPECT().ListDelegations(ctx, 11, 10, filter).Return(expected, nil)
+
+		result, _, err := service.GetDelegations(ctx, 2, 10, filter)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, result)
+	})
+
+	t.Run("negative max id", func(t *testing.T) {
+		maxID := int64(-1)
+		_, _, err := service.GetDelegations(ctx, 1, 10, model.DelegationFilter{MaxTzktID: &maxID})
+		assert.True(t, apperrors.IsValidationError(err))
+	})
+}
+
+func TestDelegationService_GetDelegationByTzktID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+	logger := zerolog.Nop()
+	service := NewDelegationService(repo, logger, DelegationServiceOptions{})
+	ctx := context.Background()
+
+	t.Run("found", func(t *testing.T) {
+		expected := &model.Delegation{TzktID: 42, Delegator: "tz1", Amount: 100, Level: 1, Timestamp: fixedTime()}
+		repo.EXPECT().GetByTzktID(ctx, int64(42)).Return(expected, nil)
+
+		result, err := service.GetDelegationByTzktID(ctx, 42)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, result)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		repo.EXPECT().GetByTzktID(ctx, int64(43)).Return(nil, apperrors.NewNotFoundError("delegation", "43"))
+
+		result, err := service.GetDelegationByTzktID(ctx, 43)
+		assert.True(t, apperrors.IsNotFoundError(err))
+		assert.Nil(t, result)
+	})
+
+	t.Run("invalid tzktID", func(t *testing.T) {
+		result, err := service.GetDelegationByTzktID(ctx, 0)
+		assert.True(t, apperrors.IsValidationError(err))
+		assert.Nil(t, result)
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		repo.EXPECT().GetByTzktID(ctx, int64(44)).Return(nil, assert.AnError)
+
+		result, err := service.GetDelegationByTzktID(ctx, 44)
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.Nil(t, result)
+	})
+}
+
+func TestDelegationService_DeleteDelegationByTzktID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+	service := NewDelegationService(repo, zerolog.Nop(), DelegationServiceOptions{})
+	ctx := context.Background()
+
+	t.Run("deleted", func(t *testing.T) {
+		repo.EXPECT().DeleteByTzktID(ctx, int64(42)).Return(int64(1), nil)
+
+		assert.NoError(t, service.DeleteDelegationByTzktID(ctx, 42))
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		repo.EXPECT().DeleteByTzktID(ctx, int64(43)).Return(int64(0), nil)
+
+		err := service.DeleteDelegationByTzktID(ctx, 43)
+		assert.True(t, apperrors.IsNotFoundError(err))
+	})
+
+	t.Run("invalid tzktID", func(t *testing.T) {
+		err := service.DeleteDelegationByTzktID(ctx, 0)
+		assert.True(t, apperrors.IsValidationError(err))
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		repo.EXPECT().DeleteByTzktID(ctx, int64(44)).Return(int64(0), assert.AnError)
+
+		assert.ErrorIs(t, service.DeleteDelegationByTzktID(ctx, 44), assert.AnError)
+	})
+}
+
+func TestDelegationService_GetDelegatorSummary(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+	logger := zerolog.Nop()
+	service := NewDelegationService(repo, logger, DelegationServiceOptions{})
+	ctx := context.Background()
+
+	const address = "tz1a1SAaXRt9yoGMx29rh9FsBF4UzmvojdTL"
+
+	t.Run("found", func(t *testing.T) {
+		expected := &model.DelegatorSummary{Delegator: address, FirstSeen: fixedTime(), LastSeen: fixedTime(), Count: 1, TotalAmount: 100}
+		repo.EXPECT().GetDelegatorSummary(ctx, address).Return(expected, nil)
+
+		result, err := service.GetDelegatorSummary(ctx, address)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, result)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		repo.EXPECT().GetDelegatorSummary(ctx, address).Return(nil, apperrors.NewNotFoundError("delegator", address))
+
+		result, err := service.GetDelegatorSummary(ctx, address)
+		assert.True(t, apperrors.IsNotFoundError(err))
+		assert.Nil(t, result)
+	})
+
+	t.Run("invalid address", func(t *testing.T) {
+		result, err := service.GetDelegatorSummary(ctx, "tz1-not-an-address")
+		assert.True(t, apperrors.IsValidationError(err))
+		assert.Nil(t, result)
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		repo.EXPECT().GetDelegatorSummary(ctx, address).Return(nil, assert.AnError)
+
+		result, err := service.GetDelegatorSummary(ctx, address)
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.Nil(t, result)
+	})
+}
+
+func TestDelegationService_CountDelegations(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+	logger := zerolog.Nop()
+	service := NewDelegationService(repo, logger, DelegationServiceOptions{})
+	ctx := context.Background()
+
+	t.Run("counts matching delegations", func(t *testing.T) {
+		year := 2022
+		filter := model.DelegationFilter{Year: &year}
+		repo.EXPECT().CountDelegations(ctx, filter).Return(int64(42), nil)
+
+		count, err := service.CountDelegations(ctx, filter)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(42), count)
+	})
+
+	t.Run("invalid filter", func(t *testing.T) {
+		year := 2017
+		_, err := service.CountDelegations(ctx, model.DelegationFilter{Year: &year})
+		assert.True(t, apperrors.IsValidationError(err))
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		repo.EXPECT().CountDelegations(ctx, model.DelegationFilter{}).Return(int64(0), assert.AnError)
+
+		_, err := service.CountDelegations(ctx, model.DelegationFilter{})
+		assert.ErrorIs(t, err, assert.AnError)
+	})
+}
+
+func TestDelegationService_ApproximateCountDelegations(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+	service := NewDelegationService(repo, zerolog.Nop(), DelegationServiceOptions{})
+	ctx := context.Background()
+
+	t.Run("unfiltered total is estimated", func(t *testing.T) {
+		repo.EXPECT().ApproximateCount(ctx).Return(int64(98000), nil)
+
+		count, exact, err := service.ApproximateCountDelegations(ctx, model.DelegationFilter{})
+		assert.NoError(t, err)
+		assert.False(t, exact)
+		assert.Equal(t, int64(98000), count)
+	})
+
+	t.Run("filtered count is exact", func(t *testing.T) {
+		year := 2022
+		filter := model.DelegationFilter{Year: &year}
+		repo.EXPECT().CountDelegations(ctx, filter).Return(int64(42), nil)
+
+		count, exact, err := service.ApproximateCountDelegations(ctx, filter)
+		assert.NoError(t, err)
+		assert.True(t, exact)
+		assert.Equal(t, int64(42), count)
+	})
+
+	t.Run("no estimate yet falls back to exact", func(t *testing.T) {
+		repo.EXPECT().ApproximateCount(ctx).Return(int64(-1), nil)
+		repo.EXPECT().CountDelegations(ctx, model.DelegationFilter{}).Return(int64(5), nil)
+
+		count, exact, err := service.ApproximateCountDelegations(ctx, model.DelegationFilter{})
+		assert.NoError(t, err)
+		assert.True(t, exact)
+		assert.Equal(t, int64(5), count)
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		repo.EXPECT().ApproximateCount(ctx).Return(int64(0), assert.AnError)
+
+		_, _, err := service.ApproximateCountDelegations(ctx, model.DelegationFilter{})
+		assert.ErrorIs(t, err, assert.AnError)
+	})
+}
+
+func TestDelegationService_StreamDelegations(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+	logger := zerolog.Nop()
+	service := NewDelegationService(repo, logger, DelegationServiceOptions{})
+	ctx := context.Background()
+
+	t.Run("passes rows through", func(t *testing.T) {
+		year := 2022
+		filter := model.DelegationFilter{Year: &year}
+		repo.EXPECT().StreamDelegations(ctx, filter, gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ model.DelegationFilter, fn func(model.Delegation) error) error {
+				return fn(model.Delegation{TzktID: 1, Delegator: "tz1", Timestamp: fixedTime()})
+			})
+
+		var got []model.Delegation
+		err := service.StreamDelegations(ctx, filter, func(d model.Delegation) error {
+			got = append(got, d)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Len(t, got, 1)
+		assert.Equal(t, "tz1", got[0].Delegator)
+	})
+
+	t.Run("invalid year", func(t *testing.T) {
+		year := 2017
+		err := service.StreamDelegations(ctx, model.DelegationFilter{Year: &year}, func(model.Delegation) error { return nil })
+		assert.True(t, apperrors.IsValidationError(err))
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		dbErr := apperrors.NewDatabaseError("stream", "connection failed")
+		repo.EXPECT().StreamDelegations(ctx, model.DelegationFilter{}, gomock.Any()).Return(dbErr)
+
+		err := service.StreamDelegations(ctx, model.DelegationFilter{}, func(model.Delegation) error { return nil })
+		assert.True(t, apperrors.IsDatabaseError(err))
+	})
+}
+
+func TestDelegationService_GetStatsByYear(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+	logger := zerolog.Nop()
+	service := NewDelegationService(repo, logger, DelegationServiceOptions{})
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		expected := []model.YearStats{{Year: 2022, Count: 2, TotalAmount: 300}}
+		repo.EXPECT().AggregateByYear(ctx).Return(expected, nil)
+
+		stats, err := service.GetStatsByYear(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, stats)
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		dbErr := apperrors.NewDatabaseError("aggregate", "connection failed")
+		repo.EXPECT().AggregateByYear(ctx).Return(nil, dbErr)
+
+		stats, err := service.GetStatsByYear(ctx)
+		assert.Nil(t, stats)
+		assert.True(t, apperrors.IsDatabaseError(err))
+	})
+}
+
+func TestDelegationService_GetMonthlyStats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+	logger := zerolog.Nop()
+	service := NewDelegationService(repo, logger, DelegationServiceOptions{})
+	ctx := context.Background()
+
+	t.Run("sparse data fills all months", func(t *testing.T) {
+		repo.EXPECT().AggregateByMonth(ctx, 2022).Return([]model.MonthStats{
+			{Month: 2, Count: 3, TotalAmount: 1500000},
+			{Month: 11, Count: 1, TotalAmount: 250},
+		}, nil)
+
+		stats, err := service.GetMonthlyStats(ctx, 2022)
+		assert.NoError(t, err)
+		if assert.Len(t, stats, 12) {
+			for i, m := range stats {
+				assert.Equal(t, i+1, m.Month)
+			}
+			assert.Equal(t, model.MonthStats{Month: 1}, stats[0])
+			assert.Equal(t, model.MonthStats{Month: 2, Count: 3, TotalAmount: 1500000}, stats[1])
+			assert.Equal(t, model.MonthStats{Month: 11, Count: 1, TotalAmount: 250}, stats[10])
+			assert.Equal(t, model.MonthStats{Month: 12}, stats[11])
+		}
+	})
+
+	t.Run("invalid year", func(t *testing.T) {
+		stats, err := service.GetMonthlyStats(ctx, 2017)
+		assert.Nil(t, stats)
+		assert.True(t, apperrors.IsValidationError(err))
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		repo.EXPECT().AggregateByMonth(ctx, 2022).Return(nil, apperrors.NewDatabaseError("aggregate", "connection failed"))
+
+		stats, err := service.GetMonthlyStats(ctx, 2022)
+		assert.Nil(t, stats)
+		assert.True(t, apperrors.IsDatabaseError(err))
+	})
+}
+
+func TestDelegationService_GetTopDelegators(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+	logger := zerolog.Nop()
+	service := NewDelegationService(repo, logger, DelegationServiceOptions{})
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		year := 2022
+		expected := []model.DelegatorStats{{Delegator: "tz1", Count: 2, TotalAmount: 300}}
+		repo.EXPECT().AggregateTopDelegators(ctx, 10, &year).Return(expected, nil)
+
+		stats, err := service.GetTopDelegators(ctx, 10, &year)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, stats)
+	})
+
+	t.Run("invalid limit", func(t *testing.T) {
+		for _, limit := range []int{0, 101} {
+			stats, err := service.GetTopDelegators(ctx, limit, nil)
+			assert.Nil(t, stats)
+			assert.True(t, apperrors.IsValidationError(err))
+		}
+	})
+
+	t.Run("invalid year", func(t *testing.T) {
+		year := 2017
+		stats, err := service.GetTopDelegators(ctx, 10, &year)
+		assert.Nil(t, stats)
+		assert.True(t, apperrors.IsValidationError(err))
+	})
+}
+
+func TestDelegationService_CountDistinctDelegators(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+	service := NewDelegationService(repo, zerolog.Nop(), DelegationServiceOptions{})
+	ctx := context.Background()
+
+	t.Run("success", func(t *testing.T) {
+		year := 2022
+		repo.EXPECT().CountDistinctDelegators(ctx, &year).Return(int64(42), nil)
+
+		count, err := service.CountDistinctDelegators(ctx, &year)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(42), count)
+	})
+
+	t.Run("invalid year", func(t *testing.T) {
+		year := 2017
+		count, err := service.CountDistinctDelegators(ctx, &year)
+		assert.Zero(t, count)
+		assert.True(t, apperrors.IsValidationError(err))
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		repo.EXPECT().CountDistinctDelegators(ctx, (*int)(nil)).Return(int64(0), apperrors.NewDatabaseError("count distinct delegators", "failed"))
+
+		_, err := service.CountDistinctDelegators(ctx, nil)
+		assert.True(t, apperrors.IsDatabaseError(err))
+	})
+}
+
+func TestDelegationService_LogsRequestID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var buf bytes.Buffer
+	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+	service := NewDelegationService(repo, zerolog.New(&buf), DelegationServiceOptions{})
+
+	ctx := requestid.NewContext(context.Background(), "abc-123")
+	notFoundErr := apperrors.NewNotFoundError("delegation", "43")
+	repo.EXPECT().GetByTzktID(ctx, int64(43)).Return(nil, notFoundErr)
+
+	_, err := service.GetDelegationByTzktID(ctx, 43)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	assert.Contains(t, buf.String(), `"request_id":"abc-123"`)
+	assert.Contains(t, buf.String(), `"component":"DelegationService"`)
+}
+
 func fixedTime() time.Time {
 	return time.Date(2022, 5, 5, 6, 29, 14, 0, time.UTC)
 }
+
+func TestDelegationService_ImportDelegations(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+	service := NewDelegationService(repo, zerolog.Nop(), DelegationServiceOptions{})
+	ctx := context.Background()
+	timestamp := time.Date(2022, 5, 5, 6, 29, 14, 0, time.UTC)
+
+	t.Run("valid delegations", func(t *testing.T) {
+		delegations := []model.Delegation{
+			{TzktID: 1, Timestamp: timestamp, Amount: 100, Delegator: "tz1a1SAaXRt9yoGMx29rh9FsBF4UzmvojdTL", Level: 10},
+			{TzktID: 2, Timestamp: timestamp, Amount: 0, Delegator: "KT1JejNYjmQYh8yw95u5kfQDRuxJcaUPjUnf", Level: 11, Type: model.OperationTypeOrigination},
+		}
+		repo.EXPECT().InsertDelegations(ctx, []*model.Delegation{
+			{TzktID: 1, Timestamp: timestamp, Amount: 100, Delegator: "tz1a1SAaXRt9yoGMx29rh9FsBF4UzmvojdTL", Level: 10, Type: model.OperationTypeDelegation},
+			{TzktID: 2, Timestamp: timestamp, Amount: 0, Delegator: "KT1JejNYjmQYh8yw95u5kfQDRuxJcaUPjUnf", Level: 11, Type: model.OperationTypeOrigination},
+		}).Return(int64(1), nil)
+
+		result, err := service.ImportDelegations(ctx, delegations)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), result.Inserted)
+		assert.Equal(t, int64(1), result.Skipped, "the delegation already stored is skipped")
+		assert.Empty(t, result.Errors)
+	})
+
+	t.Run("partially invalid delegations", func(t *testing.T) {
+		delegations := []model.Delegation{
+			{TzktID: 1, Timestamp: timestamp, Amount: 100, Delegator: "tz1a1SAaXRt9yoGMx29rh9FsBF4UzmvojdTL"},
+			{TzktID: 0, Timestamp: timestamp, Amount: 100, Delegator: "tz1a1SAaXRt9yoGMx29rh9FsBF4UzmvojdTL"},
+			{TzktID: 3, Timestamp: timestamp, Amount: 100, Delegator: "tz1notanaddress"},
+			{TzktID: 4, Timestamp: timestamp, Amount: -5, Delegator: "tz1a1SAaXRt9yoGMx29rh9FsBF4UzmvojdTL"},
+			{TzktID: 5, Timestamp: timestamp, Amount: 100, Delegator: "tz1a1SAaXRt9yoGMx29rh9FsBF4UzmvojdTL", Type: "transaction"},
+			{TzktID: 1, Timestamp: timestamp, Amount: 200, Delegator: "tz1a1SAaXRt9yoGMx29rh9FsBF4UzmvojdTL"},
+		}
+		repo.EXPECT().InsertDelegations(ctx, gomock.Len(1)).Return(int64(1), nil)
+
+		result, err := service.ImportDelegations(ctx, delegations)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), result.Inserted)
+		assert.Equal(t, int64(0), result.Skipped)
+		if !assert.Len(t, result.Errors, 5) {
+			return
+		}
+		for i, e := range result.Errors {
+			assert.Equal(t, i+1, e.Index)
+		}
+		assert.Contains(t, result.Errors[0].Message, "tzkt_id")
+		assert.Contains(t, result.Errors[1].Message, "address")
+		assert.Contains(t, result.Errors[2].Message, "amount")
+		assert.Contains(t, result.Errors[3].Message, "type")
+		assert.Contains(t, result.Errors[4].Message, "duplicate")
+		assert.Equal(t, int64(1), result.Errors[4].TzktID)
+	})
+
+	t.Run("insert fails", func(t *testing.T) {
+		delegations := []model.Delegation{{TzktID: 1, Timestamp: timestamp, Delegator: "tz1a1SAaXRt9yoGMx29rh9FsBF4UzmvojdTL"}}
+		repo.EXPECT().InsertDelegations(ctx, gomock.Any()).Return(int64(0), assert.AnError)
+
+		result, err := service.ImportDelegations(ctx, delegations)
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.Nil(t, result)
+	})
+}
+
+func TestDelegationService_GetSyncStatus(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+	ctx := context.Background()
+	latest := &model.Delegation{TzktID: 500, Timestamp: fixedTime()}
+	tzktLatest := &model.Delegation{TzktID: 750, Timestamp: fixedTime().Add(90 * time.Second)}
+	tzktLatestFunc := func(context.Context) (*model.Delegation, error) { return tzktLatest, nil }
+
+	t.Run("lag behind tzkt", func(t *testing.T) {
+		service := NewDelegationService(repo, zerolog.Nop(), DelegationServiceOptions{TzktLatest: tzktLatestFunc})
+		repo.EXPECT().GetLatestTzktID(ctx).Return(int64(500), nil)
+		repo.EXPECT().GetByTzktID(ctx, int64(500)).Return(latest, nil)
+
+		status, err := service.GetSyncStatus(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, &model.SyncStatus{Latest: latest, TzktLatest: tzktLatest, HasLag: true, LagTzktIDs: 250, Lag: 90 * time.Second}, status)
+	})
+
+	t.Run("nothing stored", func(t *testing.T) {
+		service := NewDelegationService(repo, zerolog.Nop(), DelegationServiceOptions{TzktLatest: tzktLatestFunc})
+		repo.EXPECT().GetLatestTzktID(ctx).Return(int64(0), nil)
+
+		status, err := service.GetSyncStatus(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, &model.SyncStatus{TzktLatest: tzktLatest}, status)
+	})
+
+	t.Run("ahead of cached tzkt value", func(t *testing.T) {
+		service := NewDelegationService(repo, zerolog.Nop(), DelegationServiceOptions{TzktLatest: tzktLatestFunc})
+		ahead := &model.Delegation{TzktID: 800, Timestamp: tzktLatest.Timestamp.Add(time.Minute)}
+		repo.EXPECT().GetLatestTzktID(ctx).Return(int64(800), nil)
+		repo.EXPECT().GetByTzktID(ctx, int64(800)).Return(ahead, nil)
+
+		status, err := service.GetSyncStatus(ctx)
+		assert.NoError(t, err)
+		assert.True(t, status.HasLag)
+		assert.Zero(t, status.LagTzktIDs)
+		assert.Zero(t, status.Lag)
+	})
+
+	t.Run("not polling tzkt", func(t *testing.T) {
+		service := NewDelegationService(repo, zerolog.Nop(), DelegationServiceOptions{})
+		repo.EXPECT().GetLatestTzktID(ctx).Return(int64(500), nil)
+		repo.EXPECT().GetByTzktID(ctx, int64(500)).Return(latest, nil)
+
+		status, err := service.GetSyncStatus(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, &model.SyncStatus{Latest: latest}, status)
+	})
+
+	t.Run("tzkt unavailable", func(t *testing.T) {
+		service := NewDelegationService(repo, zerolog.Nop(), DelegationServiceOptions{
+			TzktLatest: func(context.Context) (*model.Delegation, error) { return nil, ErrTzktCircuitOpen },
+		})
+		repo.EXPECT().GetLatestTzktID(ctx).Return(int64(500), nil)
+		repo.EXPECT().GetByTzktID(ctx, int64(500)).Return(latest, nil)
+
+		_, err := service.GetSyncStatus(ctx)
+		assert.ErrorIs(t, err, ErrTzktUnavailable)
+		assert.ErrorIs(t, err, ErrTzktCircuitOpen)
+	})
+
+	t.Run("repository error", func(t *testing.T) {
+		service := NewDelegationService(repo, zerolog.Nop(), DelegationServiceOptions{TzktLatest: tzktLatestFunc})
+		repo.EXPECT().GetLatestTzktID(ctx).Return(int64(0), apperrors.NewDatabaseError("query latest TzktID", "failed"))
+
+		_, err := service.GetSyncStatus(ctx)
+		assert.True(t, apperrors.IsDatabaseError(err))
+	})
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"tezos-delegation/internal/apperrors"
+	"tezos-delegation/internal/ports"
+	"tezos-delegation/internal/requestid"
+
+	"github.com/rs/zerolog"
+)
+
+// ErrHistoricalSyncIncomplete is returned by CheckReadiness while the poller is still backfilling history.
+var ErrHistoricalSyncIncomplete = errors.New("historical sync not complete")
+
+// ErrStartupIncomplete is returned by CheckReadiness until startup has run the migrations and reached the database.
+var ErrStartupIncomplete = errors.New("startup not complete")
+
+// ErrSchemaNotReady is returned by CheckReadiness while the delegations table can't be queried, e.g. after a failed migration.
+var ErrSchemaNotReady = errors.New("schema not ready")
+
+// ErrShuttingDown is returned by CheckReadiness once shutdown has begun draining traffic.
+var ErrShuttingDown = errors.New("shutting down")
+
+// HealthOptions tunes the readiness check
+type HealthOptions struct {
+	// SkipSyncCheck reports ready on database connectivity alone, for read-only instances
+	// that don't run the poller and so don't wait on the historical sync
+	SkipSyncCheck bool
+	// CircuitState reports the poller's Tzkt circuit breaker state; nil when the poller isn't running
+	CircuitState func() string
+	// SyncComplete reports the poller's own historical sync completion flag, which is ready before the
+	// persisted sync state when recording it fails; nil when the poller isn't running
+	SyncComplete func() bool
+	// Started reports whether startup has finished migrating and checking the database; nil means it has
+	Started func() bool
+	// Draining reports whether shutdown has begun, so load balancers stop routing new requests; nil means never
+	Draining func() bool
+}
+
+// HealthService implements HealthServicePort
+type HealthService struct {
+	Repo   ports.DelegationRepositoryPort
+	Logger zerolog.Logger
+	opts   HealthOptions
+}
+
+// Ensure HealthService implements HealthServicePort
+var _ ports.HealthServicePort = (*HealthService)(nil)
+
+func NewHealthService(repo ports.DelegationRepositoryPort, logger zerolog.Logger, opts HealthOptions) *HealthService {
+	return &HealthService{
+		Repo:   repo,
+		Logger: logger.With().Str("component", "HealthService").Logger(),
+		opts:   opts,
+	}
+}
+
+// logger returns the service logger scoped to the request carried by ctx
+func (s *HealthService) logger(ctx context.Context) *zerolog.Logger {
+	return requestid.Logger(ctx, &s.Logger)
+}
+
+// CheckReadiness reports whether the service is ready to serve queries.
+// Returns ErrShuttingDown once shutdown is draining traffic,
+// ErrStartupIncomplete while startup is still migrating the database, a database error if the
+// database can't be reached or the sync state can't be read, ErrSchemaNotReady if the delegations
+// table can't be queried, or ErrHistoricalSyncIncomplete
+// if neither the persisted sync state nor the poller reports the initial historical sync finished
+// (unless SkipSyncCheck is set).
+func (s *HealthService) CheckReadiness(ctx context.Context) error {
+	if s.opts.Draining != nil && s.opts.Draining() {
+		s.logger(ctx).Debug().Msg("Readiness check failed: shutting down")
+		return ErrShuttingDown
+	}
+	if s.opts.Started != nil && !s.opts.Started() {
+		s.logger(ctx).Debug().Msg("Readiness check failed: startup in progress")
+		return ErrStartupIncomplete
+	}
+	if err := s.Repo.CheckSchema(ctx); err != nil {
+		if apperrors.IsDatabaseUnavailableError(err) {
+			s.logger(ctx).Warn().Err(err).Msg("Readiness check failed: database unavailable")
+			return fmt.Errorf("failed to check schema: %w", err)
+		}
+		s.logger(ctx).Warn().Err(err).Msg("Readiness check failed: schema not ready")
+		return fmt.Errorf("%w: %w", ErrSchemaNotReady, err)
+	}
+	state, err := s.Repo.GetSyncState(ctx)
+	if err != nil {
+		s.logger(ctx).Warn().Err(err).Msg("Readiness check failed: database unavailable")
+		return fmt.Errorf("failed to read sync state: %w", err)
+	}
+	syncComplete := state.HistoricalComplete || (s.opts.SyncComplete != nil && s.opts.SyncComplete())
+	if !syncComplete && !s.opts.SkipSyncCheck {
+		s.logger(ctx).Debug().Int64("last_tzkt_id", state.LastTzktID).Msg("Readiness check failed: historical sync in progress")
+		return ErrHistoricalSyncIncomplete
+	}
+	return nil
+}
+
+// TzktCircuitState returns the state of the poller's Tzkt circuit breaker, or "" if the poller isn't running
+func (s *HealthService) TzktCircuitState() string {
+	if s.opts.CircuitState == nil {
+		return ""
+	}
+	return s.opts.CircuitState()
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"tezos-delegation/internal/apperrors"
+	"tezos-delegation/internal/mocks"
+	"tezos-delegation/internal/model"
+
+	"github.com/golang/mock/gomock"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthService_CheckReadiness(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+	service := NewHealthService(repo, zerolog.Nop(), HealthOptions{})
+	ctx := context.Background()
+	repo.EXPECT().CheckSchema(ctx).Return(nil).AnyTimes()
+
+	t.Run("ready", func(t *testing.T) {
+		repo.EXPECT().GetSyncState(ctx).Return(&model.SyncState{LastTzktID: 42, HistoricalComplete: true}, nil)
+		assert.NoError(t, service.CheckReadiness(ctx))
+	})
+
+	t.Run("historical sync in progress", func(t *testing.T) {
+		repo.EXPECT().GetSyncState(ctx).Return(&model.SyncState{LastTzktID: 42}, nil)
+		assert.ErrorIs(t, service.CheckReadiness(ctx), ErrHistoricalSyncIncomplete)
+	})
+
+	t.Run("database unavailable", func(t *testing.T) {
+		repo.EXPECT().GetSyncState(ctx).Return(nil, assert.AnError)
+		err := service.CheckReadiness(ctx)
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.NotErrorIs(t, err, ErrHistoricalSyncIncomplete)
+	})
+}
+
+func TestHealthService_CheckReadiness_Schema(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+	service := NewHealthService(repo, zerolog.Nop(), HealthOptions{})
+	ctx := context.Background()
+
+	t.Run("table missing", func(t *testing.T) {
+		repo.EXPECT().CheckSchema(ctx).Return(apperrors.NewDatabaseError("check schema", "delegations table not queryable"))
+		err := service.CheckReadiness(ctx)
+		assert.ErrorIs(t, err, ErrSchemaNotReady)
+	})
+
+	t.Run("database unavailable", func(t *testing.T) {
+		dbErr := apperrors.NewDatabaseUnavailableError(apperrors.NewDatabaseError("check schema", "connection refused"))
+		repo.EXPECT().CheckSchema(ctx).Return(dbErr)
+		err := service.CheckReadiness(ctx)
+		assert.True(t, apperrors.IsDatabaseUnavailableError(err))
+		assert.NotErrorIs(t, err, ErrSchemaNotReady)
+	})
+}
+
+func TestHealthService_CheckReadiness_SyncComplete(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+	complete := false
+	service := NewHealthService(repo, zerolog.Nop(), HealthOptions{SyncComplete: func() bool { return complete }})
+	ctx := context.Background()
+	repo.EXPECT().CheckSchema(ctx).Return(nil).AnyTimes()
+
+	t.Run("not ready while the poller is syncing", func(t *testing.T) {
+		repo.EXPECT().GetSyncState(ctx).Return(&model.SyncState{LastTzktID: 42}, nil)
+		assert.ErrorIs(t, service.CheckReadiness(ctx), ErrHistoricalSyncIncomplete)
+	})
+
+	t.Run("ready once the poller has caught up, before the state is persisted", func(t *testing.T) {
+		complete = true
+		repo.EXPECT().GetSyncState(ctx).Return(&model.SyncState{LastTzktID: 42}, nil)
+		assert.NoError(t, service.CheckReadiness(ctx))
+	})
+}
+
+func TestHealthService_CheckReadiness_SkipSyncCheck(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+	service := NewHealthService(repo, zerolog.Nop(), HealthOptions{SkipSyncCheck: true})
+	ctx := context.Background()
+	repo.EXPECT().CheckSchema(ctx).Return(nil).AnyTimes()
+
+	t.Run("ready during historical sync", func(t *testing.T) {
+		repo.EXPECT().GetSyncState(ctx).Return(&model.SyncState{LastTzktID: 42}, nil)
+		assert.NoError(t, service.CheckReadiness(ctx))
+	})
+
+	t.Run("database unavailable", func(t *testing.T) {
+		repo.EXPECT().GetSyncState(ctx).Return(nil, assert.AnError)
+		assert.ErrorIs(t, service.CheckReadiness(ctx), assert.AnError)
+	})
+}
+
+func TestHealthService_CheckReadiness_Started(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+	var started atomic.Bool
+	service := NewHealthService(repo, zerolog.Nop(), HealthOptions{Started: started.Load})
+	ctx := context.Background()
+	repo.EXPECT().CheckSchema(ctx).Return(nil).AnyTimes()
+
+	t.Run("not ready before startup finishes", func(t *testing.T) {
+		// The database isn't queried until the migrations have run
+		assert.ErrorIs(t, service.CheckReadiness(ctx), ErrStartupIncomplete)
+	})
+
+	t.Run("ready once started", func(t *testing.T) {
+		started.Store(true)
+		repo.EXPECT().GetSyncState(ctx).Return(&model.SyncState{HistoricalComplete: true}, nil)
+		assert.NoError(t, service.CheckReadiness(ctx))
+	})
+}
+
+func TestHealthService_CheckReadiness_Draining(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+	var draining atomic.Bool
+	service := NewHealthService(repo, zerolog.Nop(), HealthOptions{Draining: draining.Load})
+	ctx := context.Background()
+	repo.EXPECT().CheckSchema(ctx).Return(nil).AnyTimes()
+
+	t.Run("ready before shutdown", func(t *testing.T) {
+		repo.EXPECT().GetSyncState(ctx).Return(&model.SyncState{HistoricalComplete: true}, nil)
+		assert.NoError(t, service.CheckReadiness(ctx))
+	})
+
+	t.Run("not ready once draining", func(t *testing.T) {
+		draining.Store(true)
+		assert.ErrorIs(t, service.CheckReadiness(ctx), ErrShuttingDown)
+	})
+}
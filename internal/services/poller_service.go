@@ -3,70 +3,425 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand/v2"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"sync"
+	"sync/atomic"
+	"tezos-delegation/internal/apperrors"
+	"tezos-delegation/internal/metrics"
 	"tezos-delegation/internal/model"
 	"tezos-delegation/internal/ports"
 
 	"github.com/rs/zerolog"
+	"golang.org/x/time/rate"
 )
 
 const (
-	tzktBaseURL     = "https://api.tzkt.io/v1/operations/delegations"
-	pageSize        = 1000 // Tzkt max page size
-	maxRetries      = 5
-	initialBackoff  = time.Second
-	maxErrorBodyLen = 4096
-	maxTotalWait    = 2 * time.Minute
+	defaultTzktBaseURL      = "https://api.tzkt.io/v1"
+	maxPageSize             = 1000 // Tzkt max page size, and the default
+	defaultMaxRetries       = 5
+	defaultInitialBackoff   = time.Second
+	maxErrorBodyLen         = 4096
+	defaultMaxTotalWait     = 2 * time.Minute
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = time.Minute
+	defaultMaxIdleConns     = 100
+	defaultMaxIdlePerHost   = 10
+	defaultIdleConnTimeout  = 90 * time.Second
+	defaultTLSTimeout       = 10 * time.Second
+	defaultRequestTimeout   = 30 * time.Second
+	defaultLockRetry        = 30 * time.Second
+	maxTzktRedirects        = 3
+	maxBodySnippet          = 512 // Body bytes logged when a response can't be decoded
+
+	// tzktLatestTTL is how long the newest delegation on Tzkt is cached for sync status requests
+	tzktLatestTTL = 10 * time.Second
+
+	// tzktDelegationSelect trims fetched delegations to the fields stored, cutting the response size
+	tzktDelegationSelect = "select=id,timestamp,amount,sender.address,level"
 )
 
+// PollerOptions holds the tunable poller behavior loaded from configuration.
+type PollerOptions struct {
+	VerifyInserts      bool    // Read back each inserted batch to detect silent write failures
+	HistoricalWorkers  int     // Pages fetched concurrently during the historical sync; 1 or less fetches serially
+	RateLimit          float64 // Maximum Tzkt requests per second across all workers; 0 or less disables limiting
+	TrackOriginations  bool    // Also sync originations that set a delegate, stored with type "origination"
+	APIKey             string  // Sent as a bearer token in the Authorization header when set; never logged
+	PageSize           int     // Operations requested per Tzkt page; 0 or anything above maxPageSize uses maxPageSize
+	InsertBatchSize    int     // Rows stored per transaction, splitting larger fetched pages; 0 or less stores each page at once
+	BackfillBestEffort bool    // In BackfillRange, skip and report rows that fail to insert instead of failing the batch
+	AnalyzeAfterSync   string  // "analyze" or "vacuum" refreshes planner statistics once this process finishes the historical sync
+	BaseURL            string  // Root of the Tzkt API, without a trailing slash; empty uses defaultTzktBaseURL
+	Retry              RetryPolicy
+	Breaker            BreakerPolicy
+	Transport          TransportPolicy
+
+	// KeepEmptyDelegators stores operations Tzkt returns without a delegator address; by default they are dropped
+	KeepEmptyDelegators bool
+
+	// Client sends the Tzkt requests; nil uses an *http.Client tuned by Transport, which follows redirects
+	// as checkRedirect allows. Transport doesn't apply to a client passed in.
+	Client ports.HTTPClientPort
+
+	// LockRetryInterval is how often a standby instance retries the poll lock; 0 or less uses defaultLockRetry
+	LockRetryInterval time.Duration
+}
+
+// RetryPolicy bounds how long a single Tzkt request is retried. Zero fields use the defaults.
+type RetryPolicy struct {
+	MaxRetries     int           // Attempts per request before giving up
+	InitialBackoff time.Duration // First backoff delay, doubled after each retry
+	MaxTotalWait   time.Duration // No new attempt is started once this much time has passed
+}
+
+// withDefaults returns the policy with unset fields replaced by the defaults.
+func (r RetryPolicy) withDefaults() RetryPolicy {
+	if r.MaxRetries <= 0 {
+		r.MaxRetries = defaultMaxRetries
+	}
+	if r.InitialBackoff <= 0 {
+		r.InitialBackoff = defaultInitialBackoff
+	}
+	if r.MaxTotalWait <= 0 {
+		r.MaxTotalWait = defaultMaxTotalWait
+	}
+	return r
+}
+
+// BreakerPolicy configures the circuit breaker around Tzkt calls. Zero fields use the defaults.
+type BreakerPolicy struct {
+	Threshold int           // Consecutive failed fetches, after retries, that open the circuit
+	Cooldown  time.Duration // How long the circuit stays open before a single probe request is let through
+}
+
+// withDefaults returns the policy with unset fields replaced by the defaults.
+func (b BreakerPolicy) withDefaults() BreakerPolicy {
+	if b.Threshold <= 0 {
+		b.Threshold = defaultBreakerThreshold
+	}
+	if b.Cooldown <= 0 {
+		b.Cooldown = defaultBreakerCooldown
+	}
+	return b
+}
+
+// TransportPolicy tunes the connection pool and timeouts of the HTTP client used for Tzkt.
+// Zero fields use the defaults.
+type TransportPolicy struct {
+	MaxIdleConns        int           // Idle connections kept across all hosts
+	MaxIdleConnsPerHost int           // Idle connections kept per host
+	IdleConnTimeout     time.Duration // How long an idle connection is kept before closing
+	TLSHandshakeTimeout time.Duration // Longest wait for a TLS handshake
+	RequestTimeout      time.Duration // Longest a single request may take, including reading the body
+}
+
+// withDefaults returns the policy with unset fields replaced by the defaults.
+func (t TransportPolicy) withDefaults() TransportPolicy {
+	if t.MaxIdleConns <= 0 {
+		t.MaxIdleConns = defaultMaxIdleConns
+	}
+	if t.MaxIdleConnsPerHost <= 0 {
+		t.MaxIdleConnsPerHost = defaultMaxIdlePerHost
+	}
+	if t.IdleConnTimeout <= 0 {
+		t.IdleConnTimeout = defaultIdleConnTimeout
+	}
+	if t.TLSHandshakeTimeout <= 0 {
+		t.TLSHandshakeTimeout = defaultTLSTimeout
+	}
+	if t.RequestTimeout <= 0 {
+		t.RequestTimeout = defaultRequestTimeout
+	}
+	return t
+}
+
 // PollerService periodically syncs delegation data from the Tzkt API to the local database.
 type PollerService struct {
-	repo   ports.DelegationRepositoryPort // Use interface for easier mocking
-	client *http.Client                   // HTTP client for making API requests
-	wg     sync.WaitGroup                 // WaitGroup to manage goroutine lifecycle
-	logger zerolog.Logger                 // Structured logger for logging events and errors
+	repo               ports.DelegationRepositoryPort    // Use interface for easier mocking
+	client             ports.HTTPClientPort              // HTTP client for making API requests; any implementation can stand in for Tzkt in tests
+	wg                 sync.WaitGroup                    // WaitGroup to manage goroutine lifecycle
+	logger             zerolog.Logger                    // Structured logger for logging events and errors
+	historicalComplete atomic.Bool                       // Whether the initial historical sync has finished; read by readiness checks
+	historical         historicalProgress                // Progress of the historical sync run by this process
+	opts               PollerOptions                     // Tunable poller behavior
+	gate               rateGate                          // Pauses all fetches while Tzkt is rate limiting us
+	limiter            *rate.Limiter                     // Proactive client-side rate limit; nil means unlimited
+	jitter             func(time.Duration) time.Duration // Randomizes a backoff delay; nil uses fullJitter
+	breaker            *circuitBreaker                   // Skips Tzkt calls during an outage; nil never trips
+	tzktLatest         tzktLatestCache                   // Newest delegation on Tzkt, cached for sync status requests
 }
 
-// NewPoller constructs a new Poller instance with the provided repository and logger.
-func NewPoller(repo ports.DelegationRepositoryPort, logger zerolog.Logger) *PollerService {
-	// Configure HTTP client with connection pooling and timeouts
+// tzktLatestCache holds the newest delegation on Tzkt fetched for sync status requests. The mutex is held
+// while fetching, so concurrent requests wait for a single call instead of each calling Tzkt.
+type tzktLatestCache struct {
+	mu        sync.Mutex
+	value     *model.Delegation
+	fetchedAt time.Time
+}
+
+// historicalProgress tracks the historical sync run by this process, for progress logs and metrics.
+// It is only touched by the sync goroutine.
+type historicalProgress struct {
+	started  time.Time // When this process started (or resumed) the historical sync
+	batches  int64     // Batches stored so far
+	inserted int64     // Delegations inserted so far
+}
+
+// rateGate coordinates concurrent fetchers so a rate limit response seen by one pauses all of them.
+// The zero value is an open gate.
+type rateGate struct {
+	mu    sync.Mutex
+	until time.Time
+}
+
+// Pause closes the gate for d, extending any pause already in effect.
+func (g *rateGate) Pause(d time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if until := time.Now().Add(d); until.After(g.until) {
+		g.until = until
+	}
+}
+
+// Paused reports whether the gate is currently closed.
+func (g *rateGate) Paused() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return time.Now().Before(g.until)
+}
+
+// Wait blocks until the gate is open or ctx is cancelled.
+func (g *rateGate) Wait(ctx context.Context) error {
+	for {
+		g.mu.Lock()
+		wait := time.Until(g.until)
+		g.mu.Unlock()
+		if wait <= 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// NewPoller constructs a new Poller instance with the provided repository, logger, and options.
+func NewPoller(repo ports.DelegationRepositoryPort, logger zerolog.Logger, opts PollerOptions) *PollerService {
+	// Token bucket shared by all fetchers, bursting up to one second's worth of requests
+	var limiter *rate.Limiter
+	if opts.RateLimit > 0 {
+		limiter = rate.NewLimiter(rate.Limit(opts.RateLimit), max(1, int(opts.RateLimit)))
+	}
+
+	opts.Retry = opts.Retry.withDefaults()
+	opts.Breaker = opts.Breaker.withDefaults()
+	opts.Transport = opts.Transport.withDefaults()
+	if opts.LockRetryInterval <= 0 {
+		opts.LockRetryInterval = defaultLockRetry
+	}
+
+	p := &PollerService{
+		repo:    repo,
+		client:  opts.Client,
+		logger:  logger.With().Str("component", "PollerService").Logger(),
+		opts:    opts,
+		limiter: limiter,
+	}
+	if p.client == nil {
+		// Configure HTTP client with connection pooling and timeouts
+		p.client = p.newHTTPClient()
+	}
+	p.breaker = newCircuitBreaker(opts.Breaker.Threshold, opts.Breaker.Cooldown, p.onCircuitStateChange)
+	metrics.PollerTzktCircuitState.Set(0)
+	return p
+}
+
+// newHTTPClient returns the default Tzkt client, with the connection pooling and timeouts of the Transport policy
+func (p *PollerService) newHTTPClient() *http.Client {
 	transport := &http.Transport{
-		MaxIdleConns:        100,              // Maximum idle connections
-		MaxIdleConnsPerHost: 10,               // Maximum idle connections per host
-		IdleConnTimeout:     90 * time.Second, // How long to keep idle connections
-		TLSHandshakeTimeout: 10 * time.Second, // TLS handshake timeout
-		DisableCompression:  false,            // Enable compression
+		MaxIdleConns:        p.opts.Transport.MaxIdleConns,
+		MaxIdleConnsPerHost: p.opts.Transport.MaxIdleConnsPerHost,
+		IdleConnTimeout:     p.opts.Transport.IdleConnTimeout,
+		TLSHandshakeTimeout: p.opts.Transport.TLSHandshakeTimeout,
+		DisableCompression:  false, // Enable compression
+	}
+	return &http.Client{
+		Transport:     transport,
+		Timeout:       p.opts.Transport.RequestTimeout,
+		CheckRedirect: p.checkRedirect,
+	}
+}
+
+// checkRedirect follows up to maxTzktRedirects redirects from Tzkt, so a move to a new host doesn't stop
+// the sync, but warns on each one since every request pays for the extra round trip. Past the limit the
+// last redirect is returned to fetchTzktPageWithRetry, which fails with an error naming its target.
+// As with any http.Client, the Authorization header is dropped on redirects to another domain.
+func (p *PollerService) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) > maxTzktRedirects {
+		return http.ErrUseLastResponse
 	}
+	p.logger.Warn().
+		Int("status_code", req.Response.StatusCode).
+		Str("from", via[len(via)-1].URL.String()).
+		Str("to", req.URL.String()).
+		Msg("Tzkt redirected the request, update TZKT_BASE_URL")
+	return nil
+}
 
-	client := &http.Client{
-		Transport: transport,
-		Timeout:   30 * time.Second, // Set a reasonable timeout for API requests
+// operationsURL returns the URL of the Tzkt endpoint listing operations of kind, e.g. "delegations"
+func (p *PollerService) operationsURL(kind string) string {
+	base := p.opts.BaseURL
+	if base == "" {
+		base = defaultTzktBaseURL
 	}
+	return base + "/operations/" + kind
+}
+
+// pageSize returns the number of operations requested per Tzkt page
+func (p *PollerService) pageSize() int {
+	if p.opts.PageSize > 0 && p.opts.PageSize < maxPageSize {
+		return p.opts.PageSize
+	}
+	return maxPageSize
+}
+
+// HistoricalSyncComplete reports whether the initial historical sync has finished, as known to this poller.
+// Safe to call from any goroutine.
+func (p *PollerService) HistoricalSyncComplete() bool {
+	return p.historicalComplete.Load()
+}
+
+// CircuitState returns the state of the circuit breaker around Tzkt calls:
+// CircuitClosed, CircuitOpen or CircuitHalfOpen.
+func (p *PollerService) CircuitState() string {
+	return p.breaker.State()
+}
+
+// LatestTzktDelegation returns the newest delegation on Tzkt, for comparing with the newest one stored.
+// The value is cached for tzktLatestTTL, so frequent sync status requests don't hammer Tzkt. It is fetched
+// with a single attempt outside the poller's retries, rate limiter and circuit breaker, so status requests
+// can't hold up or trip the sync. Safe to call from any goroutine.
+func (p *PollerService) LatestTzktDelegation(ctx context.Context) (*model.Delegation, error) {
+	p.tzktLatest.mu.Lock()
+	defer p.tzktLatest.mu.Unlock()
+	if p.tzktLatest.value != nil && time.Since(p.tzktLatest.fetchedAt) < tzktLatestTTL {
+		return p.tzktLatest.value, nil
+	}
+
+	var result []tzktDelegation
+	if err := p.fetchTzktPageOnce(ctx, p.operationsURL("delegations")+"?limit=1&sort.desc=id&"+tzktDelegationSelect, "latest delegation", &result); err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		return nil, apperrors.NewExternalAPIError("tzkt", "fetch latest delegation", "no delegations returned")
+	}
+
+	op := result[0]
+	p.tzktLatest.value = &model.Delegation{
+		TzktID:    op.ID,
+		Timestamp: op.Timestamp.Time,
+		Amount:    op.Amount,
+		Delegator: op.delegator(),
+		Level:     op.Level,
+		Type:      model.OperationTypeDelegation,
+	}
+	p.tzktLatest.fetchedAt = time.Now()
+	return p.tzktLatest.value, nil
+}
+
+// onCircuitStateChange logs circuit breaker transitions and reports them in the metrics
+func (p *PollerService) onCircuitStateChange(from, to string) {
+	switch to {
+	case CircuitOpen:
+		metrics.PollerTzktCircuitState.Set(2)
+		metrics.PollerTzktCircuitTrips.Inc()
+		p.logger.Error().Str("from", from).Dur("cooldown", p.opts.Breaker.Cooldown).Msg("Tzkt circuit breaker tripped, skipping Tzkt calls until the cooldown ends")
+	case CircuitHalfOpen:
+		metrics.PollerTzktCircuitState.Set(1)
+		p.logger.Info().Msg("Tzkt circuit breaker half-open, sending a probe request")
+	case CircuitClosed:
+		metrics.PollerTzktCircuitState.Set(0)
+		p.logger.Info().Str("from", from).Msg("Tzkt circuit breaker closed")
+	}
+}
+
+// tzktTimeLayouts are the timestamp layouts accepted from Tzkt, tried in order. Parsing accepts fractional
+// seconds of any precision whether or not the layout has them; layouts without a zone are read as UTC.
+var tzktTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+}
 
-	return &PollerService{
-		repo:   repo,
-		client: client,
-		logger: logger.With().Str("component", "PollerService").Logger(),
+// tzktTime is a timestamp returned by Tzkt. Tzkt is mostly consistent about RFC 3339, but timestamps
+// have been seen with and without fractional seconds, with offsets instead of Z and without a zone,
+// so decoding tries each of tzktTimeLayouts and only fails when none match.
+type tzktTime struct {
+	time.Time
+}
+
+// UnmarshalJSON decodes a Tzkt timestamp string, normalized to UTC. The error quotes the offending
+// value, so it shows up in the malformed response warning.
+func (t *tzktTime) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("timestamp %s is not a string", data)
+	}
+	for _, layout := range tzktTimeLayouts {
+		if parsed, err := time.Parse(layout, s); err == nil {
+			t.Time = parsed.UTC()
+			return nil
+		}
 	}
+	return fmt.Errorf("unrecognized timestamp format %q", s)
 }
 
 // tzktDelegation represents the structure of a delegation operation returned by the Tzkt API.
+// Requested with tzktDelegationSelect, Tzkt flattens the sender's address into a "sender.address"
+// field; the nested sender of the full object is still decoded in case the parameter is ignored.
 type tzktDelegation struct {
-	ID        int64     `json:"id"`        // Unique operation ID in Tzkt
-	Timestamp time.Time `json:"timestamp"` // Time of the delegation operation
-	Amount    int64     `json:"amount"`    // Amount delegated (mutez)
-	Sender    struct {
-		Address string `json:"address"` // Delegator's address
+	ID            int64    `json:"id"`             // Unique operation ID in Tzkt
+	Timestamp     tzktTime `json:"timestamp"`      // Time of the delegation operation
+	Amount        int64    `json:"amount"`         // Amount delegated (mutez)
+	SenderAddress string   `json:"sender.address"` // Delegator's address, with tzktDelegationSelect
+	Sender        struct {
+		Address string `json:"address"` // Delegator's address, in the full object
 	} `json:"sender"`
 	Level int64 `json:"level"` // Block level of the operation
 }
 
+// delegator returns the delegator's address from whichever shape Tzkt returned
+func (d tzktDelegation) delegator() string {
+	if d.SenderAddress != "" {
+		return d.SenderAddress
+	}
+	return d.Sender.Address
+}
+
+// tzktOrigination represents the fields of an origination operation returned by the Tzkt API.
+type tzktOrigination struct {
+	ID                 int64    `json:"id"`              // Unique operation ID in Tzkt
+	Timestamp          tzktTime `json:"timestamp"`       // Time of the origination
+	ContractBalance    int64    `json:"contractBalance"` // Initial balance of the originated contract (mutez)
+	OriginatedContract struct {
+		Address string `json:"address"` // Address of the originated contract, which does the delegating
+	} `json:"originatedContract"`
+	Level int64 `json:"level"` // Block level of the operation
+}
+
 // Start launches the poller in a new goroutine, beginning the sync and poll process.
 // The context is used for cancellation and shutdown.
 func (p *PollerService) Start(ctx context.Context) {
@@ -79,20 +434,101 @@ func (p *PollerService) Wait() {
 	p.wg.Wait()
 }
 
-// syncAndPoll first downloads all historical data as fast as possible (rate-limited),
-// then switches to periodic polling for new data every minute, catching up if behind.
+// syncAndPoll runs the sync on whichever instance holds the poll lock, so several instances can share a
+// database: the others stand by and take over when the leader stops or loses its database session.
 func (p *PollerService) syncAndPoll(ctx context.Context) {
 	defer p.wg.Done()
+	for p.acquirePollLock(ctx) {
+		lost := p.sync(ctx)
+		// Release with a fresh context, since ctx is usually cancelled by now
+		releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := p.repo.ReleasePollLock(releaseCtx); err != nil {
+			p.logger.Warn().Err(err).Msg("failed to release poll lock")
+		}
+		cancel()
+		if !lost {
+			return
+		}
+	}
+}
+
+// acquirePollLock blocks until this instance holds the poll lock, retrying every LockRetryInterval.
+// While standing by it keeps the sync state fresh, so readiness reflects the leader's progress.
+// Returns false if ctx is cancelled first.
+func (p *PollerService) acquirePollLock(ctx context.Context) bool {
+	standby := false
+	for {
+		held, err := p.repo.TryAcquirePollLock(ctx)
+		switch {
+		case err != nil:
+			if ctx.Err() != nil {
+				return false
+			}
+			p.logger.Error().Err(err).Msg("failed to acquire poll lock")
+		case held:
+			p.logger.Info().Msg("Acquired poll lock, this instance is polling")
+			return true
+		case !standby:
+			standby = true
+			p.logger.Info().Dur("retry_interval", p.opts.LockRetryInterval).Msg("Another instance holds the poll lock, standing by")
+		}
+		if !p.historicalComplete.Load() {
+			p.loadSyncState(ctx)
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(p.opts.LockRetryInterval):
+		}
+	}
+}
+
+// holdsPollLock reports whether this instance still holds the poll lock. Errors are logged and
+// treated as still holding it, since an unreachable database also fails the sync itself.
+func (p *PollerService) holdsPollLock(ctx context.Context) bool {
+	held, err := p.repo.TryAcquirePollLock(ctx)
+	if err != nil {
+		p.logger.Warn().Err(err).Msg("failed to check poll lock")
+		return true
+	}
+	if !held {
+		p.logger.Warn().Msg("Lost poll lock to another instance, standing by")
+	}
+	return held
+}
+
+// sync first downloads all historical data as fast as possible (rate-limited),
+// then switches to periodic polling for new data every minute, catching up if behind.
+// Returns true if it stopped because the poll lock was lost, false when ctx is cancelled.
+func (p *PollerService) sync(ctx context.Context) bool {
+	// Restore persisted sync state so a restart knows whether historical sync already finished
+	p.loadSyncState(ctx)
+	previouslyComplete := p.historicalComplete.Load()
+
 	// 1. Historical sync: fast as possible within rate limits
-	p.logger.Info().Str("phase", "historical_sync").Msg("syncing historical data")
+	p.historical = historicalProgress{started: time.Now()}
+	p.logger.Info().Str("phase", "historical_sync").Bool("previously_completed", p.historicalComplete.Load()).Msg("syncing historical data")
 	for {
-		// Attempt to fetch and store a batch of delegations
-		caughtUp, err := p.syncDelegationsBatch(ctx)
+		// Attempt to fetch and store a batch of delegations, prefetching several pages at once
+		// until the backfill has completed
+		var caughtUp bool
+		var err error
+		// Prefetching relies on Tzkt offsets within a single endpoint, so it is only used for delegations alone
+		if p.opts.HistoricalWorkers > 1 && !p.historicalComplete.Load() && !p.opts.TrackOriginations {
+			caughtUp, err = p.syncHistoricalPages(ctx, p.opts.HistoricalWorkers)
+		} else {
+			caughtUp, err = p.syncDelegationsBatch(ctx)
+		}
 		if err != nil {
 			// If the context was cancelled, log and exit immediately
 			if ctx.Err() != nil {
 				p.logger.Error().Err(err).Str("phase", "historical_sync").Msg("context cancelled during historical sync, exiting")
-				return
+				return false
+			}
+			// Wait out an open circuit quietly; the breaker already logged the outage
+			if errors.Is(err, ErrTzktCircuitOpen) {
+				p.waitForCircuit(ctx)
+				continue
 			}
 			// Log the error and retry after a short delay
 			p.logger.Error().Err(err).Str("phase", "historical_sync").Msg("error during historical sync")
@@ -104,6 +540,9 @@ func (p *PollerService) syncAndPoll(ctx context.Context) {
 			break
 		}
 	}
+	if !previouslyComplete {
+		p.analyzeAfterSync(ctx)
+	}
 
 	// 2. Polling: every minute, but catch up if behind
 	p.logger.Info().Msg("caught up. Polling for new data...")
@@ -113,15 +552,23 @@ func (p *PollerService) syncAndPoll(ctx context.Context) {
 		select {
 		case <-ctx.Done():
 			// Context cancelled: exit polling loop
-			return
+			return false
 		case <-ticker.C:
+			// Stand by if another instance took over, e.g. after this one's database session dropped
+			if !p.holdsPollLock(ctx) {
+				return true
+			}
 			// On each tick, try to catch up (in case multiple batches are needed)
 			for {
 				caughtUp, err := p.syncDelegationsBatch(ctx)
 				if err != nil {
 					if ctx.Err() != nil {
 						p.logger.Error().Err(err).Str("phase", "polling").Msg("context cancelled during polling, exiting")
-						return
+						return false
+					}
+					if errors.Is(err, ErrTzktCircuitOpen) {
+						p.waitForCircuit(ctx)
+						continue
 					}
 					p.logger.Error().Err(err).Str("phase", "polling").Msg("error during polling")
 					time.Sleep(time.Second)
@@ -135,6 +582,35 @@ func (p *PollerService) syncAndPoll(ctx context.Context) {
 	}
 }
 
+// analyzeAfterSync refreshes the planner statistics of the delegations table as configured by AnalyzeAfterSync,
+// so the first queries after a backfill don't run on plans made for a nearly empty table. Failures are only
+// logged, since autovacuum eventually catches up on its own.
+func (p *PollerService) analyzeAfterSync(ctx context.Context) {
+	var vacuum bool
+	switch p.opts.AnalyzeAfterSync {
+	case "analyze":
+	case "vacuum":
+		vacuum = true
+	default:
+		return
+	}
+
+	start := time.Now()
+	if err := p.repo.AnalyzeDelegations(ctx, vacuum); err != nil {
+		p.logger.Error().Err(err).Str("phase", "historical_sync").Bool("vacuum", vacuum).Msg("failed to analyze delegations after historical sync")
+		return
+	}
+	p.logger.Info().Str("phase", "historical_sync").Bool("vacuum", vacuum).Dur("duration", time.Since(start)).Msg("Analyzed delegations after historical sync")
+}
+
+// waitForCircuit blocks until the open circuit breaker lets a probe through, at least a second, or ctx is cancelled
+func (p *PollerService) waitForCircuit(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(max(time.Second, p.breaker.RetryIn())):
+	}
+}
+
 // syncDelegationsBatch fetches a batch of new delegations from Tzkt and stores them in the database.
 // Returns (caughtUp, error): caughtUp is true if there are no more new delegations to fetch.
 func (p *PollerService) syncDelegationsBatch(ctx context.Context) (bool, error) {
@@ -148,133 +624,665 @@ func (p *PollerService) syncDelegationsBatch(ctx context.Context) (bool, error)
 		return false, fmt.Errorf("failed to get latest TzktID from database: %w", err)
 	}
 
-	// Fetch a batch of delegations from the Tzkt API, starting after lastTzktID
-	delegations, err := p.fetchDelegationBatch(ctx, lastTzktID)
+	// Fetch a batch of operations from the Tzkt API, starting after lastTzktID
+	delegations, more, err := p.fetchOperationBatch(ctx, lastTzktID, "")
 	if err != nil {
 		return false, fmt.Errorf("failed to fetch delegations from Tzkt API: %w", err)
 	}
 
+	return p.storeDelegationBatch(ctx, lastTzktID, delegations, more)
+}
+
+// syncHistoricalPages fetches up to workers consecutive pages after the latest stored TzktID concurrently,
+// then stores them strictly in page order so MAX(tzkt_id) only ever moves forward.
+// If a fetch fails, the pages before it are still stored before the error is returned.
+// Returns (caughtUp, error) like syncDelegationsBatch.
+func (p *PollerService) syncHistoricalPages(ctx context.Context, workers int) (bool, error) {
+	if ctx.Err() != nil {
+		return false, fmt.Errorf("context cancelled: %w", ctx.Err())
+	}
+
+	// Only one fetch gets through a breaker that isn't closed, the probe, and the refused fetches would
+	// cancel it; fetch a single page until the probe has closed the breaker
+	if p.breaker.State() != CircuitClosed {
+		return p.syncDelegationsBatch(ctx)
+	}
+
+	lastTzktID, err := p.repo.GetLatestTzktID(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get latest TzktID from database: %w", err)
+	}
+
+	// Fetch pages concurrently; the first failure cancels the remaining fetches, except a refusal by the
+	// circuit breaker, which says nothing about the fetches already let through
+	pageSize := p.pageSize()
+	fetchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	pages := make([][]model.Delegation, workers)
+	errs := make([]error, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			pages[i], errs[i] = p.fetchDelegationBatch(fetchCtx, lastTzktID, i*pageSize, "")
+			if errs[i] != nil && !errors.Is(errs[i], ErrTzktCircuitOpen) {
+				cancel()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// Store in order; a short page means there is nothing beyond it
+	for i, page := range pages {
+		if errs[i] != nil {
+			return false, fmt.Errorf("failed to fetch delegations from Tzkt API (page offset %d): %w", i*pageSize, errs[i])
+		}
+		if ctx.Err() != nil {
+			return false, fmt.Errorf("context cancelled: %w", ctx.Err())
+		}
+		caughtUp, err := p.storeDelegationBatch(ctx, lastTzktID, page, len(page) == pageSize)
+		if err != nil || caughtUp {
+			return caughtUp, err
+		}
+		lastTzktID = page[len(page)-1].TzktID
+	}
+	return false, nil
+}
+
+// storeDelegationBatch inserts a fetched batch that follows lastTzktID and records sync progress.
+// more reports whether Tzkt may have further operations after the batch.
+// Returns (caughtUp, error): caughtUp is true if there was nothing more to fetch.
+func (p *PollerService) storeDelegationBatch(ctx context.Context, lastTzktID int64, delegations []model.Delegation, more bool) (bool, error) {
 	p.logger.Info().Int("fetched_delegations_count", len(delegations)).Int64("last_tzkt_id", lastTzktID).Msg("Fetched delegation batch")
 	if len(delegations) == 0 {
+		p.recordSyncState(ctx, lastTzktID, true)
 		return true, nil // caught up: no new delegations
 	}
 
-	// Convert []model.Delegation to []*model.Delegation for database insertion
-	delegationPtrs := make([]*model.Delegation, len(delegations))
-	for i := range delegations {
-		delegationPtrs[i] = &delegations[i]
+	// Operations without a delegator are left out, but the batch still counts as synced up to its last operation
+	lastStoredID := delegations[len(delegations)-1].TzktID
+	delegations = p.dropEmptyDelegators(delegations)
+
+	// Compare before inserting: in update mode a changed row is overwritten and counted as inserted, so
+	// comparing afterwards would never see the difference
+	if len(delegations) > 0 {
+		p.checkReorgs(ctx, delegations)
 	}
 
 	// Insert the new delegations into the database
-	err = p.repo.InsertDelegations(delegationPtrs)
+	inserted, err := p.insertDelegations(ctx, delegations)
 	if err != nil {
 		return false, fmt.Errorf("failed to store delegations to database: %w", err)
 	}
+	p.logger.Info().Int("fetched", len(delegations)).Int64("inserted", inserted).Int64("last_tzkt_id", lastStoredID).Msg("Stored delegation batch")
+
+	// Fewer rows inserted than fetched means some TzktIDs were already stored and skipped by ON CONFLICT,
+	// which can hide gaps in the sequence, so make it visible
+	if skipped := int64(len(delegations)) - inserted; skipped > 0 {
+		p.logger.Warn().Int("fetched", len(delegations)).Int64("inserted", inserted).Int64("skipped", skipped).Int64("last_tzkt_id", lastStoredID).Msg("Some fetched delegations were already stored and skipped")
+		metrics.PollerDuplicateSkips.Add(float64(skipped))
+	}
+
+	if p.opts.VerifyInserts && len(delegations) > 0 {
+		p.verifyInserted(ctx, delegations)
+	}
+
+	// If no endpoint returned a full page, we're caught up; otherwise, there may be more
+	caughtUp := !more
+	if !p.historicalComplete.Load() {
+		p.recordHistoricalBatch(lastStoredID, inserted)
+	}
+	p.recordSyncState(ctx, lastStoredID, caughtUp)
+	return caughtUp, nil
+}
+
+// recordHistoricalBatch logs and reports progress after a batch stored during the historical sync
+func (p *PollerService) recordHistoricalBatch(lastTzktID, inserted int64) {
+	p.historical.batches++
+	p.historical.inserted += inserted
+	metrics.PollerHistoricalBatches.Inc()
+	metrics.PollerHistoricalInserted.Add(float64(inserted))
+	p.logger.Info().
+		Str("phase", "historical_sync").
+		Int64("last_tzkt_id", lastTzktID).
+		Int64("batches", p.historical.batches).
+		Int64("inserted", p.historical.inserted).
+		Dur("elapsed", time.Since(p.historical.started)).
+		Msg("Historical sync progress")
+}
+
+// BackfillRange re-fetches the tracked operations with from <= timestamp < to and stores any that are missing,
+// e.g. to repair a gap found in historical data. The window is paged by TzktID independently of the sync cursor,
+// and the sync state is left untouched. Operations already stored are skipped by ON CONFLICT DO NOTHING,
+// so a backfill is safe to rerun. Returns the number of rows inserted.
+func (p *PollerService) BackfillRange(ctx context.Context, from, to time.Time) (int64, error) {
+	if !from.Before(to) {
+		return 0, apperrors.NewValidationError("to", fmt.Sprintf("must be after from (%s), got %s", from.Format(time.RFC3339), to.Format(time.RFC3339)))
+	}
+	filter := fmt.Sprintf("&timestamp.ge=%s&timestamp.lt=%s", from.UTC().Format(time.RFC3339), to.UTC().Format(time.RFC3339))
+
+	var lastTzktID, fetched, inserted int64
+	for {
+		if ctx.Err() != nil {
+			return inserted, fmt.Errorf("context cancelled: %w", ctx.Err())
+		}
+
+		operations, more, err := p.fetchOperationBatch(ctx, lastTzktID, filter)
+		if err != nil {
+			return inserted, fmt.Errorf("failed to fetch delegations from Tzkt API: %w", err)
+		}
+		if len(operations) > 0 {
+			n, err := p.insertBackfillBatch(ctx, operations)
+			if err != nil {
+				return inserted, fmt.Errorf("failed to store delegations to database: %w", err)
+			}
+			fetched += int64(len(operations))
+			inserted += n
+			lastTzktID = operations[len(operations)-1].TzktID
+			p.logger.Info().Int("fetched", len(operations)).Int64("inserted", n).Int64("last_tzkt_id", lastTzktID).Msg("Stored backfill batch")
+		}
+		if !more {
+			p.logger.Info().Time("from", from).Time("to", to).Int64("fetched", fetched).Int64("inserted", inserted).Msg("Backfill complete")
+			return inserted, nil
+		}
+	}
+}
+
+// insertBackfillBatch stores a backfill batch. With BackfillBestEffort, rows that fail to insert are
+// logged and counted and the rest of the batch is kept; otherwise any failure fails the whole batch.
+func (p *PollerService) insertBackfillBatch(ctx context.Context, operations []model.Delegation) (int64, error) {
+	operations = p.dropEmptyDelegators(operations)
+	if len(operations) == 0 {
+		return 0, nil
+	}
+
+	// A backfill re-fetches stored history, so compare before the upsert mode can overwrite the stored rows
+	p.checkReorgs(ctx, operations)
+
+	if !p.opts.BackfillBestEffort {
+		return p.insertDelegations(ctx, operations)
+	}
+
+	inserted, failures, err := p.repo.InsertDelegationsBestEffort(ctx, delegationPointers(operations))
+	for _, failure := range failures {
+		p.logger.Error().Err(failure).Msg("Skipped delegation that failed to insert")
+	}
+	metrics.PollerInsertFailures.Add(float64(len(failures)))
+	return inserted, err
+}
+
+// insertDelegations stores a fetched batch. With InsertBatchSize smaller than the batch, it is split into
+// sub-batches of that size, each stored in its own transaction with cumulative progress logged after it.
+// Sub-batches are stored in TzktID order, so a failure part way through leaves MAX(tzkt_id) a valid resume
+// point. Returns the rows inserted, including those of sub-batches stored before a failure.
+func (p *PollerService) insertDelegations(ctx context.Context, delegations []model.Delegation) (int64, error) {
+	if len(delegations) == 0 {
+		return 0, nil
+	}
+	size := p.opts.InsertBatchSize
+	if size <= 0 || size >= len(delegations) {
+		return p.repo.InsertDelegations(ctx, delegationPointers(delegations))
+	}
+
+	var inserted int64
+	for start := 0; start < len(delegations); start += size {
+		chunk := delegations[start:min(start+size, len(delegations))]
+		n, err := p.repo.InsertDelegations(ctx, delegationPointers(chunk))
+		if err != nil {
+			return inserted, err
+		}
+		inserted += n
+		p.logger.Info().
+			Int("stored", start+len(chunk)).
+			Int("fetched", len(delegations)).
+			Int64("inserted", inserted).
+			Int64("last_tzkt_id", chunk[len(chunk)-1].TzktID).
+			Msg("Stored delegation sub-batch")
+	}
+	return inserted, nil
+}
+
+// delegationPointers converts a batch to the pointer slice taken by InsertDelegations
+func delegationPointers(delegations []model.Delegation) []*model.Delegation {
+	ptrs := make([]*model.Delegation, len(delegations))
+	for i := range delegations {
+		ptrs[i] = &delegations[i]
+	}
+	return ptrs
+}
+
+// verifyInserted reads back the TzktIDs of a just-inserted batch and reports any that are missing,
+// catching silent write failures. Discrepancies are logged and counted but don't fail the batch.
+func (p *PollerService) verifyInserted(ctx context.Context, delegations []model.Delegation) {
+	tzktIDs := make([]int64, len(delegations))
+	for i, d := range delegations {
+		tzktIDs[i] = d.TzktID
+	}
+
+	stored, err := p.repo.CountByTzktIDs(ctx, tzktIDs)
+	if err != nil {
+		p.logger.Error().Err(err).Int("expected", len(tzktIDs)).Msg("failed to verify inserted delegations")
+		return
+	}
+
+	if missing := int64(len(tzktIDs)) - stored; missing > 0 {
+		p.logger.Error().Int("expected", len(tzktIDs)).Int64("stored", stored).Int64("missing", missing).Int64("first_tzkt_id", tzktIDs[0]).Int64("last_tzkt_id", tzktIDs[len(tzktIDs)-1]).Msg("Inserted delegations missing on read-back")
+		metrics.PollerInsertVerificationFailures.Add(float64(missing))
+	}
+}
+
+// checkReorgs compares fetched operations with the rows already stored under the same TzktIDs and warns about
+// each one whose level or timestamp differs, which suggests Tzkt moved the operation after a chain reorg.
+// Whether the stale row is kept or overwritten is up to the repository's upsert mode. Lookup failures are
+// logged and don't fail the batch.
+func (p *PollerService) checkReorgs(ctx context.Context, delegations []model.Delegation) {
+	fetched := make(map[int64]model.Delegation, len(delegations))
+	tzktIDs := make([]int64, len(delegations))
+	for i, d := range delegations {
+		fetched[d.TzktID] = d
+		tzktIDs[i] = d.TzktID
+	}
+
+	stored, err := p.repo.GetByTzktIDs(ctx, tzktIDs)
+	if err != nil {
+		p.logger.Warn().Err(err).Int("fetched", len(tzktIDs)).Msg("failed to look up stored delegations for reorg detection")
+		return
+	}
+
+	for _, s := range stored {
+		f := fetched[s.TzktID]
+		if f.Level == s.Level && f.Timestamp.Equal(s.Timestamp) {
+			continue
+		}
+		metrics.PollerReorgsSuspected.Inc()
+		p.logger.Warn().
+			Str("event", "reorg_suspected").
+			Int64("tzkt_id", s.TzktID).
+			Int64("stored_level", s.Level).
+			Int64("fetched_level", f.Level).
+			Time("stored_timestamp", s.Timestamp).
+			Time("fetched_timestamp", f.Timestamp).
+			Msg("Fetched operation differs from the stored row, chain reorg suspected")
+	}
+}
+
+// loadSyncState restores the persisted sync state. Failures are logged and treated as a fresh start,
+// since the resume point itself is always derived from the stored delegations.
+func (p *PollerService) loadSyncState(ctx context.Context) {
+	state, err := p.repo.GetSyncState(ctx)
+	if err != nil {
+		p.logger.Warn().Err(err).Msg("failed to load sync state, assuming historical sync is incomplete")
+		return
+	}
+	p.historicalComplete.Store(state.HistoricalComplete)
+	if state.HistoricalComplete {
+		metrics.PollerHistoricalComplete.Set(1)
+	}
+	metrics.PollerLastTzktID.Set(float64(state.LastTzktID))
+	p.logger.Info().Int64("last_tzkt_id", state.LastTzktID).Time("last_poll_at", state.LastPollAt).Bool("historical_complete", state.HistoricalComplete).Msg("Loaded sync state")
+}
+
+// recordSyncState persists progress after a successful batch. Once the poller has caught up,
+// the historical sync is marked complete. Failures are logged but don't fail the batch,
+// since the delegations themselves were stored successfully.
+func (p *PollerService) recordSyncState(ctx context.Context, lastTzktID int64, caughtUp bool) {
+	metrics.PollerLastTzktID.Set(float64(lastTzktID))
+	if caughtUp && !p.historicalComplete.Swap(true) {
+		elapsed := time.Since(p.historical.started)
+		metrics.PollerHistoricalComplete.Set(1)
+		metrics.PollerHistoricalDuration.Set(elapsed.Seconds())
+		p.logger.Info().
+			Str("phase", "historical_sync").
+			Int64("last_tzkt_id", lastTzktID).
+			Int64("batches", p.historical.batches).
+			Int64("inserted", p.historical.inserted).
+			Dur("duration", elapsed).
+			Msg("Historical sync complete")
+	}
+	state := model.SyncState{
+		LastTzktID:         lastTzktID,
+		LastPollAt:         time.Now().UTC(),
+		HistoricalComplete: p.historicalComplete.Load(),
+	}
+	if err := p.repo.UpdateSyncState(ctx, state); err != nil {
+		p.logger.Error().Err(err).Int64("last_tzkt_id", lastTzktID).Msg("failed to update sync state")
+	}
+}
+
+// fetchOperationBatch fetches the next batch of tracked operations after lastID, ordered by TzktID.
+// With originations enabled, both endpoints are queried from the same cursor and merged; if either returned
+// a full page, the merged batch is cut at the lowest last ID among the full pages, since operations of the
+// other type beyond it may not have been fetched yet.
+// filter holds extra Tzkt query parameters applied to every endpoint, e.g. a timestamp window; empty for none.
+// Returns (operations, more, error): more is true if Tzkt may have further operations after the batch.
+func (p *PollerService) fetchOperationBatch(ctx context.Context, lastID int64, filter string) ([]model.Delegation, bool, error) {
+	delegations, err := p.fetchDelegationBatch(ctx, lastID, 0, filter)
+	if err != nil {
+		return nil, false, err
+	}
+	if !p.opts.TrackOriginations {
+		return delegations, len(delegations) == p.pageSize(), nil
+	}
+
+	originations, err := p.fetchOriginationBatch(ctx, lastID, filter)
+	if err != nil {
+		return nil, false, err
+	}
+	operations, more := mergeOperationPages(p.pageSize(), delegations, originations)
+	return operations, more, nil
+}
+
+// mergeOperationPages merges pages fetched from different endpoints after the same cursor into one batch
+// ordered by TzktID, truncated so it contains no gaps. A page is full when it holds pageSize operations.
+// Returns whether any page was full.
+func mergeOperationPages(pageSize int, pages ...[]model.Delegation) ([]model.Delegation, bool) {
+	var merged []model.Delegation
+	more := false
+	var cutoff int64
+	for _, page := range pages {
+		merged = append(merged, page...)
+		if len(page) == pageSize {
+			if last := page[len(page)-1].TzktID; !more || last < cutoff {
+				cutoff = last
+			}
+			more = true
+		}
+	}
+
+	sort.Slice(merged, func(i, j int) bool { return merged[i].TzktID < merged[j].TzktID })
+	if more {
+		n := sort.Search(len(merged), func(i int) bool { return merged[i].TzktID > cutoff })
+		merged = merged[:n]
+	}
+	return merged, more
+}
+
+// fetchDelegationBatch fetches a batch of delegations after lastID from the Tzkt API, skipping the first offset
+// matching operations and appending filter to the query. See fetchTzktPage for retry behavior.
+func (p *PollerService) fetchDelegationBatch(ctx context.Context, lastID int64, offset int, filter string) ([]model.Delegation, error) {
+	// Construct the Tzkt API URL with pagination (id.gt=lastID), offset is used to prefetch later pages
+	url := fmt.Sprintf("%s?limit=%d&id.gt=%d&%s", p.operationsURL("delegations"), p.pageSize(), lastID, tzktDelegationSelect)
+	if offset > 0 {
+		url += fmt.Sprintf("&offset=%d", offset)
+	}
+	url += filter
+
+	var result []tzktDelegation
+	if err := p.fetchTzktPage(ctx, url, "delegations", &result); err != nil {
+		return nil, err
+	}
 
-	// If less than a full page was fetched, we're caught up; otherwise, there may be more
-	return len(delegations) < pageSize, nil
+	// Convert to model.Delegation slice for database storage
+	delegations := make([]model.Delegation, len(result))
+	for i, op := range result {
+		p.checkAddress(op.ID, op.delegator())
+		delegations[i] = model.Delegation{
+			TzktID:    op.ID,
+			Timestamp: op.Timestamp.Time,
+			Amount:    op.Amount,
+			Delegator: op.delegator(),
+			Level:     op.Level,
+			Type:      model.OperationTypeDelegation,
+		}
+	}
+
+	return delegations, nil
+}
+
+// fetchOriginationBatch fetches a batch of originations that set a delegate after lastID from the Tzkt API.
+// The originated contract is recorded as the delegator and its initial balance as the amount.
+// filter is appended to the query.
+func (p *PollerService) fetchOriginationBatch(ctx context.Context, lastID int64, filter string) ([]model.Delegation, error) {
+	url := fmt.Sprintf("%s?limit=%d&id.gt=%d&contractDelegate.null=false&status=applied", p.operationsURL("originations"), p.pageSize(), lastID) + filter
+
+	var result []tzktOrigination
+	if err := p.fetchTzktPage(ctx, url, "originations", &result); err != nil {
+		return nil, err
+	}
+
+	originations := make([]model.Delegation, len(result))
+	for i, op := range result {
+		p.checkAddress(op.ID, op.OriginatedContract.Address)
+		originations[i] = model.Delegation{
+			TzktID:    op.ID,
+			Timestamp: op.Timestamp.Time,
+			Amount:    op.ContractBalance,
+			Delegator: op.OriginatedContract.Address,
+			Level:     op.Level,
+			Type:      model.OperationTypeOrigination,
+		}
+	}
+
+	return originations, nil
+}
+
+// dropEmptyDelegators returns operations without those whose delegator address is empty or whitespace, which
+// Tzkt returns for some baker self-operations, and logs how many were dropped. With KeepEmptyDelegators they
+// are all kept, and checkAddress warns about each one. operations itself is left unchanged.
+func (p *PollerService) dropEmptyDelegators(operations []model.Delegation) []model.Delegation {
+	if p.opts.KeepEmptyDelegators {
+		return operations
+	}
+
+	kept := make([]model.Delegation, 0, len(operations))
+	for _, op := range operations {
+		if strings.TrimSpace(op.Delegator) != "" {
+			kept = append(kept, op)
+		}
+	}
+	if dropped := len(operations) - len(kept); dropped > 0 {
+		metrics.PollerEmptyDelegatorsSkipped.Add(float64(dropped))
+		p.logger.Warn().
+			Int("fetched", len(operations)).
+			Int("dropped", dropped).
+			Int64("last_tzkt_id", operations[len(operations)-1].TzktID).
+			Msg("Dropped operations without a delegator address")
+	}
+	return kept
+}
+
+// checkAddress logs a warning if a delegator address returned by Tzkt is malformed.
+// The operation is still stored as received, since Tzkt is the source of truth.
+func (p *PollerService) checkAddress(tzktID int64, address string) {
+	if err := model.ValidateTezosAddress(address); err != nil {
+		p.logger.Warn().Err(err).Int64("tzkt_id", tzktID).Str("delegator", address).Msg("Malformed delegator address from Tzkt")
+	}
 }
 
-// fetchDelegationBatch fetches a batch of delegations from the Tzkt API, handling rate limits, server errors, and retries.
+// fetchTzktPage fetches url from the Tzkt API through the circuit breaker, see fetchTzktPageWithRetry.
+// Returns ErrTzktCircuitOpen without calling Tzkt while the breaker is open. A fetch that fails after
+// its retries counts toward opening the breaker; a cancelled one doesn't count either way.
+func (p *PollerService) fetchTzktPage(ctx context.Context, url, kind string, result any) error {
+	if err := p.breaker.Allow(); err != nil {
+		return err
+	}
+	err := p.fetchTzktPageWithRetry(ctx, url, kind, result)
+	switch {
+	case err == nil:
+		p.breaker.Success()
+	case ctx.Err() != nil:
+		p.breaker.Release()
+	default:
+		p.breaker.Failure()
+	}
+	return err
+}
+
+// fetchTzktPageWithRetry fetches url from the Tzkt API and decodes the JSON body into result,
+// handling rate limits, server errors, and retries. kind names the operations in errors.
 //
-// - Retries on HTTP 429 (Too Many Requests) and 503 (Service Unavailable), respecting the Retry-After header if present.
+// - Retries on HTTP 429 (Too Many Requests) and 503 (Service Unavailable), respecting the Retry-After header if present;
+// the wait is shared through the rate gate, so concurrent fetchers back off together.
 // - Retries on all 5xx server errors with exponential backoff.
+// - Retries on malformed or truncated JSON bodies with exponential backoff, logging the start of the body for diagnostics.
 // - Fails fast on other non-200 status codes, logging the response body for diagnostics.
 // - Enforces a maximum number of retries and a maximum total wait time.
 // - All network and retry waits are cancellable via the provided context.
-func (p *PollerService) fetchDelegationBatch(ctx context.Context, lastID int64) ([]model.Delegation, error) {
-	// Construct the Tzkt API URL with pagination (id.gt=lastID)
-	url := fmt.Sprintf("%s?limit=%d&id.gt=%d", tzktBaseURL, pageSize, lastID)
-
-	var resp *http.Response
-	var err error
-	backoff := initialBackoff
+func (p *PollerService) fetchTzktPageWithRetry(ctx context.Context, url, kind string, result any) error {
+	var lastErr error
+	decoded := false
+	policy := p.opts.Retry.withDefaults()
+	backoff := policy.InitialBackoff
 	start := time.Now()
 
 retryLoop:
-	for attempt := 0; attempt < maxRetries && time.Since(start) < maxTotalWait; attempt++ {
+	for attempt := 0; attempt < policy.MaxRetries && time.Since(start) < policy.MaxTotalWait; attempt++ {
+		// Hold off while any fetcher is rate limited, then take a token from the client-side limiter
+		if err := p.gate.Wait(ctx); err != nil {
+			return err
+		}
+		if p.limiter != nil {
+			if err := p.limiter.Wait(ctx); err != nil {
+				return err
+			}
+		}
+
 		// Create a new HTTP request with context for cancellation/timeout support
 		req, reqErr := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 		if reqErr != nil {
-			return nil, reqErr
+			return reqErr
+		}
+		if p.opts.APIKey != "" {
+			req.Header.Set("Authorization", "Bearer "+p.opts.APIKey)
 		}
-		resp, err = p.client.Do(req)
+		resp, err := p.client.Do(req)
 		if err != nil {
 			// Network error or context cancellation
-			return nil, err
+			return err
 		}
 
 		// Handle HTTP status codes
 		switch resp.StatusCode {
 		case http.StatusOK:
-			// Success: break out of retry loop and process response
-			break retryLoop
+			// Success: read and decode the body; malformed JSON counts toward the retry budget
+			body, readErr := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if readErr == nil {
+				readErr = json.Unmarshal(body, result)
+			}
+			if readErr == nil {
+				decoded = true
+				break retryLoop
+			}
+			lastErr = apperrors.NewExternalAPIErrorWithCause("tzkt", "decode "+kind, "malformed response body", readErr)
+			snippet := body
+			if len(snippet) > maxBodySnippet {
+				snippet = snippet[:maxBodySnippet]
+			}
+			wait := p.backoffWait(backoff, policy.MaxTotalWait-time.Since(start))
+			p.logger.Warn().Err(readErr).Str("body_prefix", string(snippet)).Int("body_len", len(body)).Int("attempt", attempt+1).Int("max_retries", policy.MaxRetries).Dur("wait_time", wait).Msg("HTTP malformed response body, retrying in")
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait):
+			}
+			backoff *= 2
+			continue
 		case http.StatusTooManyRequests, http.StatusServiceUnavailable:
 			// Rate limited or temporarily unavailable: check Retry-After header
 			retryAfter := resp.Header.Get("Retry-After")
 			resp.Body.Close()
+			lastErr = apperrors.NewExternalAPIError("tzkt", "fetch "+kind, fmt.Sprintf("status code %d", resp.StatusCode))
 			var wait time.Duration
 			if d, err := parseRetryAfter(retryAfter); err == nil && d > 0 {
 				wait = d
 				p.logger.Info().Int("status_code", resp.StatusCode).Str("retry_after", retryAfter).Dur("wait_time", wait).Msg("HTTP status too many requests, retrying in")
 			} else {
 				// Fallback to exponential backoff if Retry-After is missing or invalid
-				wait = backoff
-				p.logger.Info().Int("status_code", resp.StatusCode).Dur("wait_time", wait).Int("attempt", attempt+1).Int("max_retries", maxRetries).Msg("HTTP status too many requests, invalid/missing Retry-After, backoff")
+				wait = p.backoffWait(backoff, policy.MaxTotalWait-time.Since(start))
+				p.logger.Info().Int("status_code", resp.StatusCode).Dur("wait_time", wait).Int("attempt", attempt+1).Int("max_retries", policy.MaxRetries).Msg("HTTP status too many requests, invalid/missing Retry-After, backoff")
 				backoff *= 2
 			}
-			// Wait for the specified duration or until context is cancelled
-			select {
-			case <-ctx.Done():
-				return nil, ctx.Err()
-			case <-time.After(wait):
-			}
+			// Pause all fetchers; the gate is waited on before the next attempt
+			p.gate.Pause(wait)
 			continue
 		default:
 			if resp.StatusCode >= 500 && resp.StatusCode < 600 {
 				// Server error: retry with exponential backoff
 				resp.Body.Close()
-				p.logger.Info().Int("status_code", resp.StatusCode).Int("attempt", attempt+1).Int("max_retries", maxRetries).Dur("wait_time", backoff).Msg("HTTP server error, retrying in")
+				lastErr = apperrors.NewExternalAPIError("tzkt", "fetch "+kind, fmt.Sprintf("status code %d", resp.StatusCode))
+				wait := p.backoffWait(backoff, policy.MaxTotalWait-time.Since(start))
+				p.logger.Info().Int("status_code", resp.StatusCode).Int("attempt", attempt+1).Int("max_retries", policy.MaxRetries).Dur("wait_time", wait).Msg("HTTP server error, retrying in")
 				select {
 				case <-ctx.Done():
-					return nil, ctx.Err()
-				case <-time.After(backoff):
+					return ctx.Err()
+				case <-time.After(wait):
 				}
 				backoff *= 2
 				continue
 			}
+			if resp.StatusCode >= 300 && resp.StatusCode < 400 {
+				// A redirect checkRedirect didn't follow: too many, or without a Location to follow
+				location := resp.Header.Get("Location")
+				resp.Body.Close()
+				p.logger.Error().Int("status_code", resp.StatusCode).Str("location", location).Msg("HTTP redirect not followed, not retrying")
+				return apperrors.NewExternalAPIError("tzkt", "fetch "+kind, fmt.Sprintf("redirect with status %d to %q not followed, update TZKT_BASE_URL", resp.StatusCode, location))
+			}
 			// Other unexpected status codes: log and return error with response body
 			body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyLen))
 			resp.Body.Close()
 			p.logger.Error().Int("status_code", resp.StatusCode).Str("body", string(body)).Msg("HTTP unexpected, not retrying")
-			return nil, fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(body))
+			return fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(body))
 		}
 	}
-	if resp == nil {
-		return nil, fmt.Errorf("nil response: %w", err)
+	if !decoded {
+		if lastErr == nil {
+			lastErr = apperrors.NewExternalAPIError("tzkt", "fetch "+kind, "no response received")
+		}
+		return fmt.Errorf("retries exhausted: %w", lastErr)
 	}
-	defer resp.Body.Close()
+	return nil
 
-	// Decode the JSON response into tzktDelegation structs
-	var result []tzktDelegation
-	err = json.NewDecoder(resp.Body).Decode(&result)
+}
+
+// fetchTzktPageOnce fetches url from the Tzkt API and decodes the JSON body into result with a single
+// attempt, for requests made on behalf of API clients. It bypasses the retries, the client-side rate
+// limiter and the circuit breaker, which belong to the sync, and fails straight away while the sync is
+// paused by a Tzkt rate limit. kind names the operations in errors.
+func (p *PollerService) fetchTzktPageOnce(ctx context.Context, url, kind string, result any) error {
+	if p.gate.Paused() {
+		return apperrors.NewExternalAPIError("tzkt", "fetch "+kind, "rate limited, not calling Tzkt until the pause ends")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("error decoding response body: %w", err)
+		return err
+	}
+	if p.opts.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.opts.APIKey)
 	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return apperrors.NewExternalAPIErrorWithCause("tzkt", "fetch "+kind, "request failed", err)
+	}
+	defer resp.Body.Close()
 
-	// Convert to model.Delegation slice for database storage
-	delegations := make([]model.Delegation, len(result))
-	for i, op := range result {
-		delegations[i] = model.Delegation{
-			TzktID:    op.ID,
-			Timestamp: op.Timestamp,
-			Amount:    op.Amount,
-			Delegator: op.Sender.Address,
-			Level:     op.Level,
-		}
+	if resp.StatusCode != http.StatusOK {
+		return apperrors.NewExternalAPIError("tzkt", "fetch "+kind, fmt.Sprintf("status code %d", resp.StatusCode))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return apperrors.NewExternalAPIErrorWithCause("tzkt", "decode "+kind, "malformed response body", err)
+	}
+	return nil
+}
+
+// backoffWait returns the delay before the next retry: backoff with jitter applied, so concurrent fetchers
+// and instances don't retry in lockstep, capped by the time left in the retry budget.
+func (p *PollerService) backoffWait(backoff, remaining time.Duration) time.Duration {
+	jitter := p.jitter
+	if jitter == nil {
+		jitter = fullJitter
 	}
+	return max(0, min(jitter(backoff), remaining))
+}
 
-	return delegations, nil
+// fullJitter returns a uniformly random duration in [0, d].
+func fullJitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return rand.N(d + 1)
 }
 
 // parseRetryAfter parses the Retry-After header, supporting both seconds and HTTP-date formats.
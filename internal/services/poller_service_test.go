@@ -1,20 +1,54 @@
 package services
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
+	"tezos-delegation/internal/apperrors"
+	"tezos-delegation/internal/metrics"
 	"tezos-delegation/internal/mocks"
+	"tezos-delegation/internal/model"
+	"tezos-delegation/internal/ports"
 
 	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
 )
 
+// doerFunc stands in for the Tzkt client, answering each request with the response f returns
+type doerFunc func(req *http.Request) *http.Response
+
+func (f doerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req), nil
+}
+
+// cancellableDoer answers requests after delay unless their context is cancelled first, as a real client does
+type cancellableDoer struct {
+	delay   time.Duration
+	respond func(req *http.Request) *http.Response
+}
+
+func (d cancellableDoer) Do(req *http.Request) (*http.Response, error) {
+	select {
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	case <-time.After(d.delay):
+		return d.respond(req), nil
+	}
+}
+
+// roundTripFunc answers requests sent through an http.Client, for tests that rely on the client's own behavior
 type roundTripFunc func(req *http.Request) *http.Response
 
 func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
@@ -26,26 +60,240 @@ func TestPollerService_syncDelegationsBatch_Success(t *testing.T) {
 	defer ctrl.Finish()
 
 	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+	repo.EXPECT().GetByTzktIDs(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
 	logger := zerolog.Nop()
 	ps := &PollerService{
 		repo:   repo,
 		logger: logger,
-		client: &http.Client{Transport: roundTripFunc(func(req *http.Request) *http.Response {
+		client: doerFunc(func(req *http.Request) *http.Response {
 			return &http.Response{
 				StatusCode: 200,
 				Body:       io.NopCloser(strings.NewReader(`[{"id":1,"timestamp":"2022-05-05T06:29:14Z","amount":100,"sender":{"address":"tz1"},"level":1}]`)),
 				Header:     make(http.Header),
 			}
-		})},
+		}),
+	}
+
+	ctx := context.Background()
+	repo.EXPECT().GetLatestTzktID(ctx).Return(int64(0), nil)
+	repo.EXPECT().InsertDelegations(gomock.Any(), gomock.Any()).Return(int64(1), nil)
+	repo.EXPECT().UpdateSyncState(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, state model.SyncState) error {
+		assert.Equal(t, int64(1), state.LastTzktID)
+		assert.True(t, state.HistoricalComplete)
+		assert.False(t, state.LastPollAt.IsZero())
+		return nil
+	})
+
+	caughtUp, err := ps.syncDelegationsBatch(ctx)
+	assert.NoError(t, err)
+	assert.True(t, caughtUp)
+}
+
+func TestPollerService_syncDelegationsBatch_DuplicateSkips(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+	logger := zerolog.Nop()
+	ps := &PollerService{
+		repo:   repo,
+		logger: logger,
+		client: doerFunc(func(req *http.Request) *http.Response {
+			return &http.Response{
+				StatusCode: 200,
+				Body: io.NopCloser(strings.NewReader(`[{"id":1,"timestamp":"2022-05-05T06:29:14Z","amount":100,"sender":{"address":"tz1"},"level":1},` +
+					`{"id":2,"timestamp":"2022-05-05T06:29:14Z","amount":200,"sender":{"address":"tz2"},"level":2},` +
+					`{"id":3,"timestamp":"2022-05-05T06:29:14Z","amount":300,"sender":{"address":"tz3"},"level":3}]`)),
+				Header: make(http.Header),
+			}
+		}),
+	}
+
+	ctx := context.Background()
+	before := testutil.ToFloat64(metrics.PollerDuplicateSkips)
+	reorgsBefore := testutil.ToFloat64(metrics.PollerReorgsSuspected)
+	repo.EXPECT().GetLatestTzktID(ctx).Return(int64(0), nil)
+	repo.EXPECT().InsertDelegations(gomock.Any(), gomock.Any()).Return(int64(1), nil)
+	// The fetched operations are compared with the stored rows: 2 is unchanged, 3 is stored at another level
+	timestamp := time.Date(2022, 5, 5, 6, 29, 14, 0, time.UTC)
+	repo.EXPECT().GetByTzktIDs(ctx, []int64{1, 2, 3}).Return([]model.Delegation{
+		{TzktID: 2, Level: 2, Timestamp: timestamp},
+		{TzktID: 3, Level: 4, Timestamp: timestamp},
+	}, nil)
+	repo.EXPECT().UpdateSyncState(ctx, gomock.Any()).Return(nil)
+
+	caughtUp, err := ps.syncDelegationsBatch(ctx)
+	assert.NoError(t, err)
+	assert.True(t, caughtUp)
+	assert.Equal(t, float64(2), testutil.ToFloat64(metrics.PollerDuplicateSkips)-before)
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.PollerReorgsSuspected)-reorgsBefore)
+}
+
+func TestPollerService_syncDelegationsBatch_ReorgInUpdateMode(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+	ps := &PollerService{
+		repo:   repo,
+		logger: zerolog.Nop(),
+		client: doerFunc(func(req *http.Request) *http.Response {
+			return &http.Response{
+				StatusCode: 200,
+				Body: io.NopCloser(strings.NewReader(`[{"id":1,"timestamp":"2022-05-05T06:29:14Z","amount":100,"sender":{"address":"tz1"},"level":1},` +
+					`{"id":2,"timestamp":"2022-05-05T06:29:14Z","amount":200,"sender":{"address":"tz2"},"level":2}]`)),
+				Header: make(http.Header),
+			}
+		}),
 	}
 
 	ctx := context.Background()
+	reorgsBefore := testutil.ToFloat64(metrics.PollerReorgsSuspected)
 	repo.EXPECT().GetLatestTzktID(ctx).Return(int64(0), nil)
-	repo.EXPECT().InsertDelegations(gomock.Any()).Return(nil)
+	// With POLLER_UPSERT_MODE=update the changed row is rewritten and counted as inserted, so nothing is
+	// skipped; the comparison has to happen before the insert overwrites it
+	gomock.InOrder(
+		repo.EXPECT().GetByTzktIDs(ctx, []int64{1, 2}).Return([]model.Delegation{
+			{TzktID: 2, Level: 3, Timestamp: time.Date(2022, 5, 5, 6, 29, 14, 0, time.UTC)},
+		}, nil),
+		repo.EXPECT().InsertDelegations(gomock.Any(), gomock.Len(2)).Return(int64(2), nil),
+	)
+	repo.EXPECT().UpdateSyncState(ctx, gomock.Any()).Return(nil)
 
 	caughtUp, err := ps.syncDelegationsBatch(ctx)
 	assert.NoError(t, err)
 	assert.True(t, caughtUp)
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.PollerReorgsSuspected)-reorgsBefore)
+}
+
+func TestPollerService_syncDelegationsBatch_VerifyInserts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+	repo.EXPECT().GetByTzktIDs(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	logger := zerolog.Nop()
+	ps := &PollerService{
+		repo:   repo,
+		logger: logger,
+		opts:   PollerOptions{VerifyInserts: true},
+		client: doerFunc(func(req *http.Request) *http.Response {
+			return &http.Response{
+				StatusCode: 200,
+				Body: io.NopCloser(strings.NewReader(`[{"id":1,"timestamp":"2022-05-05T06:29:14Z","amount":100,"sender":{"address":"tz1"},"level":1},` +
+					`{"id":2,"timestamp":"2022-05-05T06:29:14Z","amount":200,"sender":{"address":"tz2"},"level":2}]`)),
+				Header: make(http.Header),
+			}
+		}),
+	}
+
+	ctx := context.Background()
+
+	t.Run("all rows persisted", func(t *testing.T) {
+		before := testutil.ToFloat64(metrics.PollerInsertVerificationFailures)
+		repo.EXPECT().GetLatestTzktID(ctx).Return(int64(0), nil)
+		repo.EXPECT().InsertDelegations(gomock.Any(), gomock.Any()).Return(int64(2), nil)
+		repo.EXPECT().CountByTzktIDs(ctx, []int64{1, 2}).Return(int64(2), nil)
+		repo.EXPECT().UpdateSyncState(ctx, gomock.Any()).Return(nil)
+
+		_, err := ps.syncDelegationsBatch(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, float64(0), testutil.ToFloat64(metrics.PollerInsertVerificationFailures)-before)
+	})
+
+	t.Run("row missing after insert", func(t *testing.T) {
+		before := testutil.ToFloat64(metrics.PollerInsertVerificationFailures)
+		repo.EXPECT().GetLatestTzktID(ctx).Return(int64(0), nil)
+		repo.EXPECT().InsertDelegations(gomock.Any(), gomock.Any()).Return(int64(2), nil)
+		repo.EXPECT().CountByTzktIDs(ctx, []int64{1, 2}).Return(int64(1), nil)
+		repo.EXPECT().UpdateSyncState(ctx, gomock.Any()).Return(nil)
+
+		_, err := ps.syncDelegationsBatch(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, float64(1), testutil.ToFloat64(metrics.PollerInsertVerificationFailures)-before)
+	})
+}
+
+func TestPollerService_syncDelegationsBatch_EmptyDelegator(t *testing.T) {
+	const batch = `[
+		{"id":1,"timestamp":"2022-05-05T06:29:14Z","amount":100,"sender.address":"tz1a","level":1},
+		{"id":2,"timestamp":"2022-05-05T06:29:14Z","amount":200,"sender.address":"  ","level":1},
+		{"id":3,"timestamp":"2022-05-05T06:29:14Z","amount":300,"level":1}
+	]`
+	newPoller := func(repo *mocks.MockDelegationRepositoryPort, body string, keep bool) *PollerService {
+		return &PollerService{
+			repo:   repo,
+			logger: zerolog.Nop(),
+			opts:   PollerOptions{KeepEmptyDelegators: keep},
+			client: doerFunc(func(req *http.Request) *http.Response {
+				return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header)}
+			}),
+		}
+	}
+	storedIDs := func(t *testing.T, want ...int64) func(context.Context, []*model.Delegation) (int64, error) {
+		return func(_ context.Context, delegations []*model.Delegation) (int64, error) {
+			ids := make([]int64, len(delegations))
+			for i, d := range delegations {
+				ids[i] = d.TzktID
+			}
+			assert.Equal(t, want, ids)
+			return int64(len(delegations)), nil
+		}
+	}
+	ctx := context.Background()
+
+	t.Run("dropped by default", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+		repo.EXPECT().GetByTzktIDs(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+		repo.EXPECT().GetLatestTzktID(ctx).Return(int64(0), nil)
+		repo.EXPECT().InsertDelegations(ctx, gomock.Any()).DoAndReturn(storedIDs(t, 1))
+		repo.EXPECT().UpdateSyncState(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, state model.SyncState) error {
+			assert.Equal(t, int64(3), state.LastTzktID)
+			return nil
+		})
+
+		before := testutil.ToFloat64(metrics.PollerEmptyDelegatorsSkipped)
+		caughtUp, err := newPoller(repo, batch, false).syncDelegationsBatch(ctx)
+		assert.NoError(t, err)
+		assert.True(t, caughtUp)
+		assert.Equal(t, float64(2), testutil.ToFloat64(metrics.PollerEmptyDelegatorsSkipped)-before)
+	})
+
+	t.Run("batch of only empty delegators", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+		repo.EXPECT().GetByTzktIDs(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+		repo.EXPECT().GetLatestTzktID(ctx).Return(int64(0), nil)
+		repo.EXPECT().UpdateSyncState(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, state model.SyncState) error {
+			assert.Equal(t, int64(7), state.LastTzktID)
+			return nil
+		})
+
+		body := `[{"id":7,"timestamp":"2022-05-05T06:29:14Z","amount":100,"sender.address":"","level":1}]`
+		caughtUp, err := newPoller(repo, body, false).syncDelegationsBatch(ctx)
+		assert.NoError(t, err)
+		assert.True(t, caughtUp)
+	})
+
+	t.Run("kept when configured", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+		repo.EXPECT().GetByTzktIDs(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+		repo.EXPECT().GetLatestTzktID(ctx).Return(int64(0), nil)
+		repo.EXPECT().InsertDelegations(ctx, gomock.Any()).DoAndReturn(storedIDs(t, 1, 2, 3))
+		repo.EXPECT().UpdateSyncState(ctx, gomock.Any()).Return(nil)
+
+		caughtUp, err := newPoller(repo, batch, true).syncDelegationsBatch(ctx)
+		assert.NoError(t, err)
+		assert.True(t, caughtUp)
+	})
 }
 
 func TestPollerService_syncDelegationsBatch_NoNewData(t *testing.T) {
@@ -57,17 +305,18 @@ func TestPollerService_syncDelegationsBatch_NoNewData(t *testing.T) {
 	ps := &PollerService{
 		repo:   repo,
 		logger: logger,
-		client: &http.Client{Transport: roundTripFunc(func(req *http.Request) *http.Response {
+		client: doerFunc(func(req *http.Request) *http.Response {
 			return &http.Response{
 				StatusCode: 200,
 				Body:       io.NopCloser(strings.NewReader(`[]`)),
 				Header:     make(http.Header),
 			}
-		})},
+		}),
 	}
 
 	ctx := context.Background()
 	repo.EXPECT().GetLatestTzktID(ctx).Return(int64(0), nil)
+	repo.EXPECT().UpdateSyncState(ctx, gomock.Any()).Return(nil)
 
 	caughtUp, err := ps.syncDelegationsBatch(ctx)
 	assert.NoError(t, err)
@@ -102,13 +351,13 @@ func TestPollerService_syncDelegationsBatch_APIError(t *testing.T) {
 	ps := &PollerService{
 		repo:   repo,
 		logger: logger,
-		client: &http.Client{Transport: roundTripFunc(func(req *http.Request) *http.Response {
+		client: doerFunc(func(req *http.Request) *http.Response {
 			return &http.Response{
 				StatusCode: 500,
 				Body:       io.NopCloser(strings.NewReader(`error`)),
 				Header:     make(http.Header),
 			}
-		})},
+		}),
 	}
 
 	ctx := context.Background()
@@ -119,6 +368,330 @@ func TestPollerService_syncDelegationsBatch_APIError(t *testing.T) {
 	assert.Contains(t, err.Error(), "failed to fetch delegations from Tzkt API")
 }
 
+func TestPollerService_fetchDelegationBatch_MalformedJSON(t *testing.T) {
+	calls := 0
+	ps := &PollerService{
+		logger: zerolog.Nop(),
+		client: doerFunc(func(req *http.Request) *http.Response {
+			calls++
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(strings.NewReader(`not json`)),
+				Header:     make(http.Header),
+			}
+		}),
+	}
+
+	delegations, err := ps.fetchDelegationBatch(context.Background(), 0, 0, "")
+	assert.Nil(t, delegations)
+	assert.True(t, apperrors.IsExternalAPIError(err))
+	assert.Contains(t, err.Error(), "malformed response body")
+	assert.Equal(t, defaultMaxRetries, calls, "malformed bodies should count toward the retry budget")
+}
+
+func TestPollerService_fetchDelegationBatch_MalformedJSONRecovers(t *testing.T) {
+	bodies := []string{
+		`[{"id":1,"timestamp":"2022-05-05T06:29:14Z","amo`,
+		`[{"id":1,"timestamp":"2022-05-05T06:29:14Z","amount":100,"sender":{"address":"tz1"},"level":1}]`,
+	}
+	calls := 0
+	ps := &PollerService{
+		logger: zerolog.Nop(),
+		client: doerFunc(func(req *http.Request) *http.Response {
+			body := bodies[calls]
+			calls++
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Header:     make(http.Header),
+			}
+		}),
+	}
+
+	delegations, err := ps.fetchDelegationBatch(context.Background(), 0, 0, "")
+	assert.NoError(t, err)
+	assert.Len(t, delegations, 1)
+	assert.Equal(t, 2, calls)
+}
+
+func TestPollerService_fetchDelegationBatch_MalformedAddress(t *testing.T) {
+	var buf bytes.Buffer
+	ps := &PollerService{
+		logger: zerolog.New(&buf),
+		client: doerFunc(func(req *http.Request) *http.Response {
+			return &http.Response{
+				StatusCode: 200,
+				Body: io.NopCloser(strings.NewReader(`[{"id":1,"timestamp":"2022-05-05T06:29:14Z","amount":100,"sender":{"address":"tz1a1SAaXRt9yoGMx29rh9FsBF4UzmvojdTL"},"level":1},` +
+					`{"id":2,"timestamp":"2022-05-05T06:29:14Z","amount":100,"sender":{"address":"tz1bogus"},"level":1}]`)),
+				Header: make(http.Header),
+			}
+		}),
+	}
+
+	// Malformed addresses are logged but still stored as received
+	delegations, err := ps.fetchDelegationBatch(context.Background(), 0, 0, "")
+	assert.NoError(t, err)
+	assert.Len(t, delegations, 2)
+	assert.Equal(t, "tz1bogus", delegations[1].Delegator)
+	assert.Equal(t, 1, strings.Count(buf.String(), "Malformed delegator address"))
+	assert.Contains(t, buf.String(), `"tzkt_id":2`)
+}
+
+func TestTzktTime_UnmarshalJSON(t *testing.T) {
+	want := time.Date(2022, 5, 5, 6, 29, 14, 0, time.UTC)
+	tests := []struct {
+		name  string
+		input string
+		want  time.Time
+	}{
+		{"whole seconds with Z", `"2022-05-05T06:29:14Z"`, want},
+		{"milliseconds with Z", `"2022-05-05T06:29:14.123Z"`, want.Add(123 * time.Millisecond)},
+		{"nanoseconds with Z", `"2022-05-05T06:29:14.123456789Z"`, want.Add(123456789 * time.Nanosecond)},
+		{"offset", `"2022-05-05T08:29:14+02:00"`, want},
+		{"fractional seconds with offset", `"2022-05-05T08:29:14.5+02:00"`, want.Add(500 * time.Millisecond)},
+		{"offset without colon", `"2022-05-05T08:29:14+0200"`, want},
+		{"no zone", `"2022-05-05T06:29:14"`, want},
+		{"fractional seconds without zone", `"2022-05-05T06:29:14.25"`, want.Add(250 * time.Millisecond)},
+		{"space separator", `"2022-05-05 06:29:14Z"`, want},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got tzktTime
+			assert.NoError(t, json.Unmarshal([]byte(tt.input), &got))
+			assert.True(t, tt.want.Equal(got.Time), "got %s", got.Time)
+			assert.Equal(t, time.UTC, got.Location())
+		})
+	}
+
+	for _, input := range []string{`"05/05/2022 06:29:14"`, `"2022-05-05"`, `""`, `1651732154`} {
+		t.Run("invalid "+input, func(t *testing.T) {
+			var got tzktTime
+			err := json.Unmarshal([]byte(input), &got)
+			assert.ErrorContains(t, err, input)
+		})
+	}
+}
+
+func TestPollerService_fetchDelegationBatch_TimestampFormats(t *testing.T) {
+	t.Run("varying precision and zones", func(t *testing.T) {
+		ps := &PollerService{
+			logger: zerolog.Nop(),
+			client: doerFunc(func(req *http.Request) *http.Response {
+				return &http.Response{
+					StatusCode: 200,
+					Body: io.NopCloser(strings.NewReader(`[{"id":1,"timestamp":"2022-05-05T06:29:14Z","amount":100,"sender":{"address":"tz1"},"level":1},` +
+						`{"id":2,"timestamp":"2022-05-05T08:29:15.250+02:00","amount":100,"sender":{"address":"tz1"},"level":2},` +
+						`{"id":3,"timestamp":"2022-05-05T06:29:16","amount":100,"sender":{"address":"tz1"},"level":3}]`)),
+					Header: make(http.Header),
+				}
+			}),
+		}
+
+		delegations, err := ps.fetchDelegationBatch(context.Background(), 0, 0, "")
+		assert.NoError(t, err)
+		if assert.Len(t, delegations, 3) {
+			assert.Equal(t, time.Date(2022, 5, 5, 6, 29, 14, 0, time.UTC), delegations[0].Timestamp)
+			assert.Equal(t, time.Date(2022, 5, 5, 6, 29, 15, 250_000_000, time.UTC), delegations[1].Timestamp)
+			assert.Equal(t, time.Date(2022, 5, 5, 6, 29, 16, 0, time.UTC), delegations[2].Timestamp)
+		}
+	})
+
+	t.Run("unrecognized format logged", func(t *testing.T) {
+		var buf bytes.Buffer
+		ps := &PollerService{
+			logger: zerolog.New(&buf),
+			opts:   PollerOptions{Retry: RetryPolicy{MaxRetries: 1}},
+			client: doerFunc(func(req *http.Request) *http.Response {
+				return &http.Response{
+					StatusCode: 200,
+					Body:       io.NopCloser(strings.NewReader(`[{"id":1,"timestamp":"05/05/2022 06:29","amount":100,"sender":{"address":"tz1"},"level":1}]`)),
+					Header:     make(http.Header),
+				}
+			}),
+		}
+
+		_, err := ps.fetchDelegationBatch(context.Background(), 0, 0, "")
+		assert.True(t, apperrors.IsExternalAPIError(err))
+		assert.Contains(t, buf.String(), `unrecognized timestamp format \"05/05/2022 06:29\"`)
+	})
+}
+
+func TestPollerService_fetchDelegationBatch_SelectFields(t *testing.T) {
+	ps := &PollerService{
+		logger: zerolog.Nop(),
+		client: doerFunc(func(req *http.Request) *http.Response {
+			assert.Equal(t, "id,timestamp,amount,sender.address,level", req.URL.Query().Get("select"))
+			// With select, Tzkt returns only the selected fields, with the sender's address flattened
+			return &http.Response{
+				StatusCode: 200,
+				Body: io.NopCloser(strings.NewReader(`[{"id":42,"timestamp":"2022-05-05T06:29:14Z","amount":125896,` +
+					`"sender.address":"tz1a1SAaXRt9yoGMx29rh9FsBF4UzmvojdTL","level":2338084}]`)),
+				Header: make(http.Header),
+			}
+		}),
+	}
+
+	delegations, err := ps.fetchDelegationBatch(context.Background(), 0, 0, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []model.Delegation{{
+		TzktID:    42,
+		Timestamp: time.Date(2022, 5, 5, 6, 29, 14, 0, time.UTC),
+		Amount:    125896,
+		Delegator: "tz1a1SAaXRt9yoGMx29rh9FsBF4UzmvojdTL",
+		Level:     2338084,
+		Type:      model.OperationTypeDelegation,
+	}}, delegations)
+}
+
+func TestPollerService_fetchDelegationBatch_BaseURL(t *testing.T) {
+	var urls []string
+	ps := &PollerService{
+		logger: zerolog.Nop(),
+		opts:   PollerOptions{BaseURL: "https://tzkt.internal.example/v1", TrackOriginations: true},
+		client: doerFunc(func(req *http.Request) *http.Response {
+			urls = append(urls, req.URL.Scheme+"://"+req.URL.Host+req.URL.Path)
+			return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(`[]`)), Header: make(http.Header)}
+		}),
+	}
+
+	_, _, err := ps.fetchOperationBatch(context.Background(), 0, "")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		"https://tzkt.internal.example/v1/operations/delegations",
+		"https://tzkt.internal.example/v1/operations/originations",
+	}, urls)
+}
+
+func TestPollerService_fetchDelegationBatch_Redirect(t *testing.T) {
+	const body = `[{"id":1,"timestamp":"2022-05-05T06:29:14Z","amount":100,"sender.address":"tz1","level":1}]`
+	redirect := func(location string) *http.Response {
+		header := make(http.Header)
+		if location != "" {
+			header.Set("Location", location)
+		}
+		return &http.Response{StatusCode: http.StatusMovedPermanently, Body: io.NopCloser(strings.NewReader("")), Header: header}
+	}
+	newPoller := func(buf *bytes.Buffer, fn roundTripFunc) *PollerService {
+		client := &http.Client{Transport: fn}
+		ps := &PollerService{
+			logger: zerolog.New(buf),
+			opts:   PollerOptions{Retry: RetryPolicy{MaxRetries: 2, InitialBackoff: time.Millisecond}},
+			client: client,
+		}
+		client.CheckRedirect = ps.checkRedirect
+		return ps
+	}
+
+	t.Run("followed to the new host", func(t *testing.T) {
+		var buf bytes.Buffer
+		var hosts []string
+		ps := newPoller(&buf, func(req *http.Request) *http.Response {
+			hosts = append(hosts, req.URL.Host)
+			if req.URL.Host == "api.tzkt.io" {
+				return redirect("https://new.tzkt.example" + req.URL.RequestURI())
+			}
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: make(http.Header)}
+		})
+
+		delegations, err := ps.fetchDelegationBatch(context.Background(), 0, 0, "")
+		assert.NoError(t, err)
+		assert.Len(t, delegations, 1)
+		assert.Equal(t, []string{"api.tzkt.io", "new.tzkt.example"}, hosts)
+		assert.Contains(t, buf.String(), "Tzkt redirected the request, update TZKT_BASE_URL")
+		assert.Contains(t, buf.String(), `"to":"https://new.tzkt.example/v1/operations/delegations?`)
+	})
+
+	t.Run("too many redirects", func(t *testing.T) {
+		var buf bytes.Buffer
+		calls := 0
+		ps := newPoller(&buf, func(req *http.Request) *http.Response {
+			calls++
+			return redirect(fmt.Sprintf("https://api.tzkt.io/v1/moved/%d", calls))
+		})
+
+		_, err := ps.fetchDelegationBatch(context.Background(), 0, 0, "")
+		assert.True(t, apperrors.IsExternalAPIError(err))
+		assert.ErrorContains(t, err, `redirect with status 301 to "https://api.tzkt.io/v1/moved/4" not followed, update TZKT_BASE_URL`)
+		assert.Equal(t, maxTzktRedirects+1, calls, "redirect errors shouldn't be retried")
+	})
+
+	t.Run("no location", func(t *testing.T) {
+		var buf bytes.Buffer
+		ps := newPoller(&buf, func(req *http.Request) *http.Response { return redirect("") })
+
+		_, err := ps.fetchDelegationBatch(context.Background(), 0, 0, "")
+		assert.True(t, apperrors.IsExternalAPIError(err))
+		assert.ErrorContains(t, err, "not followed, update TZKT_BASE_URL")
+	})
+}
+
+func TestPollerService_sync_AnalyzeAfterSync(t *testing.T) {
+	newPoller := func(repo *mocks.MockDelegationRepositoryPort, analyze string) *PollerService {
+		return &PollerService{
+			repo:   repo,
+			logger: zerolog.Nop(),
+			opts:   PollerOptions{AnalyzeAfterSync: analyze},
+			client: doerFunc(func(req *http.Request) *http.Response {
+				return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(`[]`)), Header: make(http.Header)}
+			}),
+		}
+	}
+
+	for analyze, vacuum := range map[string]bool{"analyze": false, "vacuum": true} {
+		t.Run(analyze+" after a fresh historical sync", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			repo.EXPECT().GetSyncState(ctx).Return(&model.SyncState{}, nil)
+			repo.EXPECT().GetLatestTzktID(ctx).Return(int64(0), nil)
+			updated := repo.EXPECT().UpdateSyncState(ctx, gomock.Any()).Return(nil)
+			repo.EXPECT().AnalyzeDelegations(ctx, vacuum).After(updated).DoAndReturn(func(context.Context, bool) error {
+				cancel() // Stop before the first poll
+				return nil
+			})
+
+			assert.False(t, newPoller(repo, analyze).sync(ctx))
+		})
+	}
+
+	t.Run("not repeated when the historical sync had completed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		repo.EXPECT().GetSyncState(ctx).Return(&model.SyncState{HistoricalComplete: true}, nil)
+		repo.EXPECT().GetLatestTzktID(ctx).Return(int64(0), nil)
+		repo.EXPECT().UpdateSyncState(ctx, gomock.Any()).DoAndReturn(func(context.Context, model.SyncState) error {
+			cancel()
+			return nil
+		})
+
+		assert.False(t, newPoller(repo, "analyze").sync(ctx))
+	})
+
+	t.Run("off", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		repo.EXPECT().GetSyncState(ctx).Return(&model.SyncState{}, nil)
+		repo.EXPECT().GetLatestTzktID(ctx).Return(int64(0), nil)
+		repo.EXPECT().UpdateSyncState(ctx, gomock.Any()).DoAndReturn(func(context.Context, model.SyncState) error {
+			cancel()
+			return nil
+		})
+
+		assert.False(t, newPoller(repo, "off").sync(ctx))
+	})
+}
+
 func TestPollerService_syncDelegationsBatch_ContextCancel(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -137,3 +710,986 @@ func TestPollerService_syncDelegationsBatch_ContextCancel(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "context cancelled")
 }
+
+func TestPollerService_syncDelegationsBatch_FullPageKeepsHistoricalIncomplete(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Build a full page so the poller knows more data may follow
+	var sb strings.Builder
+	sb.WriteString("[")
+	for i := 1; i <= maxPageSize; i++ {
+		if i > 1 {
+			sb.WriteString(",")
+		}
+		fmt.Fprintf(&sb, `{"id":%d,"timestamp":"2022-05-05T06:29:14Z","amount":100,"sender":{"address":"tz1"},"level":1}`, i)
+	}
+	sb.WriteString("]")
+	body := sb.String()
+
+	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+	repo.EXPECT().GetByTzktIDs(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	logger := zerolog.Nop()
+	ps := &PollerService{
+		repo:   repo,
+		logger: logger,
+		client: doerFunc(func(req *http.Request) *http.Response {
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Header:     make(http.Header),
+			}
+		}),
+	}
+
+	ctx := context.Background()
+	repo.EXPECT().GetLatestTzktID(ctx).Return(int64(0), nil)
+	repo.EXPECT().InsertDelegations(gomock.Any(), gomock.Any()).Return(int64(maxPageSize), nil)
+	repo.EXPECT().UpdateSyncState(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, state model.SyncState) error {
+		assert.Equal(t, int64(maxPageSize), state.LastTzktID)
+		assert.False(t, state.HistoricalComplete)
+		return nil
+	})
+
+	caughtUp, err := ps.syncDelegationsBatch(ctx)
+	assert.NoError(t, err)
+	assert.False(t, caughtUp)
+}
+
+// delegationPageJSON builds a Tzkt response body with n delegations with consecutive IDs starting at firstID
+func delegationPageJSON(firstID, n int) string {
+	var sb strings.Builder
+	sb.WriteString("[")
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		fmt.Fprintf(&sb, `{"id":%d,"timestamp":"2022-05-05T06:29:14Z","amount":100,"sender":{"address":"tz1"},"level":1}`, firstID+i)
+	}
+	sb.WriteString("]")
+	return sb.String()
+}
+
+func TestPollerService_syncDelegationsBatch_SmallPageSize(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Tzkt holds delegations 1..5, served two per page
+	const total = 5
+	var limits []string
+	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+	repo.EXPECT().GetByTzktIDs(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	ps := &PollerService{
+		repo:   repo,
+		logger: zerolog.Nop(),
+		opts:   PollerOptions{PageSize: 2},
+		client: doerFunc(func(req *http.Request) *http.Response {
+			query := req.URL.Query()
+			limits = append(limits, query.Get("limit"))
+			after, _ := strconv.Atoi(query.Get("id.gt"))
+			n := min(2, total-after)
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(strings.NewReader(delegationPageJSON(after+1, n))),
+				Header:     make(http.Header),
+			}
+		}),
+	}
+
+	ctx := context.Background()
+	var stored int64
+	repo.EXPECT().GetLatestTzktID(ctx).DoAndReturn(func(context.Context) (int64, error) { return stored, nil }).Times(3)
+	repo.EXPECT().InsertDelegations(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, ds []*model.Delegation) (int64, error) {
+		stored = ds[len(ds)-1].TzktID
+		return int64(len(ds)), nil
+	}).Times(3)
+	var states []model.SyncState
+	repo.EXPECT().UpdateSyncState(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, state model.SyncState) error {
+		states = append(states, state)
+		return nil
+	}).Times(3)
+
+	// Two full pages leave the sync incomplete; the short third page catches up
+	for i, want := range []bool{false, false, true} {
+		caughtUp, err := ps.syncDelegationsBatch(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, want, caughtUp, "batch %d", i+1)
+	}
+
+	assert.Equal(t, []string{"2", "2", "2"}, limits)
+	assert.Equal(t, int64(total), stored)
+	assert.Equal(t, []int64{2, 4, 5}, []int64{states[0].LastTzktID, states[1].LastTzktID, states[2].LastTzktID})
+	assert.False(t, states[1].HistoricalComplete)
+	assert.True(t, states[2].HistoricalComplete)
+}
+
+func TestPollerService_syncHistoricalPages_StoresInOrder(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Two full pages followed by a short one
+	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+	repo.EXPECT().GetByTzktIDs(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	ps := &PollerService{
+		repo:   repo,
+		logger: zerolog.Nop(),
+		client: doerFunc(func(req *http.Request) *http.Response {
+			assert.Equal(t, "100", req.URL.Query().Get("id.gt"))
+			offset, _ := strconv.Atoi(req.URL.Query().Get("offset"))
+			n := maxPageSize
+			if offset == 2*maxPageSize {
+				n = 5
+			}
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(strings.NewReader(delegationPageJSON(101+offset, n))),
+				Header:     make(http.Header),
+			}
+		}),
+	}
+
+	ctx := context.Background()
+	firstIDs := []int64{}
+	repo.EXPECT().GetLatestTzktID(ctx).Return(int64(100), nil)
+	repo.EXPECT().InsertDelegations(gomock.Any(), gomock.Any()).Times(3).DoAndReturn(func(_ context.Context, batch []*model.Delegation) (int64, error) {
+		firstIDs = append(firstIDs, batch[0].TzktID)
+		return int64(len(batch)), nil
+	})
+	var states []model.SyncState
+	repo.EXPECT().UpdateSyncState(ctx, gomock.Any()).Times(3).DoAndReturn(func(_ context.Context, state model.SyncState) error {
+		states = append(states, state)
+		return nil
+	})
+
+	caughtUp, err := ps.syncHistoricalPages(ctx, 3)
+	assert.NoError(t, err)
+	assert.True(t, caughtUp)
+	assert.Equal(t, []int64{101, 101 + maxPageSize, 101 + 2*maxPageSize}, firstIDs)
+	assert.Equal(t, int64(100+2*maxPageSize+5), states[2].LastTzktID)
+	assert.False(t, states[1].HistoricalComplete)
+	assert.True(t, states[2].HistoricalComplete)
+}
+
+func TestPollerService_syncHistoricalPages_FetchErrorStoresEarlierPages(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// The second page fails with a non-retryable status
+	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+	repo.EXPECT().GetByTzktIDs(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	ps := &PollerService{
+		repo:   repo,
+		logger: zerolog.Nop(),
+		client: doerFunc(func(req *http.Request) *http.Response {
+			offset, _ := strconv.Atoi(req.URL.Query().Get("offset"))
+			if offset == maxPageSize {
+				return &http.Response{StatusCode: 400, Body: io.NopCloser(strings.NewReader("bad request")), Header: make(http.Header)}
+			}
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(strings.NewReader(delegationPageJSON(1+offset, maxPageSize))),
+				Header:     make(http.Header),
+			}
+		}),
+	}
+
+	ctx := context.Background()
+	repo.EXPECT().GetLatestTzktID(ctx).Return(int64(0), nil)
+	repo.EXPECT().InsertDelegations(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, batch []*model.Delegation) (int64, error) {
+		assert.Equal(t, int64(1), batch[0].TzktID)
+		return int64(len(batch)), nil
+	})
+	repo.EXPECT().UpdateSyncState(ctx, gomock.Any()).Return(nil)
+
+	caughtUp, err := ps.syncHistoricalPages(ctx, 3)
+	assert.Error(t, err)
+	assert.False(t, caughtUp)
+	assert.Contains(t, err.Error(), "page offset 1000")
+}
+
+func TestPollerService_syncHistoricalPages_RecoversFromOpenCircuit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+	repo.EXPECT().GetByTzktIDs(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	ps := &PollerService{
+		repo:   repo,
+		logger: zerolog.Nop(),
+		client: cancellableDoer{delay: 20 * time.Millisecond, respond: func(req *http.Request) *http.Response {
+			offset, _ := strconv.Atoi(req.URL.Query().Get("offset"))
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(strings.NewReader(delegationPageJSON(1+offset, maxPageSize))),
+				Header:     make(http.Header),
+			}
+		}},
+	}
+	// The breaker opened long enough ago to let the next fetch through as the half-open probe
+	ps.breaker = newCircuitBreaker(1, time.Minute, ps.onCircuitStateChange)
+	ps.breaker.Failure()
+	ps.breaker.openedAt = time.Now().Add(-2 * time.Minute)
+
+	ctx := context.Background()
+	repo.EXPECT().GetLatestTzktID(ctx).Return(int64(0), nil).Times(2)
+	repo.EXPECT().InsertDelegations(gomock.Any(), gomock.Any()).Times(1 + 3).DoAndReturn(func(_ context.Context, batch []*model.Delegation) (int64, error) {
+		return int64(len(batch)), nil
+	})
+	repo.EXPECT().UpdateSyncState(ctx, gomock.Any()).Times(1 + 3).Return(nil)
+
+	// The probe is fetched alone, so the workers refused by the breaker can't cancel it
+	caughtUp, err := ps.syncHistoricalPages(ctx, 3)
+	assert.NoError(t, err)
+	assert.False(t, caughtUp)
+	assert.Equal(t, CircuitClosed, ps.CircuitState())
+
+	// With the breaker closed again, pages are prefetched concurrently
+	caughtUp, err = ps.syncHistoricalPages(ctx, 3)
+	assert.NoError(t, err)
+	assert.False(t, caughtUp)
+}
+
+func TestPollerService_fetchDelegationBatch_RateLimited(t *testing.T) {
+	requests := 0
+	ps := &PollerService{
+		logger: zerolog.Nop(),
+		client: doerFunc(func(req *http.Request) *http.Response {
+			requests++
+			return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(`[]`)), Header: make(http.Header)}
+		}),
+		// One token up front, the next one only after an hour
+		limiter: rate.NewLimiter(rate.Every(time.Hour), 1),
+	}
+
+	_, err := ps.fetchDelegationBatch(context.Background(), 0, 0, "")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, requests)
+
+	// The limiter has no token left, so the request is never sent
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = ps.fetchDelegationBatch(ctx, 0, 0, "")
+	assert.Error(t, err)
+	assert.Equal(t, 1, requests)
+}
+
+func TestPollerService_syncDelegationsBatch_TrackOriginations(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+	repo.EXPECT().GetByTzktIDs(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	ps := &PollerService{
+		repo:   repo,
+		logger: zerolog.Nop(),
+		opts:   PollerOptions{TrackOriginations: true},
+		client: doerFunc(func(req *http.Request) *http.Response {
+			body := `[{"id":3,"timestamp":"2022-05-05T06:29:14Z","amount":100,"sender":{"address":"tz1"},"level":1}]`
+			if strings.HasSuffix(req.URL.Path, "/originations") {
+				assert.Equal(t, "false", req.URL.Query().Get("contractDelegate.null"))
+				body = `[{"id":2,"timestamp":"2022-05-05T06:29:14Z","contractBalance":500,"originatedContract":{"address":"KT1"},"level":1}]`
+			}
+			assert.Equal(t, "1", req.URL.Query().Get("id.gt"))
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Header:     make(http.Header),
+			}
+		}),
+	}
+
+	ctx := context.Background()
+	repo.EXPECT().GetLatestTzktID(ctx).Return(int64(1), nil)
+	repo.EXPECT().InsertDelegations(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, batch []*model.Delegation) (int64, error) {
+		assert.Len(t, batch, 2)
+		assert.Equal(t, model.Delegation{TzktID: 2, Timestamp: batch[0].Timestamp, Amount: 500, Delegator: "KT1", Level: 1, Type: model.OperationTypeOrigination}, *batch[0])
+		assert.Equal(t, model.OperationTypeDelegation, batch[1].Type)
+		return 2, nil
+	})
+	repo.EXPECT().UpdateSyncState(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, state model.SyncState) error {
+		assert.Equal(t, int64(3), state.LastTzktID)
+		assert.True(t, state.HistoricalComplete)
+		return nil
+	})
+
+	caughtUp, err := ps.syncDelegationsBatch(ctx)
+	assert.NoError(t, err)
+	assert.True(t, caughtUp)
+}
+
+func TestPollerService_BackfillRange(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	from := time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+	ps := &PollerService{
+		repo:   repo,
+		logger: zerolog.Nop(),
+		client: doerFunc(func(req *http.Request) *http.Response {
+			query := req.URL.Query()
+			assert.Equal(t, "2022-05-01T00:00:00Z", query.Get("timestamp.ge"))
+			assert.Equal(t, "2022-06-01T00:00:00Z", query.Get("timestamp.lt"))
+
+			// A full first page, then a short page after it
+			body := delegationPageJSON(1, maxPageSize)
+			if query.Get("id.gt") == strconv.Itoa(maxPageSize) {
+				body = delegationPageJSON(maxPageSize+1, 2)
+			} else {
+				assert.Equal(t, "0", query.Get("id.gt"))
+			}
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Header:     make(http.Header),
+			}
+		}),
+	}
+
+	// Part of the first page was already stored; the sync state is never touched
+	repo.EXPECT().GetByTzktIDs(gomock.Any(), gomock.Any()).Return(nil, nil).Times(2)
+	gomock.InOrder(
+		repo.EXPECT().InsertDelegations(gomock.Any(), gomock.Len(maxPageSize)).Return(int64(10), nil),
+		repo.EXPECT().InsertDelegations(gomock.Any(), gomock.Len(2)).Return(int64(2), nil),
+	)
+
+	inserted, err := ps.BackfillRange(context.Background(), from, to)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(12), inserted)
+}
+
+func TestPollerService_BackfillRange_InvalidRange(t *testing.T) {
+	ps := &PollerService{logger: zerolog.Nop()}
+	day := time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	_, err := ps.BackfillRange(context.Background(), day, day)
+	assert.True(t, apperrors.IsValidationError(err))
+}
+
+func TestPollerService_BackfillRange_StoreError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+	ps := &PollerService{
+		repo:   repo,
+		logger: zerolog.Nop(),
+		client: doerFunc(func(req *http.Request) *http.Response {
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(strings.NewReader(delegationPageJSON(1, 3))),
+				Header:     make(http.Header),
+			}
+		}),
+	}
+	repo.EXPECT().GetByTzktIDs(gomock.Any(), gomock.Any()).Return(nil, nil)
+	repo.EXPECT().InsertDelegations(gomock.Any(), gomock.Any()).Return(int64(0), assert.AnError)
+
+	from := time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)
+	inserted, err := ps.BackfillRange(context.Background(), from, from.AddDate(0, 1, 0))
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.Equal(t, int64(0), inserted)
+}
+
+func TestPollerService_BackfillRange_BestEffort(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+	ps := &PollerService{
+		repo:   repo,
+		logger: zerolog.Nop(),
+		opts:   PollerOptions{BackfillBestEffort: true},
+		client: doerFunc(func(req *http.Request) *http.Response {
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(strings.NewReader(delegationPageJSON(1, 3))),
+				Header:     make(http.Header),
+			}
+		}),
+	}
+
+	// One row of the batch is rejected; the others are kept and the backfill carries on
+	before := testutil.ToFloat64(metrics.PollerInsertFailures)
+	repo.EXPECT().GetByTzktIDs(gomock.Any(), gomock.Any()).Return(nil, nil)
+	repo.EXPECT().InsertDelegationsBestEffort(gomock.Any(), gomock.Len(3)).Return(int64(2), []error{assert.AnError}, nil)
+
+	from := time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)
+	inserted, err := ps.BackfillRange(context.Background(), from, from.AddDate(0, 1, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), inserted)
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.PollerInsertFailures)-before)
+}
+
+func TestMergeOperationPages(t *testing.T) {
+	page := func(firstID, n int) []model.Delegation {
+		ops := make([]model.Delegation, n)
+		for i := range ops {
+			ops[i] = model.Delegation{TzktID: int64(firstID + i)}
+		}
+		return ops
+	}
+
+	t.Run("short pages are merged in order", func(t *testing.T) {
+		merged, more := mergeOperationPages(maxPageSize, []model.Delegation{{TzktID: 5}, {TzktID: 9}}, []model.Delegation{{TzktID: 7}})
+		assert.False(t, more)
+		assert.Equal(t, []model.Delegation{{TzktID: 5}, {TzktID: 7}, {TzktID: 9}}, merged)
+	})
+
+	t.Run("cut at the end of a full page", func(t *testing.T) {
+		// Delegations 1..maxPageSize are a full page; originations beyond it may be followed by unseen delegations
+		merged, more := mergeOperationPages(maxPageSize, page(1, maxPageSize), []model.Delegation{{TzktID: 10000}, {TzktID: int64(maxPageSize) + 5000}})
+		assert.True(t, more)
+		assert.Len(t, merged, maxPageSize)
+		assert.Equal(t, int64(maxPageSize), merged[len(merged)-1].TzktID)
+	})
+
+	t.Run("cut at the lowest full page", func(t *testing.T) {
+		merged, more := mergeOperationPages(maxPageSize, page(1, maxPageSize), page(101, maxPageSize))
+		assert.True(t, more)
+		assert.Equal(t, int64(maxPageSize), merged[len(merged)-1].TzktID)
+		assert.Len(t, merged, maxPageSize+maxPageSize-100)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		merged, more := mergeOperationPages(maxPageSize, nil, nil)
+		assert.False(t, more)
+		assert.Empty(t, merged)
+	})
+}
+
+func TestPollerService_fetchDelegationBatch_RetryPolicy(t *testing.T) {
+	serverError := func(calls *int) ports.HTTPClientPort {
+		return doerFunc(func(req *http.Request) *http.Response {
+			*calls++
+			return &http.Response{
+				StatusCode: http.StatusInternalServerError,
+				Body:       io.NopCloser(strings.NewReader("")),
+				Header:     make(http.Header),
+			}
+		})
+	}
+
+	t.Run("reduced retry count gives up sooner", func(t *testing.T) {
+		calls := 0
+		ps := &PollerService{
+			logger: zerolog.Nop(),
+			opts:   PollerOptions{Retry: RetryPolicy{MaxRetries: 2, InitialBackoff: time.Millisecond}},
+			client: serverError(&calls),
+		}
+
+		start := time.Now()
+		_, err := ps.fetchDelegationBatch(context.Background(), 0, 0, "")
+		assert.True(t, apperrors.IsExternalAPIError(err))
+		assert.Equal(t, 2, calls)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("total wait bounds the retries", func(t *testing.T) {
+		calls := 0
+		ps := &PollerService{
+			logger: zerolog.Nop(),
+			opts:   PollerOptions{Retry: RetryPolicy{MaxRetries: 20, InitialBackoff: 20 * time.Millisecond, MaxTotalWait: 50 * time.Millisecond}},
+			client: serverError(&calls),
+		}
+
+		start := time.Now()
+		_, err := ps.fetchDelegationBatch(context.Background(), 0, 0, "")
+		assert.Error(t, err)
+		assert.Less(t, calls, 20)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+}
+
+func TestPollerService_fetchDelegationBatch_CircuitBreaker(t *testing.T) {
+	calls := 0
+	healthy := false
+	ps := &PollerService{
+		logger: zerolog.Nop(),
+		opts:   PollerOptions{Retry: RetryPolicy{MaxRetries: 1, InitialBackoff: time.Millisecond}, Breaker: BreakerPolicy{Threshold: 2, Cooldown: time.Minute}},
+		client: doerFunc(func(req *http.Request) *http.Response {
+			calls++
+			if healthy {
+				return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(`[]`)), Header: make(http.Header)}
+			}
+			return &http.Response{StatusCode: http.StatusBadGateway, Body: io.NopCloser(strings.NewReader("")), Header: make(http.Header)}
+		}),
+	}
+	now := time.Now()
+	ps.breaker = newCircuitBreaker(2, time.Minute, ps.onCircuitStateChange)
+	ps.breaker.now = func() time.Time { return now }
+	ctx := context.Background()
+	trips := testutil.ToFloat64(metrics.PollerTzktCircuitTrips)
+
+	// Two failed fetches trip the breaker
+	for i := 0; i < 2; i++ {
+		_, err := ps.fetchDelegationBatch(ctx, 0, 0, "")
+		assert.True(t, apperrors.IsExternalAPIError(err))
+	}
+	assert.Equal(t, CircuitOpen, ps.CircuitState())
+	assert.Equal(t, trips+1, testutil.ToFloat64(metrics.PollerTzktCircuitTrips))
+	assert.Equal(t, float64(2), testutil.ToFloat64(metrics.PollerTzktCircuitState))
+
+	// While open, fetches fail fast without calling Tzkt
+	_, err := ps.fetchDelegationBatch(ctx, 0, 0, "")
+	assert.ErrorIs(t, err, ErrTzktCircuitOpen)
+	assert.Equal(t, 2, calls)
+
+	// After the cooldown a failed probe reopens it
+	now = now.Add(time.Minute)
+	_, err = ps.fetchDelegationBatch(ctx, 0, 0, "")
+	assert.True(t, apperrors.IsExternalAPIError(err))
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, CircuitOpen, ps.CircuitState())
+
+	// Once Tzkt recovers, the next probe closes it
+	healthy = true
+	now = now.Add(time.Minute)
+	_, err = ps.fetchDelegationBatch(ctx, 0, 0, "")
+	assert.NoError(t, err)
+	assert.Equal(t, 4, calls)
+	assert.Equal(t, CircuitClosed, ps.CircuitState())
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.PollerTzktCircuitState))
+}
+
+func TestPollerService_fetchDelegationBatch_APIKey(t *testing.T) {
+	const apiKey = "test-api-key-0123456789"
+
+	fetch := func(opts PollerOptions, status int) (http.Header, string, error) {
+		var header http.Header
+		var buf bytes.Buffer
+		ps := &PollerService{
+			logger: zerolog.New(&buf),
+			opts:   opts,
+			client: doerFunc(func(req *http.Request) *http.Response {
+				header = req.Header
+				return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(`[]`)), Header: make(http.Header)}
+			}),
+		}
+		_, err := ps.fetchDelegationBatch(context.Background(), 0, 0, "")
+		return header, buf.String(), err
+	}
+
+	t.Run("configured", func(t *testing.T) {
+		header, _, err := fetch(PollerOptions{APIKey: apiKey}, http.StatusOK)
+		assert.NoError(t, err)
+		assert.Equal(t, "Bearer "+apiKey, header.Get("Authorization"))
+	})
+
+	t.Run("not configured", func(t *testing.T) {
+		header, _, err := fetch(PollerOptions{}, http.StatusOK)
+		assert.NoError(t, err)
+		assert.Empty(t, header.Values("Authorization"))
+	})
+
+	t.Run("not logged on rejection", func(t *testing.T) {
+		_, logs, err := fetch(PollerOptions{APIKey: apiKey}, http.StatusUnauthorized)
+		assert.Error(t, err)
+		assert.NotContains(t, logs, apiKey)
+		assert.NotContains(t, err.Error(), apiKey)
+	})
+}
+
+func TestPollerService_fetchDelegationBatch_Jitter(t *testing.T) {
+	calls := 0
+	var backoffs []time.Duration
+	ps := &PollerService{
+		logger: zerolog.Nop(),
+		opts:   PollerOptions{Retry: RetryPolicy{MaxRetries: 4, InitialBackoff: 10 * time.Millisecond}},
+		// Deterministic jitter: record the backoff and wait half of it
+		jitter: func(d time.Duration) time.Duration {
+			backoffs = append(backoffs, d)
+			return d / 2
+		},
+		client: doerFunc(func(req *http.Request) *http.Response {
+			calls++
+			return &http.Response{
+				StatusCode: http.StatusBadGateway,
+				Body:       io.NopCloser(strings.NewReader("")),
+				Header:     make(http.Header),
+			}
+		}),
+	}
+
+	_, err := ps.fetchDelegationBatch(context.Background(), 0, 0, "")
+	assert.Error(t, err)
+	assert.Equal(t, 4, calls)
+	// Jitter is applied to the undisturbed exponential sequence
+	assert.Equal(t, []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond, 80 * time.Millisecond}, backoffs)
+}
+
+func TestPollerService_backoffWait(t *testing.T) {
+	ps := &PollerService{jitter: func(d time.Duration) time.Duration { return d }}
+
+	assert.Equal(t, time.Second, ps.backoffWait(time.Second, time.Minute))
+	assert.Equal(t, 100*time.Millisecond, ps.backoffWait(time.Second, 100*time.Millisecond), "capped by the remaining budget")
+	assert.Equal(t, time.Duration(0), ps.backoffWait(time.Second, -time.Second))
+
+	// The default full jitter stays within [0, backoff]
+	ps.jitter = nil
+	for i := 0; i < 1000; i++ {
+		wait := ps.backoffWait(time.Second, time.Minute)
+		assert.GreaterOrEqual(t, wait, time.Duration(0))
+		assert.LessOrEqual(t, wait, time.Second)
+	}
+}
+
+func TestRetryPolicy_withDefaults(t *testing.T) {
+	assert.Equal(t, RetryPolicy{MaxRetries: defaultMaxRetries, InitialBackoff: defaultInitialBackoff, MaxTotalWait: defaultMaxTotalWait}, RetryPolicy{}.withDefaults())
+
+	custom := RetryPolicy{MaxRetries: 3, InitialBackoff: time.Millisecond, MaxTotalWait: time.Second}
+	assert.Equal(t, custom, custom.withDefaults())
+	assert.Equal(t, custom, NewPoller(nil, zerolog.Nop(), PollerOptions{Retry: custom}).opts.Retry)
+}
+
+func TestNewPoller_RateLimit(t *testing.T) {
+	ps := NewPoller(nil, zerolog.Nop(), PollerOptions{RateLimit: 5})
+	assert.NotNil(t, ps.limiter)
+	assert.Equal(t, rate.Limit(5), ps.limiter.Limit())
+	assert.Equal(t, 5, ps.limiter.Burst())
+
+	assert.Nil(t, NewPoller(nil, zerolog.Nop(), PollerOptions{}).limiter)
+}
+
+func TestNewPoller_Transport(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		ps := NewPoller(nil, zerolog.Nop(), PollerOptions{})
+		client, ok := ps.client.(*http.Client)
+		if !assert.True(t, ok) {
+			return
+		}
+		transport, ok := client.Transport.(*http.Transport)
+		if !assert.True(t, ok) {
+			return
+		}
+		assert.Equal(t, 100, transport.MaxIdleConns)
+		assert.Equal(t, 10, transport.MaxIdleConnsPerHost)
+		assert.Equal(t, 90*time.Second, transport.IdleConnTimeout)
+		assert.Equal(t, 10*time.Second, transport.TLSHandshakeTimeout)
+		assert.Equal(t, 30*time.Second, client.Timeout)
+	})
+
+	t.Run("configured", func(t *testing.T) {
+		ps := NewPoller(nil, zerolog.Nop(), PollerOptions{Transport: TransportPolicy{
+			MaxIdleConns:        8,
+			MaxIdleConnsPerHost: 4,
+			IdleConnTimeout:     15 * time.Second,
+			TLSHandshakeTimeout: 3 * time.Second,
+			RequestTimeout:      time.Minute,
+		}})
+		client, ok := ps.client.(*http.Client)
+		if !assert.True(t, ok) {
+			return
+		}
+		transport, ok := client.Transport.(*http.Transport)
+		if !assert.True(t, ok) {
+			return
+		}
+		assert.Equal(t, 8, transport.MaxIdleConns)
+		assert.Equal(t, 4, transport.MaxIdleConnsPerHost)
+		assert.Equal(t, 15*time.Second, transport.IdleConnTimeout)
+		assert.Equal(t, 3*time.Second, transport.TLSHandshakeTimeout)
+		assert.Equal(t, time.Minute, client.Timeout)
+	})
+}
+
+func TestNewPoller_Client(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+	var urls []string
+	ps := NewPoller(repo, zerolog.Nop(), PollerOptions{Client: doerFunc(func(req *http.Request) *http.Response {
+		urls = append(urls, req.URL.String())
+		return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(`[]`)), Header: make(http.Header)}
+	})})
+
+	ctx := context.Background()
+	repo.EXPECT().GetLatestTzktID(ctx).Return(int64(41), nil)
+	repo.EXPECT().UpdateSyncState(ctx, gomock.Any()).Return(nil)
+
+	caughtUp, err := ps.syncDelegationsBatch(ctx)
+	assert.NoError(t, err)
+	assert.True(t, caughtUp)
+	if assert.Len(t, urls, 1) {
+		assert.Contains(t, urls[0], "id.gt=41")
+	}
+}
+
+func TestRateGate(t *testing.T) {
+	var g rateGate
+
+	// An open gate doesn't block
+	assert.NoError(t, g.Wait(context.Background()))
+
+	g.Pause(50 * time.Millisecond)
+	start := time.Now()
+	assert.NoError(t, g.Wait(context.Background()))
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+
+	// A cancelled wait returns the context error
+	g.Pause(time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, g.Wait(ctx), context.Canceled)
+}
+
+func TestPollerService_HistoricalSyncProgress(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+	repo.EXPECT().GetByTzktIDs(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	ps := &PollerService{repo: repo, logger: zerolog.Nop()}
+	ps.historical = historicalProgress{started: time.Now().Add(-time.Minute)}
+	ctx := context.Background()
+
+	batches := testutil.ToFloat64(metrics.PollerHistoricalBatches)
+	inserted := testutil.ToFloat64(metrics.PollerHistoricalInserted)
+	metrics.PollerHistoricalComplete.Set(0)
+
+	// A full batch mid-sync counts towards progress
+	repo.EXPECT().InsertDelegations(gomock.Any(), gomock.Any()).Return(int64(2), nil)
+	repo.EXPECT().UpdateSyncState(ctx, gomock.Any()).Return(nil)
+	caughtUp, err := ps.storeDelegationBatch(ctx, 0, []model.Delegation{{TzktID: 5, Delegator: "tz1"}, {TzktID: 9, Delegator: "tz1"}}, true)
+	assert.NoError(t, err)
+	assert.False(t, caughtUp)
+	assert.False(t, ps.HistoricalSyncComplete())
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.PollerHistoricalBatches)-batches)
+	assert.Equal(t, float64(2), testutil.ToFloat64(metrics.PollerHistoricalInserted)-inserted)
+	assert.Equal(t, float64(9), testutil.ToFloat64(metrics.PollerLastTzktID))
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.PollerHistoricalComplete))
+
+	// An empty batch means caught up: the sync is complete and its duration recorded
+	repo.EXPECT().UpdateSyncState(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, state model.SyncState) error {
+		assert.True(t, state.HistoricalComplete)
+		return nil
+	})
+	caughtUp, err = ps.storeDelegationBatch(ctx, 9, nil, false)
+	assert.NoError(t, err)
+	assert.True(t, caughtUp)
+	assert.True(t, ps.HistoricalSyncComplete())
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.PollerHistoricalComplete))
+	assert.GreaterOrEqual(t, testutil.ToFloat64(metrics.PollerHistoricalDuration), time.Minute.Seconds())
+
+	// Batches stored while polling don't count as historical progress
+	repo.EXPECT().InsertDelegations(gomock.Any(), gomock.Any()).Return(int64(1), nil)
+	repo.EXPECT().UpdateSyncState(ctx, gomock.Any()).Return(nil)
+	_, err = ps.storeDelegationBatch(ctx, 9, []model.Delegation{{TzktID: 10, Delegator: "tz1"}}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.PollerHistoricalBatches)-batches)
+	assert.Equal(t, float64(10), testutil.ToFloat64(metrics.PollerLastTzktID))
+}
+
+func TestPollerService_storeDelegationBatch_InsertBatchSize(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+	repo.EXPECT().GetByTzktIDs(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	ps := &PollerService{repo: repo, logger: zerolog.Nop(), opts: PollerOptions{InsertBatchSize: 2}}
+	ps.historicalComplete.Store(true)
+	ctx := context.Background()
+	page := []model.Delegation{{TzktID: 1, Delegator: "tz1"}, {TzktID: 2, Delegator: "tz1"}, {TzktID: 3, Delegator: "tz1"}, {TzktID: 4, Delegator: "tz1"}, {TzktID: 5, Delegator: "tz1"}}
+
+	// storeChunk expects the next sub-batch to hold tzktIDs, in order
+	storeChunk := func(tzktIDs ...int64) *gomock.Call {
+		return repo.EXPECT().InsertDelegations(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, delegations []*model.Delegation) (int64, error) {
+			ids := make([]int64, len(delegations))
+			for i, d := range delegations {
+				ids[i] = d.TzktID
+			}
+			assert.Equal(t, tzktIDs, ids)
+			return int64(len(delegations)), nil
+		})
+	}
+
+	t.Run("page split into sub-batches", func(t *testing.T) {
+		gomock.InOrder(
+			storeChunk(1, 2),
+			storeChunk(3, 4),
+			storeChunk(5),
+			repo.EXPECT().UpdateSyncState(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, state model.SyncState) error {
+				assert.Equal(t, int64(5), state.LastTzktID)
+				return nil
+			}),
+		)
+		caughtUp, err := ps.storeDelegationBatch(ctx, 0, page, true)
+		assert.NoError(t, err)
+		assert.False(t, caughtUp)
+	})
+
+	t.Run("failed sub-batch fails the page without recording progress", func(t *testing.T) {
+		gomock.InOrder(
+			storeChunk(1, 2),
+			repo.EXPECT().InsertDelegations(ctx, gomock.Any()).Return(int64(0), assert.AnError),
+		)
+		_, err := ps.storeDelegationBatch(ctx, 0, page, true)
+		assert.ErrorIs(t, err, assert.AnError)
+	})
+
+	t.Run("page no larger than the batch size stored at once", func(t *testing.T) {
+		storeChunk(1, 2)
+		repo.EXPECT().UpdateSyncState(ctx, gomock.Any()).Return(nil)
+		_, err := ps.storeDelegationBatch(ctx, 0, page[:2], false)
+		assert.NoError(t, err)
+	})
+}
+
+func TestPollerService_loadSyncState(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+	ps := &PollerService{repo: repo, logger: zerolog.Nop()}
+	ctx := context.Background()
+
+	t.Run("restores historical complete flag", func(t *testing.T) {
+		repo.EXPECT().GetSyncState(ctx).Return(&model.SyncState{LastTzktID: 10, HistoricalComplete: true}, nil)
+		ps.loadSyncState(ctx)
+		assert.True(t, ps.historicalComplete.Load())
+	})
+
+	t.Run("error keeps current flag", func(t *testing.T) {
+		ps.historicalComplete.Store(false)
+		repo.EXPECT().GetSyncState(ctx).Return(nil, errors.New("db error"))
+		ps.loadSyncState(ctx)
+		assert.False(t, ps.historicalComplete.Load())
+	})
+}
+
+func TestPollerService_acquirePollLock(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+	ps := &PollerService{repo: repo, logger: zerolog.Nop(), opts: PollerOptions{LockRetryInterval: time.Millisecond}}
+	ctx := context.Background()
+
+	t.Run("stands by until the lock is free", func(t *testing.T) {
+		gomock.InOrder(
+			repo.EXPECT().TryAcquirePollLock(ctx).Return(false, nil),
+			repo.EXPECT().GetSyncState(ctx).Return(&model.SyncState{HistoricalComplete: true}, nil),
+			repo.EXPECT().TryAcquirePollLock(ctx).Return(false, errors.New("db error")),
+			repo.EXPECT().TryAcquirePollLock(ctx).Return(true, nil),
+		)
+		assert.True(t, ps.acquirePollLock(ctx))
+		// The leader's progress was picked up while standing by
+		assert.True(t, ps.historicalComplete.Load())
+	})
+
+	t.Run("cancelled while standing by", func(t *testing.T) {
+		cancelCtx, cancel := context.WithCancel(ctx)
+		repo.EXPECT().TryAcquirePollLock(cancelCtx).DoAndReturn(func(context.Context) (bool, error) {
+			cancel()
+			return false, nil
+		})
+		assert.False(t, ps.acquirePollLock(cancelCtx))
+	})
+}
+
+func TestPollerService_holdsPollLock(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockDelegationRepositoryPort(ctrl)
+	ps := &PollerService{repo: repo, logger: zerolog.Nop()}
+	ctx := context.Background()
+
+	repo.EXPECT().TryAcquirePollLock(ctx).Return(true, nil)
+	assert.True(t, ps.holdsPollLock(ctx))
+
+	repo.EXPECT().TryAcquirePollLock(ctx).Return(false, nil)
+	assert.False(t, ps.holdsPollLock(ctx))
+
+	// A failed check keeps polling rather than handing over on a transient error
+	repo.EXPECT().TryAcquirePollLock(ctx).Return(false, errors.New("db error"))
+	assert.True(t, ps.holdsPollLock(ctx))
+}
+
+func TestPollerService_LatestTzktDelegation(t *testing.T) {
+	var urls []string
+	body := `[{"id":1098912000,"timestamp":"2022-05-05T06:31:44Z","amount":500,"sender":{"address":"tz1a1SAaXRt9yoGMx29rh9FsBF4UzmvojdTL"},"level":2338090}]`
+	ps := &PollerService{
+		logger: zerolog.Nop(),
+		client: doerFunc(func(req *http.Request) *http.Response {
+			urls = append(urls, req.URL.String())
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Header:     make(http.Header),
+			}
+		}),
+	}
+
+	latest, err := ps.LatestTzktDelegation(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, &model.Delegation{
+		TzktID:    1098912000,
+		Timestamp: time.Date(2022, 5, 5, 6, 31, 44, 0, time.UTC),
+		Amount:    500,
+		Delegator: "tz1a1SAaXRt9yoGMx29rh9FsBF4UzmvojdTL",
+		Level:     2338090,
+		Type:      model.OperationTypeDelegation,
+	}, latest)
+	assert.Equal(t, []string{"https://api.tzkt.io/v1/operations/delegations?limit=1&sort.desc=id&" + tzktDelegationSelect}, urls)
+
+	t.Run("cached", func(t *testing.T) {
+		again, err := ps.LatestTzktDelegation(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, latest, again)
+		assert.Len(t, urls, 1)
+	})
+
+	t.Run("refetched once stale", func(t *testing.T) {
+		ps.tzktLatest.fetchedAt = time.Now().Add(-tzktLatestTTL)
+		_, err := ps.LatestTzktDelegation(context.Background())
+		assert.NoError(t, err)
+		assert.Len(t, urls, 2)
+	})
+
+	t.Run("no delegations", func(t *testing.T) {
+		ps := &PollerService{
+			logger: zerolog.Nop(),
+			client: doerFunc(func(req *http.Request) *http.Response {
+				return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(`[]`)), Header: make(http.Header)}
+			}),
+		}
+		_, err := ps.LatestTzktDelegation(context.Background())
+		assert.True(t, apperrors.IsExternalAPIError(err))
+	})
+
+	t.Run("single attempt outside the circuit breaker", func(t *testing.T) {
+		calls := 0
+		ps := NewPoller(nil, zerolog.Nop(), PollerOptions{
+			RateLimit: 1,
+			Client: doerFunc(func(req *http.Request) *http.Response {
+				calls++
+				return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: io.NopCloser(strings.NewReader("")), Header: make(http.Header)}
+			}),
+		})
+
+		for range 10 {
+			_, err := ps.LatestTzktDelegation(context.Background())
+			assert.True(t, apperrors.IsExternalAPIError(err))
+		}
+		// No retries, no waiting on the one-per-second limiter, and the sync's breaker stays closed
+		assert.Equal(t, 10, calls)
+		assert.Equal(t, CircuitClosed, ps.CircuitState())
+	})
+
+	t.Run("sync rate limited", func(t *testing.T) {
+		ps := &PollerService{
+			logger: zerolog.Nop(),
+			client: doerFunc(func(req *http.Request) *http.Response {
+				t.Fatal("Tzkt called while the sync is paused")
+				return nil
+			}),
+		}
+		ps.gate.Pause(time.Minute)
+		_, err := ps.LatestTzktDelegation(context.Background())
+		assert.True(t, apperrors.IsExternalAPIError(err))
+	})
+}